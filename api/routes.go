@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"onlyflick/internal/handler"
 	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
 
 	"encoding/json"
 
@@ -21,8 +22,8 @@ func SetupRoutes() http.Handler {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:55273", "http://localhost:3000", "*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", middleware.IdempotencyKeyHeader},
+		ExposedHeaders:   []string{"Link", middleware.IdempotentReplayedHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -35,13 +36,33 @@ func SetupRoutes() http.Handler {
 		w.Write([]byte("✅ OnlyFlick API is running"))
 	})
 
+	// Clés publiques de vérification des access tokens (JWKS)
+	r.Get("/.well-known/jwks.json", handler.JWKSHandler)
+
 	// ========================
 	// Authentification
 	// ========================
 	r.Post("/register", handler.RegisterHandler)
 	r.Post("/login", handler.LoginHandler)
+	r.Post("/auth/refresh", handler.RefreshHandler)
+	r.With(middleware.JWTMiddleware).Post("/auth/logout", handler.LogoutHandler)
+	r.Get("/auth/verify-email", handler.VerifyEmailHandler)
+	r.Post("/auth/forgot-password", handler.ForgotPasswordHandler)
+	r.Post("/auth/reset-password", handler.ResetPasswordHandler)
+	r.Post("/auth/mfa/verify", handler.VerifyMFAHandler)
+	r.With(middleware.JWTMiddlewareWithRole()).Post("/auth/verify-email/resend", handler.ResendVerificationEmailHandler)
 	r.Get("/auth/check-username", handler.CheckUsernameHandler)
 
+	// Connexion via un fournisseur OpenID Connect (PKCE)
+	r.Get("/auth/oidc/providers", handler.ListOIDCProviders)
+	r.Get("/auth/oidc/{provider}/authorize", handler.StartOIDCLoginHandler)
+	r.Post("/auth/oidc/{provider}/callback", handler.OIDCCallbackHandler)
+
+	// ========================
+	// Webhooks (signature vérifiée par le handler)
+	// ========================
+	r.Post("/webhooks/stripe", handler.StripeWebhookHandler)
+
 	// ========================
 	// Utilisateur connecté (Profile)
 	// ========================
@@ -51,12 +72,34 @@ func SetupRoutes() http.Handler {
 		profile.Get("/", handler.ProfileHandler)
 		profile.Patch("/", handler.UpdateProfile)
 		profile.Delete("/", handler.DeleteAccount)
+		profile.Post("/export", handler.ExportMyData)
 		profile.Post("/request-upgrade", handler.RequestCreatorUpgrade)
 
 		profile.Get("/stats", handler.GetProfileStats)
 		profile.Get("/posts", handler.GetUserPosts)
 		profile.Post("/avatar", handler.UploadAvatar)
 		profile.Patch("/bio", handler.UpdateBio)
+
+		// Sessions ouvertes (appareils connectés)
+		profile.Get("/sessions", handler.ListMySessions)
+		profile.Delete("/sessions/{id}", handler.RevokeMySession)
+		profile.Post("/sessions/revoke-others", handler.RevokeMyOtherSessions)
+
+		// Double authentification (TOTP)
+		profile.Post("/mfa/enroll", handler.EnrollMFA)
+		profile.Post("/mfa/confirm", handler.ConfirmMFA)
+		profile.Post("/mfa/recovery-codes", handler.RegenerateMFARecoveryCodes)
+		profile.Delete("/mfa", handler.DisableMFAHandler)
+
+		// Comptes externes liés (OpenID Connect)
+		profile.Get("/identities", handler.ListMyIdentities)
+		profile.Post("/identities/{provider}", handler.LinkOIDCIdentity)
+		profile.Delete("/identities/{id}", handler.UnlinkMyIdentity)
+
+		// Jetons d'accès personnels (scripts et intégrations)
+		profile.Get("/tokens", handler.ListMyAccessTokens)
+		profile.Post("/tokens", handler.CreateMyAccessToken)
+		profile.Delete("/tokens/{id}", handler.DeleteMyAccessToken)
 	})
 
 	// ========================
@@ -64,25 +107,53 @@ func SetupRoutes() http.Handler {
 	// ========================
 	r.Route("/admin", func(admin chi.Router) {
 		admin.Use(middleware.JWTMiddlewareWithRole("admin"))
+		admin.Use(middleware.RequireAdminMFA)
 
 		// Tableau de bord de l'admin (statistiques globales)
-		admin.Get("/dashboard", handler.AdminDashboard)
+		admin.With(middleware.RequirePermission(policy.AdminDashboard)).Get("/dashboard", handler.AdminDashboard)
 
 		// Liste des demandes de créateurs en attente
-		admin.Get("/creator-requests", handler.ListCreatorRequests)
+		admin.With(middleware.RequirePermission(policy.CreatorRequestReview)).Get("/creator-requests", handler.ListCreatorRequests)
 
 		// Approuver ou rejeter une demande de créateur
-		admin.Post("/creator-requests/{id}/approve", handler.ApproveCreatorRequest)
-		admin.Post("/creator-requests/{id}/reject", handler.RejectCreatorRequest)
+		admin.With(middleware.RequirePermission(policy.CreatorRequestReview)).Post("/creator-requests/{id}/approve", handler.ApproveCreatorRequest)
+		admin.With(middleware.RequirePermission(policy.CreatorRequestReview)).Post("/creator-requests/{id}/reject", handler.RejectCreatorRequest)
+
+		// Recherche d'utilisateurs par e-mail exact, username, prénom ou nom
+		admin.With(middleware.RequirePermission(policy.UserList)).Get("/users", handler.AdminSearchUsers)
 
 		// Supprimer un utilisateur par ID
-		admin.Delete("/users/{id}", handler.DeleteAccountByID)
+		admin.With(middleware.RequirePermission(policy.UserDelete)).Delete("/users/{id}", handler.DeleteAccountByID)
+
+		// Réinitialisation de la double authentification d'un utilisateur (appareil perdu)
+		admin.With(middleware.RequirePermission(policy.UserSecurityManage)).Delete("/users/{id}/mfa", handler.AdminResetUserMFA)
+
+		// Déverrouillage d'un compte bloqué après trop d'échecs de connexion
+		admin.With(middleware.RequirePermission(policy.UserSecurityManage)).Post("/users/{id}/unlock", handler.UnlockAccountByID)
+
+		// Suspensions de comptes (durée ou définitive) et levée
+		admin.With(middleware.RequirePermission(policy.UserSuspend)).Get("/suspensions", handler.ListSuspensions)
+		admin.With(middleware.RequirePermission(policy.UserSuspend)).Post("/users/{id}/suspend", handler.SuspendUserByID)
+		admin.With(middleware.RequirePermission(policy.UserSuspend)).Delete("/users/{id}/suspension", handler.LiftSuspensionByID)
+
+		// Périmètre d'un administrateur (moderator, finance, support)
+		admin.With(middleware.RequirePermission(policy.AdminRoleManage)).Put("/users/{id}/admin-role", handler.SetAdminRoleByID)
 
 		// Liste des créateurs avec leurs statistiques
-		admin.Get("/creators", handler.ListCreators)
+		admin.With(middleware.RequirePermission(policy.CreatorRead)).Get("/creators", handler.ListCreators)
 
 		// Détails d'un créateur spécifique
-		admin.Get("/creator/{id}", handler.GetCreatorDetails)
+		admin.With(middleware.RequirePermission(policy.CreatorRead)).Get("/creator/{id}", handler.GetCreatorDetails)
+
+		// Remboursement total ou partiel d'un paiement
+		admin.With(middleware.RequirePermission(policy.PaymentRefund), middleware.IdempotencyMiddleware).Post("/payments/{id}/refund", handler.RefundPaymentByID)
+
+		// Lots de versements aux créateurs (export CSV)
+		admin.With(middleware.RequirePermission(policy.PayoutManage), middleware.IdempotencyMiddleware).Post("/payouts", handler.CreatePayoutBatch)
+		admin.With(middleware.RequirePermission(policy.PayoutManage)).Get("/payouts/{id}/export", handler.ExportPayoutBatch)
+
+		// Journal d'audit des actions d'administration et de modération (admin complet)
+		admin.With(middleware.RequirePermission(policy.AuditRead)).Get("/audit", handler.ListAuditLog)
 	})
 
 	// ========================
@@ -91,25 +162,49 @@ func SetupRoutes() http.Handler {
 	r.Route("/creator", func(creator chi.Router) {
 		creator.Use(middleware.JWTMiddlewareWithRole("creator"))
 
-		creator.Post("/posts", handler.CreatePost)
+		creator.With(middleware.RequireVerifiedEmail).Post("/posts", handler.CreatePost)
 		creator.Get("/posts", handler.ListMyPosts)
+
+		// Tarif mensuel et paliers d'abonnement
+		creator.Get("/pricing", handler.GetMyPricing)
+		creator.Put("/pricing", handler.UpdateMyPricing)
+		creator.Post("/tiers", handler.CreateTier)
+		creator.Patch("/tiers/{id}", handler.UpdateTier)
+		creator.Delete("/tiers/{id}", handler.DeleteTier)
+
+		// Codes promo
+		creator.Get("/promo-codes", handler.ListMyPromoCodes)
+		creator.Post("/promo-codes", handler.CreatePromoCode)
+		creator.Delete("/promo-codes/{id}", handler.DeletePromoCode)
+
+		// Revenus et relevés mensuels
+		creator.Get("/earnings", handler.GetMyEarnings)
+		creator.Get("/earnings/statements", handler.GetMyStatements)
 	})
 
 	// ========================
 	// Posts publics et abonnés
 	// ========================
-	r.Get("/posts/all", handler.ListAllVisiblePosts)
+	// Authentification facultative : un visiteur voit le fil public, un lecteur connecté ses posts débloqués
+	r.With(middleware.JWTMiddleware).Get("/posts/all", handler.ListAllVisiblePosts)
 	r.With(middleware.JWTMiddleware).Get("/posts/recommended", handler.GetRecommendedPosts)
 	r.With(middleware.JWTMiddleware).Get("/posts/from/{creator_id}", handler.ListPostsFromCreator)
 	r.With(middleware.JWTMiddleware).Get("/posts/from/{creator_id}/subscriber-only", handler.ListSubscriberOnlyPostsFromCreator)
 
+	// Achat d'un post payant à l'unité
+	r.With(middleware.JWTMiddleware, middleware.IdempotencyMiddleware).Post("/posts/{id}/unlock", handler.UnlockPost)
+
+	// Pourboires aux créateurs (profil, post ou conversation)
+	r.With(middleware.JWTMiddleware, middleware.IdempotencyMiddleware).Post("/tips", handler.CreateTip)
+	r.With(middleware.JWTMiddleware).Get("/posts/{id}/tips", handler.ListPostTips)
+
 	// ========================
 	// Gestion des posts (Creator/Admin)
 	// ========================
 	r.Route("/posts", func(p chi.Router) {
 		p.Use(middleware.JWTMiddlewareWithRole("creator", "admin"))
 
-		p.Post("/", handler.CreatePost)
+		p.With(middleware.RequireVerifiedEmail).Post("/", handler.CreatePost)
 		p.Get("/me", handler.ListMyPosts)
 		p.Get("/{id}", handler.GetPostByID)
 
@@ -162,7 +257,7 @@ func SetupRoutes() http.Handler {
 		users.Use(middleware.JWTMiddleware)
 
 		// Liste des utilisateurs (privée, admin uniquement)
-		users.With(middleware.JWTMiddlewareWithRole("admin")).Get("/all", handler.GetAllUsersHandler)
+		users.With(middleware.JWTMiddlewareWithRole("admin"), middleware.RequireAdminMFA, middleware.RequirePermission(policy.UserList)).Get("/all", handler.GetAllUsersHandler)
 
 		// Obtenir le profil public d'un utilisateur
 		users.Get("/{user_id}", handler.GetUserProfileHandler)
@@ -175,6 +270,9 @@ func SetupRoutes() http.Handler {
 
 		users.Get("/{user_id}/followers", handler.GetUserFollowersHandler)
 		users.Get("/{user_id}/following", handler.GetUserFollowingHandler)
+
+		// Catalogue d'abonnement d'un créateur
+		users.Get("/{user_id}/pricing", handler.GetCreatorPricingHandler)
 	})
 	// ========================
 	// 📊 TRACKING DES INTERACTIONS
@@ -190,13 +288,13 @@ func SetupRoutes() http.Handler {
 		s.Use(middleware.JWTMiddlewareWithRole("subscriber", "creator", "admin"))
 
 		// 🔥 NOUVEAU : S'abonner à un créateur (sans paiement immédiat)
-		s.Post("/{creator_id}", handler.Subscribe)
+		s.With(middleware.RequireVerifiedEmail, middleware.IdempotencyMiddleware).Post("/{creator_id}", handler.Subscribe)
 
 		// Route pour s'abonner à un créateur avec paiement Stripe
-		s.Post("/{creator_id}/payment", handler.SubscribeWithPayment)
+		s.With(middleware.RequireVerifiedEmail, middleware.IdempotencyMiddleware).Post("/{creator_id}/payment", handler.SubscribeWithPayment)
 
 		// Route pour se désabonner d'un créateur
-		s.Delete("/{creator_id}", handler.UnSubscribe)
+		s.With(middleware.IdempotencyMiddleware).Delete("/{creator_id}", handler.UnSubscribe)
 
 		// Route pour récupérer la liste des abonnements d'un utilisateur
 		s.Get("/", handler.ListMySubscriptions)
@@ -210,7 +308,7 @@ func SetupRoutes() http.Handler {
 	// ========================
 	r.Route("/comments", func(c chi.Router) {
 		c.Use(middleware.JWTMiddleware)
-		c.Post("/", handler.CreateComment)
+		c.With(middleware.RequireVerifiedEmail).Post("/", handler.CreateComment)
 		c.Delete("/{id}", handler.DeleteComment)
 		c.Get("/post/{post_id}", handler.GetComments)
 	})
@@ -232,10 +330,10 @@ func SetupRoutes() http.Handler {
 		rep.With(middleware.JWTMiddleware).Post("/", handler.CreateReport)
 
 		// Gestion des signalements (admin)
-		rep.With(middleware.JWTMiddlewareWithRole("admin")).Get("/", handler.ListReports)
-		rep.With(middleware.JWTMiddlewareWithRole("admin")).Get("/pending", handler.ListPendingReports)
-		rep.With(middleware.JWTMiddlewareWithRole("admin")).Patch("/{id}", handler.UpdateReportStatus)
-		rep.With(middleware.JWTMiddlewareWithRole("admin")).Post("/{id}/action", handler.AdminActOnReport)
+		rep.With(middleware.JWTMiddlewareWithRole("admin"), middleware.RequireAdminMFA, middleware.RequirePermission(policy.ReportRead)).Get("/", handler.ListReports)
+		rep.With(middleware.JWTMiddlewareWithRole("admin"), middleware.RequireAdminMFA, middleware.RequirePermission(policy.ReportRead)).Get("/pending", handler.ListPendingReports)
+		rep.With(middleware.JWTMiddlewareWithRole("admin"), middleware.RequireAdminMFA, middleware.RequirePermission(policy.ReportModerate)).Patch("/{id}", handler.UpdateReportStatus)
+		rep.With(middleware.JWTMiddlewareWithRole("admin"), middleware.RequireAdminMFA, middleware.RequirePermission(policy.ReportModerate)).Post("/{id}/action", handler.AdminActOnReport)
 	})
 
 	// ========================
@@ -245,10 +343,10 @@ func SetupRoutes() http.Handler {
 		mr.Use(middleware.JWTMiddleware)
 
 		mr.Get("/", handler.GetMyConversations)
-		mr.Post("/{receiverId}", handler.StartConversation)
+		mr.With(middleware.RequireVerifiedEmail).Post("/{receiverId}", handler.StartConversation)
 
 		mr.Get("/{id}/messages", handler.GetMessagesInConversation)
-		mr.Post("/{id}/messages", handler.SendMessageInConversation)
+		mr.With(middleware.RequireVerifiedEmail).Post("/{id}/messages", handler.SendMessageInConversation)
 	})
 
 	// ========================
@@ -256,6 +354,7 @@ func SetupRoutes() http.Handler {
 	// ========================
 	r.Route("/ws", func(wsRouter chi.Router) {
 		wsRouter.Use(middleware.WebSocketJWTMiddleware)
+		wsRouter.Use(middleware.RequireVerifiedEmail)
 		wsRouter.Get("/messages/{conversation_id}", handler.HandleMessagesWebSocket)
 	})
 
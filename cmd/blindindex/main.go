@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"onlyflick/internal/config"
+	"onlyflick/internal/database"
+	"onlyflick/internal/service"
+	"onlyflick/internal/utils"
+)
+
+// Commande de calcul des index aveugles des utilisateurs (e-mail et noms). Les index manquants
+// sont complétés au démarrage du serveur ; à lancer avec -all après un changement de BLIND_INDEX_KEY :
+//
+//	go run ./cmd/blindindex -batch-size 500
+func main() {
+	batchSize := flag.Int("batch-size", 500, "nombre d'utilisateurs lus par lot")
+	all := flag.Bool("all", false, "recalcule les index de tous les utilisateurs, y compris ceux déjà indexés")
+	flag.Parse()
+
+	config.LoadEnv()
+	if err := utils.LoadBlindIndexKey(); err != nil {
+		log.Fatalf("❌ [BLINDINDEX] %v", err)
+	}
+	database.Init()
+	defer database.DB.Close()
+
+	stats, err := service.BackfillUserBlindIndexes(*batchSize, *all)
+	if err != nil {
+		log.Fatalf("❌ [BLINDINDEX] Échec après %d utilisateurs lus : %v", stats.Scanned, err)
+	}
+	if stats.Failed > 0 {
+		log.Printf("⚠️  [BLINDINDEX] %d utilisateurs ignorés (voir les journaux ci-dessus)", stats.Failed)
+	}
+	log.Printf("✅ [BLINDINDEX] Terminé : %d utilisateurs lus, %d indexés", stats.Scanned, stats.Indexed)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"onlyflick/internal/config"
+	"onlyflick/internal/database"
+	"onlyflick/internal/service"
+)
+
+// Commande de rechiffrement des données personnelles vers la clé courante
+// (ENCRYPTION_KEY_ID), à lancer après une rotation de clé ou pour chiffrer
+// les lignes historiques stockées en clair :
+//
+//	go run ./cmd/reencrypt -batch-size 500
+//
+// L'ancienne clé doit rester dans ENCRYPTION_KEYS jusqu'à la fin du passage.
+func main() {
+	batchSize := flag.Int("batch-size", 500, "nombre de lignes lues par lot")
+	dryRun := flag.Bool("dry-run", false, "compte les valeurs à rechiffrer sans les écrire")
+	flag.Parse()
+
+	config.LoadEnv()
+	database.Init()
+	defer database.DB.Close()
+
+	stats, err := service.ReencryptPersonalData(*batchSize, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ [REENCRYPT] Échec du rechiffrement après %d valeurs lues : %v", stats.Scanned, err)
+	}
+	if stats.Conflicts > 0 {
+		log.Printf("⚠️  [REENCRYPT] %d valeurs modifiées pendant le passage : relancer la commande", stats.Conflicts)
+	}
+	log.Printf("✅ [REENCRYPT] Terminé : %d valeurs lues, %d rechiffrées", stats.Scanned, stats.Reencrypted)
+}
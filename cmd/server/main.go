@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"onlyflick/api"
 	"onlyflick/internal/config"
 	"onlyflick/internal/database"
 	"onlyflick/internal/service"
+	"onlyflick/internal/utils"
 	"os"
 )
 
@@ -34,6 +36,48 @@ func main() {
 	service.InitImageKit()
 	log.Println("[SERVICE] Service ImageKit initialisé.")
 
+	// Initialisation du fournisseur de paiement (PAYMENT_PROVIDER=stripe|fake)
+	log.Println("[SERVICE] Initialisation du fournisseur de paiement...")
+	service.InitPaymentProvider()
+
+	// Clé des index aveugles (BLIND_INDEX_KEY) : une clé invalide fausserait toutes les recherches
+	log.Println("[SERVICE] Vérification de la clé des index aveugles...")
+	if err := utils.LoadBlindIndexKey(); err != nil {
+		log.Fatalf("[SERVICE] ❌ Clé des index aveugles invalide : %v", err)
+	}
+
+	// Index aveugles des utilisateurs antérieurs à leur introduction : la recherche par e-mail n'utilise que l'index
+	log.Println("[BD] Calcul des index aveugles manquants...")
+	stats, err := service.BackfillUserBlindIndexes(500, false)
+	if err != nil {
+		log.Fatalf("[BD] ❌ Calcul des index aveugles impossible : %v", err)
+	}
+	if stats.Failed > 0 {
+		log.Printf("[BD] ⚠️  %d utilisateurs sans index aveugle (e-mail illisible ou en double) ne pourront pas se connecter", stats.Failed)
+	}
+
+	// Chargement des clés de signature des access tokens (JWT_KEYS_DIR)
+	log.Println("[SERVICE] Chargement des clés JWT...")
+	if err := service.InitJWTKeys(); err != nil {
+		log.Fatalf("[SERVICE] ❌ Chargement des clés JWT impossible : %v", err)
+	}
+
+	// Initialisation de l'envoi d'e-mails (MAIL_PROVIDER=smtp|log)
+	log.Println("[SERVICE] Initialisation de l'envoi d'e-mails...")
+	service.InitMailer()
+
+	// Fournisseurs de connexion OpenID Connect (OIDC_PROVIDERS)
+	log.Println("[SERVICE] Chargement des fournisseurs OpenID Connect...")
+	service.InitOIDCProviders()
+
+	// Démarrage du planificateur de renouvellement / expiration des abonnements
+	log.Println("[SERVICE] Démarrage du planificateur des abonnements...")
+	go service.StartSubscriptionScheduler(context.Background(), service.LoadSchedulerConfig())
+
+	// Purge des données expirées et des comptes supprimés (toujours active)
+	log.Println("[SERVICE] Démarrage de la purge des données expirées...")
+	go service.StartMaintenanceCleanup(context.Background(), service.LoadCleanupConfig())
+
 	// Configuration des routes de l'API
 	log.Println("[ROUTAGE] Configuration des routes de l'API...")
 	router := api.SetupRoutes()
@@ -2,8 +2,10 @@ package config
 
 import (
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -42,3 +44,22 @@ func LoadEnv() {
 
 	log.Println("[CONFIG] ✅ Toutes les variables d'environnement requises sont définies.")
 }
+
+// DefaultCommissionRate est la commission par défaut de la plateforme, en points de base (20 %).
+const DefaultCommissionRate = 2000
+
+// PlatformCommissionRate retourne la commission prélevée sur chaque paiement, en points de base,
+// lue depuis PLATFORM_COMMISSION_PERCENT (ex. "20" ou "12.5").
+func PlatformCommissionRate() int {
+	raw := os.Getenv("PLATFORM_COMMISSION_PERCENT")
+	if raw == "" {
+		return DefaultCommissionRate
+	}
+
+	percent, err := strconv.ParseFloat(raw, 64)
+	if err != nil || percent < 0 || percent > 100 {
+		log.Printf("[CONFIG] ⚠️  PLATFORM_COMMISSION_PERCENT invalide (%s), utilisation de %d points de base", raw, DefaultCommissionRate)
+		return DefaultCommissionRate
+	}
+	return int(math.Round(percent * 100))
+}
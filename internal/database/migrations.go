@@ -19,6 +19,29 @@ func RunMigrations() {
 	runConversationsMigration()
 	runMessagesMigration()
 	runPaymentsMigration()
+	runStripeEventsMigration()
+	runPricingMigration()
+	runBillingMigration()
+	runLedgerMigration()
+	runRefundsMigration()
+	runPromoCodesMigration()
+	runPostPurchasesMigration()
+	runTipsMigration()
+	runIdempotencyKeysMigration()
+	runAuthSessionsMigration()
+	runSessionDevicesMigration()
+	runEmailVerificationMigration()
+	runPasswordResetMigration()
+	runMFAMigration()
+	runLoginThrottlesMigration()
+	runUserIdentitiesMigration()
+	runPersonalAccessTokensMigration()
+	runAdminRolesMigration()
+	runMediaFilesMigration()
+	runUserBlindIndexMigration()
+	runAccountDeletionMigration()
+	runUserSuspensionsMigration()
+	runAdminAuditLogMigration()
 
 	// NOUVELLES MIGRATIONS POUR LE SYSTÈME DE RECHERCHE
 	runUsersUpdateMigration()        // Mise à jour table users avec username, avatar_url, bio
@@ -282,6 +305,724 @@ func runPaymentsMigration() {
 	log.Println("✅ [payments] Table 'payments' migrée avec succès.")
 }
 
+// runStripeEventsMigration crée la table 'stripe_events' utilisée pour l'idempotence du webhook Stripe.
+func runStripeEventsMigration() {
+	log.Println("➡️  [stripe_events] Migration de la table 'stripe_events'...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS stripe_events (
+		event_id TEXT PRIMARY KEY,
+		type VARCHAR(100) NOT NULL,
+		processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_payments_stripe_payment_id ON payments(stripe_payment_id);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [stripe_events] Échec de la migration de la table 'stripe_events' : %v", err)
+	}
+	log.Println("✅ [stripe_events] Table 'stripe_events' migrée avec succès.")
+}
+
+// runPricingMigration crée les tables de tarification des créateurs et lie les abonnements à un palier.
+func runPricingMigration() {
+	log.Println("➡️  [pricing] Migration des tables 'creator_pricing' et 'subscription_tiers'...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS creator_pricing (
+		creator_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		price INT NOT NULL CHECK (price > 0),
+		currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS subscription_tiers (
+		id SERIAL PRIMARY KEY,
+		creator_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(50) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		price INT NOT NULL CHECK (price > 0),
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_subscription_tiers_creator ON subscription_tiers(creator_id);
+
+	-- Palier auquel l'abonnement a été souscrit (NULL = tarif de base)
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name='subscriptions' AND column_name='tier_id'
+		) THEN
+			ALTER TABLE subscriptions ADD COLUMN tier_id BIGINT REFERENCES subscription_tiers(id) ON DELETE SET NULL;
+		END IF;
+	END$$;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [pricing] Échec de la migration des tables de tarification : %v", err)
+	}
+	log.Println("✅ [pricing] Tables de tarification migrées avec succès.")
+}
+
+// runBillingMigration ajoute les colonnes nécessaires au renouvellement automatique des abonnements.
+func runBillingMigration() {
+	log.Println("➡️  [billing] Ajout des colonnes de renouvellement des abonnements...")
+
+	query := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS stripe_customer_id TEXT;
+
+	ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS auto_renew BOOLEAN NOT NULL DEFAULT TRUE;
+	ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS renewal_attempts INT NOT NULL DEFAULT 0;
+	ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS next_renewal_at TIMESTAMPTZ;
+	ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS renewal_claimed_until TIMESTAMPTZ;
+	ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS payment_method_id TEXT;
+
+	CREATE INDEX IF NOT EXISTS idx_subscriptions_renewal ON subscriptions(end_at) WHERE status = TRUE;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [billing] Échec de la migration des colonnes de renouvellement : %v", err)
+	}
+	log.Println("✅ [billing] Colonnes de renouvellement ajoutées avec succès.")
+}
+
+// runLedgerMigration crée le grand livre des revenus des créateurs (partie double) et les lots de versements.
+func runLedgerMigration() {
+	log.Println("➡️  [ledger] Migration du grand livre des revenus...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS payout_batches (
+		id SERIAL PRIMARY KEY,
+		created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+		cutoff TIMESTAMPTZ NOT NULL,
+		total_amount BIGINT NOT NULL DEFAULT 0,
+		creator_count INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	-- Les opérations sont conservées après la suppression d'un créateur ou d'un paiement
+	CREATE TABLE IF NOT EXISTS ledger_transactions (
+		id BIGSERIAL PRIMARY KEY,
+		kind VARCHAR(20) NOT NULL CHECK (kind IN ('payment', 'refund', 'payout')),
+		creator_id BIGINT NOT NULL,
+		payment_id BIGINT REFERENCES payments(id) ON DELETE SET NULL,
+		gross_amount BIGINT NOT NULL,
+		commission_amount BIGINT NOT NULL DEFAULT 0,
+		net_amount BIGINT NOT NULL,
+		commission_rate INT NOT NULL DEFAULT 0,
+		currency VARCHAR(3) NOT NULL,
+		balance_after BIGINT NOT NULL,
+		payout_batch_id BIGINT REFERENCES payout_batches(id),
+		paid_out_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS ux_ledger_transactions_payment ON ledger_transactions(payment_id) WHERE kind = 'payment';
+	CREATE INDEX IF NOT EXISTS idx_ledger_transactions_creator ON ledger_transactions(creator_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_ledger_transactions_unpaid ON ledger_transactions(creator_id) WHERE payout_batch_id IS NULL;
+
+	CREATE TABLE IF NOT EXISTS ledger_entries (
+		id BIGSERIAL PRIMARY KEY,
+		transaction_id BIGINT NOT NULL REFERENCES ledger_transactions(id),
+		account VARCHAR(30) NOT NULL,
+		creator_id BIGINT,
+		currency VARCHAR(3) NOT NULL,
+		debit BIGINT NOT NULL DEFAULT 0 CHECK (debit >= 0),
+		credit BIGINT NOT NULL DEFAULT 0 CHECK (credit >= 0),
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON ledger_entries(account, creator_id);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [ledger] Échec de la migration du grand livre : %v", err)
+	}
+	log.Println("✅ [ledger] Grand livre des revenus migré avec succès.")
+}
+
+// runRefundsMigration lie les lignes de remboursement de 'payments' à leur paiement d'origine.
+func runRefundsMigration() {
+	log.Println("➡️  [refunds] Ajout des remboursements à la table 'payments'...")
+
+	query := `
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS refund_of BIGINT REFERENCES payments(id) ON DELETE CASCADE;
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS refund_reason TEXT NOT NULL DEFAULT '';
+
+	CREATE INDEX IF NOT EXISTS idx_payments_refund_of ON payments(refund_of) WHERE refund_of IS NOT NULL;
+	CREATE UNIQUE INDEX IF NOT EXISTS ux_payments_refund_provider_id ON payments(stripe_payment_id)
+		WHERE refund_of IS NOT NULL AND stripe_payment_id <> '';
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [refunds] Échec de la migration des remboursements : %v", err)
+	}
+	log.Println("✅ [refunds] Remboursements ajoutés avec succès.")
+}
+
+// runPromoCodesMigration crée les codes promo des créateurs et ajoute l'essai gratuit aux tarifs et abonnements.
+func runPromoCodesMigration() {
+	log.Println("➡️  [promo_codes] Migration des codes promo et essais gratuits...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS promo_codes (
+		id BIGSERIAL PRIMARY KEY,
+		creator_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code VARCHAR(32) NOT NULL,
+		discount_type VARCHAR(10) NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+		discount_value INT NOT NULL CHECK (discount_value > 0),
+		max_redemptions INT CHECK (max_redemptions > 0),
+		redemptions_count INT NOT NULL DEFAULT 0,
+		expires_at TIMESTAMPTZ,
+		first_period_only BOOLEAN NOT NULL DEFAULT FALSE,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	-- Les codes sont stockés en majuscules et uniques par créateur
+	CREATE UNIQUE INDEX IF NOT EXISTS ux_promo_codes_creator_code ON promo_codes(creator_id, code);
+
+	-- Un abonné ne peut utiliser un code qu'une seule fois
+	CREATE TABLE IF NOT EXISTS promo_code_redemptions (
+		id BIGSERIAL PRIMARY KEY,
+		promo_code_id BIGINT NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		subscription_id BIGINT NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (promo_code_id, user_id)
+	);
+	-- Paiement de la première période : l'utilisation est libérée s'il échoue
+	ALTER TABLE promo_code_redemptions ADD COLUMN IF NOT EXISTS payment_id BIGINT REFERENCES payments(id) ON DELETE SET NULL;
+
+	ALTER TABLE creator_pricing ADD COLUMN IF NOT EXISTS trial_days INT NOT NULL DEFAULT 0;
+
+	-- Réduction restant à appliquer aux prochains débits et fin de l'essai gratuit
+	ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS promo_code_id BIGINT REFERENCES promo_codes(id) ON DELETE SET NULL;
+	ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS trial_end_at TIMESTAMPTZ;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [promo_codes] Échec de la migration des codes promo : %v", err)
+	}
+	log.Println("✅ [promo_codes] Codes promo et essais gratuits migrés avec succès.")
+}
+
+// runPostPurchasesMigration ajoute les posts payants à l'unité et leurs achats.
+// Les achats sont des paiements sans abonnement, rattachés directement au créateur.
+func runPostPurchasesMigration() {
+	log.Println("➡️  [post_purchases] Migration des posts payants à l'unité...")
+
+	query := `
+	ALTER TABLE posts ADD COLUMN IF NOT EXISTS price INT NOT NULL DEFAULT 0;
+
+	-- Paiements ponctuels (achat de post) : pas d'abonnement, créateur renseigné
+	ALTER TABLE payments ALTER COLUMN subscription_id DROP NOT NULL;
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'subscription';
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS creator_id BIGINT REFERENCES users(id) ON DELETE SET NULL;
+
+	CREATE TABLE IF NOT EXISTS post_purchases (
+		id BIGSERIAL PRIMARY KEY,
+		post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+		buyer_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_post_purchases_buyer ON post_purchases(buyer_id, post_id);
+	CREATE UNIQUE INDEX IF NOT EXISTS ux_post_purchases_payment ON post_purchases(payment_id);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [post_purchases] Échec de la migration des posts payants : %v", err)
+	}
+	log.Println("✅ [post_purchases] Posts payants à l'unité migrés avec succès.")
+}
+
+// runTipsMigration crée la table 'tips' (pourboires liés à un paiement) et les messages de type pourboire.
+func runTipsMigration() {
+	log.Println("➡️  [tips] Migration des pourboires...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS tips (
+		id BIGSERIAL PRIMARY KEY,
+		sender_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		creator_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		payment_id BIGINT NOT NULL UNIQUE REFERENCES payments(id) ON DELETE CASCADE,
+		amount INT NOT NULL CHECK (amount > 0),
+		message TEXT NOT NULL DEFAULT '',
+		post_id BIGINT REFERENCES posts(id) ON DELETE SET NULL,
+		conversation_id BIGINT REFERENCES conversations(id) ON DELETE SET NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tips_post ON tips(post_id) WHERE post_id IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS idx_tips_creator ON tips(creator_id, created_at);
+
+	-- Messages de type pourboire dans le fil des conversations
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'text';
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS tip_id BIGINT REFERENCES tips(id) ON DELETE SET NULL;
+	CREATE UNIQUE INDEX IF NOT EXISTS ux_messages_tip ON messages(tip_id) WHERE tip_id IS NOT NULL;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [tips] Échec de la migration des pourboires : %v", err)
+	}
+	log.Println("✅ [tips] Pourboires migrés avec succès.")
+}
+
+// runIdempotencyKeysMigration crée la table 'idempotency_keys' (réponses mémorisées des requêtes rejouables).
+func runIdempotencyKeysMigration() {
+	log.Println("➡️  [idempotency_keys] Migration des clés d'idempotence...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL DEFAULT 0,
+		idem_key TEXT NOT NULL,
+		fingerprint TEXT NOT NULL,
+		status_code INT NOT NULL DEFAULT 0,
+		content_type TEXT NOT NULL DEFAULT '',
+		response_body BYTEA,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMPTZ NOT NULL,
+		UNIQUE (user_id, idem_key)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [idempotency_keys] Échec de la migration des clés d'idempotence : %v", err)
+	}
+	log.Println("✅ [idempotency_keys] Clés d'idempotence migrées avec succès.")
+}
+
+// runAuthSessionsMigration crée les tables 'auth_sessions' et 'refresh_tokens' (refresh tokens hachés, rotation par session).
+func runAuthSessionsMigration() {
+	log.Println("➡️  [auth_sessions] Migration des sessions et refresh tokens...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS auth_sessions (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		revoked_at TIMESTAMPTZ,
+		revoked_reason VARCHAR(20) NOT NULL DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions(user_id) WHERE revoked_at IS NULL;
+
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id BIGSERIAL PRIMARY KEY,
+		session_id BIGINT NOT NULL REFERENCES auth_sessions(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at TIMESTAMPTZ NOT NULL,
+		rotated_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(session_id);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [auth_sessions] Échec de la migration des sessions : %v", err)
+	}
+	log.Println("✅ [auth_sessions] Sessions et refresh tokens migrés avec succès.")
+}
+
+// runSessionDevicesMigration ajoute aux sessions l'appareil (user agent, IP) et la date de dernière activité.
+func runSessionDevicesMigration() {
+	log.Println("➡️  [session_devices] Migration des appareils de session...")
+
+	query := `
+	ALTER TABLE auth_sessions ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+	ALTER TABLE auth_sessions ADD COLUMN IF NOT EXISTS ip_address TEXT NOT NULL DEFAULT '';
+	ALTER TABLE auth_sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [session_devices] Échec de la migration des appareils de session : %v", err)
+	}
+	log.Println("✅ [session_devices] Appareils de session migrés avec succès.")
+}
+
+// runEmailVerificationMigration ajoute la confirmation d'adresse e-mail aux utilisateurs.
+// Les comptes existant avant la migration sont considérés comme vérifiés.
+func runEmailVerificationMigration() {
+	log.Println("➡️  [email_verification] Migration de la vérification des e-mails...")
+
+	query := `
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'users' AND column_name = 'email_verified_at'
+		) THEN
+			ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+			UPDATE users SET email_verified_at = created_at;
+		END IF;
+	END $$;
+
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verification_sent_at TIMESTAMPTZ;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [email_verification] Échec de la migration de la vérification des e-mails : %v", err)
+	}
+	log.Println("✅ [email_verification] Vérification des e-mails migrée avec succès.")
+}
+
+// runPasswordResetMigration crée les tables 'password_reset_tokens' (jetons hachés à usage unique)
+// et 'password_reset_attempts' (journal des demandes pour la limitation par e-mail et par IP).
+func runPasswordResetMigration() {
+	log.Println("➡️  [password_reset] Migration de la réinitialisation des mots de passe...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS password_reset_tokens (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at TIMESTAMPTZ NOT NULL,
+		used_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens(user_id) WHERE used_at IS NULL;
+
+	CREATE TABLE IF NOT EXISTS password_reset_attempts (
+		id BIGSERIAL PRIMARY KEY,
+		kind VARCHAR(10) NOT NULL CHECK (kind IN ('forgot', 'reset')),
+		email_hash TEXT NOT NULL DEFAULT '',
+		ip_address TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_password_reset_attempts_email ON password_reset_attempts(kind, email_hash, created_at);
+	CREATE INDEX IF NOT EXISTS idx_password_reset_attempts_ip ON password_reset_attempts(kind, ip_address, created_at);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [password_reset] Échec de la migration de la réinitialisation des mots de passe : %v", err)
+	}
+	log.Println("✅ [password_reset] Réinitialisation des mots de passe migrée avec succès.")
+}
+
+// runMFAMigration crée les tables de la double authentification TOTP : secret chiffré par utilisateur,
+// codes de secours hachés et défis de connexion en attente du second facteur.
+func runMFAMigration() {
+	log.Println("➡️  [mfa] Migration de la double authentification...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS user_mfa (
+		user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		secret TEXT NOT NULL,
+		enabled_at TIMESTAMPTZ,
+		last_used_step BIGINT NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash TEXT NOT NULL,
+		used_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (user_id, code_hash)
+	);
+
+	CREATE TABLE IF NOT EXISTS mfa_challenges (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		attempts INT NOT NULL DEFAULT 0,
+		expires_at TIMESTAMPTZ NOT NULL,
+		used_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_mfa_challenges_expires ON mfa_challenges(expires_at);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [mfa] Échec de la migration de la double authentification : %v", err)
+	}
+	log.Println("✅ [mfa] Double authentification migrée avec succès.")
+}
+
+// runLoginThrottlesMigration crée la table 'login_throttles' : échecs de connexion par compte et par IP,
+// partagés entre les instances de l'API (délai croissant et verrouillage temporaire).
+func runLoginThrottlesMigration() {
+	log.Println("➡️  [login_throttles] Migration de la protection contre la force brute...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS login_throttles (
+		scope VARCHAR(10) NOT NULL CHECK (scope IN ('account', 'ip')),
+		subject TEXT NOT NULL,
+		failures INT NOT NULL DEFAULT 0,
+		last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		locked_until TIMESTAMPTZ,
+		PRIMARY KEY (scope, subject)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_login_throttles_last_failure ON login_throttles(last_failure_at);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [login_throttles] Échec de la migration de la protection contre la force brute : %v", err)
+	}
+	log.Println("✅ [login_throttles] Protection contre la force brute migrée avec succès.")
+}
+
+// runUserIdentitiesMigration crée la table 'user_identities' (comptes externes OpenID Connect liés aux utilisateurs)
+// et la table 'oidc_auth_states' (state, nonce et vérificateur PKCE des connexions en cours).
+func runUserIdentitiesMigration() {
+	log.Println("➡️  [user_identities] Migration des identités externes (OpenID Connect)...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS user_identities (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		provider VARCHAR(50) NOT NULL,
+		subject TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_login_at TIMESTAMPTZ,
+		UNIQUE (provider, subject),
+		UNIQUE (user_id, provider)
+	);
+
+	CREATE TABLE IF NOT EXISTS oidc_auth_states (
+		state_hash TEXT PRIMARY KEY,
+		provider VARCHAR(50) NOT NULL,
+		code_verifier TEXT NOT NULL,
+		nonce TEXT NOT NULL,
+		link_user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+		expires_at TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_oidc_auth_states_expires ON oidc_auth_states(expires_at);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [user_identities] Échec de la migration des identités externes : %v", err)
+	}
+	log.Println("✅ [user_identities] Identités externes migrées avec succès.")
+}
+
+// runPersonalAccessTokensMigration crée la table 'personal_access_tokens' (jetons d'API nommés des intégrations,
+// stockés hachés avec leurs scopes).
+func runPersonalAccessTokensMigration() {
+	log.Println("➡️  [personal_access_tokens] Migration de la table 'personal_access_tokens'...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS personal_access_tokens (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(100) NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		token_prefix VARCHAR(16) NOT NULL,
+		scopes TEXT NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		last_used_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user ON personal_access_tokens(user_id);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [personal_access_tokens] Échec de la migration de la table 'personal_access_tokens' : %v", err)
+	}
+	log.Println("✅ [personal_access_tokens] Table 'personal_access_tokens' migrée avec succès.")
+}
+
+// runAdminRolesMigration ajoute le périmètre des administrateurs (moderator, finance, support).
+// Les administrateurs existants gardent tous les droits (périmètre vide).
+func runAdminRolesMigration() {
+	log.Println("➡️  [admin_roles] Migration des périmètres d'administration...")
+
+	query := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS admin_role VARCHAR(20) NOT NULL DEFAULT '';
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [admin_roles] Échec de la migration des périmètres d'administration : %v", err)
+	}
+	log.Println("✅ [admin_roles] Périmètres d'administration migrés avec succès.")
+}
+
+// runMediaFilesMigration crée la table 'media_files' qui rattache chaque fichier envoyé à ImageKit
+// à l'utilisateur qui l'a envoyé. Les fichiers envoyés avant ce suivi sont repris depuis les posts qui les utilisent.
+func runMediaFilesMigration() {
+	log.Println("➡️  [media_files] Migration de la table 'media_files'...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS media_files (
+		file_id TEXT PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_media_files_user ON media_files(user_id);
+
+	INSERT INTO media_files (file_id, user_id, url, created_at)
+	SELECT DISTINCT ON (file_id) file_id, user_id, COALESCE(media_url, ''), created_at
+	FROM posts
+	WHERE COALESCE(file_id, '') <> ''
+	ORDER BY file_id, created_at
+	ON CONFLICT (file_id) DO NOTHING;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [media_files] Échec de la migration de la table 'media_files' : %v", err)
+	}
+	log.Println("✅ [media_files] Table 'media_files' migrée avec succès.")
+}
+
+// runUserBlindIndexMigration ajoute les index aveugles des colonnes chiffrées de 'users' :
+// HMAC de l'e-mail normalisé (recherche exacte, unicité) et des préfixes des noms (recherche
+// par préfixe). Les lignes existantes sont complétées au démarrage du serveur (index partiel des
+// lignes à compléter) ; la commande cmd/blindindex -all les recalcule après un changement de clé.
+func runUserBlindIndexMigration() {
+	log.Println("➡️  [user_blind_index] Migration des index aveugles des utilisateurs...")
+
+	query := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS email_bidx TEXT;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS name_bidx TEXT[] NOT NULL DEFAULT '{}';
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_bidx ON users(email_bidx) WHERE email_bidx IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS idx_users_name_bidx ON users USING GIN (name_bidx);
+	CREATE INDEX IF NOT EXISTS idx_users_email_bidx_missing ON users(id) WHERE email_bidx IS NULL;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [user_blind_index] Échec de la migration des index aveugles : %v", err)
+	}
+	log.Println("✅ [user_blind_index] Index aveugles des utilisateurs migrés avec succès.")
+}
+
+// runAccountDeletionMigration ajoute la suppression différée des comptes : demande de suppression
+// (annulable par une connexion pendant le délai de grâce) puis date d'anonymisation du compte.
+func runAccountDeletionMigration() {
+	log.Println("➡️  [account_deletion] Migration de la suppression des comptes...")
+
+	query := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMPTZ;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+	CREATE INDEX IF NOT EXISTS idx_users_deletion_requested ON users(deletion_requested_at)
+		WHERE deletion_requested_at IS NOT NULL AND deleted_at IS NULL;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [account_deletion] Échec de la migration de la suppression des comptes : %v", err)
+	}
+	log.Println("✅ [account_deletion] Suppression des comptes migrée avec succès.")
+}
+
+// runUserSuspensionsMigration crée la table des suspensions de comptes prononcées par les administrateurs.
+// Une suspension est active tant qu'elle n'est pas levée et que sa fin (sauf suspension définitive) n'est pas atteinte ;
+// au plus une suspension non levée par utilisateur.
+func runUserSuspensionsMigration() {
+	log.Println("➡️  [user_suspensions] Migration des suspensions de comptes...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS user_suspensions (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		admin_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+		reason TEXT NOT NULL,
+		permanent BOOLEAN NOT NULL DEFAULT FALSE,
+		ends_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		lifted_at TIMESTAMPTZ,
+		lifted_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+		CHECK (permanent OR ends_at IS NOT NULL)
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_user_suspensions_open ON user_suspensions(user_id) WHERE lifted_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_user_suspensions_created ON user_suspensions(created_at DESC);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [user_suspensions] Échec de la migration des suspensions de comptes : %v", err)
+	}
+	log.Println("✅ [user_suspensions] Suspensions de comptes migrées avec succès.")
+}
+
+// runAdminAuditLogMigration crée le journal d'audit des actions d'administration et de modération.
+// La table est en ajout seul : des déclencheurs refusent toute modification ou suppression de ligne.
+// actor_id n'a volontairement pas de clé étrangère pour que le journal survive à la suppression des comptes.
+func runAdminAuditLogMigration() {
+	log.Println("➡️  [admin_audit_log] Migration du journal d'audit d'administration...")
+
+	query := `
+	CREATE TABLE IF NOT EXISTS admin_audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		actor_id BIGINT NOT NULL,
+		action VARCHAR(64) NOT NULL,
+		target_type VARCHAR(32) NOT NULL,
+		target_id BIGINT NOT NULL,
+		before JSONB,
+		after JSONB,
+		ip_address TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_admin_audit_log_actor ON admin_audit_log(actor_id, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_admin_audit_log_target ON admin_audit_log(target_type, target_id, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_admin_audit_log_action ON admin_audit_log(action, id DESC);
+
+	CREATE OR REPLACE FUNCTION admin_audit_log_append_only() RETURNS TRIGGER AS $$
+	BEGIN
+		RAISE EXCEPTION 'admin_audit_log est en ajout seul';
+	END;
+	$$ LANGUAGE plpgsql;
+
+	DROP TRIGGER IF EXISTS trg_admin_audit_log_append_only ON admin_audit_log;
+	CREATE TRIGGER trg_admin_audit_log_append_only
+		BEFORE UPDATE OR DELETE ON admin_audit_log
+		FOR EACH ROW EXECUTE FUNCTION admin_audit_log_append_only();
+
+	DROP TRIGGER IF EXISTS trg_admin_audit_log_no_truncate ON admin_audit_log;
+	CREATE TRIGGER trg_admin_audit_log_no_truncate
+		BEFORE TRUNCATE ON admin_audit_log
+		FOR EACH STATEMENT EXECUTE FUNCTION admin_audit_log_append_only();
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		log.Fatalf("❌ [admin_audit_log] Échec de la migration du journal d'audit : %v", err)
+	}
+	log.Println("✅ [admin_audit_log] Journal d'audit d'administration migré avec succès.")
+}
+
 // ===================== NOUVELLES MIGRATIONS POUR LE SYSTÈME DE RECHERCHE =====================
 
 // ===================== MISE À JOUR TABLE USERS =====================
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// Scopes des jetons d'accès personnels : chacun ouvre un groupe de routes aux intégrations.
+const (
+	ScopePostsWrite   = "posts:write"   // Publication, modification et médias des posts
+	ScopeStatsRead    = "stats:read"    // Statistiques du profil et revenus du créateur
+	ScopeMessagesRead = "messages:read" // Lecture des conversations et des messages
+)
+
+// AccessTokenScopes liste les scopes qu'un jeton d'accès personnel peut recevoir.
+var AccessTokenScopes = []string{ScopePostsWrite, ScopeStatsRead, ScopeMessagesRead}
+
+// AccessTokenPrefix distingue les jetons d'accès personnels des JWT dans le header Authorization.
+const AccessTokenPrefix = "ofp_"
+
+// PersonalAccessToken est un jeton d'API nommé, créé par un utilisateur pour ses scripts et intégrations.
+// Seule son empreinte est conservée ; Prefix permet de le reconnaître dans la liste.
+type PersonalAccessToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAccessToken est retourné une seule fois à la création : Token n'est plus jamais affiché ensuite.
+type CreatedAccessToken struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// AccessTokenIdentity est l'utilisateur authentifié par un jeton d'accès personnel, avec son rôle courant.
+type AccessTokenIdentity struct {
+	TokenID int64
+	UserID  int64
+	Role    Role
+	Scopes  []string
+}
+
+// HasScope indique si le jeton a reçu le scope demandé.
+func (t AccessTokenIdentity) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditActor identifie l'administrateur à l'origine d'une action privilégiée et l'adresse IP de sa requête.
+type AuditActor struct {
+	ActorID   int64
+	IPAddress string
+}
+
+// AuditEntry est une ligne du journal d'audit des actions d'administration (table en ajout seul).
+// Before et After sont des instantanés JSON de la cible avant et après l'action (absents s'ils sont sans objet).
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	ActorID    int64           `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   int64           `json:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IPAddress  string          `json:"ip_address"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter restreint la lecture du journal d'audit ; les champs à zéro ne filtrent pas.
+type AuditFilter struct {
+	ActorID    int64
+	Action     string
+	TargetType string
+	TargetID   int64
+	Since      *time.Time
+	Until      *time.Time
+}
+
+// Actions enregistrées dans le journal d'audit.
+const (
+	AuditCreatorRequestApprove = "creator_request.approve"
+	AuditCreatorRequestReject  = "creator_request.reject"
+	AuditUserDelete            = "user.delete"
+	AuditUserMFAReset          = "user.mfa_reset"
+	AuditUserUnlock            = "user.unlock"
+	AuditUserSuspend           = "user.suspend"
+	AuditUserUnsuspend         = "user.unsuspend"
+	AuditUserAdminRole         = "user.admin_role"
+	AuditPaymentRefund         = "payment.refund"
+	AuditPayoutBatchCreate     = "payout_batch.create"
+	AuditReportStatus          = "report.status"
+	AuditReportAct             = "report.act"
+)
+
+// Types de cibles des actions d'administration.
+const (
+	AuditTargetUser           = "user"
+	AuditTargetCreatorRequest = "creator_request"
+	AuditTargetPayment        = "payment"
+	AuditTargetPayoutBatch    = "payout_batch"
+	AuditTargetReport         = "report"
+)
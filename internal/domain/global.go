@@ -26,5 +26,6 @@ type GlobalStats struct {
 	TotalUsers   int64 `json:"total_users"`
 	TotalPosts   int64 `json:"total_posts"`
 	TotalReports int64 `json:"total_reports"`
-	TotalRevenue int64 `json:"total_revenue"`
+	TotalRevenue int64 `json:"total_revenue"` // Revenus nets de remboursements, pourboires inclus
+	TipsRevenue  int64 `json:"tips_revenue"`  // Part des revenus issue des pourboires
 }
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// IdempotencyKeyTTL est la durée pendant laquelle une clé d'idempotence rejoue la réponse mémorisée.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyInFlightLease est la durée au-delà de laquelle une clé sans réponse mémorisée (requête
+// interrompue par un arrêt du serveur) est considérée comme abandonnée et peut être réservée à nouveau.
+const IdempotencyInFlightLease = 5 * time.Minute
+
+// MaxIdempotencyKeyLen est la longueur maximale acceptée pour l'en-tête Idempotency-Key.
+const MaxIdempotencyKeyLen = 255
+
+// IdempotencyRecord représente une requête mémorisée pour une clé d'idempotence d'un utilisateur.
+// StatusCode vaut 0 tant que la requête d'origine est en cours de traitement.
+type IdempotencyRecord struct {
+	ID           int64
+	UserID       int64
+	Key          string
+	Fingerprint  string // Empreinte SHA-256 de la méthode, du chemin et du corps de la requête
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	ExpiresAt    time.Time
+}
+
+// Completed indique si la réponse de la requête d'origine a été mémorisée.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
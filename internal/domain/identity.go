@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// UserIdentity lie un compte externe (fournisseur OpenID Connect et son identifiant "sub") à un utilisateur.
+type UserIdentity struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OIDCAuthState conserve, le temps de l'aller-retour chez le fournisseur, le nonce et le vérificateur PKCE
+// d'une connexion OpenID Connect. LinkUserID est renseigné pour une liaison explicite depuis le profil.
+type OIDCAuthState struct {
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	LinkUserID   int64
+}
+
+// OIDCAuthStateTTL borne la durée de l'aller-retour chez le fournisseur.
+const OIDCAuthStateTTL = 10 * time.Minute
@@ -0,0 +1,98 @@
+package domain
+
+import "time"
+
+// Comptes du grand livre des revenus (comptabilité en partie double).
+const (
+	LedgerAccountPaymentClearing = "payment_clearing" // Fonds encaissés chez le prestataire de paiement
+	LedgerAccountPlatformRevenue = "platform_revenue" // Commission de la plateforme
+	LedgerAccountCreatorPayable  = "creator_payable"  // Solde dû au créateur
+)
+
+// Types d'opérations du grand livre.
+const (
+	LedgerKindPayment = "payment" // Paiement encaissé pour un créateur
+	LedgerKindRefund  = "refund"  // Remboursement d'un paiement
+	LedgerKindPayout  = "payout"  // Versement au créateur
+)
+
+// LedgerTransaction est une opération du grand livre : montant brut, commission de la plateforme
+// et montant net du créateur (en centimes). Ses écritures (LedgerEntry) sont équilibrées.
+type LedgerTransaction struct {
+	ID               int64      `json:"id"`
+	Kind             string     `json:"kind"`
+	CreatorID        int64      `json:"creator_id"`
+	PaymentID        *int64     `json:"payment_id,omitempty"`
+	GrossAmount      int64      `json:"gross_amount"`
+	CommissionAmount int64      `json:"commission_amount"`
+	NetAmount        int64      `json:"net_amount"`
+	CommissionRate   int        `json:"commission_rate"` // En points de base (2000 = 20 %)
+	Currency         string     `json:"currency"`
+	BalanceAfter     int64      `json:"balance_after"` // Solde net du créateur après l'opération
+	PayoutBatchID    *int64     `json:"payout_batch_id,omitempty"`
+	PaidOutAt        *time.Time `json:"paid_out_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// LedgerEntry est une écriture au débit ou au crédit d'un compte.
+type LedgerEntry struct {
+	ID            int64  `json:"id"`
+	TransactionID int64  `json:"transaction_id"`
+	Account       string `json:"account"`
+	CreatorID     *int64 `json:"creator_id,omitempty"`
+	Currency      string `json:"currency"`
+	Debit         int64  `json:"debit"`
+	Credit        int64  `json:"credit"`
+}
+
+// EarningsTotals agrège les opérations d'un créateur sur une période, par devise.
+type EarningsTotals struct {
+	Currency         string `json:"currency"`
+	GrossAmount      int64  `json:"gross_amount"`
+	CommissionAmount int64  `json:"commission_amount"` // Commission nette des remboursements
+	RefundedAmount   int64  `json:"refunded_amount"`
+	NetAmount        int64  `json:"net_amount"` // Net du créateur après commission et remboursements
+	PaidOutAmount    int64  `json:"paid_out_amount"`
+	PaymentsCount    int64  `json:"payments_count"`
+}
+
+// CreatorBalance est le solde net restant dû au créateur dans une devise.
+type CreatorBalance struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+}
+
+// CreatorEarnings est la réponse de GET /creator/earnings.
+type CreatorEarnings struct {
+	From         *time.Time          `json:"from,omitempty"`
+	To           *time.Time          `json:"to,omitempty"`
+	Totals       []EarningsTotals    `json:"totals"`
+	Balances     []CreatorBalance    `json:"balances"`
+	Transactions []LedgerTransaction `json:"transactions"`
+}
+
+// MonthlyStatement est le relevé mensuel des revenus d'un créateur.
+type MonthlyStatement struct {
+	Month string `json:"month"` // Format AAAA-MM
+	EarningsTotals
+}
+
+// PayoutBatch est un lot de versements exporté par un administrateur.
+type PayoutBatch struct {
+	ID           int64     `json:"id"`
+	CreatedBy    int64     `json:"created_by"`
+	Cutoff       time.Time `json:"cutoff"`
+	TotalAmount  int64     `json:"total_amount"`
+	CreatorCount int       `json:"creator_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PayoutLine est le versement dû à un créateur dans un lot.
+type PayoutLine struct {
+	BatchID           int64  `json:"batch_id"`
+	CreatorID         int64  `json:"creator_id"`
+	Username          string `json:"username"`
+	Currency          string `json:"currency"`
+	Amount            int64  `json:"amount"`
+	TransactionsCount int    `json:"transactions_count"`
+}
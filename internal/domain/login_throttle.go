@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// Portées des compteurs d'échecs de connexion.
+const (
+	LoginThrottleAccount = "account" // subject = identifiant de l'utilisateur
+	LoginThrottleIP      = "ip"      // subject = adresse IP du client
+)
+
+// LoginThrottle représente les échecs de connexion récents d'un compte ou d'une adresse IP.
+type LoginThrottle struct {
+	Scope         string
+	Subject       string
+	Failures      int // Échecs consécutifs depuis le dernier succès ou verrouillage
+	LastFailureAt time.Time
+	LockedUntil   *time.Time // Verrouillage temporaire en cours, nil sinon
+}
@@ -2,10 +2,19 @@ package domain
 
 import "time"
 
+// Types de messages d'une conversation.
+const (
+	MessageKindText = "text"
+	MessageKindTip  = "tip" // Pourboire confirmé, affiché dans le fil de la conversation
+)
+
 type Message struct {
 	ID             int64     `json:"id"`
 	ConversationID int64     `json:"conversation_id"`
 	SenderID       int64     `json:"sender_id"`
 	Content        string    `json:"content"`
+	Kind           string    `json:"kind"`                 // text ou tip
+	TipID          *int64    `json:"tip_id,omitempty"`     // Pourboire associé (messages de type tip)
+	TipAmount      int       `json:"tip_amount,omitempty"` // Montant du pourboire en centimes
 	CreatedAt      time.Time `json:"created_at"`
 }
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// MFAEnrollment est retourné au début de l'enrôlement TOTP : le secret et l'URI otpauth:// à afficher en QR code.
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFAState décrit la double authentification d'un utilisateur. Le secret est stocké chiffré.
+type MFAState struct {
+	UserID       int64
+	Secret       string
+	EnabledAt    *time.Time // nil tant que l'enrôlement n'est pas confirmé
+	LastUsedStep int64      // Dernier pas TOTP accepté, pour refuser le rejeu d'un code
+}
+
+// Enabled indique si la double authentification est active.
+func (s *MFAState) Enabled() bool {
+	return s != nil && s.EnabledAt != nil
+}
+
+// MFAChallenge est retourné par la connexion quand un second facteur est requis.
+type MFAChallenge struct {
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int64  `json:"expires_in"` // Durée de validité du défi, en secondes
+}
+
+// Paramètres de la double authentification.
+const (
+	MFARecoveryCodeCount    = 10              // Codes de secours générés à chaque (ré)génération
+	MFAChallengeTTL         = 5 * time.Minute // Durée pour saisir le second facteur après le mot de passe
+	MaxMFAChallengeAttempts = 5               // Codes erronés tolérés par défi
+)
@@ -2,15 +2,36 @@ package domain
 
 import "time"
 
-// Payment représente un paiement effectué pour un abonnement.
+// Payment représente un paiement effectué pour un abonnement ou un paiement ponctuel (post payant, pourboire).
 type Payment struct {
-	ID              int64     `json:"id"`                // Identifiant unique du paiement
-	SubscriptionID  int64     `json:"subscription_id"`   // Identifiant de l'abonnement
-	StripePaymentID string    `json:"stripe_payment_id"` // ID de paiement Stripe
-	PayerID         string    `json:"payer_id"`          // Identifiant de l'utilisateur qui a payé
-	StartAt         time.Time `json:"start_at"`          // Date de début de l'abonnement
-	EndAt           time.Time `json:"end_at"`            // Date de fin de l'abonnement
-	Amount          int       `json:"amount"`            // Montant payé (en centimes)
-	Status          string    `json:"status"`            // Statut du paiement (succeeded, failed, etc.)
-	CreatedAt       time.Time `json:"created_at"`        // Date de création du paiement
+	ID              int64     `json:"id"`                        // Identifiant unique du paiement
+	Kind            string    `json:"kind"`                      // Objet du paiement (subscription, post_purchase, tip)
+	SubscriptionID  *int64    `json:"subscription_id,omitempty"` // Identifiant de l'abonnement (paiements d'abonnement uniquement)
+	CreatorID       *int64    `json:"creator_id,omitempty"`      // Créateur bénéficiaire (achats ponctuels)
+	StripePaymentID string    `json:"stripe_payment_id"`         // ID de paiement Stripe
+	PayerID         string    `json:"payer_id"`                  // Identifiant de l'utilisateur qui a payé
+	StartAt         time.Time `json:"start_at"`                  // Date de début de l'abonnement
+	EndAt           time.Time `json:"end_at"`                    // Date de fin de l'abonnement
+	Amount          int       `json:"amount"`                    // Montant payé (en centimes)
+	Status          string    `json:"status"`                    // Statut du paiement (succeeded, failed, etc.)
+	RefundOf        *int64    `json:"refund_of,omitempty"`       // Paiement d'origine (lignes de remboursement uniquement)
+	RefundReason    string    `json:"refund_reason,omitempty"`   // Motif du remboursement
+	CreatedAt       time.Time `json:"created_at"`                // Date de création du paiement
 }
+
+// Statuts possibles d'un paiement (mis à jour par le webhook Stripe).
+const (
+	PaymentStatusPending           = "pending"
+	PaymentStatusSucceeded         = "succeeded"
+	PaymentStatusFailed            = "failed"
+	PaymentStatusRefunded          = "refunded"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+	PaymentStatusRefund            = "refund" // Ligne de remboursement liée au paiement d'origine (refund_of)
+)
+
+// Objets possibles d'un paiement.
+const (
+	PaymentKindSubscription = "subscription"
+	PaymentKindPostPurchase = "post_purchase"
+	PaymentKindTip          = "tip"
+)
@@ -13,6 +13,8 @@ const (
 	Public Visibility = "public"
 	// SubscriberOnly : le post est réservé aux abonnés.
 	SubscriberOnly Visibility = "subscriber"
+	// PayPerView : le post est débloqué à l'unité, à son propre prix (y compris pour les abonnés).
+	PayPerView Visibility = "ppv"
 )
 
 // Post représente une publication effectuée par un utilisateur.
@@ -28,6 +30,10 @@ type Post struct {
 	UpdatedAt   time.Time  `json:"updated_at"`
 	ImageURL    string     `json:"image_url,omitempty"`
 	VideoURL    string     `json:"video_url,omitempty"`
+
+	// ===== POSTS PAYANTS À L'UNITÉ =====
+	Price       int        `json:"price,omitempty"`           // Prix de déblocage en centimes (visibilité ppv)
+	Locked      bool       `json:"locked"`                    // Média masqué tant que le post n'est pas débloqué
 	
 	// ===== CHAMP TAGS AJOUTÉ =====
 	Tags        []string   `json:"tags,omitempty"`            // Tags associés au post
@@ -75,6 +81,15 @@ func (p *Post) Update(title, description string) {
 	p.UpdatedAt = time.Now()
 }
 
+// Lock masque le média d'un post payant non débloqué par le lecteur.
+func (p *Post) Lock() {
+	p.MediaURL = ""
+	p.FileID = ""
+	p.ImageURL = ""
+	p.VideoURL = ""
+	p.Locked = true
+}
+
 // ===== NOUVELLES MÉTHODES POUR GÉRER LES TAGS =====
 
 // AddTag ajoute un tag au post s'il n'existe pas déjà
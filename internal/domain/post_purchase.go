@@ -0,0 +1,17 @@
+package domain
+
+// Bornes du prix de déblocage d'un post payant (en centimes).
+const (
+	MinPostPrice = 100
+	MaxPostPrice = 50000
+)
+
+// PostPurchaseCheckout décrit l'achat d'un post payant : paiement à confirmer côté front-end.
+// Le post est débloqué à la confirmation du paiement par le webhook.
+type PostPurchaseCheckout struct {
+	PostID       int64  `json:"post_id"`
+	PaymentID    int64  `json:"payment_id"`
+	Amount       int    `json:"amount"`
+	Currency     string `json:"currency"`
+	ClientSecret string `json:"client_secret"`
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Prix appliqué lorsqu'un créateur n'a pas encore configuré son tarif (en centimes).
+const (
+	DefaultSubscriptionPrice    = 499
+	DefaultSubscriptionCurrency = "EUR"
+)
+
+// Bornes acceptées pour un prix mensuel (en centimes).
+const (
+	MinSubscriptionPrice = 100
+	MaxSubscriptionPrice = 50000
+)
+
+// SupportedCurrencies liste les devises acceptées pour les abonnements.
+var SupportedCurrencies = map[string]bool{
+	"EUR": true,
+	"USD": true,
+	"GBP": true,
+	"CHF": true,
+	"CAD": true,
+}
+
+// CreatorPricing représente le tarif mensuel de base d'un créateur et ses paliers.
+type CreatorPricing struct {
+	CreatorID int64              `json:"creator_id"`
+	Price     int                `json:"price"`      // Prix mensuel de base (en centimes)
+	Currency  string             `json:"currency"`   // Code ISO 4217 (EUR, USD...)
+	TrialDays int                `json:"trial_days"` // Essai gratuit offert aux nouveaux abonnés (0 = aucun)
+	Tiers     []SubscriptionTier `json:"tiers"`      // Paliers supplémentaires actifs
+	UpdatedAt *time.Time         `json:"updated_at,omitempty"`
+}
+
+// SubscriptionTier représente un palier d'abonnement (ex. "Basic", "VIP").
+type SubscriptionTier struct {
+	ID          int64     `json:"id"`
+	CreatorID   int64     `json:"creator_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Price       int       `json:"price"` // Prix mensuel du palier (en centimes)
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FormatPrice convertit un montant en centimes en chaîne décimale (499 -> "4.99").
+func FormatPrice(amount int) string {
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
@@ -0,0 +1,80 @@
+package domain
+
+import "time"
+
+// Types de réduction d'un code promo.
+const (
+	DiscountPercent = "percent" // Pourcentage du prix (1 à 100)
+	DiscountFixed   = "fixed"   // Montant fixe en centimes
+)
+
+// Montant minimal débitable (en centimes) : une réduction ne peut pas descendre en dessous, sauf gratuité totale.
+const MinChargeAmount = 50
+
+// Durée maximale d'un essai gratuit (en jours).
+const MaxTrialDays = 90
+
+// PromoCode représente un code de réduction créé par un créateur pour ses abonnements.
+type PromoCode struct {
+	ID               int64      `json:"id"`
+	CreatorID        int64      `json:"creator_id"`
+	Code             string     `json:"code"`
+	DiscountType     string     `json:"discount_type"`             // percent ou fixed
+	DiscountValue    int        `json:"discount_value"`            // Pourcentage ou centimes selon le type
+	MaxRedemptions   *int       `json:"max_redemptions,omitempty"` // nil = illimité
+	RedemptionsCount int        `json:"redemptions_count"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	FirstPeriodOnly  bool       `json:"first_period_only"` // Réduction limitée à la première période payée
+	Active           bool       `json:"active"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// Redeemable indique si le code peut encore être utilisé à la date now.
+func (p *PromoCode) Redeemable(now time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.ExpiresAt != nil && !now.Before(*p.ExpiresAt) {
+		return false
+	}
+	return p.MaxRedemptions == nil || p.RedemptionsCount < *p.MaxRedemptions
+}
+
+// Apply retourne le montant à débiter après réduction (0 = période offerte).
+// Un montant réduit non nul est porté au minimum débitable.
+func (p *PromoCode) Apply(amount int) int {
+	discounted := amount
+	switch p.DiscountType {
+	case DiscountPercent:
+		discounted = amount - (amount*p.DiscountValue+50)/100
+	case DiscountFixed:
+		discounted = amount - p.DiscountValue
+	}
+
+	if discounted <= 0 {
+		return 0
+	}
+	if discounted < MinChargeAmount {
+		return MinChargeAmount
+	}
+	return discounted
+}
+
+// SubscriptionCheckout décrit le résultat d'une souscription : paiement à confirmer,
+// période offerte ou essai gratuit (moyen de paiement à enregistrer pour le premier débit).
+type SubscriptionCheckout struct {
+	SubscriptionID int64      `json:"subscription_id"`
+	Amount         int        `json:"amount"`          // Montant débité pour la première période (en centimes)
+	OriginalAmount int        `json:"original_amount"` // Prix avant réduction
+	Currency       string     `json:"currency"`
+	PromoCode      string     `json:"promo_code,omitempty"`
+	TrialEndsAt    *time.Time `json:"trial_ends_at,omitempty"`
+	ClientSecret   string     `json:"client_secret"`
+	IntentType     string     `json:"intent_type"` // payment (paiement à confirmer) ou setup (enregistrement de la carte)
+}
+
+// Types d'intent retournés au front-end lors d'une souscription.
+const (
+	IntentTypePayment = "payment"
+	IntentTypeSetup   = "setup"
+)
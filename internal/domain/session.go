@@ -0,0 +1,49 @@
+package domain
+
+import "time"
+
+// Session représente une session de connexion : famille de refresh tokens issue d'une même authentification.
+// Les access tokens portent l'identifiant de session (claim sid) et sont refusés dès la révocation de celle-ci.
+type Session struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	UserAgent     string     `json:"user_agent"`
+	IPAddress     string     `json:"ip_address"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at"` // Dernière activité, à SessionActivityInterval près
+	Current       bool       `json:"current"`      // Session de la requête en cours
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"` // logout, reuse...
+}
+
+// SessionClient décrit l'appareil à l'origine d'une connexion ou d'un renouvellement.
+type SessionClient struct {
+	UserAgent string
+	IPAddress string
+}
+
+// SessionActivityInterval espace les mises à jour de la dernière activité d'une session par ses requêtes.
+const SessionActivityInterval = 5 * time.Minute
+
+// MaxUserAgentLen borne la longueur du user agent conservé pour une session.
+const MaxUserAgentLen = 512
+
+// Motifs de révocation d'une session.
+const (
+	SessionRevokedLogout   = "logout"
+	SessionRevokedReuse    = "reuse"            // Réutilisation d'un refresh token déjà renouvelé
+	SessionRevokedUser     = "user"             // Fermée depuis la liste des appareils
+	SessionRevokedPassword = "password"         // Changement de mot de passe
+	SessionRevokedReset    = "reset"            // Réinitialisation du mot de passe par e-mail
+	SessionRevokedMFA      = "mfa"              // Activation de la double authentification
+	SessionRevokedDeletion = "account_deletion" // Demande de suppression du compte
+)
+
+// AuthTokens est la paire de jetons retournée à la connexion et au renouvellement.
+type AuthTokens struct {
+	AccessToken      string    `json:"token"` // Nom conservé pour la compatibilité du front-end
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresIn        int64     `json:"expires_in"` // Durée de validité de l'access token, en secondes
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	SessionID        int64     `json:"-"`
+}
@@ -4,11 +4,30 @@ import "time"
 
 // Subscription représente un abonnement d'un utilisateur (Subscriber) à un créateur (Creator).
 type Subscription struct {
-	ID              int64     `json:"id"`                // Identifiant unique de l'abonnement
-	SubscriberID    int64     `json:"subscriber_id"`     // Identifiant de l'utilisateur qui s'abonne
-	CreatorID       int64     `json:"creator_id"`        // Identifiant du créateur auquel on s'abonne
-	CreatedAt       time.Time `json:"created_at"`        // Date de création de l'abonnement
-	EndAt           time.Time `json:"end_at"`            // Date de fin de l'abonnement
-	Status          bool      `json:"status"`            // Statut du paiement (true = actif, false = inactif)
-	PaymentIntentID string    `json:"payment_intent_id"` // Stripe Payment Intent ID
+	ID              int64      `json:"id"`                      // Identifiant unique de l'abonnement
+	SubscriberID    int64      `json:"subscriber_id"`           // Identifiant de l'utilisateur qui s'abonne
+	CreatorID       int64      `json:"creator_id"`              // Identifiant du créateur auquel on s'abonne
+	CreatedAt       time.Time  `json:"created_at"`              // Date de création de l'abonnement
+	EndAt           time.Time  `json:"end_at"`                  // Date de fin de l'abonnement
+	Status          bool       `json:"status"`                  // Statut du paiement (true = actif, false = inactif)
+	PaymentIntentID string     `json:"payment_intent_id"`       // Stripe Payment Intent ID
+	TierID          *int64     `json:"tier_id,omitempty"`       // Palier souscrit (nil = tarif de base du créateur)
+	AutoRenew       bool       `json:"auto_renew"`              // Renouvellement automatique à la fin de la période
+	PromoCodeID     *int64     `json:"promo_code_id,omitempty"` // Code promo appliqué aux prochains débits
+	TrialEndAt      *time.Time `json:"trial_end_at,omitempty"`  // Fin de l'essai gratuit
+}
+
+// Modes de résiliation d'un abonnement.
+const (
+	CancelAtPeriodEnd = "period_end" // Accès conservé jusqu'à la fin de la période payée, sans renouvellement
+	CancelNow         = "now"        // Fin immédiate avec remboursement au prorata de la période restante
+)
+
+// SubscriptionCancellation décrit le résultat d'une résiliation.
+type SubscriptionCancellation struct {
+	SubscriptionID int64     `json:"subscription_id"`
+	Mode           string    `json:"mode"`
+	EndsAt         time.Time `json:"ends_at"`
+	RefundedAmount int64     `json:"refunded_amount"` // En centimes
+	Refunds        []Payment `json:"refunds"`
 }
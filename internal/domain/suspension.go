@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// UserSuspension représente la suspension d'un compte prononcée par un administrateur.
+// Le compte ne peut plus s'authentifier et ses posts sont masqués tant que la suspension est active.
+type UserSuspension struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Username  string     `json:"username,omitempty"`
+	AdminID   *int64     `json:"admin_id"` // nil si l'administrateur a été supprimé depuis
+	Reason    string     `json:"reason"`
+	Permanent bool       `json:"permanent"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"` // nil pour une suspension définitive
+	CreatedAt time.Time  `json:"created_at"`
+	LiftedAt  *time.Time `json:"lifted_at,omitempty"` // Levée manuelle ou remplacement par une nouvelle suspension
+	LiftedBy  *int64     `json:"lifted_by,omitempty"`
+}
+
+// ActiveAt indique si la suspension est en vigueur à l'instant donné.
+func (s *UserSuspension) ActiveAt(now time.Time) bool {
+	if s.LiftedAt != nil {
+		return false
+	}
+	return s.Permanent || (s.EndsAt != nil && s.EndsAt.After(now))
+}
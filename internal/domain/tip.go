@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// Bornes du montant d'un pourboire (en centimes) et de son message.
+const (
+	MinTipAmount     = 100
+	MaxTipAmount     = 50000
+	MaxTipMessageLen = 500
+)
+
+// Tip représente un pourboire envoyé à un créateur, sur son profil, sur un post ou dans une conversation.
+// Il n'est visible qu'une fois son paiement confirmé.
+type Tip struct {
+	ID             int64     `json:"id"`
+	SenderID       int64     `json:"sender_id"`
+	CreatorID      int64     `json:"creator_id"`
+	PaymentID      int64     `json:"payment_id"`
+	Amount         int       `json:"amount"` // Montant en centimes
+	Currency       string    `json:"currency"`
+	Message        string    `json:"message,omitempty"`
+	PostID         *int64    `json:"post_id,omitempty"`
+	ConversationID *int64    `json:"conversation_id,omitempty"`
+	SenderUsername string    `json:"sender_username,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TipCheckout décrit un pourboire en attente de paiement : client_secret à confirmer côté front-end.
+type TipCheckout struct {
+	Tip          *Tip   `json:"tip"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// PostTips regroupe les pourboires confirmés reçus sur un post.
+type PostTips struct {
+	PostID      int64 `json:"post_id"`
+	Count       int   `json:"count"`
+	TotalAmount int   `json:"total_amount"`
+	Tips        []Tip `json:"tips"`
+}
@@ -11,6 +11,25 @@ const (
 	RoleAdmin      Role = "admin"      // Administrateur
 )
 
+// AdminRole restreint un administrateur à un périmètre ; vide pour un administrateur complet.
+type AdminRole string
+
+const (
+	AdminRoleFull      AdminRole = ""          // Tous les droits d'administration
+	AdminRoleModerator AdminRole = "moderator" // Signalements et suppression de contenus
+	AdminRoleFinance   AdminRole = "finance"   // Remboursements et versements aux créateurs
+	AdminRoleSupport   AdminRole = "support"   // Comptes utilisateurs et demandes de créateurs
+)
+
+// IsValid indique si le sous-rôle d'administration est connu.
+func (r AdminRole) IsValid() bool {
+	switch r {
+	case AdminRoleFull, AdminRoleModerator, AdminRoleFinance, AdminRoleSupport:
+		return true
+	}
+	return false
+}
+
 // User représente un utilisateur de la plateforme.
 type User struct {
 	ID        int64     `json:"id"`         // Identifiant unique de l'utilisateur
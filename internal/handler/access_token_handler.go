@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"onlyflick/pkg/response"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateAccessTokenRequest décrit un jeton d'accès personnel à créer.
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// CreateMyAccessToken crée un jeton d'accès personnel ; sa valeur n'est affichée que dans cette réponse.
+// Route: POST /profile/tokens
+func CreateMyAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[CreateMyAccessToken] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	var req CreateAccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Requête invalide")
+		return
+	}
+
+	token, err := service.CreatePersonalAccessToken(userID, req.Name, req.Scopes, req.ExpiresInDays, time.Now())
+	switch {
+	case errors.Is(err, service.ErrAccessTokenNameInvalid):
+		response.RespondWithError(w, http.StatusBadRequest, "Nom requis (100 caractères maximum)")
+	case errors.Is(err, service.ErrAccessTokenScopeInvalid):
+		response.RespondWithError(w, http.StatusBadRequest, "Scopes invalides : posts:write, stats:read, messages:read")
+	case errors.Is(err, service.ErrAccessTokenExpiryInvalid):
+		response.RespondWithError(w, http.StatusBadRequest, "Durée de validité invalide (1 à 365 jours)")
+	case errors.Is(err, service.ErrAccessTokenLimitReached):
+		response.RespondWithError(w, http.StatusConflict, "Nombre maximal de jetons actifs atteint")
+	case err != nil:
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur création du jeton")
+	default:
+		response.RespondWithJSON(w, http.StatusCreated, token)
+	}
+}
+
+// ListMyAccessTokens retourne les jetons d'accès personnels de l'utilisateur connecté (sans leur valeur).
+// Route: GET /profile/tokens
+func ListMyAccessTokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[ListMyAccessTokens] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	tokens, err := repository.ListPersonalAccessTokens(userID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération des jetons")
+		return
+	}
+	response.RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+// DeleteMyAccessToken révoque un jeton d'accès personnel de l'utilisateur connecté.
+// Route: DELETE /profile/tokens/{id}
+func DeleteMyAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[DeleteMyAccessToken] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	tokenID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID de jeton invalide")
+		return
+	}
+
+	if err := repository.DeletePersonalAccessToken(tokenID, userID); err != nil {
+		if errors.Is(err, repository.ErrAccessTokenNotFound) {
+			response.RespondWithError(w, http.StatusNotFound, "Jeton introuvable")
+			return
+		}
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur suppression du jeton")
+		return
+	}
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Jeton révoqué"})
+}
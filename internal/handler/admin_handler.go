@@ -1,11 +1,18 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"onlyflick/internal/domain"
 	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
 	"onlyflick/pkg/response"
 
 	"github.com/go-chi/chi/v5"
@@ -88,7 +95,7 @@ func ApproveCreatorRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := repository.ApproveCreatorRequest(requestID); err != nil {
+	if err := repository.ApproveCreatorRequest(requestID, auditActor(r)); err != nil {
 		log.Printf("[ApproveCreatorRequest][ERREUR] Échec de l'approbation de la demande %d : %v", requestID, err)
 		response.RespondWithError(w, http.StatusInternalServerError, "Échec de l'approbation de la demande : "+err.Error())
 		return
@@ -110,7 +117,7 @@ func RejectCreatorRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := repository.RejectCreatorRequest(requestID); err != nil {
+	if err := repository.RejectCreatorRequest(requestID, auditActor(r)); err != nil {
 		log.Printf("[RejectCreatorRequest][ERREUR] Échec du rejet de la demande %d : %v", requestID, err)
 		response.RespondWithError(w, http.StatusInternalServerError, "Échec du rejet de la demande : "+err.Error())
 		return
@@ -120,7 +127,8 @@ func RejectCreatorRequest(w http.ResponseWriter, r *http.Request) {
 	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Demande rejetée"})
 }
 
-// DeleteAccountByID supprime le compte d'un utilisateur selon son ID passé dans l'URL
+// DeleteAccountByID purge immédiatement le compte d'un utilisateur selon son ID passé dans l'URL,
+// sans délai de grâce (médias supprimés, données personnelles anonymisées, paiements conservés).
 func DeleteAccountByID(w http.ResponseWriter, r *http.Request) {
 	log.Println("[DeleteAccountByID] Suppression du compte utilisateur par ID")
 
@@ -132,12 +140,233 @@ func DeleteAccountByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := repository.DeleteUser(userID); err != nil {
-		log.Printf("[DeleteAccountByID][ERREUR] Suppression du compte utilisateur %d échouée : %v", userID, err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Échec de la suppression du compte : "+err.Error())
+	actor := auditActor(r)
+	if err := service.PurgeAccount(userID, time.Now(), &actor); err != nil {
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			response.RespondWithError(w, http.StatusNotFound, "Utilisateur non trouvé")
+			return
+		}
+		log.Printf("[DeleteAccountByID][ERREUR] Purge du compte utilisateur %d échouée : %v", userID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Échec de la suppression du compte")
 		return
 	}
 
-	log.Printf("[DeleteAccountByID] Compte utilisateur %d supprimé avec succès", userID)
+	log.Printf("[DeleteAccountByID] Compte utilisateur %d purgé avec succès", userID)
 	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Compte supprimé"})
 }
+
+// UnlockAccountByID lève le verrouillage d'un compte bloqué après trop d'échecs de connexion.
+// Route: POST /admin/users/{id}/unlock
+func UnlockAccountByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID utilisateur invalide")
+		return
+	}
+
+	unlocked, err := service.UnlockAccount(userID, auditActor(r))
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Échec du déverrouillage du compte")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":  "Compte déverrouillé",
+		"unlocked": unlocked,
+	})
+}
+
+// SetAdminRoleByID définit le périmètre d'un administrateur (moderator, finance, support ou "" pour tous les droits).
+// Route: PUT /admin/users/{id}/admin-role
+func SetAdminRoleByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID utilisateur invalide")
+		return
+	}
+
+	var body struct {
+		AdminRole domain.AdminRole `json:"admin_role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !body.AdminRole.IsValid() {
+		response.RespondWithError(w, http.StatusBadRequest, "Périmètre invalide : moderator, finance, support ou vide")
+		return
+	}
+
+	if err := repository.SetAdminRole(userID, body.AdminRole, auditActor(r)); err != nil {
+		if errors.Is(err, repository.ErrAdminNotFound) {
+			response.RespondWithError(w, http.StatusNotFound, "Administrateur introuvable")
+			return
+		}
+		response.RespondWithError(w, http.StatusInternalServerError, "Échec de la mise à jour du périmètre")
+		return
+	}
+
+	log.Printf("[SetAdminRoleByID] Périmètre de l'administrateur %d : %q", userID, body.AdminRole)
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"message":    "Périmètre mis à jour",
+		"admin_role": string(body.AdminRole),
+	})
+}
+
+// AdminSearchUsers recherche des utilisateurs par e-mail exact ou par username, prénom et nom
+// (paramètre q, au moins 2 caractères), sans déchiffrer toute la table.
+// Route: GET /admin/users?q=...&limit=...&offset=...
+func AdminSearchUsers(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(query)) < 2 {
+		response.RespondWithError(w, http.StatusBadRequest, "Recherche d'au moins 2 caractères requise")
+		return
+	}
+	limit, offset := parsePaginationParams(r)
+
+	users, total, err := repository.AdminSearchUsers(query, limit, offset)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Échec de la recherche des utilisateurs")
+		return
+	}
+
+	log.Printf("[AdminSearchUsers] %d utilisateurs trouvés (total : %d)", len(users), total)
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"users":  users,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// RefundPaymentByID rembourse totalement (montant absent) ou partiellement un paiement via le fournisseur de paiement.
+// Route: POST /admin/payments/{id}/refund
+func RefundPaymentByID(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Printf("[RefundPaymentByID][ERREUR] ID paiement invalide : %v", err)
+		response.RespondWithError(w, http.StatusBadRequest, "ID paiement invalide")
+		return
+	}
+
+	var input struct {
+		Amount int64  `json:"amount"` // En centimes, 0 ou absent = remboursement total
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		response.RespondWithError(w, http.StatusBadRequest, "Corps de requête invalide")
+		return
+	}
+	if input.Amount < 0 {
+		response.RespondWithError(w, http.StatusBadRequest, "Montant invalide")
+		return
+	}
+
+	actor := auditActor(r)
+	refund, err := service.RefundPayment(paymentID, input.Amount, strings.TrimSpace(input.Reason), &actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrPaymentNotFound):
+			response.RespondWithError(w, http.StatusNotFound, "Paiement introuvable")
+		case errors.Is(err, service.ErrPaymentNotRefundable):
+			response.RespondWithError(w, http.StatusBadRequest, "Ce paiement ne peut pas être remboursé")
+		case errors.Is(err, repository.ErrRefundExceedsAmount):
+			response.RespondWithError(w, http.StatusBadRequest, "Le montant dépasse le montant remboursable")
+		default:
+			log.Printf("[RefundPaymentByID][ERREUR] Remboursement du paiement %d échoué : %v", paymentID, err)
+			response.RespondWithError(w, http.StatusBadGateway, "Échec du remboursement")
+		}
+		return
+	}
+
+	log.Printf("[RefundPaymentByID] Paiement %d remboursé de %d centimes", paymentID, refund.Amount)
+	response.RespondWithJSON(w, http.StatusOK, refund)
+}
+
+// SuspendUserByID suspend le compte d'un utilisateur pour une durée (en heures) ou définitivement,
+// en remplaçant une éventuelle suspension en cours. L'utilisateur est prévenu par e-mail.
+// Route: POST /admin/users/{id}/suspend
+func SuspendUserByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID utilisateur invalide")
+		return
+	}
+
+	var body struct {
+		Reason        string `json:"reason"`
+		DurationHours int    `json:"duration_hours"` // Ignoré (doit être absent) pour une suspension définitive
+		Permanent     bool   `json:"permanent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Corps de requête invalide")
+		return
+	}
+
+	suspension, err := service.SuspendUser(auditActor(r), userID, body.Reason, time.Duration(body.DurationHours)*time.Hour, body.Permanent, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSuspensionInvalid):
+			response.RespondWithError(w, http.StatusBadRequest, "Motif requis et durée positive, ou suspension définitive sans durée")
+		case errors.Is(err, service.ErrSuspensionForbidden):
+			response.RespondWithError(w, http.StatusForbidden, "Ce compte ne peut pas être suspendu")
+		case errors.Is(err, repository.ErrAccountNotFound):
+			response.RespondWithError(w, http.StatusNotFound, "Utilisateur non trouvé")
+		default:
+			log.Printf("[SuspendUserByID][ERREUR] Suspension de l'utilisateur %d échouée : %v", userID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Échec de la suspension du compte")
+		}
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusCreated, suspension)
+}
+
+// LiftSuspensionByID lève la suspension active d'un utilisateur et l'en informe par e-mail.
+// Route: DELETE /admin/users/{id}/suspension
+func LiftSuspensionByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID utilisateur invalide")
+		return
+	}
+
+	suspension, err := service.LiftUserSuspension(auditActor(r), userID, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrSuspensionNotFound) {
+			response.RespondWithError(w, http.StatusNotFound, "Aucune suspension active pour cet utilisateur")
+			return
+		}
+		log.Printf("[LiftSuspensionByID][ERREUR] Levée de la suspension de l'utilisateur %d échouée : %v", userID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Échec de la levée de la suspension")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, suspension)
+}
+
+// ListSuspensions liste les suspensions, les plus récentes d'abord : toutes, ou seulement celles
+// en vigueur (active=true), éventuellement pour un utilisateur (user_id).
+// Route: GET /admin/suspensions?active=true&user_id=...&limit=...&offset=...
+func ListSuspensions(w http.ResponseWriter, r *http.Request) {
+	activeOnly := r.URL.Query().Get("active") == "true"
+	var userID int64
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			response.RespondWithError(w, http.StatusBadRequest, "ID utilisateur invalide")
+			return
+		}
+		userID = id
+	}
+	limit, offset := parsePaginationParams(r)
+
+	suspensions, total, err := repository.ListSuspensions(activeOnly, userID, time.Now(), limit, offset)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Échec de la récupération des suspensions")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"suspensions": suspensions,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"onlyflick/internal/domain"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/pkg/response"
+)
+
+// auditActor identifie l'administrateur connecté et l'IP de sa requête pour le journal d'audit.
+func auditActor(r *http.Request) domain.AuditActor {
+	adminID, _ := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	return domain.AuditActor{ActorID: adminID, IPAddress: sessionClient(r).IPAddress}
+}
+
+// ListAuditLog liste le journal d'audit d'administration, les entrées les plus récentes d'abord.
+// Filtres : actor_id, action, target_type, target_id, since et until (RFC 3339 ou AAAA-MM-JJ).
+// La page suivante s'obtient en repassant next_cursor dans cursor (null sur la dernière page).
+// Route: GET /admin/audit?actor_id=...&action=...&target_type=...&target_id=...&since=...&until=...&cursor=...&limit=...
+func ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.AuditFilter{Action: query.Get("action"), TargetType: query.Get("target_type")}
+
+	ids := map[string]*int64{"actor_id": &filter.ActorID, "target_id": &filter.TargetID}
+	var cursor int64
+	ids["cursor"] = &cursor
+	for name, dest := range ids {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			response.RespondWithError(w, http.StatusBadRequest, "Paramètre "+name+" invalide")
+			return
+		}
+		*dest = id
+	}
+
+	dates := map[string]**time.Time{"since": &filter.Since, "until": &filter.Until}
+	for name, dest := range dates {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := parseAuditDate(raw)
+		if err != nil {
+			response.RespondWithError(w, http.StatusBadRequest, "Paramètre "+name+" invalide (RFC 3339 ou AAAA-MM-JJ)")
+			return
+		}
+		*dest = &t
+	}
+
+	limit, _ := parsePaginationParams(r)
+	// Une entrée de plus pour savoir s'il reste une page
+	entries, err := repository.ListAuditEntries(filter, cursor, limit+1)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Échec de la lecture du journal d'audit")
+		return
+	}
+
+	var nextCursor *int64
+	if len(entries) > limit {
+		entries = entries[:limit]
+		nextCursor = &entries[limit-1].ID
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"entries":     entries,
+		"next_cursor": nextCursor,
+	})
+}
+
+// parseAuditDate accepte un horodatage RFC 3339 ou une date AAAA-MM-JJ (minuit UTC).
+func parseAuditDate(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
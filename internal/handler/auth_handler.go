@@ -2,10 +2,14 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"onlyflick/internal/domain"
 	"onlyflick/internal/middleware"
@@ -29,6 +33,10 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // ===== VALIDATION USERNAME =====
 func validateUsername(username string) (bool, string) {
 	// Nettoyer le username (retirer les espaces)
@@ -131,9 +139,14 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// ===== CHIFFREMENT DONNÉES SENSIBLES =====
-	encryptedEmail, _ := utils.EncryptAES(req.Email)
-	encryptedFirstName, _ := utils.EncryptAES(req.FirstName)
-	encryptedLastName, _ := utils.EncryptAES(req.LastName)
+	encryptedEmail, errEmail := utils.EncryptAES(req.Email)
+	encryptedFirstName, errFirstName := utils.EncryptAES(req.FirstName)
+	encryptedLastName, errLastName := utils.EncryptAES(req.LastName)
+	if errEmail != nil || errFirstName != nil || errLastName != nil {
+		log.Printf("[RegisterHandler] Erreur chiffrement des données personnelles : %v", errors.Join(errEmail, errFirstName, errLastName))
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur chiffrement des données")
+		return
+	}
 
 	// ===== CRÉATION UTILISATEUR AVEC USERNAME =====
 	user := &domain.User{
@@ -153,8 +166,13 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ===== GÉNÉRATION JWT =====
-	token, err := service.GenerateJWT(user.ID, string(user.Role))
+	// ===== E-MAIL DE VÉRIFICATION (ENVOI ASYNCHRONE) =====
+	recipient := *user
+	recipient.Email = req.Email
+	go service.SendVerificationEmail(&recipient)
+
+	// ===== OUVERTURE DE SESSION (ACCESS + REFRESH TOKEN) =====
+	tokens, err := service.IssueSessionTokens(user.ID, string(user.Role), sessionClient(r))
 	if err != nil {
 		response.RespondWithError(w, http.StatusInternalServerError, "Erreur JWT")
 		return
@@ -167,7 +185,10 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		"message": "Inscription réussie",
 		"user_id": user.ID,
 		"username": user.Username,  // ===== RETOURNER LE USERNAME =====
-		"token":   token,
+		"token":   tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"email_verified": false,
 	})
 }
 
@@ -180,33 +201,185 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ===== PROTECTION CONTRE LA FORCE BRUTE (PAR IP PUIS PAR COMPTE) =====
+	now := time.Now()
+	clientIP := utils.ClientIP(r)
+	if err := service.CheckLoginThrottle(domain.LoginThrottleIP, clientIP, now); err != nil {
+		respondLoginThrottled(w, err)
+		return
+	}
+
 	user, err := repository.GetUserByEmail(req.Email)
 	if err != nil || user == nil {
+		service.RecordFailedLogin(nil, clientIP, now)
 		response.RespondWithError(w, http.StatusUnauthorized, "Email ou mot de passe invalide")
 		return
 	}
 
+	if err := service.CheckLoginThrottle(domain.LoginThrottleAccount, strconv.FormatInt(user.ID, 10), now); err != nil {
+		respondLoginThrottled(w, err)
+		return
+	}
+
 	if !service.CheckPasswordHash(req.Password, user.Password) {
+		service.RecordFailedLogin(user, clientIP, now)
 		response.RespondWithError(w, http.StatusUnauthorized, "Email ou mot de passe invalide")
 		return
 	}
+	service.RecordSuccessfulLogin(user.ID)
+
+	completeLogin(w, r, user, now)
+}
+
+// completeLogin termine une authentification réussie (mot de passe ou fournisseur externe) :
+// défi de second facteur si la double authentification est active, ouverture de session sinon.
+func completeLogin(w http.ResponseWriter, r *http.Request, user *domain.User, now time.Time) {
+	// ===== COMPTE SUSPENDU : CONNEXION REFUSÉE =====
+	suspension, err := repository.GetActiveSuspension(user.ID, now)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification du compte")
+		return
+	}
+	if suspension != nil {
+		log.Printf("[completeLogin] Connexion refusée, compte suspendu - ID: %d", user.ID)
+		response.RespondWithErrorCode(w, http.StatusForbidden, service.SuspendedErrorCode, service.SuspensionMessage(suspension))
+		return
+	}
+
+	// ===== DOUBLE AUTHENTIFICATION : DÉFI AU LIEU DES JETONS =====
+	mfaEnabled, err := repository.IsMFAEnabled(user.ID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification du compte")
+		return
+	}
+	if mfaEnabled {
+		challenge, err := service.StartMFAChallenge(user.ID, now)
+		if err != nil {
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur ouverture du défi de connexion")
+			return
+		}
+		log.Printf("[completeLogin] Second facteur requis - ID: %d", user.ID)
+		response.RespondWithJSON(w, http.StatusOK, challenge)
+		return
+	}
 
-	token, err := service.GenerateJWT(user.ID, string(user.Role))
+	tokens, err := service.IssueSessionTokens(user.ID, string(user.Role), sessionClient(r))
 	if err != nil {
 		response.RespondWithError(w, http.StatusInternalServerError, "Erreur génération JWT")
 		return
 	}
 
-	log.Printf("[LoginHandler] Connexion réussie - ID: %d, Username: %s", user.ID, user.Username)
+	respondWithSession(w, user, tokens, false)
+}
+
+// respondWithSession retourne les jetons d'une session ouverte, avec l'état du compte utile au front-end.
+// mfaVerified indique que le second facteur vient d'être présenté.
+func respondWithSession(w http.ResponseWriter, user *domain.User, tokens *domain.AuthTokens, mfaVerified bool) {
+	emailVerified, err := repository.IsEmailVerified(user.ID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification du compte")
+		return
+	}
+
+	// Se reconnecter pendant le délai de grâce annule la suppression du compte
+	deletionCancelled, err := repository.CancelAccountDeletion(user.ID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification du compte")
+		return
+	}
+	if deletionCancelled {
+		log.Printf("[respondWithSession] Suppression du compte annulée par la connexion - ID: %d", user.ID)
+	}
+
+	log.Printf("[respondWithSession] Connexion réussie - ID: %d, Username: %s", user.ID, user.Username)
 
 	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "Connexion réussie",
 		"user_id": user.ID,
 		"username": user.Username,  // ===== RETOURNER LE USERNAME =====
-		"token":   token,
+		"token":   tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"email_verified": emailVerified,
+		"mfa_enrollment_required": user.IsAdmin() && !mfaVerified, // Les administrateurs doivent activer la double authentification
+		"account_deletion_cancelled": deletionCancelled,
 	})
 }
 
+// respondLoginThrottled répond à une tentative de connexion refusée par la protection contre la force brute.
+// La réponse est la même pour un compte verrouillé et une IP ralentie, afin de ne pas révéler l'existence du compte.
+func respondLoginThrottled(w http.ResponseWriter, err error) {
+	var throttled *service.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		log.Printf("[LoginHandler] Erreur vérification des échecs de connexion : %v", err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur lors de la connexion")
+		return
+	}
+
+	retryAfter := int64(math.Ceil(throttled.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	response.RespondWithError(w, http.StatusTooManyRequests, "Trop de tentatives de connexion, réessayez plus tard")
+}
+
+// RefreshHandler échange un refresh token contre une nouvelle paire de jetons.
+// Route: POST /auth/refresh
+func RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		response.RespondWithError(w, http.StatusBadRequest, "Refresh token manquant")
+		return
+	}
+
+	tokens, err := service.RefreshSessionTokens(req.RefreshToken, sessionClient(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRefreshTokenReused):
+			response.RespondWithError(w, http.StatusUnauthorized, "Refresh token déjà utilisé, session révoquée")
+		case errors.Is(err, repository.ErrRefreshTokenInvalid):
+			response.RespondWithError(w, http.StatusUnauthorized, "Refresh token invalide ou expiré")
+		default:
+			log.Printf("[RefreshHandler] Erreur renouvellement de session : %v", err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur renouvellement de session")
+		}
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+// LogoutHandler ferme la session du refresh token fourni, ou à défaut celle de l'access token courant.
+// Route: POST /auth/logout
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.RespondWithError(w, http.StatusBadRequest, "Requête invalide")
+			return
+		}
+	}
+
+	var err error
+	if req.RefreshToken != "" {
+		err = service.RevokeRefreshToken(req.RefreshToken)
+	} else {
+		userID, _ := r.Context().Value(middleware.ContextUserIDKey).(int64)
+		sessionID, _ := r.Context().Value(middleware.ContextSessionIDKey).(int64)
+		if sessionID == 0 {
+			response.RespondWithError(w, http.StatusBadRequest, "Aucune session à fermer")
+			return
+		}
+		err = repository.RevokeSession(sessionID, userID, domain.SessionRevokedLogout)
+	}
+
+	// Une session déjà fermée est considérée comme déconnectée
+	if err != nil && !errors.Is(err, repository.ErrRefreshTokenInvalid) && !errors.Is(err, repository.ErrSessionNotFound) {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur lors de la déconnexion")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Déconnexion réussie"})
+}
+
 func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("[AUTH][ProfileHandler] Récupération du profil")
 
@@ -223,10 +396,8 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Décryptage des champs chiffrés (déjà fait dans GetUserByID, mais on refait par sécurité)
-	firstName, _ := utils.DecryptAES(user.FirstName)
-	lastName, _ := utils.DecryptAES(user.LastName)
-	email, _ := utils.DecryptAES(user.Email)
+	// Champs déjà déchiffrés par GetUserByID
+	firstName, lastName, email := user.FirstName, user.LastName, user.Email
 
 	// ===== RETOURNER TOUS LES CHAMPS DU PROFIL =====
 	profile := domain.User{
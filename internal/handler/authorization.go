@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
+	"onlyflick/internal/repository"
+	"onlyflick/pkg/response"
+)
+
+// authorize soumet l'action à la politique d'accès. En cas de refus, la réponse d'erreur
+// (message deniedMsg) est écrite et false est retourné.
+func authorize(w http.ResponseWriter, r *http.Request, funcName string, action policy.Action, resource policy.Resource, deniedMsg string) bool {
+	actor, err := middleware.ActorFromRequest(r)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification des droits")
+		return false
+	}
+	if !policy.Can(actor, action, resource) {
+		log.Printf("[%s] Action %s refusée à l'utilisateur %d", funcName, action, actor.UserID)
+		response.RespondWithError(w, http.StatusForbidden, deniedMsg)
+		return false
+	}
+	return true
+}
+
+// canViewSubscriberContent indique si l'utilisateur connecté peut voir le contenu réservé aux abonnés
+// du créateur ; l'abonnement n'est vérifié que si la politique ne suffit pas à l'accorder.
+func canViewSubscriberContent(r *http.Request, viewerID, creatorID int64) (bool, error) {
+	actor, err := middleware.ActorFromRequest(r)
+	if err != nil {
+		return false, err
+	}
+	resource := policy.Resource{OwnerID: creatorID}
+	if policy.Can(actor, policy.PostViewSubscriberContent, resource) {
+		return true, nil
+	}
+	resource.Subscribed, err = repository.IsSubscribed(viewerID, creatorID)
+	if err != nil {
+		return false, err
+	}
+	return policy.Can(actor, policy.PostViewSubscriberContent, resource), nil
+}
+
+// conversationResource décrit une conversation pour la politique d'accès : le créateur en est le propriétaire,
+// et l'abonnement de l'autre participant est vérifié quand c'est lui qui agit.
+// En cas d'erreur, la réponse est écrite et false est retourné.
+func conversationResource(w http.ResponseWriter, funcName string, conversationID, userID int64) (policy.Resource, bool) {
+	creatorID, subscriberID, err := repository.GetConversationParticipants(conversationID)
+	if errors.Is(err, repository.ErrConversationNotFound) {
+		response.RespondWithError(w, http.StatusNotFound, "Conversation introuvable")
+		return policy.Resource{}, false
+	}
+	if err != nil {
+		log.Printf("[%s] Erreur lecture de la conversation %d : %v", funcName, conversationID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur interne")
+		return policy.Resource{}, false
+	}
+
+	resource := policy.Resource{OwnerID: creatorID, ParticipantIDs: []int64{creatorID, subscriberID}}
+	if userID == subscriberID {
+		resource.Subscribed, err = repository.IsSubscribed(subscriberID, creatorID)
+		if err != nil {
+			log.Printf("[%s] Erreur vérification abonnement (user: %d, creator: %d) : %v", funcName, subscriberID, creatorID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur interne")
+			return policy.Resource{}, false
+		}
+	}
+	return resource, true
+}
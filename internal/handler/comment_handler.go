@@ -8,6 +8,7 @@ import (
 
 	"onlyflick/internal/domain"
 	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
 	"onlyflick/internal/repository"
 	"onlyflick/pkg/response"
 
@@ -33,6 +34,10 @@ func CreateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorize(w, r, "CreateComment", policy.CommentCreate, policy.None, "Non autorisé à commenter") {
+		return
+	}
+
 	comment.UserID = userID
 
 	if err := repository.CreateComment(&comment); err != nil {
@@ -68,7 +73,7 @@ func GetComments(w http.ResponseWriter, r *http.Request) {
 	response.RespondWithJSON(w, http.StatusOK, comments)
 }
 
-// DeleteComment permet à un admin ou auteur du commentaire de le supprimer.
+// DeleteComment permet à l'auteur du commentaire ou à un modérateur de le supprimer.
 func DeleteComment(w http.ResponseWriter, r *http.Request) {
 	log.Println("[CommentHandler] Suppression d'un commentaire")
 
@@ -80,9 +85,6 @@ func DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	roleVal := r.Context().Value(middleware.ContextUserRoleKey)
-	userRole, _ := roleVal.(string)
-
 	commentIDStr := chi.URLParam(r, "id")
 	commentID, err := strconv.ParseInt(commentIDStr, 10, 64)
 	if err != nil {
@@ -98,9 +100,7 @@ func DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if userRole != "admin" && comment.UserID != userID {
-		response.RespondWithError(w, http.StatusForbidden, "Non autorisé à supprimer ce commentaire")
-		log.Printf("[DeleteComment] Accès refusé utilisateur %d pour commentaire %d", userID, commentID)
+	if !authorize(w, r, "DeleteComment", policy.CommentDelete, policy.Resource{OwnerID: comment.UserID}, "Non autorisé à supprimer ce commentaire") {
 		return
 	}
 
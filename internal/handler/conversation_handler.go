@@ -3,7 +3,9 @@ package handler
 import (
 	"log"
 	"net/http"
+	"onlyflick/internal/domain"
 	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
 	"onlyflick/internal/repository"
 	"onlyflick/pkg/response"
 	"strconv"
@@ -31,36 +33,34 @@ func StartConversation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. Vérification que le receiver est bien un créateur
+	// 2. Le destinataire doit être un créateur auquel l'utilisateur est abonné
 	receiver, err := repository.GetUserByID(receiverID)
-	if err != nil || receiver == nil || receiver.Role != "creator" {
+	if err != nil || receiver == nil || receiver.Role != domain.RoleCreator {
 		log.Printf("[StartConversation] Utilisateur cible %d n’est pas un créateur", receiverID)
 		response.RespondWithError(w, http.StatusForbidden, "Vous ne pouvez discuter qu’avec des créateurs")
 		return
 	}
 
-	// 3. Vérifier si l'utilisateur est abonné à ce créateur
 	isSub, err := repository.IsSubscribed(userID, receiverID)
 	if err != nil {
 		log.Printf("[StartConversation] Erreur vérification abonnement: %v", err)
 		response.RespondWithError(w, http.StatusInternalServerError, "Erreur interne")
 		return
 	}
-	if !isSub {
-		log.Printf("[StartConversation] Utilisateur %d NON abonné à %d", userID, receiverID)
-		response.RespondWithError(w, http.StatusForbidden, "Vous devez être abonné pour démarrer la discussion")
+	resource := policy.Resource{OwnerID: receiverID, OwnerRole: receiver.Role, Subscribed: isSub}
+	if !authorize(w, r, "StartConversation", policy.ConversationStart, resource, "Vous devez être abonné pour démarrer la discussion") {
 		return
 	}
 
-	// 4. Démarrer ou récupérer la conversation
-	convID, err := repository.GetConversationByParticipants(userID, receiverID)
+	// 3. Démarrer ou récupérer la conversation (le créateur est le destinataire)
+	convID, err := repository.GetConversationByParticipants(receiverID, userID)
 	if err != nil {
 		log.Printf("[StartConversation] Erreur DB: %v", err)
 		response.RespondWithError(w, http.StatusInternalServerError, "Erreur interne")
 		return
 	}
 	if convID == 0 {
-		convID, err = repository.CreateConversation(userID, receiverID)
+		convID, err = repository.CreateConversation(receiverID, userID)
 		if err != nil {
 			log.Printf("[StartConversation] Erreur création conversation: %v", err)
 			response.RespondWithError(w, http.StatusInternalServerError, "Erreur création conversation")
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/pkg/response"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Nombre d'opérations renvoyées par défaut et au maximum par GET /creator/earnings
+const (
+	defaultEarningsLimit = 50
+	maxEarningsLimit     = 200
+)
+
+// parseEarningsPeriod lit la période demandée : ?month=AAAA-MM, ou ?from=AAAA-MM-JJ et/ou ?to=AAAA-MM-JJ (inclus).
+// Retourne un message d'erreur si la période est invalide.
+func parseEarningsPeriod(r *http.Request) (*time.Time, *time.Time, string) {
+	query := r.URL.Query()
+
+	if month := query.Get("month"); month != "" {
+		start, err := time.Parse("2006-01", month)
+		if err != nil {
+			return nil, nil, "Mois invalide (format attendu : AAAA-MM)"
+		}
+		end := start.AddDate(0, 1, 0)
+		return &start, &end, ""
+	}
+
+	var from, to *time.Time
+	if raw := query.Get("from"); raw != "" {
+		start, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, nil, "Date de début invalide (format attendu : AAAA-MM-JJ)"
+		}
+		from = &start
+	}
+	if raw := query.Get("to"); raw != "" {
+		end, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, nil, "Date de fin invalide (format attendu : AAAA-MM-JJ)"
+		}
+		end = end.AddDate(0, 0, 1) // Date de fin incluse
+		to = &end
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, "La date de début doit précéder la date de fin"
+	}
+
+	return from, to, ""
+}
+
+// GetMyEarnings retourne les revenus du créateur connecté : totaux par devise (brut, commission,
+// remboursements, net, versé), solde restant dû et dernières opérations du grand livre.
+// Route: GET /creator/earnings?from=&to= ou ?month=
+func GetMyEarnings(w http.ResponseWriter, r *http.Request) {
+	creatorID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[GetMyEarnings] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	from, to, msg := parseEarningsPeriod(r)
+	if msg != "" {
+		response.RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	limit := defaultEarningsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if l, err := strconv.Atoi(raw); err == nil && l > 0 && l <= maxEarningsLimit {
+			limit = l
+		}
+	}
+
+	earnings, err := repository.GetCreatorEarnings(creatorID, from, to, limit)
+	if err != nil {
+		log.Printf("[GetMyEarnings] Erreur récupération des revenus du créateur %d : %v", creatorID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération des revenus")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, earnings)
+}
+
+// GetMyStatements retourne les relevés mensuels du créateur connecté pour une année (année courante par défaut).
+// Route: GET /creator/earnings/statements?year=AAAA
+func GetMyStatements(w http.ResponseWriter, r *http.Request) {
+	creatorID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[GetMyStatements] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	year := time.Now().Year()
+	if raw := r.URL.Query().Get("year"); raw != "" {
+		y, err := strconv.Atoi(raw)
+		if err != nil || y < 2000 || y > 9999 {
+			response.RespondWithError(w, http.StatusBadRequest, "Année invalide")
+			return
+		}
+		year = y
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	statements, err := repository.GetCreatorMonthlyStatements(creatorID, from, from.AddDate(1, 0, 0))
+	if err != nil {
+		log.Printf("[GetMyStatements] Erreur récupération des relevés du créateur %d : %v", creatorID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération des relevés")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"year":       year,
+		"statements": statements,
+	})
+}
+
+// CreatePayoutBatch crée un lot de versements pour les revenus non versés antérieurs à ?before=AAAA-MM-JJ
+// (maintenant par défaut), marque les opérations comme versées et renvoie le lot au format CSV.
+// Route: POST /admin/payouts
+func CreatePayoutBatch(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[CreatePayoutBatch] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	cutoff := time.Now()
+	if raw := r.URL.Query().Get("before"); raw != "" {
+		before, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.RespondWithError(w, http.StatusBadRequest, "Date invalide (format attendu : AAAA-MM-JJ)")
+			return
+		}
+		cutoff = before
+	}
+
+	batch, err := repository.CreatePayoutBatch(auditActor(r), cutoff)
+	if err != nil {
+		log.Printf("[CreatePayoutBatch] Erreur création du lot de versements : %v", err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur création du lot de versements")
+		return
+	}
+
+	log.Printf("[CreatePayoutBatch] Lot %d créé par l'admin %d", batch.ID, adminID)
+	writePayoutCSV(w, batch.ID)
+}
+
+// ExportPayoutBatch renvoie à nouveau le CSV d'un lot de versements existant.
+// Route: GET /admin/payouts/{id}/export
+func ExportPayoutBatch(w http.ResponseWriter, r *http.Request) {
+	batchID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID de lot invalide")
+		return
+	}
+
+	exists, err := repository.PayoutBatchExists(batchID)
+	if err != nil {
+		log.Printf("[ExportPayoutBatch] Erreur vérification du lot %d : %v", batchID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur export du lot de versements")
+		return
+	}
+	if !exists {
+		response.RespondWithError(w, http.StatusNotFound, "Lot de versements introuvable")
+		return
+	}
+
+	writePayoutCSV(w, batchID)
+}
+
+// writePayoutCSV écrit les versements d'un lot au format CSV (une ligne par créateur et devise).
+func writePayoutCSV(w http.ResponseWriter, batchID int64) {
+	lines, err := repository.GetPayoutLines(batchID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur export du lot de versements")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"payouts-%d.csv\"", batchID))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"batch_id", "creator_id", "username", "currency", "amount_cents", "amount", "transactions"})
+	for _, l := range lines {
+		_ = writer.Write([]string{
+			strconv.FormatInt(l.BatchID, 10),
+			strconv.FormatInt(l.CreatorID, 10),
+			l.Username,
+			l.Currency,
+			strconv.FormatInt(l.Amount, 10),
+			domain.FormatPrice(int(l.Amount)),
+			strconv.Itoa(l.TransactionsCount),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("[writePayoutCSV] Erreur écriture du CSV du lot %d : %v", batchID, err)
+	}
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"onlyflick/pkg/response"
+	"time"
+)
+
+// VerifyEmailHandler confirme l'adresse e-mail à partir du lien signé reçu par e-mail.
+// Route: GET /auth/verify-email?token=...
+func VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		response.RespondWithError(w, http.StatusBadRequest, "Lien de vérification manquant")
+		return
+	}
+
+	userID, err := service.VerifyEmailToken(token, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrVerificationTokenExpired):
+			response.RespondWithError(w, http.StatusGone, "Lien de vérification expiré, demandez un nouvel e-mail")
+		case errors.Is(err, service.ErrInvalidVerificationToken):
+			response.RespondWithError(w, http.StatusBadRequest, "Lien de vérification invalide")
+		default:
+			log.Printf("[VerifyEmailHandler] Erreur confirmation de l'adresse : %v", err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur lors de la vérification")
+		}
+		return
+	}
+
+	log.Printf("[VerifyEmailHandler] Adresse e-mail de l'utilisateur %d vérifiée", userID)
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Adresse e-mail vérifiée"})
+}
+
+// ResendVerificationEmailHandler renvoie l'e-mail de vérification à l'utilisateur connecté.
+// Route: POST /auth/verify-email/resend
+func ResendVerificationEmailHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[ResendVerificationEmailHandler] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	verified, err := repository.IsEmailVerified(userID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification du compte")
+		return
+	}
+	if verified {
+		response.RespondWithError(w, http.StatusConflict, "Adresse e-mail déjà vérifiée")
+		return
+	}
+
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusNotFound, "Utilisateur introuvable")
+		return
+	}
+
+	if err := service.SendVerificationEmail(user); err != nil {
+		if errors.Is(err, service.ErrVerificationEmailThrottled) {
+			response.RespondWithError(w, http.StatusTooManyRequests, "Un e-mail vient d'être envoyé, réessayez dans une minute")
+			return
+		}
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur lors de l'envoi de l'e-mail")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "E-mail de vérification envoyé"})
+}
@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"net/http"
+	"onlyflick/internal/service"
+	"onlyflick/pkg/response"
+)
+
+// JWKSHandler publie les clés publiques de vérification des access tokens, pour les services tiers.
+// Route: GET /.well-known/jwks.json
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.RespondWithJSON(w, http.StatusOK, service.JWKS())
+}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
+	"onlyflick/internal/repository"
 	"onlyflick/internal/service"
 	"onlyflick/pkg/response"
 	"path/filepath"
@@ -16,6 +19,15 @@ import (
 func UploadMedia(w http.ResponseWriter, r *http.Request) {
 	log.Println("[UploadMedia] Début de l'upload")
 
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+	if !authorize(w, r, "UploadMedia", policy.MediaUpload, policy.None, "Accès refusé") {
+		return
+	}
+
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
 		log.Printf("[UploadMedia] Erreur parsing multipart : %v", err)
 		response.RespondWithError(w, http.StatusBadRequest, "Formulaire invalide")
@@ -44,6 +56,12 @@ func UploadMedia(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Le propriétaire du fichier est retenu pour autoriser sa suppression
+	if err := repository.RecordMediaFile(fileID, userID, url); err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Échec de l'enregistrement du média")
+		return
+	}
+
 	log.Printf("[UploadMedia] Succès - URL=%s, ID=%s", url, fileID)
 	response.RespondWithJSON(w, http.StatusOK, map[string]string{
 		"url":     url,
@@ -52,19 +70,11 @@ func UploadMedia(w http.ResponseWriter, r *http.Request) {
 }
 
 // DeleteMedia supprime un média via son file_id.
-// Accès restreint aux rôles "admin" et "creator".
+// Seul son propriétaire (ou un modérateur) peut le supprimer ; un fichier dont le propriétaire
+// est inconnu n'est supprimable que par un modérateur.
 func DeleteMedia(w http.ResponseWriter, r *http.Request) {
 	log.Println("[DeleteMedia] Suppression de média")
 
-	roleVal := r.Context().Value(middleware.ContextUserRoleKey)
-	userRole, _ := roleVal.(string)
-
-	if userRole != "admin" && userRole != "creator" {
-		log.Printf("[DeleteMedia] Rôle non autorisé : %s", userRole)
-		response.RespondWithError(w, http.StatusForbidden, "Accès refusé")
-		return
-	}
-
 	fileID := chi.URLParam(r, "file_id")
 	if fileID == "" {
 		log.Println("[DeleteMedia] file_id manquant")
@@ -72,11 +82,30 @@ func DeleteMedia(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ownerID, err := repository.GetMediaOwner(fileID)
+	switch {
+	case errors.Is(err, repository.ErrMediaNotFound):
+		log.Printf("[DeleteMedia] Média sans propriétaire connu : %s", fileID)
+		if !authorize(w, r, "DeleteMedia", policy.MediaDelete, policy.None, "Accès refusé") {
+			return
+		}
+	case err != nil:
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification du média")
+		return
+	default:
+		if !authorize(w, r, "DeleteMedia", policy.MediaDelete, policy.Resource{OwnerID: ownerID}, "Accès refusé") {
+			return
+		}
+	}
+
 	if err := service.DeleteFile(fileID); err != nil {
 		log.Printf("[DeleteMedia] Échec suppression ID=%s : %v", fileID, err)
 		response.RespondWithError(w, http.StatusInternalServerError, "Suppression échouée")
 		return
 	}
+	if err := repository.DeleteMediaFile(fileID); err != nil {
+		log.Printf("[DeleteMedia] Média %s supprimé d'ImageKit mais pas de la base : %v", fileID, err)
+	}
 
 	log.Printf("[DeleteMedia] Suppression réussie - ID=%s", fileID)
 	response.RespondWithJSON(w, http.StatusOK, map[string]string{
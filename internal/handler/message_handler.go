@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"onlyflick/internal/database"
 	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
 	"onlyflick/internal/repository"
 	"onlyflick/pkg/response"
 	"strconv"
@@ -26,15 +26,8 @@ func GetConversationMessages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Vérifie que l'utilisateur participe à la conversation
-	isParticipant, err := repository.IsUserInConversation(convID, userID)
-	if err != nil {
-		log.Printf("[GetConversationMessages] Erreur vérification participant : %v", err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Erreur interne")
-		return
-	}
-	if !isParticipant {
-		log.Printf("[GetConversationMessages] Accès refusé à la conversation %d", convID)
-		response.RespondWithError(w, http.StatusForbidden, "Accès interdit à cette conversation")
+	resource, ok := conversationResource(w, "GetConversationMessages", convID, userID)
+	if !ok || !authorize(w, r, "GetConversationMessages", policy.ConversationRead, resource, "Accès interdit à cette conversation") {
 		return
 	}
 
@@ -72,15 +65,8 @@ func SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isIn, err := repository.IsUserInConversation(convID, userID)
-	if err != nil {
-		log.Printf("[SendMessage] Erreur vérification: %v", err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Erreur interne")
-		return
-	}
-	if !isIn {
-		log.Printf("[SendMessage] Utilisateur non autorisé à envoyer dans convers %d", convID)
-		response.RespondWithError(w, http.StatusForbidden, "Accès interdit")
+	resource, ok := conversationResource(w, "SendMessage", convID, userID)
+	if !ok || !authorize(w, r, "SendMessage", policy.ConversationSend, resource, "Accès interdit") {
 		return
 	}
 
@@ -133,6 +119,11 @@ func GetMessagesInConversation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	resource, ok := conversationResource(w, "GetMessagesInConversation", convID, userID)
+	if !ok || !authorize(w, r, "GetMessagesInConversation", policy.ConversationRead, resource, "Accès interdit à cette conversation") {
+		return
+	}
+
 	messages, err := repository.GetMessagesForConversation(convID, userID)
 	if err != nil {
 		log.Printf("[GetMessagesInConversation] Erreur récupération messages pour conversation %d : %v", convID, err)
@@ -157,38 +148,11 @@ func SendMessageInConversation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isInConv, err := repository.IsUserInConversation(conversationID, userID)
-	if err != nil {
-		log.Printf("[SendMessageInConversation] Erreur vérif participation : %v", err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Erreur participation")
+	// Participant de la conversation et, côté abonné, abonnement toujours actif
+	resource, ok := conversationResource(w, "SendMessageInConversation", conversationID, userID)
+	if !ok || !authorize(w, r, "SendMessageInConversation", policy.ConversationSend, resource, "Vous devez être abonné pour discuter") {
 		return
 	}
-	if !isInConv {
-		log.Printf("[SendMessageInConversation] Utilisateur %d n’est pas dans la conversation %d", userID, conversationID)
-		response.RespondWithError(w, http.StatusForbidden, "Accès non autorisé")
-		return
-	}
-
-	// Ici tu peux rajouter une requête directe pour récupérer creator_id et subscriber_id
-	var creatorID, subscriberID int64
-	err = database.DB.QueryRow(`
-		SELECT creator_id, subscriber_id FROM conversations WHERE id = $1
-	`, conversationID).Scan(&creatorID, &subscriberID)
-	if err != nil {
-		log.Printf("[SendMessageInConversation] Conversation introuvable ou erreur SQL : %v", err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Conversation non trouvée")
-		return
-	}
-
-	// Vérifie abonnement
-	if userID == subscriberID {
-		isSub, err := repository.IsSubscribed(subscriberID, creatorID)
-		if err != nil || !isSub {
-			log.Printf("[SendMessageInConversation] Utilisateur %d n’est pas abonné au créateur %d", subscriberID, creatorID)
-			response.RespondWithError(w, http.StatusForbidden, "Vous devez être abonné pour discuter")
-			return
-		}
-	}
 
 	var req struct {
 		Content string `json:"content"`
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"onlyflick/pkg/response"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// MFACodeRequest porte un code TOTP ou un code de secours.
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFAVerifyRequest répond au défi retourné par la connexion quand la double authentification est active.
+type MFAVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// respondMFAError traduit les erreurs de double authentification en réponses HTTP.
+func respondMFAError(w http.ResponseWriter, funcName string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode):
+		response.RespondWithError(w, http.StatusUnauthorized, "Code de vérification invalide")
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		response.RespondWithError(w, http.StatusConflict, "Double authentification déjà activée")
+	case errors.Is(err, service.ErrMFANotEnrolled):
+		response.RespondWithError(w, http.StatusBadRequest, "Double authentification non configurée")
+	case errors.Is(err, repository.ErrMFAChallengeInvalid):
+		response.RespondWithError(w, http.StatusUnauthorized, "Défi de connexion invalide ou expiré, reconnectez-vous")
+	default:
+		log.Printf("[%s] Erreur double authentification : %v", funcName, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur de double authentification")
+	}
+}
+
+// decodeMFACode lit le code de la requête ; retourne false après avoir répondu en cas d'erreur.
+func decodeMFACode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		response.RespondWithError(w, http.StatusBadRequest, "Code de vérification requis")
+		return "", false
+	}
+	return req.Code, true
+}
+
+// EnrollMFA démarre l'enrôlement TOTP et retourne le secret et l'URI à afficher en QR code.
+// Route: POST /profile/mfa/enroll
+func EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[EnrollMFA] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusNotFound, "Utilisateur introuvable")
+		return
+	}
+
+	enrollment, err := service.StartMFAEnrollment(user)
+	if err != nil {
+		respondMFAError(w, "EnrollMFA", err)
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, enrollment)
+}
+
+// ConfirmMFA active la double authentification avec un premier code et retourne les codes de secours.
+// Route: POST /profile/mfa/confirm
+func ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[ConfirmMFA] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+	sessionID, _ := r.Context().Value(middleware.ContextSessionIDKey).(int64)
+
+	code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := service.ConfirmMFAEnrollment(userID, sessionID, code, time.Now())
+	if err != nil {
+		respondMFAError(w, "ConfirmMFA", err)
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":        "Double authentification activée",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableMFAHandler désactive la double authentification après vérification d'un code.
+// Route: DELETE /profile/mfa
+func DisableMFAHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[DisableMFAHandler] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+	if !authorize(w, r, "DisableMFAHandler", policy.MFADisable, policy.None, "La double authentification est obligatoire pour les administrateurs") {
+		return
+	}
+
+	code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := service.DisableMFA(userID, code, time.Now()); err != nil {
+		respondMFAError(w, "DisableMFAHandler", err)
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Double authentification désactivée"})
+}
+
+// RegenerateMFARecoveryCodes remplace les codes de secours après vérification d'un code.
+// Route: POST /profile/mfa/recovery-codes
+func RegenerateMFARecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[RegenerateMFARecoveryCodes] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := service.RegenerateRecoveryCodes(userID, code, time.Now())
+	if err != nil {
+		respondMFAError(w, "RegenerateMFARecoveryCodes", err)
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{"recovery_codes": recoveryCodes})
+}
+
+// VerifyMFAHandler échange le défi de connexion et un code TOTP (ou de secours) contre les jetons de session.
+// Route: POST /auth/mfa/verify
+func VerifyMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var req MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		response.RespondWithError(w, http.StatusBadRequest, "challenge_token et code requis")
+		return
+	}
+
+	user, tokens, err := service.CompleteMFAChallenge(req.ChallengeToken, req.Code, sessionClient(r), time.Now())
+	if err != nil {
+		respondMFAError(w, "VerifyMFAHandler", err)
+		return
+	}
+
+	respondWithSession(w, user, tokens, true)
+}
+
+// AdminResetUserMFA supprime la double authentification d'un utilisateur ayant perdu son appareil
+// et ses codes de secours ; il devra se réenrôler.
+// Route: DELETE /admin/users/{id}/mfa
+func AdminResetUserMFA(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID utilisateur invalide")
+		return
+	}
+
+	actor := auditActor(r)
+	if err := repository.DisableMFA(userID, &actor); err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur réinitialisation de la double authentification")
+		return
+	}
+
+	log.Printf("[AdminResetUserMFA] Double authentification de l'utilisateur %d réinitialisée", userID)
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Double authentification réinitialisée"})
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"onlyflick/pkg/response"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// OIDCCallbackRequest transmet le code d'autorisation et le state reçus du fournisseur sur la redirect URL.
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// respondOIDCError traduit les erreurs OpenID Connect en réponses HTTP.
+func respondOIDCError(w http.ResponseWriter, funcName string, err error) {
+	switch {
+	case errors.Is(err, service.ErrOIDCProviderUnknown):
+		response.RespondWithError(w, http.StatusNotFound, "Fournisseur de connexion inconnu")
+	case errors.Is(err, repository.ErrOIDCStateInvalid):
+		response.RespondWithError(w, http.StatusBadRequest, "Connexion expirée ou déjà utilisée, recommencez")
+	case errors.Is(err, service.ErrOIDCIDTokenInvalid):
+		response.RespondWithError(w, http.StatusUnauthorized, "Identité du fournisseur invalide")
+	case errors.Is(err, service.ErrOIDCEmailMissing):
+		response.RespondWithError(w, http.StatusBadRequest, "Le fournisseur n'a pas communiqué d'adresse e-mail")
+	case errors.Is(err, service.ErrOIDCLinkRequired):
+		response.RespondWithError(w, http.StatusConflict, "Un compte existe déjà pour cette adresse : connectez-vous puis liez ce fournisseur depuis votre profil")
+	case errors.Is(err, repository.ErrIdentityAlreadyLinked):
+		response.RespondWithError(w, http.StatusConflict, "Ce compte externe est déjà lié à un utilisateur")
+	default:
+		log.Printf("[%s] Erreur OpenID Connect : %v", funcName, err)
+		response.RespondWithError(w, http.StatusBadGateway, "Erreur de communication avec le fournisseur")
+	}
+}
+
+// ListOIDCProviders retourne les fournisseurs de connexion externes disponibles.
+// Route: GET /auth/oidc/providers
+func ListOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{"providers": service.OIDCProviderNames()})
+}
+
+// StartOIDCLoginHandler retourne l'URL d'autorisation du fournisseur vers laquelle rediriger l'utilisateur.
+// Route: GET /auth/oidc/{provider}/authorize
+func StartOIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	authURL, err := service.StartOIDCLogin(chi.URLParam(r, "provider"), 0, time.Now())
+	if err != nil {
+		respondOIDCError(w, "StartOIDCLoginHandler", err)
+		return
+	}
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"authorization_url": authURL})
+}
+
+// OIDCCallbackHandler termine la connexion externe : ouverture de session (ou défi de second facteur),
+// après création ou liaison du compte si nécessaire. Termine aussi une liaison démarrée depuis le profil.
+// Route: POST /auth/oidc/{provider}/callback
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	var req OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" || req.State == "" {
+		response.RespondWithError(w, http.StatusBadRequest, "code et state requis")
+		return
+	}
+
+	now := time.Now()
+	result, err := service.CompleteOIDCLogin(chi.URLParam(r, "provider"), req.Code, req.State, now)
+	if err != nil {
+		respondOIDCError(w, "OIDCCallbackHandler", err)
+		return
+	}
+
+	// Liaison explicite : l'utilisateur est déjà connecté, aucune nouvelle session n'est ouverte
+	if result.LinkOnly {
+		response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Compte externe lié"})
+		return
+	}
+
+	service.RecordSuccessfulLogin(result.User.ID)
+	completeLogin(w, r, result.User, now)
+}
+
+// LinkOIDCIdentity démarre la liaison d'un compte externe à l'utilisateur connecté et retourne l'URL d'autorisation.
+// Route: POST /profile/identities/{provider}
+func LinkOIDCIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[LinkOIDCIdentity] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	authURL, err := service.StartOIDCLogin(chi.URLParam(r, "provider"), userID, time.Now())
+	if err != nil {
+		respondOIDCError(w, "LinkOIDCIdentity", err)
+		return
+	}
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"authorization_url": authURL})
+}
+
+// ListMyIdentities retourne les comptes externes liés à l'utilisateur connecté.
+// Route: GET /profile/identities
+func ListMyIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[ListMyIdentities] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	identities, err := repository.ListUserIdentities(userID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération des comptes liés")
+		return
+	}
+	response.RespondWithJSON(w, http.StatusOK, identities)
+}
+
+// UnlinkMyIdentity supprime la liaison d'un compte externe de l'utilisateur connecté.
+// Route: DELETE /profile/identities/{id}
+func UnlinkMyIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[UnlinkMyIdentity] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	identityID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID de compte lié invalide")
+		return
+	}
+
+	if err := repository.DeleteUserIdentity(identityID, userID); err != nil {
+		if errors.Is(err, repository.ErrIdentityNotFound) {
+			response.RespondWithError(w, http.StatusNotFound, "Compte lié introuvable")
+			return
+		}
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur suppression du compte lié")
+		return
+	}
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Compte externe délié"})
+}
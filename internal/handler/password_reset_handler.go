@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/service"
+	"onlyflick/internal/utils"
+	"onlyflick/pkg/response"
+	"strings"
+	"time"
+)
+
+// ForgotPasswordRequest représente une demande d'e-mail de réinitialisation.
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest représente la soumission d'un nouveau mot de passe avec le jeton reçu par e-mail.
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ForgotPasswordHandler envoie un lien de réinitialisation si l'adresse correspond à un compte.
+// La réponse ne révèle pas l'existence du compte.
+// Route: POST /auth/forgot-password
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		response.RespondWithError(w, http.StatusBadRequest, "Email est obligatoire")
+		return
+	}
+
+	if err := service.RequestPasswordReset(req.Email, utils.ClientIP(r), time.Now()); err != nil {
+		if errors.Is(err, service.ErrPasswordResetThrottled) {
+			response.RespondWithError(w, http.StatusTooManyRequests, "Trop de demandes, réessayez plus tard")
+			return
+		}
+		log.Printf("[ForgotPasswordHandler] Erreur demande de réinitialisation : %v", err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur lors de la demande de réinitialisation")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusAccepted, map[string]string{
+		"message": "Si un compte correspond à cette adresse, un e-mail de réinitialisation a été envoyé",
+	})
+}
+
+// ResetPasswordHandler remplace le mot de passe à partir du jeton reçu par e-mail et ferme toutes les sessions.
+// Route: POST /auth/reset-password
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Requête invalide")
+		return
+	}
+	if strings.TrimSpace(req.Password) == "" {
+		response.RespondWithError(w, http.StatusBadRequest, "Mot de passe est obligatoire")
+		return
+	}
+
+	if err := service.ResetPassword(req.Token, req.Password, utils.ClientIP(r), time.Now()); err != nil {
+		switch {
+		case errors.Is(err, service.ErrPasswordResetThrottled):
+			response.RespondWithError(w, http.StatusTooManyRequests, "Trop de tentatives, réessayez plus tard")
+		case errors.Is(err, service.ErrInvalidPasswordReset):
+			response.RespondWithError(w, http.StatusBadRequest, "Lien de réinitialisation invalide ou expiré")
+		default:
+			log.Printf("[ResetPasswordHandler] Erreur réinitialisation du mot de passe : %v", err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur lors de la réinitialisation")
+		}
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Mot de passe réinitialisé, veuillez vous reconnecter"})
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -9,6 +10,7 @@ import (
 
 	"onlyflick/internal/domain"
 	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
 	"onlyflick/internal/repository"
 	"onlyflick/internal/service"
 	"onlyflick/pkg/response"
@@ -25,7 +27,8 @@ type CreatePostRequest struct {
 	Title       string   `json:"title"`
 	Description string   `json:"description"`
 	MediaURL    string   `json:"media_url"`
-	Visibility  string   `json:"visibility"` // public | subscriber
+	Visibility  string   `json:"visibility"` // public | subscriber | ppv
+	Price       int      `json:"price"`      // Prix de déblocage en centimes (ppv)
 	Tags        []string `json:"tags"`       // ✅ Ajout des tags
 }
 
@@ -34,10 +37,31 @@ type UpdatePostRequest struct {
 	Title       string   `json:"title"`
 	Description string   `json:"description"`
 	MediaURL    string   `json:"media_url"`
-	Visibility  string   `json:"visibility"` // public | subscriber
+	Visibility  string   `json:"visibility"` // public | subscriber | ppv
+	Price       int      `json:"price"`      // Prix de déblocage en centimes (ppv)
 	Tags        []string `json:"tags"`       // ✅ Ajout des tags
 }
 
+// parsePostPrice valide le prix de déblocage d'un post payant (champ "price", en centimes).
+// Les autres visibilités n'ont pas de prix ; current est conservé si le champ est absent.
+func parsePostPrice(visibility domain.Visibility, raw string, current int) (int, string) {
+	if visibility != domain.PayPerView {
+		return 0, ""
+	}
+	price := current
+	if raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, "Prix du post invalide"
+		}
+		price = parsed
+	}
+	if price < domain.MinPostPrice || price > domain.MaxPostPrice {
+		return 0, fmt.Sprintf("Le prix d'un post payant doit être compris entre %d et %d centimes", domain.MinPostPrice, domain.MaxPostPrice)
+	}
+	return price, ""
+}
+
 // ==============================
 // Handlers principaux
 // ==============================
@@ -53,6 +77,9 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 		log.Println("[CreatePost] Tentative d'accès non autorisé")
 		return
 	}
+	if !authorize(w, r, "CreatePost", policy.PostCreate, policy.None, "Non autorisé à publier") {
+		return
+	}
 
 	// Parse le formulaire multipart
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
@@ -67,6 +94,12 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 	visibility := r.FormValue("visibility")
 	tagsJSON := r.FormValue("tags") // ✅ Récupérer le champ tags
 
+	price, msg := parsePostPrice(domain.Visibility(visibility), r.FormValue("price"), 0)
+	if msg != "" {
+		response.RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
 	log.Printf("[CreatePost] Données reçues: title=%s, description=%s, visibility=%s, tags=%s", 
 		title, description, visibility, tagsJSON)
 
@@ -93,6 +126,11 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 			log.Printf("[CreatePost] Échec de l'upload de l'image : %v", err)
 			return
 		}
+		// Le propriétaire du fichier est retenu pour autoriser sa suppression
+		if err := repository.RecordMediaFile(fileID, userID, mediaURL); err != nil {
+			response.RespondWithError(w, http.StatusInternalServerError, "Échec de l'enregistrement du média")
+			return
+		}
 	} else {
 		log.Printf("[CreatePost] Aucun fichier média fourni ou erreur : %v", err)
 	}
@@ -105,6 +143,7 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 		MediaURL:    mediaURL,
 		FileID:      fileID,
 		Visibility:  domain.Visibility(visibility),
+		Price:       price,
 	}
 
 	// ✅ Créer le post et récupérer l'ID
@@ -187,6 +226,34 @@ func GetPostByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Post réservé aux abonnés : soumis à la politique d'accès
+	viewerID, _ := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if post.Visibility == domain.SubscriberOnly {
+		canView, err := canViewSubscriberContent(r, viewerID, post.UserID)
+		if err != nil {
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification abonnement")
+			log.Printf("[GetPostByID] Erreur vérif abonnement (user: %d, creator: %d) : %v", viewerID, post.UserID, err)
+			return
+		}
+		if !canView {
+			log.Printf("[GetPostByID] Accès refusé au post abonné %d pour l'utilisateur %d", post.ID, viewerID)
+			response.RespondWithError(w, http.StatusForbidden, "Non autorisé à voir le contenu abonné")
+			return
+		}
+	}
+
+	// Post payant : média masqué tant que le lecteur ne l'a pas débloqué
+	if post.Visibility == domain.PayPerView && post.UserID != viewerID {
+		unlocked, err := repository.HasUnlockedPost(post.ID, viewerID)
+		if err != nil {
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification de l'achat du post")
+			return
+		}
+		if !unlocked {
+			post.Lock()
+		}
+	}
+
 	log.Printf("[GetPostByID] Post récupéré (ID: %d, Titre: %s)", post.ID, post.Title)
 	response.RespondWithJSON(w, http.StatusOK, post)
 }
@@ -226,9 +293,6 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	roleVal := r.Context().Value("userRole")
-	userRole, _ := roleVal.(string)
-
 	postID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		response.RespondWithError(w, http.StatusBadRequest, "ID du post invalide")
@@ -244,9 +308,7 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Vérification des droits
-	if userRole != "admin" && post.UserID != userID {
-		response.RespondWithError(w, http.StatusForbidden, "Non autorisé à modifier ce post")
-		log.Printf("[UpdatePost] Utilisateur %d a tenté de modifier le post %d sans permission", userID, postID)
+	if !authorize(w, r, "UpdatePost", policy.PostUpdate, policy.Resource{OwnerID: post.UserID}, "Non autorisé à modifier ce post") {
 		return
 	}
 
@@ -263,6 +325,13 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 	post.Visibility = domain.Visibility(r.FormValue("visibility"))
 	tagsJSON := r.FormValue("tags") // ✅ Récupérer le champ tags
 
+	price, msg := parsePostPrice(post.Visibility, r.FormValue("price"), post.Price)
+	if msg != "" {
+		response.RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	post.Price = price
+
 	// ✅ Parser les tags JSON
 	var tags []string
 	if tagsJSON != "" {
@@ -282,12 +351,17 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 			log.Printf("[UpdatePost] Échec de l'upload de la nouvelle image : %v", err)
 			return
 		}
+		if err := repository.RecordMediaFile(newFileID, post.UserID, newURL); err != nil {
+			response.RespondWithError(w, http.StatusInternalServerError, "Échec de l'enregistrement du média")
+			return
+		}
 
 		// Suppression de l'ancienne image si existante
 		if post.FileID != "" {
 			if err := service.DeleteFile(post.FileID); err != nil {
 				log.Printf("[UpdatePost] Attention : échec de suppression de l'ancienne image (FileID: %s) : %v", post.FileID, err)
 			} else {
+				repository.DeleteMediaFile(post.FileID)
 				log.Printf("[UpdatePost] Ancienne image supprimée (FileID: %s)", post.FileID)
 			}
 		}
@@ -340,9 +414,6 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	roleVal := r.Context().Value("userRole")
-	userRole, _ := roleVal.(string)
-
 	idStr := chi.URLParam(r, "id")
 	postID, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -359,9 +430,7 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Vérification des droits
-	if userRole != "admin" && post.UserID != userID {
-		response.RespondWithError(w, http.StatusForbidden, "Non autorisé à supprimer ce post")
-		log.Printf("[DeletePost] Utilisateur %d a tenté de supprimer le post %d sans permission", userID, postID)
+	if !authorize(w, r, "DeletePost", policy.PostDelete, policy.Resource{OwnerID: post.UserID}, "Non autorisé à supprimer ce post") {
 		return
 	}
 
@@ -377,6 +446,7 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 		if err := service.DeleteFile(post.FileID); err != nil {
 			log.Printf("[DeletePost] Attention : post %d supprimé mais suppression du média échouée (FileID: %s) : %v", postID, post.FileID, err)
 		} else {
+			repository.DeleteMediaFile(post.FileID)
 			log.Printf("[DeletePost] Média supprimé (FileID: %s)", post.FileID)
 		}
 	}
@@ -389,26 +459,48 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 // Handlers de listing
 // ==============================
 
-// ListAllVisiblePosts liste tous les posts visibles selon le rôle
+// ListAllVisiblePosts liste tous les posts visibles par le demandeur : les posts réservés aux abonnés
+// ne sont retenus que si la politique d'accès l'autorise (propriétaire, abonné ou modérateur).
 func ListAllVisiblePosts(w http.ResponseWriter, r *http.Request) {
 	log.Println("[ListAllVisiblePosts] Handler appelé")
 
-	roleVal := r.Context().Value("userRole")
-	userRole, _ := roleVal.(string)
-
-	if userRole != "subscriber" && userRole != "creator" && userRole != "admin" {
+	viewerID, _ := r.Context().Value(middleware.ContextUserIDKey).(int64) // 0 pour un visiteur
+	userRole, _ := r.Context().Value(middleware.ContextUserRoleKey).(string)
+	if viewerID == 0 {
 		userRole = "guest"
 	}
 
-	posts, err := repository.ListVisiblePosts(userRole)
+	posts, err := repository.ListVisiblePosts(userRole, viewerID)
 	if err != nil {
 		response.RespondWithError(w, http.StatusInternalServerError, "Impossible de récupérer les posts visibles")
 		log.Printf("[ListAllVisiblePosts] Erreur lors de la récupération des posts visibles pour le rôle %s : %v", userRole, err)
 		return
 	}
 
-	log.Printf("[ListAllVisiblePosts] %d posts visibles listés pour le rôle %s", len(posts), userRole)
-	response.RespondWithJSON(w, http.StatusOK, posts)
+	// Droit de voir le contenu abonné, évalué une fois par créateur
+	canView := map[int64]bool{}
+	visible := make([]domain.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.Visibility == domain.SubscriberOnly {
+			allowed, checked := canView[post.UserID]
+			if !checked {
+				allowed, err = canViewSubscriberContent(r, viewerID, post.UserID)
+				if err != nil {
+					response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification abonnement")
+					log.Printf("[ListAllVisiblePosts] Erreur vérif abonnement (user: %d, creator: %d) : %v", viewerID, post.UserID, err)
+					return
+				}
+				canView[post.UserID] = allowed
+			}
+			if !allowed {
+				continue
+			}
+		}
+		visible = append(visible, post)
+	}
+
+	log.Printf("[ListAllVisiblePosts] %d posts visibles listés pour le rôle %s", len(visible), userRole)
+	response.RespondWithJSON(w, http.StatusOK, visible)
 }
 
 // ListPostsFromCreator liste les posts d'un créateur selon les droits du demandeur
@@ -436,19 +528,15 @@ func ListPostsFromCreator(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Droits
-	canViewPrivate := (role == "admin" || requesterID == creatorID)
-	if !canViewPrivate {
-		isSub, err := repository.IsSubscribed(requesterID, creatorID)
-		if err != nil {
-			response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification abonnement")
-			log.Printf("[ListPostsFromCreator] Erreur vérif abonnement (user: %d, creator: %d) : %v", requesterID, creatorID, err)
-			return
-		}
-		canViewPrivate = isSub
+	canViewPrivate, err := canViewSubscriberContent(r, requesterID, creatorID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification abonnement")
+		log.Printf("[ListPostsFromCreator] Erreur vérif abonnement (user: %d, creator: %d) : %v", requesterID, creatorID, err)
+		return
 	}
 
 	// Récupération des posts
-	posts, err := repository.ListPostsFromCreator(creatorID, canViewPrivate)
+	posts, err := repository.ListPostsFromCreator(creatorID, canViewPrivate, requesterID)
 	if err != nil {
 		response.RespondWithError(w, http.StatusInternalServerError, "Impossible de lister les posts")
 		log.Printf("[ListPostsFromCreator] Erreur récupération posts créateur %d : %v", creatorID, err)
@@ -471,9 +559,6 @@ func ListSubscriberOnlyPostsFromCreator(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Récupération sécurisée du rôle
-	role, _ := r.Context().Value(middleware.ContextUserRoleKey).(string) // par défaut "", pas de panic
-
 	// Lecture du paramètre d'URL
 	creatorIDStr := chi.URLParam(r, "creator_id")
 	creatorID, err := strconv.ParseInt(creatorIDStr, 10, 64)
@@ -484,13 +569,11 @@ func ListSubscriberOnlyPostsFromCreator(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Vérification des droits
-	if role != "admin" && requesterID != creatorID {
-		isSub, err := repository.IsSubscribed(requesterID, creatorID)
-		if err != nil || !isSub {
-			response.RespondWithError(w, http.StatusForbidden, "Non autorisé à voir le contenu abonné")
-			log.Printf("[ListSubscriberOnlyPostsFromCreator] Accès refusé : user %d, creator %d, abonné: %v, err: %v", requesterID, creatorID, isSub, err)
-			return
-		}
+	canView, err := canViewSubscriberContent(r, requesterID, creatorID)
+	if err != nil || !canView {
+		response.RespondWithError(w, http.StatusForbidden, "Non autorisé à voir le contenu abonné")
+		log.Printf("[ListSubscriberOnlyPostsFromCreator] Accès refusé : user %d, creator %d, err: %v", requesterID, creatorID, err)
+		return
 	}
 
 	// Récupération des posts abonnés
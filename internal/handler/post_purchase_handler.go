@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"onlyflick/pkg/response"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// UnlockPost démarre l'achat d'un post payant à l'unité et retourne le client_secret du paiement.
+// Route: POST /posts/{id}/unlock
+func UnlockPost(w http.ResponseWriter, r *http.Request) {
+	buyerID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[UnlockPost] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	postID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID du post invalide")
+		return
+	}
+
+	post, err := repository.GetPostByID(postID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusNotFound, "Post introuvable")
+		return
+	}
+
+	checkout, err := service.StartPostPurchase(post, buyerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPostNotForSale):
+			response.RespondWithError(w, http.StatusBadRequest, "Ce post n'est pas payant")
+		case errors.Is(err, service.ErrPostAlreadyUnlocked):
+			response.RespondWithError(w, http.StatusConflict, "Post déjà débloqué")
+		default:
+			log.Printf("[UnlockPost] Erreur achat du post %d par %d : %v", postID, buyerID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur lors de l'achat du post")
+		}
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusCreated, checkout)
+}
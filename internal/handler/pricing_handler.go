@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/pkg/response"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// tierInput représente le corps de création ou modification d'un palier
+type tierInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Price       int    `json:"price"` // En centimes
+}
+
+// validateTierInput vérifie le nom et le prix d'un palier
+func validateTierInput(input *tierInput) string {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" || len(input.Name) > 50 {
+		return "Le nom du palier doit contenir entre 1 et 50 caractères"
+	}
+	if input.Price < domain.MinSubscriptionPrice || input.Price > domain.MaxSubscriptionPrice {
+		return "Prix du palier invalide"
+	}
+	return ""
+}
+
+// GetMyPricing retourne le tarif et les paliers du créateur connecté.
+// Route: GET /creator/pricing
+func GetMyPricing(w http.ResponseWriter, r *http.Request) {
+	creatorID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[GetMyPricing] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	pricing, err := repository.GetCreatorPricing(creatorID)
+	if err != nil {
+		log.Printf("[GetMyPricing] Erreur récupération tarif du créateur %d : %v", creatorID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération du tarif")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, pricing)
+}
+
+// UpdateMyPricing définit le prix mensuel de base, la devise et l'essai gratuit du créateur connecté.
+// Route: PUT /creator/pricing
+func UpdateMyPricing(w http.ResponseWriter, r *http.Request) {
+	creatorID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[UpdateMyPricing] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	var input struct {
+		Price     int    `json:"price"`      // En centimes
+		Currency  string `json:"currency"`   // Code ISO 4217
+		TrialDays *int   `json:"trial_days"` // Absent = durée d'essai inchangée
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Printf("[UpdateMyPricing] Erreur de décodage du corps : %v", err)
+		response.RespondWithError(w, http.StatusBadRequest, "Corps de requête invalide")
+		return
+	}
+
+	input.Currency = strings.ToUpper(strings.TrimSpace(input.Currency))
+	if input.Currency == "" {
+		input.Currency = domain.DefaultSubscriptionCurrency
+	}
+	if !domain.SupportedCurrencies[input.Currency] {
+		response.RespondWithError(w, http.StatusBadRequest, "Devise non supportée")
+		return
+	}
+	if input.Price < domain.MinSubscriptionPrice || input.Price > domain.MaxSubscriptionPrice {
+		response.RespondWithError(w, http.StatusBadRequest, "Prix d'abonnement invalide")
+		return
+	}
+	if input.TrialDays != nil && (*input.TrialDays < 0 || *input.TrialDays > domain.MaxTrialDays) {
+		response.RespondWithError(w, http.StatusBadRequest, "Durée d'essai invalide")
+		return
+	}
+
+	// Sans durée d'essai dans la requête, la durée actuelle est conservée
+	current, err := repository.GetCreatorPricing(creatorID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération du tarif")
+		return
+	}
+	trialDays := current.TrialDays
+	if input.TrialDays != nil {
+		trialDays = *input.TrialDays
+	}
+
+	if err := repository.UpsertCreatorPricing(creatorID, input.Price, input.Currency, trialDays); err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur mise à jour du tarif")
+		return
+	}
+
+	pricing, err := repository.GetCreatorPricing(creatorID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération du tarif")
+		return
+	}
+
+	log.Printf("[UpdateMyPricing] Tarif du créateur %d mis à jour : %s %s", creatorID, domain.FormatPrice(input.Price), input.Currency)
+	response.RespondWithJSON(w, http.StatusOK, pricing)
+}
+
+// CreateTier ajoute un palier d'abonnement pour le créateur connecté.
+// Route: POST /creator/tiers
+func CreateTier(w http.ResponseWriter, r *http.Request) {
+	creatorID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[CreateTier] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	var input tierInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Printf("[CreateTier] Erreur de décodage du corps : %v", err)
+		response.RespondWithError(w, http.StatusBadRequest, "Corps de requête invalide")
+		return
+	}
+	if msg := validateTierInput(&input); msg != "" {
+		response.RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	tier, err := repository.CreateSubscriptionTier(creatorID, input.Name, input.Description, input.Price)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur création du palier")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusCreated, tier)
+}
+
+// UpdateTier modifie un palier du créateur connecté.
+// Route: PATCH /creator/tiers/{id}
+func UpdateTier(w http.ResponseWriter, r *http.Request) {
+	creatorID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[UpdateTier] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	tierID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID de palier invalide")
+		return
+	}
+
+	var input tierInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Printf("[UpdateTier] Erreur de décodage du corps : %v", err)
+		response.RespondWithError(w, http.StatusBadRequest, "Corps de requête invalide")
+		return
+	}
+	if msg := validateTierInput(&input); msg != "" {
+		response.RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := repository.UpdateSubscriptionTier(tierID, creatorID, input.Name, input.Description, input.Price); err != nil {
+		if errors.Is(err, repository.ErrTierNotFound) {
+			response.RespondWithError(w, http.StatusNotFound, "Palier introuvable")
+			return
+		}
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur mise à jour du palier")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Palier mis à jour"})
+}
+
+// DeleteTier retire un palier du catalogue du créateur connecté.
+// Route: DELETE /creator/tiers/{id}
+func DeleteTier(w http.ResponseWriter, r *http.Request) {
+	creatorID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[DeleteTier] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	tierID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID de palier invalide")
+		return
+	}
+
+	if err := repository.DeactivateSubscriptionTier(tierID, creatorID); err != nil {
+		if errors.Is(err, repository.ErrTierNotFound) {
+			response.RespondWithError(w, http.StatusNotFound, "Palier introuvable")
+			return
+		}
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur suppression du palier")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Palier supprimé"})
+}
+
+// GetCreatorPricingHandler retourne le catalogue public d'un créateur.
+// Route: GET /users/{user_id}/pricing
+func GetCreatorPricingHandler(w http.ResponseWriter, r *http.Request) {
+	creatorID, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+	if err != nil || creatorID <= 0 {
+		response.RespondWithError(w, http.StatusBadRequest, "ID créateur invalide")
+		return
+	}
+
+	creator, err := repository.GetUserByID(creatorID)
+	if err != nil || creator == nil || creator.Role != domain.RoleCreator {
+		response.RespondWithError(w, http.StatusNotFound, "Créateur non trouvé")
+		return
+	}
+
+	pricing, err := repository.GetCreatorPricing(creatorID)
+	if err != nil {
+		log.Printf("[GetCreatorPricingHandler] Erreur récupération tarif du créateur %d : %v", creatorID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération du tarif")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, pricing)
+}
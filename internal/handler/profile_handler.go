@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"onlyflick/internal/domain"
 	"onlyflick/internal/middleware"
 	"onlyflick/internal/repository"
 	"onlyflick/internal/service"
@@ -50,20 +51,21 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Chiffrement des champs sensibles
-	if req.FirstName != nil {
-		if encrypted, err := utils.EncryptAES(*req.FirstName); err == nil {
-			req.FirstName = &encrypted
-		}
+	var newEmail string
+	if req.Email != nil {
+		newEmail = *req.Email
 	}
-	if req.LastName != nil {
-		if encrypted, err := utils.EncryptAES(*req.LastName); err == nil {
-			req.LastName = &encrypted
+	for _, field := range []*string{req.FirstName, req.LastName, req.Email} {
+		if field == nil {
+			continue
 		}
-	}
-	if req.Email != nil {
-		if encrypted, err := utils.EncryptAES(*req.Email); err == nil {
-			req.Email = &encrypted
+		encrypted, err := utils.EncryptAES(*field)
+		if err != nil {
+			log.Printf("[UpdateProfile] Erreur chiffrement des données personnelles : %v", err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur chiffrement des données")
+			return
 		}
+		*field = encrypted
 	}
 	if req.Password != nil {
 		if hashed, err := service.HashPassword(*req.Password); err == nil {
@@ -84,13 +86,32 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Une nouvelle adresse e-mail doit être confirmée à son tour
+	if newEmail != "" {
+		if err := repository.ResetEmailVerification(userID); err == nil {
+			if user, err := repository.GetUserByID(userID); err == nil {
+				user.Email = newEmail
+				go service.SendVerificationEmail(user)
+			}
+		}
+	}
+
+	// Un changement de mot de passe ferme toutes les autres sessions
+	if req.Password != nil {
+		currentID, _ := r.Context().Value(middleware.ContextSessionIDKey).(int64)
+		if _, err := repository.RevokeOtherSessions(userID, currentID, domain.SessionRevokedPassword); err != nil {
+			log.Printf("[ERROR] Révocation des sessions de l'utilisateur %d échouée : %v", userID, err)
+		}
+	}
+
 	log.Printf("[SUCCESS] Profil de l'utilisateur %d mis à jour avec succès", userID)
 	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Profil mis à jour"})
 }
 
-// DeleteAccount supprime le compte utilisateur (existant)
+// DeleteAccount demande la suppression du compte utilisateur. Le profil est masqué et les sessions
+// fermées immédiatement ; le compte est purgé après AccountDeletionGracePeriod, sauf reconnexion entre-temps.
 func DeleteAccount(w http.ResponseWriter, r *http.Request) {
-	log.Println("[PROFILE] DeleteAccount - Suppression compte")
+	log.Println("[PROFILE] DeleteAccount - Demande de suppression du compte")
 
 	userIDVal := r.Context().Value(middleware.ContextUserIDKey)
 	userID, ok := userIDVal.(int64)
@@ -99,14 +120,42 @@ func DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := repository.DeleteUser(userID); err != nil {
-		log.Printf("[ERROR] Suppression du compte utilisateur %d échouée : %v", userID, err)
+	purgeAt, err := service.RequestAccountDeletion(userID, time.Now())
+	if err != nil {
+		log.Printf("[ERROR] Demande de suppression du compte utilisateur %d échouée : %v", userID, err)
 		response.RespondWithError(w, http.StatusInternalServerError, "Échec de la suppression du compte")
 		return
 	}
 
-	log.Printf("[SUCCESS] Compte utilisateur %d supprimé avec succès", userID)
-	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Compte supprimé"})
+	log.Printf("[SUCCESS] Suppression du compte utilisateur %d programmée pour le %s", userID, purgeAt.Format(time.RFC3339))
+	response.RespondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message":  "Suppression du compte programmée. Reconnectez-vous avant la date de purge pour l'annuler.",
+		"purge_at": purgeAt,
+	})
+}
+
+// ExportMyData retourne une archive ZIP des données personnelles de l'utilisateur connecté
+// (profil, posts, commentaires, messages, likes, abonnements et paiements au format JSON).
+func ExportMyData(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	archive, err := service.BuildAccountExport(userID)
+	if err != nil {
+		log.Printf("[ERROR] Export des données de l'utilisateur %d échoué : %v", userID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Échec de l'export des données")
+		return
+	}
+
+	filename := fmt.Sprintf("onlyflick-export-%d-%s.zip", userID, time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
 }
 
 // RequestCreatorUpgrade demande de passage en créateur (existant)
@@ -198,7 +247,7 @@ func GetUserPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	posts, err := repository.GetUserPosts(userID, page, limit, postType)
+	posts, err := repository.GetUserPosts(userID, page, limit, postType, userID)
 	if err != nil {
 		log.Printf("[ERROR] Erreur récupération posts pour user %d: %v", userID, err)
 		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération posts")
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/pkg/response"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Format accepté pour un code promo (après mise en majuscules)
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// promoCodeInput représente le corps de création d'un code promo
+type promoCodeInput struct {
+	Code            string     `json:"code"`
+	DiscountType    string     `json:"discount_type"`   // percent ou fixed
+	DiscountValue   int        `json:"discount_value"`  // Pourcentage (1-100) ou montant en centimes
+	MaxRedemptions  *int       `json:"max_redemptions"` // Absent = illimité
+	ExpiresAt       *time.Time `json:"expires_at"`      // Absent = sans expiration
+	FirstPeriodOnly bool       `json:"first_period_only"`
+}
+
+// validatePromoCodeInput vérifie le format du code et les paramètres de la réduction
+func validatePromoCodeInput(input *promoCodeInput, now time.Time) string {
+	input.Code = repository.NormalizePromoCode(input.Code)
+	if !promoCodePattern.MatchString(input.Code) {
+		return "Le code doit contenir entre 3 et 32 lettres, chiffres, tirets ou soulignés"
+	}
+
+	switch input.DiscountType {
+	case domain.DiscountPercent:
+		if input.DiscountValue < 1 || input.DiscountValue > 100 {
+			return "Le pourcentage de réduction doit être compris entre 1 et 100"
+		}
+	case domain.DiscountFixed:
+		if input.DiscountValue < 1 || input.DiscountValue > domain.MaxSubscriptionPrice {
+			return "Montant de réduction invalide"
+		}
+	default:
+		return "Type de réduction invalide (percent ou fixed)"
+	}
+
+	if input.MaxRedemptions != nil && *input.MaxRedemptions < 1 {
+		return "Le nombre maximal d'utilisations doit être positif"
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return "La date d'expiration doit être dans le futur"
+	}
+	return ""
+}
+
+// ListMyPromoCodes retourne les codes promo du créateur connecté.
+// Route: GET /creator/promo-codes
+func ListMyPromoCodes(w http.ResponseWriter, r *http.Request) {
+	creatorID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[ListMyPromoCodes] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	codes, err := repository.ListPromoCodes(creatorID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération des codes promo")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, codes)
+}
+
+// CreatePromoCode crée un code promo pour les abonnements au créateur connecté.
+// Route: POST /creator/promo-codes
+func CreatePromoCode(w http.ResponseWriter, r *http.Request) {
+	creatorID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[CreatePromoCode] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	var input promoCodeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Printf("[CreatePromoCode] Erreur de décodage du corps : %v", err)
+		response.RespondWithError(w, http.StatusBadRequest, "Corps de requête invalide")
+		return
+	}
+	if msg := validatePromoCodeInput(&input, time.Now()); msg != "" {
+		response.RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	promo := &domain.PromoCode{
+		CreatorID:       creatorID,
+		Code:            input.Code,
+		DiscountType:    input.DiscountType,
+		DiscountValue:   input.DiscountValue,
+		MaxRedemptions:  input.MaxRedemptions,
+		ExpiresAt:       input.ExpiresAt,
+		FirstPeriodOnly: input.FirstPeriodOnly,
+	}
+	if err := repository.CreatePromoCode(promo); err != nil {
+		if errors.Is(err, repository.ErrPromoCodeExists) {
+			response.RespondWithError(w, http.StatusConflict, "Ce code promo existe déjà")
+			return
+		}
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur création du code promo")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusCreated, promo)
+}
+
+// DeletePromoCode désactive un code promo du créateur connecté.
+// Route: DELETE /creator/promo-codes/{id}
+func DeletePromoCode(w http.ResponseWriter, r *http.Request) {
+	creatorID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[DeletePromoCode] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	promoID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID de code promo invalide")
+		return
+	}
+
+	if err := repository.DeactivatePromoCode(promoID, creatorID); err != nil {
+		if errors.Is(err, repository.ErrPromoCodeNotFound) {
+			response.RespondWithError(w, http.StatusNotFound, "Code promo introuvable")
+			return
+		}
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur désactivation du code promo")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Code promo désactivé"})
+}
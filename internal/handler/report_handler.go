@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
 	"onlyflick/internal/repository"
 	"onlyflick/pkg/response"
 	"strconv"
@@ -21,6 +22,9 @@ func CreateReport(w http.ResponseWriter, r *http.Request) {
 		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
 		return
 	}
+	if !authorize(w, r, "CreateReport", policy.ReportCreate, policy.None, "Non autorisé à signaler un contenu") {
+		return
+	}
 
 	var input struct {
 		ContentType string `json:"content_type"` // "post" ou "comment"
@@ -92,7 +96,7 @@ func UpdateReportStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := repository.UpdateReportStatus(reportID, body.Status, time.Now()); err != nil {
+	if err := repository.UpdateReportStatus(reportID, body.Status, time.Now(), auditActor(r)); err != nil {
 		log.Printf("[UpdateReportStatus] Échec update statut %d : %v", reportID, err)
 		response.RespondWithError(w, http.StatusInternalServerError, "Mise à jour échouée")
 		return
@@ -129,7 +133,7 @@ func AdminActOnReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := repository.AdminActOnReport(reportID, body.Action); err != nil {
+	if err := repository.AdminActOnReport(reportID, body.Action, auditActor(r)); err != nil {
 		log.Printf("[AdminActOnReport] Erreur action '%s' sur report %d : %v", body.Action, reportID, err)
 		response.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/utils"
+	"onlyflick/pkg/response"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// sessionClient décrit l'appareil à l'origine de la requête (user agent et IP).
+func sessionClient(r *http.Request) domain.SessionClient {
+	return domain.SessionClient{UserAgent: r.UserAgent(), IPAddress: utils.ClientIP(r)}
+}
+
+// ListMySessions retourne les sessions ouvertes de l'utilisateur connecté, la session courante étant signalée.
+// Route: GET /profile/sessions
+func ListMySessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[ListMySessions] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+	currentID, _ := r.Context().Value(middleware.ContextSessionIDKey).(int64)
+
+	sessions, err := repository.ListActiveSessions(userID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération des sessions")
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, sessions)
+}
+
+// RevokeMySession ferme une session de l'utilisateur connecté (déconnexion d'un appareil).
+// Route: DELETE /profile/sessions/{id}
+func RevokeMySession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[RevokeMySession] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID de session invalide")
+		return
+	}
+
+	if err := repository.RevokeSession(sessionID, userID, domain.SessionRevokedUser); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			response.RespondWithError(w, http.StatusNotFound, "Session introuvable")
+			return
+		}
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur fermeture de la session")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Session fermée"})
+}
+
+// RevokeMyOtherSessions ferme toutes les sessions de l'utilisateur connecté sauf la session courante.
+// Route: POST /profile/sessions/revoke-others
+func RevokeMyOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[RevokeMyOtherSessions] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+	currentID, _ := r.Context().Value(middleware.ContextSessionIDKey).(int64)
+
+	revoked, err := repository.RevokeOtherSessions(userID, currentID, domain.SessionRevokedUser)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur fermeture des sessions")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Autres sessions fermées",
+		"revoked": revoked,
+	})
+}
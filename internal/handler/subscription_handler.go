@@ -2,20 +2,21 @@
 package handler
 
 import (
-	"fmt"
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"onlyflick/internal/domain"
 	"onlyflick/internal/middleware"
 	"onlyflick/internal/repository"
-	"onlyflick/internal/utils"
+	"onlyflick/internal/service"
 	"onlyflick/pkg/response"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/stripe/stripe-go"
-	"github.com/stripe/stripe-go/paymentintent"
 )
 
 // ===== STRUCTURES POUR LES RÉPONSES =====
@@ -44,6 +45,7 @@ type FollowingProfile struct {
 	AvatarURL        string `json:"avatar_url"`
 	Bio              string `json:"bio"`
 	SubscriptionPrice string `json:"subscription_price"`
+	Currency         string `json:"currency"`
 	CreatedAt        string `json:"created_at"`
 }
 
@@ -233,6 +235,47 @@ func SubscribeWithPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Palier optionnel choisi par l'abonné (absent = tarif de base du créateur) et code promo éventuel
+	var input struct {
+		TierID    *int64 `json:"tier_id"`
+		PromoCode string `json:"promo_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		log.Printf("[SubscribeWithPayment] Erreur de décodage du corps : %v", err)
+		response.RespondWithError(w, http.StatusBadRequest, "Corps de requête invalide")
+		return
+	}
+
+	// Vérifier que le palier demandé existe avant toute écriture
+	if _, _, err := repository.ResolveSubscriptionPrice(creatorID, input.TierID); err != nil {
+		if errors.Is(err, repository.ErrTierNotFound) {
+			response.RespondWithError(w, http.StatusBadRequest, "Palier d'abonnement invalide")
+			return
+		}
+		log.Printf("[SubscribeWithPayment] Erreur de récupération du tarif du créateur %d : %v", creatorID, err)
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur de tarification")
+		return
+	}
+
+	// Vérifier que le code promo est utilisable avant toute écriture
+	now := time.Now()
+	var promo *domain.PromoCode
+	if strings.TrimSpace(input.PromoCode) != "" {
+		promo, err = repository.GetPromoCodeByCode(creatorID, input.PromoCode)
+		if errors.Is(err, repository.ErrPromoCodeNotFound) {
+			response.RespondWithError(w, http.StatusBadRequest, "Code promo invalide")
+			return
+		}
+		if err != nil {
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur de vérification du code promo")
+			return
+		}
+		if !promo.Redeemable(now) {
+			response.RespondWithError(w, http.StatusBadRequest, "Code promo expiré ou épuisé")
+			return
+		}
+	}
+
 	// Vérifier si l'utilisateur est déjà abonné au créateur
 	subscription, err := repository.GetActiveSubscription(subscriberID, creatorID)
 	if err != nil {
@@ -241,17 +284,14 @@ func SubscribeWithPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Si l'abonnement est inactif, réactiver et procéder au paiement
-	if subscription != nil && !subscription.Status {
-		log.Printf("[SubscribeWithPayment] Abonnement inactif trouvé, réactivation et paiement pour l'utilisateur %d au créateur %d", subscriberID, creatorID)
-		err := repository.ReactivateSubscription(subscription.ID, time.Now())
-		if err != nil {
-			log.Printf("[SubscribeWithPayment] Erreur lors de la réactivation de l'abonnement : %v", err)
+	// Abonnement actif résilié en fin de période : reprise du renouvellement automatique
+	if subscription != nil && subscription.Status && !subscription.AutoRenew {
+		if err := repository.SetAutoRenew(subscription.ID, true); err != nil {
 			response.RespondWithError(w, http.StatusInternalServerError, "Erreur de réactivation d'abonnement")
 			return
 		}
-		log.Printf("[SubscribeWithPayment] Abonnement réactivé pour l'utilisateur %d au créateur %d", subscriberID, creatorID)
-		response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Abonnement réactivé avec succès"})
+		log.Printf("[SubscribeWithPayment] Renouvellement repris pour l'utilisateur %d au créateur %d", subscriberID, creatorID)
+		response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Renouvellement automatique réactivé"})
 		return
 	}
 
@@ -262,92 +302,75 @@ func SubscribeWithPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Si l'abonnement n'existe pas, créer un nouvel abonnement
-	newSubscription, err := repository.Subscribe(subscriberID, creatorID)
-	if err != nil {
-		log.Printf("[SubscribeWithPayment] Erreur lors de l'abonnement : %v", err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Erreur d'abonnement")
-		return
-	}
-
-	// Créer un PaymentIntent via Stripe
-	stripe.Key = os.Getenv("STRIPE_SECRET_KEY")
-
-	intentParams := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(499), // Montant en centimes (499 = 4.99€)
-		Currency: stripe.String(string(stripe.CurrencyEUR)),
-	}
-
-	intent, err := paymentintent.New(intentParams)
-	if err != nil {
-		log.Printf("[SubscribeWithPayment] Erreur Stripe lors de la création du PaymentIntent : %v", err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Erreur de paiement")
-		return
+	var subscriptionID int64
+	if subscription != nil {
+		// Abonnement inactif : réactivation directe si la période payée n'est pas échue
+		log.Printf("[SubscribeWithPayment] Abonnement inactif trouvé, réactivation pour l'utilisateur %d au créateur %d", subscriberID, creatorID)
+		reactivated, err := repository.ReactivateSubscription(subscription.ID, now)
+		if err != nil {
+			log.Printf("[SubscribeWithPayment] Erreur lors de la réactivation de l'abonnement : %v", err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur de réactivation d'abonnement")
+			return
+		}
+		if reactivated {
+			log.Printf("[SubscribeWithPayment] Abonnement réactivé pour l'utilisateur %d au créateur %d", subscriberID, creatorID)
+			response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Abonnement réactivé avec succès"})
+			return
+		}
+		if err := repository.SetSubscriptionTier(subscription.ID, input.TierID); err != nil {
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur de réactivation d'abonnement")
+			return
+		}
+		subscriptionID = subscription.ID
+	} else {
+		// Si l'abonnement n'existe pas, le créer inactif : il sera activé par le webhook Stripe
+		newSubscription, err := repository.CreatePendingSubscription(subscriberID, creatorID, input.TierID)
+		if err != nil {
+			log.Printf("[SubscribeWithPayment] Erreur lors de l'abonnement : %v", err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur d'abonnement")
+			return
+		}
+		subscriptionID = newSubscription.ID
 	}
 
-	// Ajouter Metadata après la création du PaymentIntent
-	intent.Metadata = map[string]string{"subscription_id": fmt.Sprintf("%d", newSubscription.ID)}
-
-	// Enregistrer le paiement dans la base de données
-	_, err = repository.CreatePayment(newSubscription.ID, intent.ID, fmt.Sprintf("%d", subscriberID), time.Now(), time.Now().AddDate(0, 1, 0), 499, "succeeded")
+	// Essai gratuit pour un premier abonnement, sinon PaymentIntent au tarif (éventuellement réduit) du créateur
+	checkout, err := service.StartSubscriptionCheckout(subscriptionID, subscriberID, creatorID, input.TierID, promo, subscription == nil, now)
 	if err != nil {
-		log.Printf("[SubscribeWithPayment] Erreur d'enregistrement du paiement : %v", err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Erreur d'enregistrement du paiement")
+		// L'utilisation du code promo est enregistrée avec le paiement : rien n'est consommé en cas d'échec
+		switch {
+		case errors.Is(err, repository.ErrPromoCodeAlreadyRedeemed):
+			response.RespondWithError(w, http.StatusBadRequest, "Code promo déjà utilisé")
+		case errors.Is(err, repository.ErrPromoCodeUnavailable), errors.Is(err, repository.ErrPromoCodeNotFound):
+			response.RespondWithError(w, http.StatusBadRequest, "Code promo expiré ou épuisé")
+		default:
+			log.Printf("[SubscribeWithPayment] Erreur lors de la création du paiement : %v", err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur de paiement")
+		}
 		return
 	}
 
-	// Prolonger l'abonnement de 1 mois
-	_, err = repository.UpdateSubscriptionEndDate(newSubscription.ID, time.Now().AddDate(0, 1, 0))
-	if err != nil {
-		log.Printf("[SubscribeWithPayment] Erreur lors de la mise à jour de l'abonnement %d : %v", newSubscription.ID, err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Erreur lors de la mise à jour de l'abonnement")
-		return
+	message := "Paiement en attente de confirmation"
+	switch {
+	case checkout.TrialEndsAt != nil:
+		message = "Essai gratuit activé"
+	case checkout.Amount == 0:
+		message = "Première période offerte"
 	}
 
-	log.Printf("[SubscribeWithPayment] Paiement réussi pour l'utilisateur %d et créateur %d", subscriberID, creatorID)
-	response.RespondWithJSON(w, http.StatusOK, map[string]string{
-		"message":       "Abonnement et paiement réussis",
-		"client_secret": intent.ClientSecret,
+	log.Printf("[SubscribeWithPayment] Souscription initiée pour l'utilisateur %d et créateur %d (%s)", subscriberID, creatorID, checkout.IntentType)
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":       message,
+		"client_secret": checkout.ClientSecret,
+		"checkout":      checkout,
 	})
 }
 
-// Subscribe permet à un utilisateur de s'abonner à un créateur sans paiement immédiat.
+// Subscribe est l'ancienne route d'abonnement, conservée pour les clients existants. Elle passe par
+// le même parcours que SubscribeWithPayment : l'abonnement reste en attente jusqu'à la confirmation
+// du paiement par le webhook, au tarif du créateur.
+// Route: POST /subscriptions/{creator_id}
 func Subscribe(w http.ResponseWriter, r *http.Request) {
-	// Récupération de l'ID de l'utilisateur depuis le contexte
-	userVal := r.Context().Value(middleware.ContextUserIDKey)
-	subscriberID, ok := userVal.(int64)
-	if !ok {
-		log.Println("[Subscribe] Utilisateur non authentifié")
-		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
-		return
-	}
-
-	// Récupération de l'ID du créateur depuis les paramètres de l'URL
-	creatorID, err := strconv.ParseInt(chi.URLParam(r, "creator_id"), 10, 64)
-	if err != nil {
-		log.Printf("[Subscribe] ID du créateur invalide : %v", err)
-		response.RespondWithError(w, http.StatusBadRequest, "ID du créateur invalide")
-		return
-	}
-
-	// Vérification que l'utilisateur ne tente pas de s'abonner à lui-même
-	if subscriberID == creatorID {
-		log.Printf("[Subscribe] Tentative d'auto-abonnement utilisateur %d", subscriberID)
-		response.RespondWithError(w, http.StatusBadRequest, "Impossible de s'abonner à soi-même")
-		return
-	}
-
-	// Créer l'abonnement sans paiement immédiat
-	_, err = repository.Subscribe(subscriberID, creatorID)
-	if err != nil {
-		log.Printf("[Subscribe] Erreur lors de l'abonnement (sub: %d -> creator: %d) : %v", subscriberID, creatorID, err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Erreur d'abonnement")
-		return
-	}
-
-	// L'abonnement a été créé avec succès
-	log.Printf("[Subscribe] Utilisateur %d abonné au créateur %d", subscriberID, creatorID)
-	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Abonnement réussi, paiement en attente"})
+	SubscribeWithPayment(w, r)
 }
 
 // UnSubscribe permet à un utilisateur de se désabonner d'un créateur.
@@ -369,15 +392,35 @@ func UnSubscribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Effectuer le désabonnement
-	if err := repository.Unsubscribe(subscriberID, creatorID); err != nil {
-		log.Printf("[UnSubscribe] Erreur désabonnement (sub: %d -> creator: %d) : %v", subscriberID, creatorID, err)
-		response.RespondWithError(w, http.StatusInternalServerError, "Erreur de désabonnement")
+	// Mode de résiliation : fin de période (défaut) ou immédiate avec remboursement au prorata
+	input := struct {
+		Mode string `json:"mode"`
+	}{Mode: domain.CancelAtPeriodEnd}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		log.Printf("[UnSubscribe] Erreur de décodage du corps : %v", err)
+		response.RespondWithError(w, http.StatusBadRequest, "Corps de requête invalide")
 		return
 	}
 
-	log.Printf("[UnSubscribe] Utilisateur %d désabonné du créateur %d", subscriberID, creatorID)
-	response.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Désabonnement réussi"})
+	cancellation, err := service.CancelSubscription(subscriberID, creatorID, input.Mode, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidCancellationMode):
+			response.RespondWithError(w, http.StatusBadRequest, "Mode de résiliation invalide (period_end ou now)")
+		case errors.Is(err, service.ErrNoActiveSubscription):
+			response.RespondWithError(w, http.StatusNotFound, "Aucun abonnement actif à ce créateur")
+		default:
+			log.Printf("[UnSubscribe] Erreur désabonnement (sub: %d -> creator: %d) : %v", subscriberID, creatorID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur de désabonnement")
+		}
+		return
+	}
+
+	log.Printf("[UnSubscribe] Utilisateur %d désabonné du créateur %d (%s)", subscriberID, creatorID, cancellation.Mode)
+	response.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":      "Désabonnement réussi",
+		"cancellation": cancellation,
+	})
 }
 
 // ListMySubscriptions retourne la liste des abonnements de l'utilisateur.
@@ -412,9 +455,8 @@ func getFollowerProfile(userID int64) (*FollowerProfile, error) {
 		return nil, err
 	}
 
-	// Décryptage des données (ajustez selon votre logique)
-	firstName, _ := utils.DecryptAES(user.FirstName)
-	lastName, _ := utils.DecryptAES(user.LastName)
+	// Champs déjà déchiffrés par le repository
+	firstName, lastName := user.FirstName, user.LastName
 
 	profile := &FollowerProfile{
 		ID:        user.ID,
@@ -438,9 +480,14 @@ func getFollowingProfile(userID int64) (*FollowingProfile, error) {
 		return nil, err
 	}
 
-	// Décryptage des données (ajustez selon votre logique)
-	firstName, _ := utils.DecryptAES(user.FirstName)
-	lastName, _ := utils.DecryptAES(user.LastName)
+	// Tarif du créateur depuis le catalogue
+	pricing, err := repository.GetCreatorPricing(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Champs déjà déchiffrés par le repository
+	firstName, lastName := user.FirstName, user.LastName
 
 	profile := &FollowingProfile{
 		ID:        user.ID,
@@ -451,7 +498,8 @@ func getFollowingProfile(userID int64) (*FollowingProfile, error) {
 		Role:      string(user.Role), // Conversion de domain.Role vers string
 		AvatarURL: user.AvatarURL,
 		Bio:       user.Bio,
-		SubscriptionPrice: "4.99", // Prix fixe ou récupéré depuis la DB
+		SubscriptionPrice: domain.FormatPrice(pricing.Price),
+		Currency:  pricing.Currency,
 		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	}
 
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"onlyflick/pkg/response"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// tipInput représente le corps d'envoi d'un pourboire
+type tipInput struct {
+	CreatorID      int64  `json:"creator_id"`
+	Amount         int    `json:"amount"` // Montant en centimes
+	Message        string `json:"message"`
+	PostID         *int64 `json:"post_id"`         // Pourboire sur un post du créateur
+	ConversationID *int64 `json:"conversation_id"` // Pourboire dans une conversation avec le créateur
+}
+
+// CreateTip démarre le paiement d'un pourboire à un créateur et retourne le client_secret du paiement.
+// Route: POST /tips
+func CreateTip(w http.ResponseWriter, r *http.Request) {
+	senderID, ok := r.Context().Value(middleware.ContextUserIDKey).(int64)
+	if !ok {
+		log.Println("[CreateTip] Utilisateur non authentifié")
+		response.RespondWithError(w, http.StatusUnauthorized, "Utilisateur non authentifié")
+		return
+	}
+
+	var input tipInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Printf("[CreateTip] Erreur de décodage du corps : %v", err)
+		response.RespondWithError(w, http.StatusBadRequest, "Corps de requête invalide")
+		return
+	}
+
+	checkout, err := service.StartTip(&domain.Tip{
+		SenderID:       senderID,
+		CreatorID:      input.CreatorID,
+		Amount:         input.Amount,
+		Message:        input.Message,
+		PostID:         input.PostID,
+		ConversationID: input.ConversationID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTip):
+			response.RespondWithError(w, http.StatusBadRequest, "Montant ou message du pourboire invalide")
+		case errors.Is(err, service.ErrTipSelf):
+			response.RespondWithError(w, http.StatusBadRequest, "Impossible de s'envoyer un pourboire")
+		case errors.Is(err, service.ErrTipRecipient):
+			response.RespondWithError(w, http.StatusNotFound, "Créateur introuvable")
+		case errors.Is(err, service.ErrTipTarget):
+			response.RespondWithError(w, http.StatusBadRequest, "Post ou conversation invalide pour ce créateur")
+		default:
+			log.Printf("[CreateTip] Erreur pourboire de %d au créateur %d : %v", senderID, input.CreatorID, err)
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur lors de l'envoi du pourboire")
+		}
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusCreated, checkout)
+}
+
+// ListPostTips retourne les pourboires confirmés reçus sur un post.
+// Route: GET /posts/{id}/tips
+func ListPostTips(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "ID du post invalide")
+		return
+	}
+
+	tips, err := repository.ListPostTips(postID)
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération des pourboires")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, tips)
+}
@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"onlyflick/internal/domain"
 	"onlyflick/internal/middleware"
 	"onlyflick/internal/repository"
-	"onlyflick/internal/utils"
 	"onlyflick/pkg/response"
 	"strconv"
 
@@ -53,9 +53,15 @@ func GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Décryptage des champs chiffrés (sécurité supplémentaire)
-	firstName, _ := utils.DecryptAES(user.FirstName)
-	lastName, _ := utils.DecryptAES(user.LastName)
+	// Un compte en cours de suppression ou purgé n'a plus de profil public
+	if hidden, err := repository.IsAccountHidden(targetUserID); err != nil || hidden {
+		log.Printf("[GetUserProfileHandler] Profil %d masqué (suppression du compte)", targetUserID)
+		response.RespondWithError(w, http.StatusNotFound, "Utilisateur non trouvé")
+		return
+	}
+
+	// Champs déjà déchiffrés par le repository
+	firstName, lastName := user.FirstName, user.LastName
 	// Note: email n'est pas décrypté car non retourné dans le profil public (sécurité)
 
 	// ===== NOUVEAU : Récupération des statistiques utilisateur =====
@@ -115,8 +121,15 @@ func GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Informations spécifiques aux créateurs
 	if user.Role == "creator" {
-		publicProfile["subscription_price"] = "4.99" // Prix fixe pour l'abonnement
-		publicProfile["currency"] = "EUR"
+		// Tarif et paliers définis par le créateur
+		pricing, err := repository.GetCreatorPricing(targetUserID)
+		if err != nil {
+			log.Printf("[GetUserProfileHandler] Erreur récupération tarif du créateur %d: %v", targetUserID, err)
+		} else {
+			publicProfile["subscription_price"] = domain.FormatPrice(pricing.Price)
+			publicProfile["currency"] = pricing.Currency
+			publicProfile["subscription_tiers"] = pricing.Tiers
+		}
 
 		// Informations d'abonnement pour l'utilisateur connecté
 		if currentUserID != targetUserID {
@@ -205,7 +218,7 @@ func GetUserPostsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[GetUserPostsHandler] Type de posts visibles: %s", postType)
 
 	// Récupérer les posts
-	posts, err := repository.GetUserPosts(targetUserID, page, limit, postType)
+	posts, err := repository.GetUserPosts(targetUserID, page, limit, postType, currentUserID)
 	if err != nil {
 		log.Printf("[GetUserPostsHandler] Erreur récupération posts: %v", err)
 		response.RespondWithError(w, http.StatusInternalServerError, "Erreur récupération des posts")
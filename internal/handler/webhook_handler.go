@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"onlyflick/pkg/response"
+	"strconv"
+
+	"github.com/stripe/stripe-go"
+)
+
+// Taille maximale acceptée pour le corps d'un webhook Stripe
+const maxWebhookBodyBytes = int64(65536)
+
+// StripeWebhookHandler reçoit les événements Stripe, vérifie leur signature et met à jour
+// les paiements et abonnements. Route: POST /webhooks/stripe
+func StripeWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes)
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("[StripeWebhookHandler] Erreur lecture du corps : %v", err)
+		response.RespondWithError(w, http.StatusBadRequest, "Corps de requête invalide")
+		return
+	}
+
+	event, err := service.Payments().VerifyWebhook(payload, r.Header.Get("Stripe-Signature"))
+	if err != nil {
+		log.Printf("[StripeWebhookHandler] Événement rejeté : %v", err)
+		response.RespondWithError(w, http.StatusBadRequest, "Signature Stripe invalide")
+		return
+	}
+
+	apply, err := stripeEventApplier(event)
+	if err != nil {
+		log.Printf("[StripeWebhookHandler] Événement %s illisible : %v", event.ID, err)
+		response.RespondWithError(w, http.StatusBadRequest, "Événement Stripe invalide")
+		return
+	}
+	if apply == nil {
+		log.Printf("[StripeWebhookHandler] Événement %s de type %s ignoré", event.ID, event.Type)
+		response.RespondWithJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+
+	processed, err := repository.ProcessStripeEvent(event.ID, event.Type, apply)
+	if err != nil {
+		// Une erreur 5xx force Stripe à renvoyer l'événement plus tard
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur de traitement de l'événement")
+		return
+	}
+
+	status := "processed"
+	if !processed {
+		status = "duplicate"
+	}
+	log.Printf("[StripeWebhookHandler] Événement %s (%s) : %s", event.ID, event.Type, status)
+	response.RespondWithJSON(w, http.StatusOK, map[string]string{"status": status})
+}
+
+// stripeEventApplier décode l'objet de l'événement et retourne la mise à jour à appliquer.
+// Retourne nil pour les types d'événements non gérés.
+func stripeEventApplier(event *service.WebhookEvent) (func(tx *sql.Tx) error, error) {
+	switch event.Type {
+	case service.StripeEventPaymentSucceeded, service.StripeEventPaymentFailed:
+		var intent stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data, &intent); err != nil {
+			return nil, err
+		}
+		if event.Type == service.StripeEventPaymentSucceeded {
+			paymentMethodID := ""
+			if intent.PaymentMethod != nil {
+				paymentMethodID = intent.PaymentMethod.ID
+			}
+			return func(tx *sql.Tx) error { return repository.MarkPaymentSucceeded(tx, intent.ID, paymentMethodID) }, nil
+		}
+		return func(tx *sql.Tx) error { return repository.MarkPaymentFailed(tx, intent.ID) }, nil
+
+	case service.StripeEventChargeRefunded:
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data, &charge); err != nil {
+			return nil, err
+		}
+		if charge.PaymentIntent == "" {
+			return nil, fmt.Errorf("charge %s sans payment_intent", charge.ID)
+		}
+		refundID := ""
+		if charge.Refunds != nil && len(charge.Refunds.Data) > 0 {
+			refundID = charge.Refunds.Data[0].ID // Remboursement le plus récent
+		}
+		return func(tx *sql.Tx) error {
+			return repository.MarkPaymentRefunded(tx, charge.PaymentIntent, refundID, charge.AmountRefunded)
+		}, nil
+
+	case service.StripeEventSetupSucceeded:
+		var setup stripe.SetupIntent
+		if err := json.Unmarshal(event.Data, &setup); err != nil {
+			return nil, err
+		}
+		subscriptionID, err := strconv.ParseInt(setup.Metadata["subscription_id"], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("metadata subscription_id invalide pour %s", setup.ID)
+		}
+		if setup.PaymentMethod == nil || setup.PaymentMethod.ID == "" {
+			return nil, fmt.Errorf("setup_intent %s sans payment_method", setup.ID)
+		}
+		paymentMethodID := setup.PaymentMethod.ID
+		return func(tx *sql.Tx) error {
+			return repository.SaveSubscriptionPaymentMethod(tx, subscriptionID, paymentMethodID)
+		}, nil
+
+	case service.StripeEventSubscriptionDeleted:
+		var sub stripe.Subscription
+		if err := json.Unmarshal(event.Data, &sub); err != nil {
+			return nil, err
+		}
+		subscriptionID, err := strconv.ParseInt(sub.Metadata["subscription_id"], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("metadata subscription_id invalide pour %s", sub.ID)
+		}
+		return func(tx *sql.Tx) error { return repository.EndSubscription(tx, subscriptionID) }, nil
+	}
+
+	return nil, nil
+}
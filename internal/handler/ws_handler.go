@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
 	"onlyflick/internal/repository"
 	"onlyflick/pkg/response"
 	"onlyflick/pkg/ws"
@@ -42,8 +43,11 @@ func HandleMessagesWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok, err = repository.IsUserInConversation(convID, userID)
-	if err != nil || !ok {
+	// La connexion est déjà établie : un refus la ferme simplement
+	creatorID, subscriberID, err := repository.GetConversationParticipants(convID)
+	actor, actorErr := middleware.ActorFromRequest(r)
+	resource := policy.Resource{OwnerID: creatorID, ParticipantIDs: []int64{creatorID, subscriberID}}
+	if err != nil || actorErr != nil || !policy.Can(actor, policy.ConversationRead, resource) {
 		log.Printf("[WebSocket] Accès interdit pour user %d à conv %d", userID, convID)
 		return
 	}
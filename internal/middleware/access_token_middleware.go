@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"onlyflick/pkg/response"
+	"regexp"
+	"time"
+)
+
+// accessTokenRoute ouvre une route (méthode et chemin) aux jetons d'accès personnels porteurs du scope.
+type accessTokenRoute struct {
+	method string
+	path   *regexp.Regexp
+	scope  string
+}
+
+// Routes accessibles aux jetons d'accès personnels. Toutes les autres (compte, jetons, paiements,
+// administration...) restent réservées aux sessions ouvertes par mot de passe.
+var accessTokenRoutes = []accessTokenRoute{
+	{http.MethodPost, regexp.MustCompile(`^/posts/?$`), domain.ScopePostsWrite},
+	{http.MethodPost, regexp.MustCompile(`^/creator/posts/?$`), domain.ScopePostsWrite},
+	{http.MethodGet, regexp.MustCompile(`^/(posts/me|creator/posts)/?$`), domain.ScopePostsWrite},
+	{http.MethodGet, regexp.MustCompile(`^/posts/\d+/?$`), domain.ScopePostsWrite},
+	{http.MethodPatch, regexp.MustCompile(`^/posts/\d+/?$`), domain.ScopePostsWrite},
+	{http.MethodDelete, regexp.MustCompile(`^/posts/\d+/?$`), domain.ScopePostsWrite},
+	{http.MethodPost, regexp.MustCompile(`^/media/upload/?$`), domain.ScopePostsWrite},
+	{http.MethodDelete, regexp.MustCompile(`^/media/[^/]+/?$`), domain.ScopePostsWrite},
+
+	{http.MethodGet, regexp.MustCompile(`^/profile/stats/?$`), domain.ScopeStatsRead},
+	{http.MethodGet, regexp.MustCompile(`^/creator/earnings(/statements)?/?$`), domain.ScopeStatsRead},
+
+	{http.MethodGet, regexp.MustCompile(`^/conversations/?$`), domain.ScopeMessagesRead},
+	{http.MethodGet, regexp.MustCompile(`^/conversations/\d+/messages/?$`), domain.ScopeMessagesRead},
+}
+
+// requiredAccessTokenScope retourne le scope exigé d'un jeton d'accès personnel pour la requête,
+// ou "" si la route n'est pas ouverte aux jetons.
+func requiredAccessTokenScope(method, path string) string {
+	for _, route := range accessTokenRoutes {
+		if route.method == method && route.path.MatchString(path) {
+			return route.scope
+		}
+	}
+	return ""
+}
+
+// authenticateAccessToken valide un jeton d'accès personnel et vérifie qu'il porte le scope de la route.
+// En cas de refus, la réponse d'erreur est écrite et nil est retourné.
+func authenticateAccessToken(w http.ResponseWriter, r *http.Request, funcName, tokenString string) *domain.AccessTokenIdentity {
+	identity, err := service.AuthenticatePersonalAccessToken(tokenString, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrAccessTokenInvalid) {
+			log.Printf("[%s] Jeton d'accès personnel invalide ou expiré", funcName)
+			response.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired access token")
+			return nil
+		}
+		response.RespondWithError(w, http.StatusInternalServerError, "Access token verification failed")
+		return nil
+	}
+
+	scope := requiredAccessTokenScope(r.Method, r.URL.Path)
+	if scope == "" || !identity.HasScope(scope) {
+		log.Printf("[%s] Jeton %d de l'utilisateur %d refusé sur %s %s (scope requis : %q)", funcName, identity.TokenID, identity.UserID, r.Method, r.URL.Path, scope)
+		if scope == "" {
+			response.RespondWithError(w, http.StatusForbidden, "Access tokens are not allowed on this route")
+		} else {
+			response.RespondWithError(w, http.StatusForbidden, "Access token is missing scope: "+scope)
+		}
+		return nil
+	}
+	return identity
+}
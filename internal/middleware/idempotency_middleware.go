@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"onlyflick/pkg/response"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader est l'en-tête fourni par le client pour rendre une requête rejouable sans effet de bord.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader signale une réponse rejouée depuis la mémoire des clés d'idempotence.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// Taille maximale du corps d'une requête idempotente
+	maxIdempotentBodySize = 1 << 20
+)
+
+// =====================
+// Middleware d'idempotence
+// =====================
+
+// IdempotencyMiddleware rend rejouables les requêtes portant un en-tête Idempotency-Key : la première réponse
+// est mémorisée 24h par utilisateur et renvoyée telle quelle aux requêtes suivantes portant la même clé.
+// Une clé réutilisée avec un autre corps (ou une autre route) est refusée. Sans en-tête, la requête est traitée normalement.
+// Doit être placé après le middleware JWT pour que la clé soit propre à l'utilisateur.
+func IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > domain.MaxIdempotencyKeyLen {
+			response.RespondWithError(w, http.StatusBadRequest, "Clé d'idempotence trop longue")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+		if err != nil {
+			log.Printf("[IdempotencyMiddleware] Erreur lecture du corps : %v", err)
+			response.RespondWithError(w, http.StatusRequestEntityTooLarge, "Corps de requête trop volumineux")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID, _ := r.Context().Value(ContextUserIDKey).(int64)
+		fingerprint := requestFingerprint(r.Method, r.URL.Path, body)
+
+		record, reserved, err := repository.ReserveIdempotencyKey(userID, key, fingerprint, time.Now())
+		if err != nil {
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification de la clé d'idempotence")
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.Fingerprint != fingerprint:
+				log.Printf("[IdempotencyMiddleware] Clé %q réutilisée avec une requête différente par l'utilisateur %d", key, userID)
+				response.RespondWithError(w, http.StatusUnprocessableEntity, "Clé d'idempotence déjà utilisée pour une autre requête")
+			case !record.Completed():
+				response.RespondWithError(w, http.StatusConflict, "Requête déjà en cours de traitement pour cette clé d'idempotence")
+			default:
+				log.Printf("[IdempotencyMiddleware] Réponse rejouée pour la clé %q de l'utilisateur %d", key, userID)
+				if record.ContentType != "" {
+					w.Header().Set("Content-Type", record.ContentType)
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(record.StatusCode)
+				w.Write(record.ResponseBody)
+			}
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			// La clé est libérée si le handler panique, pour autoriser une nouvelle tentative
+			if p := recover(); p != nil {
+				repository.ReleaseIdempotencyKey(record.ID)
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+
+		// Les erreurs serveur ne sont pas mémorisées : le client peut réessayer avec la même clé
+		if rec.status >= http.StatusInternalServerError {
+			repository.ReleaseIdempotencyKey(record.ID)
+			return
+		}
+		repository.SaveIdempotentResponse(record.ID, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes())
+	})
+}
+
+// requestFingerprint calcule l'empreinte d'une requête (méthode, chemin et corps).
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder transmet la réponse au client tout en conservant son statut et son corps.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -2,12 +2,16 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"onlyflick/internal/repository"
 	"onlyflick/internal/service"
+	"onlyflick/internal/utils"
 	"onlyflick/pkg/response"
 
 	"github.com/golang-jwt/jwt"
@@ -19,8 +23,9 @@ import (
 type contextKey string
 
 const (
-	ContextUserIDKey   contextKey = "userID"
-	ContextUserRoleKey contextKey = "userRole"
+	ContextUserIDKey    contextKey = "userID"
+	ContextUserRoleKey  contextKey = "userRole"
+	ContextSessionIDKey contextKey = "sessionID" // 0 pour un token sans session
 )
 
 // =====================
@@ -47,7 +52,29 @@ func JWTMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Jeton d'accès personnel d'une intégration : refusé explicitement s'il est invalide ou hors scope
+		if service.IsPersonalAccessToken(tokenString) {
+			identity := authenticateAccessToken(w, r, "JWTMiddleware", tokenString)
+			if identity == nil {
+				return
+			}
+			if rejectSuspendedUser(w, "JWTMiddleware", identity.UserID) {
+				return
+			}
+			log.Printf("[JWTMiddleware] Utilisateur authentifié par jeton d'accès: ID=%d, Role=%s\n", identity.UserID, identity.Role)
+			ctx := context.WithValue(r.Context(), ContextUserIDKey, identity.UserID)
+			ctx = context.WithValue(ctx, ContextUserRoleKey, string(identity.Role))
+			ctx = context.WithValue(ctx, ContextSessionIDKey, int64(0))
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		token, err := service.ValidateJWT(tokenString)
+		if errors.Is(err, service.ErrSessionRevoked) {
+			log.Println("[JWTMiddleware] Session révoquée, token refusé")
+			response.RespondWithError(w, http.StatusUnauthorized, "Session revoked")
+			return
+		}
 		if err != nil || !token.Valid {
 			log.Printf("[JWTMiddleware] Token invalide ou expiré: %v, utilisateur guest\n", err)
 			ctx := context.WithValue(r.Context(), ContextUserRoleKey, "guest")
@@ -66,9 +93,16 @@ func JWTMiddleware(next http.Handler) http.Handler {
 		userID, _ := claims["sub"].(float64)
 		userRole, _ := claims["role"].(string)
 
+		if rejectSuspendedUser(w, "JWTMiddleware", int64(userID)) {
+			return
+		}
+
 		log.Printf("[JWTMiddleware] Utilisateur authentifié: ID=%d, Role=%s\n", int64(userID), userRole)
+		sessionID := service.SessionIDFromClaims(claims)
+		touchSession(r, sessionID)
 		ctx := context.WithValue(r.Context(), ContextUserIDKey, int64(userID))
 		ctx = context.WithValue(ctx, ContextUserRoleKey, userRole)
+		ctx = context.WithValue(ctx, ContextSessionIDKey, sessionID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -96,6 +130,27 @@ func JWTMiddlewareWithRole(allowedRoles ...string) func(http.Handler) http.Handl
 				return
 			}
 
+			if service.IsPersonalAccessToken(tokenString) {
+				identity := authenticateAccessToken(w, r, "JWTMiddlewareWithRole", tokenString)
+				if identity == nil {
+					return
+				}
+				if !roleAllowed(string(identity.Role), allowedRoles) {
+					log.Printf("[JWTMiddlewareWithRole] Accès refusé pour le rôle: %s\n", identity.Role)
+					response.RespondWithError(w, http.StatusForbidden, fmt.Sprintf("Access denied for role: %s", identity.Role))
+					return
+				}
+				if rejectSuspendedUser(w, "JWTMiddlewareWithRole", identity.UserID) {
+					return
+				}
+				log.Printf("[JWTMiddlewareWithRole] Accès autorisé par jeton d'accès: ID=%d, Role=%s\n", identity.UserID, identity.Role)
+				ctx := context.WithValue(r.Context(), ContextUserIDKey, identity.UserID)
+				ctx = context.WithValue(ctx, ContextUserRoleKey, string(identity.Role))
+				ctx = context.WithValue(ctx, ContextSessionIDKey, int64(0))
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			token, err := service.ValidateJWT(tokenString)
 			if err != nil || !token.Valid {
 				log.Printf("[JWTMiddlewareWithRole] Token invalide ou expiré: %v\n", err)
@@ -125,25 +180,61 @@ func JWTMiddlewareWithRole(allowedRoles ...string) func(http.Handler) http.Handl
 			}
 
 			// Vérifie le rôle si des rôles sont spécifiés
-			if len(allowedRoles) > 0 {
-				authorized := false
-				for _, role := range allowedRoles {
-					if userRole == role {
-						authorized = true
-						break
-					}
-				}
-				if !authorized {
-					log.Printf("[JWTMiddlewareWithRole] Accès refusé pour le rôle: %s\n", userRole)
-					response.RespondWithError(w, http.StatusForbidden, fmt.Sprintf("Access denied for role: %s", userRole))
-					return
-				}
+			if !roleAllowed(userRole, allowedRoles) {
+				log.Printf("[JWTMiddlewareWithRole] Accès refusé pour le rôle: %s\n", userRole)
+				response.RespondWithError(w, http.StatusForbidden, fmt.Sprintf("Access denied for role: %s", userRole))
+				return
+			}
+
+			if rejectSuspendedUser(w, "JWTMiddlewareWithRole", int64(userID)) {
+				return
 			}
 
 			log.Printf("[JWTMiddlewareWithRole] Accès autorisé: ID=%d, Role=%s\n", int64(userID), userRole)
+			sessionID := service.SessionIDFromClaims(claims)
+			touchSession(r, sessionID)
 			ctx := context.WithValue(r.Context(), ContextUserIDKey, int64(userID))
 			ctx = context.WithValue(ctx, ContextUserRoleKey, userRole)
+			ctx = context.WithValue(ctx, ContextSessionIDKey, sessionID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// rejectSuspendedUser refuse la requête d'un compte suspendu (403, code account_suspended).
+// Retourne true si la réponse d'erreur a été écrite.
+func rejectSuspendedUser(w http.ResponseWriter, funcName string, userID int64) bool {
+	suspension, err := repository.GetActiveSuspension(userID, time.Now())
+	if err != nil {
+		response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification du compte")
+		return true
+	}
+	if suspension == nil {
+		return false
+	}
+	log.Printf("[%s] Accès refusé à l'utilisateur %d : compte suspendu", funcName, userID)
+	response.RespondWithErrorCode(w, http.StatusForbidden, service.SuspendedErrorCode, service.SuspensionMessage(suspension))
+	return true
+}
+
+// touchSession enregistre l'activité de la session de l'access token (sans session : rien à faire).
+// Une erreur n'interrompt pas la requête.
+func touchSession(r *http.Request, sessionID int64) {
+	if sessionID <= 0 {
+		return
+	}
+	repository.TouchSession(sessionID, utils.ClientIP(r), time.Now())
+}
+
+// roleAllowed indique si le rôle figure parmi les rôles autorisés (tous les rôles si la liste est vide).
+func roleAllowed(userRole string, allowedRoles []string) bool {
+	if len(allowedRoles) == 0 {
+		return true
+	}
+	for _, role := range allowedRoles {
+		if userRole == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"onlyflick/internal/repository"
+	"onlyflick/pkg/response"
+)
+
+// RequireAdminMFA impose la double authentification aux administrateurs : tant qu'elle n'est pas
+// activée, les routes d'administration sont refusées (l'enrôlement reste accessible depuis /profile/mfa).
+// Doit être placé après JWTMiddlewareWithRole.
+func RequireAdminMFA(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(ContextUserRoleKey).(string)
+		userID, ok := r.Context().Value(ContextUserIDKey).(int64)
+		if !ok || role != "admin" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		enabled, err := repository.IsMFAEnabled(userID)
+		if err != nil {
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification du compte")
+			return
+		}
+		if !enabled {
+			log.Printf("[RequireAdminMFA] Accès refusé à l'administrateur %d : double authentification non activée", userID)
+			response.RespondWithError(w, http.StatusForbidden, "Double authentification obligatoire pour les administrateurs")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
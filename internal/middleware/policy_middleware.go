@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/policy"
+	"onlyflick/internal/repository"
+	"onlyflick/pkg/response"
+)
+
+// ActorFromRequest construit l'acteur de la requête à partir du contexte posé par le middleware JWT.
+// Le périmètre d'un administrateur est relu en base pour qu'un changement s'applique immédiatement.
+func ActorFromRequest(r *http.Request) (policy.Actor, error) {
+	userID, _ := r.Context().Value(ContextUserIDKey).(int64)
+	role, _ := r.Context().Value(ContextUserRoleKey).(string)
+	actor := policy.Actor{UserID: userID, Role: domain.Role(role)}
+	if userID == 0 || actor.Role != domain.RoleAdmin {
+		return actor, nil
+	}
+
+	adminRole, err := repository.GetAdminRole(userID)
+	if err != nil {
+		return actor, err
+	}
+	actor.AdminRole = adminRole
+	return actor, nil
+}
+
+// RequirePermission refuse la requête si l'acteur n'a pas le droit d'effectuer l'action,
+// pour les actions qui ne portent pas sur une ressource particulière. Doit être placé après le middleware JWT.
+func RequirePermission(action policy.Action) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor, err := ActorFromRequest(r)
+			if err != nil {
+				response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification des droits")
+				return
+			}
+			if !policy.Can(actor, action, policy.None) {
+				log.Printf("[RequirePermission] Action %s refusée à l'utilisateur %d (rôle %s, périmètre %q)", action, actor.UserID, actor.Role, actor.AdminRole)
+				response.RespondWithError(w, http.StatusForbidden, "Accès refusé")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"onlyflick/internal/repository"
+	"onlyflick/pkg/response"
+)
+
+// RequireVerifiedEmail refuse l'accès aux utilisateurs dont l'adresse e-mail n'est pas confirmée
+// (publication, messagerie, abonnements). Doit être placé après le middleware JWT ;
+// les visiteurs non authentifiés sont laissés au handler.
+func RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(ContextUserIDKey).(int64)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		verified, err := repository.IsEmailVerified(userID)
+		if err != nil {
+			response.RespondWithError(w, http.StatusInternalServerError, "Erreur vérification du compte")
+			return
+		}
+		if !verified {
+			log.Printf("[RequireVerifiedEmail] Accès refusé à l'utilisateur %d : adresse e-mail non confirmée", userID)
+			response.RespondWithError(w, http.StatusForbidden, "Adresse e-mail non vérifiée")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
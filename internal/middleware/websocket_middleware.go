@@ -80,6 +80,10 @@ func WebSocketJWTMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if rejectSuspendedUser(w, "WebSocketJWTMiddleware", int64(userID)) {
+			return
+		}
+
 		log.Printf("[WebSocketJWTMiddleware] WebSocket authentifié: ID=%d, Role=%s\n", int64(userID), userRole)
 
 		// Ajouter les informations d'authentification au contexte
@@ -0,0 +1,148 @@
+// Package policy centralise les règles d'autorisation de l'API : qui (Actor) peut effectuer quelle action
+// (Action) sur quelle ressource (Resource). Les décisions ne dépendent que des faits fournis par l'appelant
+// (propriétaire, participants, abonnement), sans accès à la base de données.
+package policy
+
+import "onlyflick/internal/domain"
+
+// Actor est l'utilisateur à l'origine de la requête. UserID vaut 0 pour un visiteur.
+type Actor struct {
+	UserID    int64
+	Role      domain.Role
+	AdminRole domain.AdminRole // Périmètre d'un administrateur, vide pour un administrateur complet
+}
+
+// Action désigne une opération soumise à autorisation.
+type Action string
+
+const (
+	// Posts
+	PostCreate                Action = "post:create"
+	PostUpdate                Action = "post:update"
+	PostDelete                Action = "post:delete"
+	PostViewSubscriberContent Action = "post:view_subscriber_content"
+
+	// Commentaires
+	CommentCreate Action = "comment:create"
+	CommentDelete Action = "comment:delete"
+
+	// Médias
+	MediaUpload Action = "media:upload"
+	MediaDelete Action = "media:delete"
+
+	// Conversations
+	ConversationStart Action = "conversation:start"
+	ConversationRead  Action = "conversation:read"
+	ConversationSend  Action = "conversation:send"
+
+	// Signalements
+	ReportCreate   Action = "report:create"
+	ReportRead     Action = "report:read"
+	ReportModerate Action = "report:moderate"
+
+	// Administration
+	AdminDashboard       Action = "admin:dashboard"
+	CreatorRead          Action = "admin:creator_read"
+	CreatorRequestReview Action = "admin:creator_request_review"
+	UserList             Action = "admin:user_list"
+	UserDelete           Action = "admin:user_delete"
+	UserSecurityManage   Action = "admin:user_security" // Réinitialisation MFA, déverrouillage
+	UserSuspend          Action = "admin:user_suspend"  // Suspension et levée de suspension des comptes
+	AdminRoleManage      Action = "admin:role_manage"
+	PaymentRefund        Action = "admin:payment_refund"
+	PayoutManage         Action = "admin:payout_manage"
+	AuditRead            Action = "admin:audit_read" // Lecture du journal d'audit d'administration
+
+	// Compte
+	MFADisable Action = "account:mfa_disable"
+)
+
+// Resource décrit la cible d'une action. Les champs sans objet pour l'action restent à zéro.
+type Resource struct {
+	OwnerID        int64       // Auteur du post ou du commentaire, propriétaire du média, créateur de la conversation
+	OwnerRole      domain.Role // Rôle du propriétaire (destinataire d'une nouvelle conversation)
+	ParticipantIDs []int64     // Participants d'une conversation
+	Subscribed     bool        // L'acteur est abonné au propriétaire
+}
+
+// None est la ressource des actions qui ne portent pas sur un objet particulier.
+var None = Resource{}
+
+// Permissions d'administration de chaque périmètre (l'administrateur complet les a toutes)
+var adminPermissions = map[domain.AdminRole]map[Action]bool{
+	domain.AdminRoleModerator: {
+		AdminDashboard: true, PostDelete: true, PostViewSubscriberContent: true, CommentDelete: true,
+		MediaDelete: true, ReportRead: true, ReportModerate: true, UserSuspend: true,
+	},
+	domain.AdminRoleFinance: {
+		AdminDashboard: true, CreatorRead: true, PaymentRefund: true, PayoutManage: true,
+	},
+	domain.AdminRoleSupport: {
+		AdminDashboard: true, CreatorRead: true, CreatorRequestReview: true, UserList: true, UserSecurityManage: true,
+	},
+}
+
+// Can indique si l'acteur peut effectuer l'action sur la ressource.
+func Can(actor Actor, action Action, resource Resource) bool {
+	if actor.UserID == 0 {
+		return false
+	}
+	isOwner := resource.OwnerID != 0 && resource.OwnerID == actor.UserID
+
+	switch action {
+	case PostCreate, MediaUpload:
+		return actor.Role == domain.RoleCreator || isFullAdmin(actor)
+	case PostUpdate:
+		return isOwner || isFullAdmin(actor)
+	case PostDelete, CommentDelete, MediaDelete, PostViewSubscriberContent:
+		if isOwner || hasAdminPermission(actor, action) {
+			return true
+		}
+		return action == PostViewSubscriberContent && resource.Subscribed
+
+	case CommentCreate, ReportCreate:
+		return true
+
+	case ConversationStart:
+		return !isOwner && resource.OwnerRole == domain.RoleCreator && resource.Subscribed
+	case ConversationRead:
+		return isParticipant(actor, resource)
+	case ConversationSend:
+		// Le créateur répond librement ; l'abonné doit l'être encore
+		return isParticipant(actor, resource) && (isOwner || resource.Subscribed)
+
+	case MFADisable:
+		return actor.Role != domain.RoleAdmin
+
+	case AdminRoleManage, UserDelete, AuditRead:
+		return isFullAdmin(actor)
+	}
+
+	return hasAdminPermission(actor, action)
+}
+
+// isFullAdmin indique si l'acteur est un administrateur sans restriction de périmètre.
+func isFullAdmin(actor Actor) bool {
+	return actor.Role == domain.RoleAdmin && actor.AdminRole == domain.AdminRoleFull
+}
+
+// hasAdminPermission indique si l'acteur est un administrateur dont le périmètre couvre l'action.
+func hasAdminPermission(actor Actor, action Action) bool {
+	if actor.Role != domain.RoleAdmin {
+		return false
+	}
+	if actor.AdminRole == domain.AdminRoleFull {
+		return true
+	}
+	return adminPermissions[actor.AdminRole][action]
+}
+
+// isParticipant indique si l'acteur participe à la conversation.
+func isParticipant(actor Actor, resource Resource) bool {
+	for _, id := range resource.ParticipantIDs {
+		if id == actor.UserID {
+			return true
+		}
+	}
+	return false
+}
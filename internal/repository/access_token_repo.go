@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAccessTokenInvalid est retournée pour un jeton d'accès personnel inconnu, supprimé ou expiré.
+	ErrAccessTokenInvalid = errors.New("jeton d'accès invalide ou expiré")
+	// ErrAccessTokenNotFound est retournée pour un jeton inconnu de l'utilisateur.
+	ErrAccessTokenNotFound = errors.New("jeton d'accès introuvable")
+)
+
+// CreatePersonalAccessToken enregistre l'empreinte d'un nouveau jeton d'accès personnel.
+func CreatePersonalAccessToken(userID int64, name, tokenHash, prefix string, scopes []string, expiresAt time.Time) (*domain.PersonalAccessToken, error) {
+	token := domain.PersonalAccessToken{UserID: userID, Name: name, Prefix: prefix, Scopes: scopes, ExpiresAt: expiresAt}
+	err := database.DB.QueryRow(`
+		INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING id, created_at
+	`, userID, name, tokenHash, prefix, strings.Join(scopes, " "), expiresAt).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		log.Printf("[CreatePersonalAccessToken] Erreur création du jeton de l'utilisateur %d : %v", userID, err)
+		return nil, err
+	}
+	return &token, nil
+}
+
+// CountPersonalAccessTokens compte les jetons non expirés de l'utilisateur.
+func CountPersonalAccessTokens(userID int64, now time.Time) (int, error) {
+	var count int
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*) FROM personal_access_tokens WHERE user_id = $1 AND expires_at > $2
+	`, userID, now).Scan(&count)
+	if err != nil {
+		log.Printf("[CountPersonalAccessTokens] Erreur comptage des jetons de l'utilisateur %d : %v", userID, err)
+	}
+	return count, err
+}
+
+// ListPersonalAccessTokens retourne les jetons de l'utilisateur, expirés compris, du plus récent au plus ancien.
+func ListPersonalAccessTokens(userID int64) ([]domain.PersonalAccessToken, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, name, token_prefix, scopes, expires_at, last_used_at, created_at
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		log.Printf("[ListPersonalAccessTokens] Erreur lecture des jetons de l'utilisateur %d : %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []domain.PersonalAccessToken{}
+	for rows.Next() {
+		var token domain.PersonalAccessToken
+		var scopes string
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &scopes, &token.ExpiresAt, &lastUsedAt, &token.CreatedAt); err != nil {
+			return nil, err
+		}
+		token.Scopes = strings.Fields(scopes)
+		if lastUsedAt.Valid {
+			token.LastUsedAt = &lastUsedAt.Time
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// DeletePersonalAccessToken révoque définitivement un jeton de l'utilisateur.
+func DeletePersonalAccessToken(tokenID, userID int64) error {
+	result, err := database.DB.Exec(`DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`, tokenID, userID)
+	if err != nil {
+		log.Printf("[DeletePersonalAccessToken] Erreur suppression du jeton %d : %v", tokenID, err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticatePersonalAccessToken retrouve le jeton non expiré correspondant à l'empreinte, note son utilisation
+// et retourne son propriétaire avec le rôle courant de celui-ci.
+func AuthenticatePersonalAccessToken(tokenHash string, now time.Time) (*domain.AccessTokenIdentity, error) {
+	var identity domain.AccessTokenIdentity
+	var scopes string
+	err := database.DB.QueryRow(`
+		UPDATE personal_access_tokens t
+		SET last_used_at = $2
+		FROM users u
+		WHERE t.token_hash = $1 AND t.expires_at > $2 AND u.id = t.user_id
+		RETURNING t.id, t.user_id, u.role, t.scopes
+	`, tokenHash, now).Scan(&identity.TokenID, &identity.UserID, &identity.Role, &scopes)
+	if err == sql.ErrNoRows {
+		return nil, ErrAccessTokenInvalid
+	}
+	if err != nil {
+		log.Printf("[AuthenticatePersonalAccessToken] Erreur lecture du jeton : %v", err)
+		return nil, err
+	}
+	identity.Scopes = strings.Fields(scopes)
+	return &identity, nil
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// ErrAccountNotFound est retournée pour un compte inexistant ou déjà anonymisé.
+var ErrAccountNotFound = errors.New("compte introuvable")
+
+// RequestAccountDeletion place le compte en attente de suppression (la date d'une demande déjà
+// en cours est conservée), ferme toutes ses sessions et révoque ses access tokens personnels.
+// Retourne la date de la demande.
+func RequestAccountDeletion(userID int64, now time.Time) (time.Time, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	var requestedAt time.Time
+	err = tx.QueryRow(`
+		UPDATE users SET deletion_requested_at = COALESCE(deletion_requested_at, $2)
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING deletion_requested_at
+	`, userID, now).Scan(&requestedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, ErrAccountNotFound
+	}
+	if err != nil {
+		log.Printf("[RequestAccountDeletion] Erreur demande de suppression du compte %d : %v", userID, err)
+		return time.Time{}, err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE auth_sessions SET revoked_at = $2, revoked_reason = $3
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID, now, domain.SessionRevokedDeletion); err != nil {
+		return time.Time{}, fmt.Errorf("révocation des sessions : %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM personal_access_tokens WHERE user_id = $1`, userID); err != nil {
+		return time.Time{}, fmt.Errorf("révocation des access tokens : %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, err
+	}
+	log.Printf("[RequestAccountDeletion] Suppression du compte %d demandée le %s", userID, requestedAt.Format(time.RFC3339))
+	return requestedAt, nil
+}
+
+// CancelAccountDeletion annule une demande de suppression en cours. Retourne false s'il n'y en avait pas.
+func CancelAccountDeletion(userID int64) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE users SET deletion_requested_at = NULL
+		WHERE id = $1 AND deletion_requested_at IS NOT NULL AND deleted_at IS NULL
+	`, userID)
+	if err != nil {
+		log.Printf("[CancelAccountDeletion] Erreur annulation de la suppression du compte %d : %v", userID, err)
+		return false, err
+	}
+	cancelled, _ := result.RowsAffected()
+	return cancelled > 0, nil
+}
+
+// IsAccountHidden indique si le profil doit être masqué : suppression demandée ou compte anonymisé.
+func IsAccountHidden(userID int64) (bool, error) {
+	var hidden bool
+	err := database.DB.QueryRow(`
+		SELECT deletion_requested_at IS NOT NULL OR deleted_at IS NOT NULL FROM users WHERE id = $1
+	`, userID).Scan(&hidden)
+	if err == sql.ErrNoRows {
+		return false, ErrAccountNotFound
+	}
+	if err != nil {
+		log.Printf("[IsAccountHidden] Erreur lecture de l'état du compte %d : %v", userID, err)
+		return false, err
+	}
+	return hidden, nil
+}
+
+// ListAccountsDueForPurge retourne au plus limit comptes dont la suppression a été demandée avant before.
+func ListAccountsDueForPurge(before time.Time, limit int) ([]int64, error) {
+	rows, err := database.DB.Query(`
+		SELECT id FROM users
+		WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at <= $1 AND deleted_at IS NULL
+		ORDER BY deletion_requested_at
+		LIMIT $2
+	`, before, limit)
+	if err != nil {
+		log.Printf("[ListAccountsDueForPurge] Erreur lecture des comptes à purger : %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ListUserMediaFileIDs retourne les identifiants ImageKit des fichiers envoyés par l'utilisateur
+// (médias suivis et fichiers de ses posts).
+func ListUserMediaFileIDs(userID int64) ([]string, error) {
+	rows, err := database.DB.Query(`
+		SELECT file_id FROM media_files WHERE user_id = $1
+		UNION
+		SELECT file_id FROM posts WHERE user_id = $1 AND COALESCE(file_id, '') <> ''
+	`, userID)
+	if err != nil {
+		log.Printf("[ListUserMediaFileIDs] Erreur lecture des médias de l'utilisateur %d : %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fileIDs []string
+	for rows.Next() {
+		var fileID string
+		if err := rows.Scan(&fileID); err != nil {
+			return nil, err
+		}
+		fileIDs = append(fileIDs, fileID)
+	}
+	return fileIDs, rows.Err()
+}
+
+// AnonymizedUser contient les valeurs (déjà chiffrées) qui remplacent les données personnelles d'un compte purgé.
+type AnonymizedUser struct {
+	FirstName string
+	LastName  string
+	Email     string
+	EmailBidx string // Index aveugle de l'adresse de remplacement, pour que la ligne reste indexée
+	Username  string
+}
+
+// AnonymizeUser purge un compte dans une seule transaction. La ligne de l'utilisateur est conservée,
+// vidée de ses données personnelles, pour que les paiements et abonnements qui la référencent restent
+// en base (obligations comptables) et que ses commentaires et messages deviennent anonymes.
+// Ses posts, likes, médias, sessions, identités et secrets sont supprimés. Une purge décidée par un
+// administrateur (actor non nil) est journalisée dans la même transaction, sans données personnelles.
+func AnonymizeUser(userID int64, anon AnonymizedUser, now time.Time, actor *domain.AuditActor) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET
+			first_name = $2, last_name = $3, email = $4, username = $5,
+			password = '', avatar_url = '', bio = '', admin_role = '',
+			email_bidx = $7, name_bidx = '{}', deleted_at = $6, updated_at = $6
+		WHERE id = $1 AND deleted_at IS NULL
+	`, userID, anon.FirstName, anon.LastName, anon.Email, anon.Username, now, anon.EmailBidx)
+	if err != nil {
+		return fmt.Errorf("anonymisation du compte : %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrAccountNotFound
+	}
+
+	statements := []string{
+		// Contenus et traces d'activité
+		`DELETE FROM posts WHERE user_id = $1`,
+		`DELETE FROM likes WHERE user_id = $1`,
+		`DELETE FROM media_files WHERE user_id = $1`,
+		`DELETE FROM user_interactions WHERE user_id = $1`,
+		`DELETE FROM creator_requests WHERE user_id = $1`,
+		`UPDATE tips SET message = '' WHERE sender_id = $1`,
+		// Abonnements conservés pour les paiements, mais plus renouvelés
+		`UPDATE subscriptions SET status = FALSE, auto_renew = FALSE, payment_method_id = NULL
+		 WHERE subscriber_id = $1 OR creator_id = $1`,
+		// Accès et secrets
+		`DELETE FROM auth_sessions WHERE user_id = $1`,
+		`DELETE FROM personal_access_tokens WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM user_mfa WHERE user_id = $1`,
+		`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM password_reset_tokens WHERE user_id = $1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, userID); err != nil {
+			return fmt.Errorf("purge du compte %d : %w", userID, err)
+		}
+	}
+
+	if err := recordAudit(tx, actor, domain.AuditUserDelete, domain.AuditTargetUser, userID,
+		map[string]interface{}{"deleted": false},
+		map[string]interface{}{"deleted": true, "username": anon.Username, "deleted_at": now}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("[AnonymizeUser] Compte %d anonymisé", userID)
+	return nil
+}
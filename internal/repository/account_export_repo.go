@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"log"
+	"onlyflick/internal/database"
+)
+
+// ExportSection décrit un fichier de l'export des données personnelles (droit d'accès RGPD)
+// et la requête qui le remplit ; $1 est l'identifiant de l'utilisateur (payments.payer_id est du texte).
+type ExportSection struct {
+	Name  string
+	Query string
+}
+
+// AccountExportSections liste les données exportées en plus du profil, dans l'ordre de l'archive.
+var AccountExportSections = []ExportSection{
+	{"posts", `
+		SELECT id, title, description, media_url, visibility, price, created_at, updated_at
+		FROM posts WHERE user_id = $1 ORDER BY id`},
+	{"comments", `
+		SELECT id, post_id, content, created_at, updated_at
+		FROM comments WHERE user_id = $1 ORDER BY id`},
+	{"messages", `
+		SELECT m.id, m.conversation_id, m.sender_id, m.content, m.kind, m.created_at
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE c.creator_id = $1 OR c.subscriber_id = $1
+		ORDER BY m.conversation_id, m.id`},
+	{"likes", `
+		SELECT post_id, created_at FROM likes WHERE user_id = $1 ORDER BY created_at`},
+	{"subscriptions", `
+		SELECT id, subscriber_id, creator_id, tier_id, status, auto_renew, created_at, end_at, trial_end_at
+		FROM subscriptions WHERE subscriber_id = $1 OR creator_id = $1 ORDER BY id`},
+	{"payments", `
+		SELECT id, kind, subscription_id, creator_id, payer_id, amount, status, refund_of, refund_reason, start_at, end_at, created_at
+		FROM payments WHERE payer_id = $1::bigint::text OR creator_id = $1 ORDER BY id`},
+}
+
+// ExportRows exécute la requête d'une section pour un utilisateur et retourne les lignes sous
+// forme de tableaux associatifs colonne → valeur, prêts à être sérialisés en JSON.
+func ExportRows(section ExportSection, userID int64) ([]map[string]interface{}, error) {
+	rows, err := database.DB.Query(section.Query, userID)
+	if err != nil {
+		log.Printf("[ExportRows] Erreur export de la section %s de l'utilisateur %d : %v", section.Name, userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
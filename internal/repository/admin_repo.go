@@ -1,10 +1,16 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"onlyflick/internal/database"
 	"onlyflick/internal/domain"
+	"onlyflick/internal/utils"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
 // GetCreatorsStats récupère les statistiques des créateurs (nombre d'abonnés, de posts, et de likes).
@@ -30,6 +36,8 @@ func GetCreatorsStats() ([]domain.CreatorStats, error) {
 			log.Printf("[GetCreatorsStats] Erreur lors du scan des données du créateur : %v", err)
 			return nil, err
 		}
+		creator.FirstName = utils.DecryptField(creator.FirstName)
+		creator.LastName = utils.DecryptField(creator.LastName)
 		creators = append(creators, creator)
 	}
 
@@ -49,6 +57,9 @@ func GetCreatorDetails(creatorID int64) (*domain.CreatorDetails, error) {
 		log.Printf("[GetCreatorDetails] Erreur récupération des infos créateur %d : %v", creatorID, err)
 		return nil, fmt.Errorf("créateur introuvable")
 	}
+	creator.FirstName = utils.DecryptField(creator.FirstName)
+	creator.LastName = utils.DecryptField(creator.LastName)
+	creator.Email = utils.DecryptField(creator.Email)
 
 	// Récupérer les abonnés du créateur
 	rows, err := database.DB.Query(`
@@ -69,6 +80,9 @@ func GetCreatorDetails(creatorID int64) (*domain.CreatorDetails, error) {
 			log.Printf("[GetCreatorDetails] Erreur scan des abonnés : %v", err)
 			return nil, err
 		}
+		subscriber.FirstName = utils.DecryptField(subscriber.FirstName)
+		subscriber.LastName = utils.DecryptField(subscriber.LastName)
+		subscriber.Email = utils.DecryptField(subscriber.Email)
 		creator.Subscribers = append(creator.Subscribers, subscriber)
 	}
 
@@ -113,8 +127,11 @@ func GetGlobalStats() (domain.GlobalStats, error) {
 			(SELECT COUNT(*) FROM users) AS total_users,
 			(SELECT COUNT(*) FROM posts) AS total_posts,
 			(SELECT COUNT(*) FROM reports) AS total_reports,
-			(SELECT SUM(amount) FROM payments) AS total_revenue
-	`).Scan(&stats.TotalUsers, &stats.TotalPosts, &stats.TotalReports, &stats.TotalRevenue)
+			(SELECT COALESCE(SUM(CASE WHEN status = 'refund' THEN -amount ELSE amount END), 0)
+			 FROM payments WHERE status IN ('succeeded', 'partially_refunded', 'refunded', 'refund')) AS total_revenue,
+			(SELECT COALESCE(SUM(CASE WHEN status = 'refund' THEN -amount ELSE amount END), 0)
+			 FROM payments WHERE kind = 'tip' AND status IN ('succeeded', 'partially_refunded', 'refunded', 'refund')) AS tips_revenue
+	`).Scan(&stats.TotalUsers, &stats.TotalPosts, &stats.TotalReports, &stats.TotalRevenue, &stats.TipsRevenue)
 	if err != nil {
 		log.Printf("[GetGlobalStats] Erreur récupération des statistiques globales : %v", err)
 		return stats, err
@@ -122,3 +139,87 @@ func GetGlobalStats() (domain.GlobalStats, error) {
 
 	return stats, nil
 }
+
+// ErrAdminNotFound est retournée quand l'utilisateur ciblé n'est pas un administrateur.
+var ErrAdminNotFound = errors.New("administrateur introuvable")
+
+// GetAdminRole retourne le périmètre d'un administrateur (vide pour un administrateur complet).
+func GetAdminRole(userID int64) (domain.AdminRole, error) {
+	var role domain.AdminRole
+	err := database.DB.QueryRow(`SELECT admin_role FROM users WHERE id = $1`, userID).Scan(&role)
+	if err != nil {
+		log.Printf("[GetAdminRole] Erreur lecture du périmètre de l'utilisateur %d : %v", userID, err)
+		return "", err
+	}
+	return role, nil
+}
+
+// SetAdminRole définit le périmètre d'un administrateur et journalise le changement dans la même transaction.
+func SetAdminRole(userID int64, role domain.AdminRole, actor domain.AuditActor) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRow(`SELECT admin_role FROM users WHERE id = $1 AND role = 'admin' FOR UPDATE`, userID).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return ErrAdminNotFound
+	}
+	if err != nil {
+		log.Printf("[SetAdminRole] Erreur lecture du périmètre de l'utilisateur %d : %v", userID, err)
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET admin_role = $2 WHERE id = $1`, userID, string(role)); err != nil {
+		log.Printf("[SetAdminRole] Erreur mise à jour du périmètre de l'utilisateur %d : %v", userID, err)
+		return err
+	}
+
+	if err := recordAudit(tx, &actor, domain.AuditUserAdminRole, domain.AuditTargetUser, userID,
+		map[string]string{"admin_role": previous},
+		map[string]string{"admin_role": string(role)}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AdminSearchUsers recherche des utilisateurs par e-mail exact (recherche contenant "@") ou par
+// username et début des mots du prénom et du nom, via les index aveugles des colonnes chiffrées.
+// Retourne aussi le nombre total de résultats.
+func AdminSearchUsers(query string, limit, offset int) ([]domain.User, int, error) {
+	where := `u.email_bidx = $1`
+	args := []interface{}{utils.EmailBlindIndex(query)}
+	if !strings.Contains(query, "@") {
+		where = `LOWER(COALESCE(u.username, '')) LIKE $1 OR (cardinality($2::text[]) > 0 AND u.name_bidx @> $2::text[])`
+		args = []interface{}{"%" + strings.ToLower(query) + "%", pq.Array(utils.NameSearchIndexes(query))}
+	}
+
+	rows, err := database.DB.Query(fmt.Sprintf(`
+		SELECT u.id, u.first_name, u.last_name, COALESCE(u.username, ''), u.email, u.role, u.created_at, COUNT(*) OVER()
+		FROM users u
+		WHERE %s
+		ORDER BY u.id
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2), append(args, limit, offset)...)
+	if err != nil {
+		log.Printf("[AdminSearchUsers] Erreur recherche des utilisateurs : %v", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []domain.User{}
+	total := 0
+	for rows.Next() {
+		var u domain.User
+		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Username, &u.Email, &u.Role, &u.CreatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		u.FirstName = utils.DecryptField(u.FirstName)
+		u.LastName = utils.DecryptField(u.LastName)
+		u.Email = utils.DecryptField(u.Email)
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
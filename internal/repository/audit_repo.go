@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"strings"
+)
+
+// auditSnapshot sérialise l'état d'une cible pour le journal d'audit (NULL si nil).
+func auditSnapshot(state interface{}) (interface{}, error) {
+	if state == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// recordAudit ajoute une entrée au journal d'audit dans la transaction de l'action, qui échoue
+// avec elle. Sans acteur (action système ou de l'utilisateur lui-même), rien n'est enregistré.
+func recordAudit(tx *sql.Tx, actor *domain.AuditActor, action, targetType string, targetID int64, before, after interface{}) error {
+	if actor == nil {
+		return nil
+	}
+	beforeJSON, err := auditSnapshot(before)
+	if err != nil {
+		return fmt.Errorf("instantané d'audit : %w", err)
+	}
+	afterJSON, err := auditSnapshot(after)
+	if err != nil {
+		return fmt.Errorf("instantané d'audit : %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO admin_audit_log (actor_id, action, target_type, target_id, before, after, ip_address, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+	`, actor.ActorID, action, targetType, targetID, beforeJSON, afterJSON, actor.IPAddress)
+	if err != nil {
+		log.Printf("[recordAudit] Erreur journalisation de %s sur %s %d : %v", action, targetType, targetID, err)
+		return fmt.Errorf("journal d'audit : %w", err)
+	}
+	return nil
+}
+
+// ListAuditEntries retourne au plus limit entrées du journal d'audit correspondant au filtre, les plus
+// récentes d'abord, d'identifiant inférieur à beforeID si non nul (pagination par curseur).
+func ListAuditEntries(filter domain.AuditFilter, beforeID int64, limit int) ([]domain.AuditEntry, error) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if beforeID > 0 {
+		add("id < $%d", beforeID)
+	}
+	if filter.ActorID > 0 {
+		add("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID > 0 {
+		add("target_id = $%d", filter.TargetID)
+	}
+	if filter.Since != nil {
+		add("created_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		add("created_at < $%d", *filter.Until)
+	}
+	args = append(args, limit)
+
+	rows, err := database.DB.Query(fmt.Sprintf(`
+		SELECT id, actor_id, action, target_type, target_id, before, after, ip_address, created_at
+		FROM admin_audit_log
+		WHERE %s
+		ORDER BY id DESC
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		log.Printf("[ListAuditEntries] Erreur lecture du journal d'audit : %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.AuditEntry{}
+	for rows.Next() {
+		var e domain.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &before, &after, &e.IPAddress, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if before != nil {
+			e.Before = json.RawMessage(before)
+		}
+		if after != nil {
+			e.After = json.RawMessage(after)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
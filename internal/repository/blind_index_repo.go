@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+// userEmailIndex calcule l'index aveugle d'un e-mail stocké (chiffré ou historique en clair).
+func userEmailIndex(storedEmail string) (string, error) {
+	email, err := utils.DecryptAES(storedEmail)
+	if err != nil {
+		return "", err
+	}
+	return utils.EmailBlindIndex(email), nil
+}
+
+// userNameIndexes calcule les index aveugles des préfixes du prénom et du nom stockés.
+func userNameIndexes(storedFirstName, storedLastName string) ([]string, error) {
+	firstName, err := utils.DecryptAES(storedFirstName)
+	if err != nil {
+		return nil, err
+	}
+	lastName, err := utils.DecryptAES(storedLastName)
+	if err != nil {
+		return nil, err
+	}
+	return utils.NameBlindIndexes(firstName, lastName), nil
+}
+
+// UserBlindIndexSource regroupe les colonnes chiffrées dont dérivent les index aveugles d'un utilisateur.
+type UserBlindIndexSource struct {
+	ID        int64
+	FirstName string
+	LastName  string
+	Email     string
+}
+
+// ListUsersForBlindIndex retourne au plus limit utilisateurs d'identifiant supérieur à afterID,
+// limités à ceux dont l'index de l'e-mail manque si missingOnly est vrai.
+func ListUsersForBlindIndex(afterID int64, limit int, missingOnly bool) ([]UserBlindIndexSource, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, first_name, last_name, email
+		FROM users
+		WHERE id > $1 AND (NOT $3 OR email_bidx IS NULL)
+		ORDER BY id
+		LIMIT $2
+	`, afterID, limit, missingOnly)
+	if err != nil {
+		log.Printf("[ListUsersForBlindIndex] Erreur lecture des utilisateurs : %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []UserBlindIndexSource
+	for rows.Next() {
+		var u UserBlindIndexSource
+		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// RefreshUserBlindIndexes recalcule et enregistre les index aveugles d'un utilisateur.
+func RefreshUserBlindIndexes(u UserBlindIndexSource) error {
+	emailIndex, err := userEmailIndex(u.Email)
+	if err != nil {
+		return err
+	}
+	nameIndexes, err := userNameIndexes(u.FirstName, u.LastName)
+	if err != nil {
+		return err
+	}
+	_, err = database.DB.Exec(`
+		UPDATE users SET email_bidx = $2, name_bidx = $3 WHERE id = $1
+	`, u.ID, emailIndex, pq.Array(nameIndexes))
+	if err != nil {
+		log.Printf("[RefreshUserBlindIndexes] Erreur mise à jour des index de l'utilisateur %d : %v", u.ID, err)
+	}
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"onlyflick/internal/database"
 	"onlyflick/internal/domain"
+	"onlyflick/internal/utils"
 )
 
 // CreateComment insère un nouveau commentaire dans la base de données.
@@ -77,6 +78,8 @@ func GetCommentsByPostID(postID int64) ([]*domain.Comment, error) {
 			log.Printf("[CommentRepo][ERREUR] Problème lors du scan d'un commentaire : %v", err)
 			return nil, fmt.Errorf("échec scan commentaire : %w", err)
 		}
+		c.FirstName = utils.DecryptField(c.FirstName)
+		c.LastName = utils.DecryptField(c.LastName)
 		comments = append(comments, &c)
 	}
 
@@ -151,6 +154,8 @@ func GetCommentByID(commentID int64) (*domain.Comment, error) {
 		return nil, fmt.Errorf("échec récupération commentaire : %w", err)
 	}
 
+	comment.FirstName = utils.DecryptField(comment.FirstName)
+	comment.LastName = utils.DecryptField(comment.LastName)
 	log.Printf("[CommentRepo] Commentaire récupéré : %+v", comment)
 	return &comment, nil
 }
@@ -203,6 +208,8 @@ func GetCommentsByUserID(userID int64, limit, offset int) ([]*domain.Comment, er
 			log.Printf("[CommentRepo][ERREUR] Problème lors du scan d'un commentaire : %v", err)
 			return nil, fmt.Errorf("échec scan commentaire : %w", err)
 		}
+		c.FirstName = utils.DecryptField(c.FirstName)
+		c.LastName = utils.DecryptField(c.LastName)
 		comments = append(comments, &c)
 	}
 
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"onlyflick/internal/database"
@@ -61,4 +62,22 @@ func IsUserInConversation(conversationID, userID int64) (bool, error) {
 
 	log.Printf("[IsUserInConversation] L'utilisateur %d est dans la conversation %d : %t", userID, conversationID, count > 0)
 	return count > 0, nil
-}
\ No newline at end of file
+}
+// ErrConversationNotFound est retournée pour une conversation inexistante.
+var ErrConversationNotFound = errors.New("conversation introuvable")
+
+// GetConversationParticipants retourne le créateur et l'abonné d'une conversation.
+func GetConversationParticipants(conversationID int64) (int64, int64, error) {
+	var creatorID, subscriberID int64
+	err := database.DB.QueryRow(`
+		SELECT creator_id, subscriber_id FROM conversations WHERE id = $1
+	`, conversationID).Scan(&creatorID, &subscriberID)
+	if err == sql.ErrNoRows {
+		return 0, 0, ErrConversationNotFound
+	}
+	if err != nil {
+		log.Printf("[GetConversationParticipants][ERREUR] Lecture des participants de la conversation %d : %v", conversationID, err)
+		return 0, 0, fmt.Errorf("failed to select conversation participants: %w", err)
+	}
+	return creatorID, subscriberID, nil
+}
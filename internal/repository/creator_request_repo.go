@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/utils"
 )
 
 // CreatorRequest représente une demande de passage en créateur.
@@ -82,6 +84,7 @@ func GetAllPendingRequests() ([]CreatorRequest, error) {
 			log.Printf("[CreatorRequest][ERREUR] Erreur lors du scan d'une ligne : %v", err)
 			return nil, fmt.Errorf("échec du scan d'une demande: %w", err)
 		}
+		r.Email = utils.DecryptField(r.Email)
 		requests = append(requests, r)
 	}
 
@@ -90,8 +93,8 @@ func GetAllPendingRequests() ([]CreatorRequest, error) {
 }
 
 // ApproveCreatorRequest approuve une demande de passage en créateur et met à jour le rôle de l'utilisateur.
-// Effectue l'opération dans une transaction pour garantir la cohérence.
-func ApproveCreatorRequest(requestID int64) error {
+// Effectue l'opération, et sa journalisation d'audit, dans une transaction pour garantir la cohérence.
+func ApproveCreatorRequest(requestID int64, actor domain.AuditActor) error {
 	log.Printf("[CreatorRequest] Approbation de la demande ID: %d", requestID)
 
 	tx, err := database.DB.Begin()
@@ -101,9 +104,15 @@ func ApproveCreatorRequest(requestID int64) error {
 	}
 	defer tx.Rollback()
 
-	// Récupération de l'ID utilisateur lié à la demande
+	// Récupération de la demande et du rôle actuel de l'utilisateur
 	var userID int64
-	err = tx.QueryRow(`SELECT user_id FROM creator_requests WHERE id = $1`, requestID).Scan(&userID)
+	var status, role string
+	err = tx.QueryRow(`
+		SELECT cr.user_id, cr.status, u.role
+		FROM creator_requests cr
+		JOIN users u ON u.id = cr.user_id
+		WHERE cr.id = $1
+		FOR UPDATE OF cr`, requestID).Scan(&userID, &status, &role)
 	if err != nil {
 		log.Printf("[CreatorRequest][ERREUR] Impossible de récupérer la demande ID: %d : %v", requestID, err)
 		return fmt.Errorf("échec de la récupération de la demande: %w", err)
@@ -123,6 +132,12 @@ func ApproveCreatorRequest(requestID int64) error {
 		return fmt.Errorf("échec de la mise à jour du statut de la demande: %w", err)
 	}
 
+	if err := recordAudit(tx, &actor, domain.AuditCreatorRequestApprove, domain.AuditTargetCreatorRequest, requestID,
+		map[string]interface{}{"status": status, "user_id": userID, "role": role},
+		map[string]interface{}{"status": "approved", "user_id": userID, "role": "creator"}); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		log.Printf("[CreatorRequest][ERREUR] Commit de la transaction échoué : %v", err)
 		return fmt.Errorf("échec du commit de la transaction: %w", err)
@@ -133,10 +148,25 @@ func ApproveCreatorRequest(requestID int64) error {
 }
 
 // RejectCreatorRequest rejette une demande de passage en créateur.
-func RejectCreatorRequest(requestID int64) error {
+func RejectCreatorRequest(requestID int64, actor domain.AuditActor) error {
 	log.Printf("[CreatorRequest] Rejet de la demande ID: %d", requestID)
 
-	_, err := database.DB.Exec(`
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[CreatorRequest][ERREUR] Impossible de démarrer la transaction : %v", err)
+		return fmt.Errorf("échec de la création de la transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var status string
+	err = tx.QueryRow(`SELECT user_id, status FROM creator_requests WHERE id = $1 FOR UPDATE`, requestID).Scan(&userID, &status)
+	if err != nil {
+		log.Printf("[CreatorRequest][ERREUR] Impossible de récupérer la demande ID: %d : %v", requestID, err)
+		return fmt.Errorf("échec de la récupération de la demande: %w", err)
+	}
+
+	_, err = tx.Exec(`
 		UPDATE creator_requests
 		SET status = 'rejected', updated_at = NOW()
 		WHERE id = $1`, requestID)
@@ -145,6 +175,17 @@ func RejectCreatorRequest(requestID int64) error {
 		return fmt.Errorf("échec du rejet de la demande: %w", err)
 	}
 
+	if err := recordAudit(tx, &actor, domain.AuditCreatorRequestReject, domain.AuditTargetCreatorRequest, requestID,
+		map[string]interface{}{"status": status, "user_id": userID},
+		map[string]interface{}{"status": "rejected", "user_id": userID}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[CreatorRequest][ERREUR] Commit de la transaction échoué : %v", err)
+		return fmt.Errorf("échec du commit de la transaction: %w", err)
+	}
+
 	log.Printf("[CreatorRequest] Demande ID: %d rejetée", requestID)
 	return nil
 }
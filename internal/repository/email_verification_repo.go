@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"log"
+	"onlyflick/internal/database"
+	"time"
+)
+
+// IsEmailVerified indique si l'utilisateur a confirmé son adresse e-mail.
+func IsEmailVerified(userID int64) (bool, error) {
+	var verified bool
+	err := database.DB.QueryRow(`
+		SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1
+	`, userID).Scan(&verified)
+	if err != nil {
+		log.Printf("[IsEmailVerified] Erreur vérification de l'e-mail de l'utilisateur %d : %v", userID, err)
+		return false, err
+	}
+	return verified, nil
+}
+
+// MarkEmailVerified enregistre la confirmation de l'adresse e-mail. Retourne false si elle était déjà confirmée.
+func MarkEmailVerified(userID int64) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE users SET email_verified_at = NOW()
+		WHERE id = $1 AND email_verified_at IS NULL
+	`, userID)
+	if err != nil {
+		log.Printf("[MarkEmailVerified] Erreur confirmation de l'e-mail de l'utilisateur %d : %v", userID, err)
+		return false, err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows > 0 {
+		log.Printf("[MarkEmailVerified] Adresse e-mail de l'utilisateur %d confirmée", userID)
+	}
+	return rows > 0, nil
+}
+
+// ResetEmailVerification marque l'adresse e-mail comme non confirmée (changement d'adresse).
+func ResetEmailVerification(userID int64) error {
+	_, err := database.DB.Exec(`
+		UPDATE users SET email_verified_at = NULL, email_verification_sent_at = NULL WHERE id = $1
+	`, userID)
+	if err != nil {
+		log.Printf("[ResetEmailVerification] Erreur réinitialisation de la vérification de l'utilisateur %d : %v", userID, err)
+	}
+	return err
+}
+
+// ReserveVerificationEmail enregistre l'envoi d'un e-mail de vérification si le précédent date d'avant notBefore.
+// Retourne false si l'adresse est déjà confirmée ou si un e-mail vient d'être envoyé.
+func ReserveVerificationEmail(userID int64, notBefore time.Time) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE users SET email_verification_sent_at = NOW()
+		WHERE id = $1 AND email_verified_at IS NULL
+			AND (email_verification_sent_at IS NULL OR email_verification_sent_at < $2)
+	`, userID, notBefore)
+	if err != nil {
+		log.Printf("[ReserveVerificationEmail] Erreur enregistrement de l'envoi pour l'utilisateur %d : %v", userID, err)
+		return false, err
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
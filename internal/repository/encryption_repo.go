@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+)
+
+// EncryptedColumn désigne une colonne de données personnelles chiffrées par
+// l'application, dont les lignes sont identifiées par KeyColumn.
+type EncryptedColumn struct {
+	Table     string
+	KeyColumn string
+	Column    string
+}
+
+// EncryptedColumns liste les colonnes couvertes par le chiffrement des données personnelles.
+var EncryptedColumns = []EncryptedColumn{
+	{Table: "users", KeyColumn: "id", Column: "first_name"},
+	{Table: "users", KeyColumn: "id", Column: "last_name"},
+	{Table: "users", KeyColumn: "id", Column: "email"},
+	{Table: "user_mfa", KeyColumn: "user_id", Column: "secret"},
+}
+
+// EncryptedValue est la valeur stockée d'une colonne chiffrée pour une ligne.
+type EncryptedValue struct {
+	ID    int64
+	Value string
+}
+
+// ListEncryptedValues retourne au plus limit valeurs de la colonne, par identifiant croissant après afterID.
+func ListEncryptedValues(col EncryptedColumn, afterID int64, limit int) ([]EncryptedValue, error) {
+	rows, err := database.DB.Query(fmt.Sprintf(`
+		SELECT %[2]s, %[3]s FROM %[1]s
+		WHERE %[2]s > $1
+		ORDER BY %[2]s
+		LIMIT $2
+	`, col.Table, col.KeyColumn, col.Column), afterID, limit)
+	if err != nil {
+		log.Printf("[ListEncryptedValues] Erreur lecture de %s.%s : %v", col.Table, col.Column, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []EncryptedValue
+	for rows.Next() {
+		var v EncryptedValue
+		if err := rows.Scan(&v.ID, &v.Value); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// ReplaceEncryptedValue remplace la valeur d'une ligne si elle n'a pas changé depuis
+// sa lecture. Retourne false si la ligne a été modifiée ou supprimée entre-temps.
+func ReplaceEncryptedValue(col EncryptedColumn, id int64, oldValue, newValue string) (bool, error) {
+	res, err := database.DB.Exec(fmt.Sprintf(`
+		UPDATE %[1]s SET %[3]s = $1
+		WHERE %[2]s = $2 AND %[3]s = $3
+	`, col.Table, col.KeyColumn, col.Column), newValue, id, oldValue)
+	if err != nil {
+		log.Printf("[ReplaceEncryptedValue] Erreur mise à jour de %s.%s (%d) : %v", col.Table, col.Column, id, err)
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
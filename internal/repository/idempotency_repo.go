@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// ReserveIdempotencyKey réserve une clé d'idempotence pour l'utilisateur. Retourne true si la clé est libre
+// (nouvelle, expirée, ou restée sans réponse au-delà du bail IdempotencyInFlightLease) : la requête doit alors être traitée. Sinon l'enregistrement existant est retourné
+// pour comparer l'empreinte et rejouer la réponse.
+func ReserveIdempotencyKey(userID int64, key, fingerprint string, now time.Time) (*domain.IdempotencyRecord, bool, error) {
+	record := &domain.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(domain.IdempotencyKeyTTL),
+	}
+
+	// Une clé expirée, ou en cours depuis plus longtemps que le bail (requête interrompue), est réutilisable :
+	// elle est réinitialisée pour la nouvelle requête
+	err := database.DB.QueryRow(`
+		INSERT INTO idempotency_keys (user_id, idem_key, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, idem_key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status_code = 0, content_type = '', response_body = NULL,
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+			OR (idempotency_keys.status_code = 0 AND idempotency_keys.created_at <= $6)
+		RETURNING id
+	`, userID, key, fingerprint, now, record.ExpiresAt, now.Add(-domain.IdempotencyInFlightLease)).Scan(&record.ID)
+	if err == nil {
+		return record, true, nil
+	}
+	if err != sql.ErrNoRows {
+		log.Printf("[ReserveIdempotencyKey] Erreur réservation de la clé %q de l'utilisateur %d : %v", key, userID, err)
+		return nil, false, err
+	}
+
+	existing := &domain.IdempotencyRecord{UserID: userID, Key: key}
+	err = database.DB.QueryRow(`
+		SELECT id, fingerprint, status_code, content_type, response_body, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idem_key = $2
+	`, userID, key).Scan(&existing.ID, &existing.Fingerprint, &existing.StatusCode, &existing.ContentType,
+		&existing.ResponseBody, &existing.ExpiresAt)
+	if err != nil {
+		log.Printf("[ReserveIdempotencyKey] Erreur lecture de la clé %q de l'utilisateur %d : %v", key, userID, err)
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// SaveIdempotentResponse mémorise la réponse de la requête associée à la clé.
+func SaveIdempotentResponse(recordID int64, statusCode int, contentType string, body []byte) error {
+	_, err := database.DB.Exec(`
+		UPDATE idempotency_keys
+		SET status_code = $2, content_type = $3, response_body = $4
+		WHERE id = $1
+	`, recordID, statusCode, contentType, body)
+	if err != nil {
+		log.Printf("[SaveIdempotentResponse] Erreur enregistrement de la réponse de la clé %d : %v", recordID, err)
+	}
+	return err
+}
+
+// ReleaseIdempotencyKey libère une clé dont la requête a échoué côté serveur, pour qu'elle puisse être rejouée.
+func ReleaseIdempotencyKey(recordID int64) error {
+	_, err := database.DB.Exec(`DELETE FROM idempotency_keys WHERE id = $1`, recordID)
+	if err != nil {
+		log.Printf("[ReleaseIdempotencyKey] Erreur libération de la clé %d : %v", recordID, err)
+	}
+	return err
+}
+
+// PurgeExpiredIdempotencyKeys supprime les clés d'idempotence expirées et retourne leur nombre.
+func PurgeExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	result, err := database.DB.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		log.Printf("[PurgeExpiredIdempotencyKeys] Erreur purge des clés expirées : %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
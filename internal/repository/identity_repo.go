@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+var (
+	// ErrOIDCStateInvalid est retournée pour un state OpenID Connect inconnu, expiré, déjà utilisé ou d'un autre fournisseur.
+	ErrOIDCStateInvalid = errors.New("state OpenID Connect invalide")
+	// ErrIdentityAlreadyLinked est retournée quand le compte externe est déjà lié à un utilisateur
+	// ou que l'utilisateur a déjà un compte lié chez ce fournisseur.
+	ErrIdentityAlreadyLinked = errors.New("identité externe déjà liée")
+	// ErrIdentityNotFound est retournée pour une identité inconnue de l'utilisateur.
+	ErrIdentityNotFound = errors.New("identité externe introuvable")
+)
+
+// CreateOIDCAuthState enregistre le state (haché) d'une connexion OpenID Connect en cours.
+func CreateOIDCAuthState(stateHash string, state domain.OIDCAuthState, expiresAt time.Time) error {
+	var linkUserID sql.NullInt64
+	if state.LinkUserID > 0 {
+		linkUserID = sql.NullInt64{Int64: state.LinkUserID, Valid: true}
+	}
+	_, err := database.DB.Exec(`
+		INSERT INTO oidc_auth_states (state_hash, provider, code_verifier, nonce, link_user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`, stateHash, state.Provider, state.CodeVerifier, state.Nonce, linkUserID, expiresAt)
+	if err != nil {
+		log.Printf("[CreateOIDCAuthState] Erreur enregistrement du state (%s) : %v", state.Provider, err)
+	}
+	return err
+}
+
+// ConsumeOIDCAuthState supprime et retourne le state d'une connexion du fournisseur : il ne sert qu'une fois.
+func ConsumeOIDCAuthState(stateHash, provider string, now time.Time) (*domain.OIDCAuthState, error) {
+	state := domain.OIDCAuthState{Provider: provider}
+	var linkUserID sql.NullInt64
+	err := database.DB.QueryRow(`
+		DELETE FROM oidc_auth_states
+		WHERE state_hash = $1 AND provider = $2 AND expires_at > $3
+		RETURNING code_verifier, nonce, link_user_id
+	`, stateHash, provider, now).Scan(&state.CodeVerifier, &state.Nonce, &linkUserID)
+	if err == sql.ErrNoRows {
+		return nil, ErrOIDCStateInvalid
+	}
+	if err != nil {
+		log.Printf("[ConsumeOIDCAuthState] Erreur lecture du state (%s) : %v", provider, err)
+		return nil, err
+	}
+	state.LinkUserID = linkUserID.Int64
+	return &state, nil
+}
+
+// PurgeExpiredOIDCAuthStates supprime les connexions OpenID Connect abandonnées.
+func PurgeExpiredOIDCAuthStates(now time.Time) (int64, error) {
+	result, err := database.DB.Exec(`DELETE FROM oidc_auth_states WHERE expires_at < $1`, now)
+	if err != nil {
+		log.Printf("[PurgeExpiredOIDCAuthStates] Erreur purge des states : %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// TouchUserIdentity retourne l'utilisateur lié au compte externe (0 s'il n'y en a pas) et date la connexion.
+func TouchUserIdentity(provider, subject string, now time.Time) (int64, error) {
+	var userID int64
+	err := database.DB.QueryRow(`
+		UPDATE user_identities SET last_login_at = $3
+		WHERE provider = $1 AND subject = $2
+		RETURNING user_id
+	`, provider, subject, now).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		log.Printf("[TouchUserIdentity] Erreur lecture de l'identité (%s) : %v", provider, err)
+		return 0, err
+	}
+	return userID, nil
+}
+
+// CreateUserIdentity lie un compte externe à l'utilisateur.
+func CreateUserIdentity(userID int64, provider, subject string, now time.Time) error {
+	var id int64
+	err := database.DB.QueryRow(`
+		INSERT INTO user_identities (user_id, provider, subject, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	`, userID, provider, subject, now).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrIdentityAlreadyLinked
+	}
+	if err != nil {
+		log.Printf("[CreateUserIdentity] Erreur liaison de l'identité %s de l'utilisateur %d : %v", provider, userID, err)
+		return err
+	}
+
+	log.Printf("[CreateUserIdentity] Identité %s liée à l'utilisateur %d", provider, userID)
+	return nil
+}
+
+// ListUserIdentities retourne les comptes externes liés à l'utilisateur.
+func ListUserIdentities(userID int64) ([]domain.UserIdentity, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, provider, subject, created_at, last_login_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		log.Printf("[ListUserIdentities] Erreur récupération des identités de l'utilisateur %d : %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []domain.UserIdentity{}
+	for rows.Next() {
+		var identity domain.UserIdentity
+		var lastLoginAt sql.NullTime
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.CreatedAt, &lastLoginAt); err != nil {
+			log.Printf("[ListUserIdentities] Erreur scan d'une identité : %v", err)
+			return nil, err
+		}
+		if lastLoginAt.Valid {
+			identity.LastLoginAt = &lastLoginAt.Time
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// DeleteUserIdentity supprime la liaison d'un compte externe de l'utilisateur.
+func DeleteUserIdentity(identityID, userID int64) error {
+	result, err := database.DB.Exec(`DELETE FROM user_identities WHERE id = $1 AND user_id = $2`, identityID, userID)
+	if err != nil {
+		log.Printf("[DeleteUserIdentity] Erreur suppression de l'identité %d : %v", identityID, err)
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
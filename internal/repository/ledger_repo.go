@@ -0,0 +1,439 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"onlyflick/internal/config"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// ledgerLine est une écriture à passer au sein d'une opération du grand livre.
+type ledgerLine struct {
+	account   string
+	creatorID *int64
+	debit     int64
+	credit    int64
+}
+
+// Agrégats communs aux gains et relevés : la commission et le net sont diminués des remboursements
+const earningsAggregates = `
+	COALESCE(SUM(gross_amount) FILTER (WHERE kind = 'payment'), 0),
+	COALESCE(SUM(commission_amount) FILTER (WHERE kind = 'payment'), 0) - COALESCE(SUM(commission_amount) FILTER (WHERE kind = 'refund'), 0),
+	COALESCE(SUM(gross_amount) FILTER (WHERE kind = 'refund'), 0),
+	COALESCE(SUM(net_amount) FILTER (WHERE kind = 'payment'), 0) - COALESCE(SUM(net_amount) FILTER (WHERE kind = 'refund'), 0),
+	COALESCE(SUM(net_amount) FILTER (WHERE kind = 'payout'), 0),
+	COUNT(*) FILTER (WHERE kind = 'payment')`
+
+// ComputeCommission retourne la commission de la plateforme sur un montant brut (arrondie au centime).
+func ComputeCommission(gross int64, rate int) int64 {
+	return (gross*int64(rate) + 5000) / 10000
+}
+
+// PostPaymentToLedger enregistre un paiement réussi (abonnement ou achat ponctuel) dans le grand livre :
+// débit des fonds encaissés, crédit de la commission et du solde net du créateur.
+// Sans effet si le paiement a déjà été enregistré.
+func PostPaymentToLedger(tx *sql.Tx, paymentID int64) error {
+	var gross, creatorID int64
+	var currency string
+	err := tx.QueryRow(`
+		SELECT p.amount, COALESCE(p.creator_id, s.creator_id), COALESCE(cp.currency, $2)
+		FROM payments p
+		LEFT JOIN subscriptions s ON s.id = p.subscription_id
+		LEFT JOIN creator_pricing cp ON cp.creator_id = COALESCE(p.creator_id, s.creator_id)
+		WHERE p.id = $1
+	`, paymentID, domain.DefaultSubscriptionCurrency).Scan(&gross, &creatorID, &currency)
+	if err != nil {
+		return fmt.Errorf("lecture du paiement %d : %w", paymentID, err)
+	}
+
+	rate := config.PlatformCommissionRate()
+	commission := ComputeCommission(gross, rate)
+
+	t := &domain.LedgerTransaction{
+		Kind:             domain.LedgerKindPayment,
+		CreatorID:        creatorID,
+		PaymentID:        &paymentID,
+		GrossAmount:      gross,
+		CommissionAmount: commission,
+		NetAmount:        gross - commission,
+		CommissionRate:   rate,
+		Currency:         currency,
+	}
+	return postLedgerTransaction(tx, t, []ledgerLine{
+		{account: domain.LedgerAccountPaymentClearing, debit: gross},
+		{account: domain.LedgerAccountPlatformRevenue, credit: commission},
+		{account: domain.LedgerAccountCreatorPayable, creatorID: &creatorID, credit: gross - commission},
+	})
+}
+
+// PostRefundToLedger enregistre le remboursement d'un paiement : la commission et le net du créateur
+// sont repris au prorata du montant remboursé. Sans effet si le paiement n'est pas au grand livre.
+func PostRefundToLedger(tx *sql.Tx, paymentID, amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	var original domain.LedgerTransaction
+	err := tx.QueryRow(`
+		SELECT creator_id, gross_amount, commission_amount, commission_rate, currency
+		FROM ledger_transactions
+		WHERE payment_id = $1 AND kind = $2
+	`, paymentID, domain.LedgerKindPayment).Scan(&original.CreatorID, &original.GrossAmount, &original.CommissionAmount, &original.CommissionRate, &original.Currency)
+	if err == sql.ErrNoRows {
+		log.Printf("[PostRefundToLedger] Paiement %d absent du grand livre, remboursement non comptabilisé", paymentID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("lecture de l'opération du paiement %d : %w", paymentID, err)
+	}
+
+	commission := int64(0)
+	if original.GrossAmount > 0 {
+		commission = amount * original.CommissionAmount / original.GrossAmount
+	}
+
+	creatorID := original.CreatorID
+	t := &domain.LedgerTransaction{
+		Kind:             domain.LedgerKindRefund,
+		CreatorID:        creatorID,
+		PaymentID:        &paymentID,
+		GrossAmount:      amount,
+		CommissionAmount: commission,
+		NetAmount:        amount - commission,
+		CommissionRate:   original.CommissionRate,
+		Currency:         original.Currency,
+	}
+	return postLedgerTransaction(tx, t, []ledgerLine{
+		{account: domain.LedgerAccountPaymentClearing, credit: amount},
+		{account: domain.LedgerAccountPlatformRevenue, debit: commission},
+		{account: domain.LedgerAccountCreatorPayable, creatorID: &creatorID, debit: amount - commission},
+	})
+}
+
+// postLedgerTransaction insère une opération et ses écritures après avoir vérifié leur équilibre.
+// Les opérations d'un même créateur sont sérialisées pour calculer le solde après opération.
+func postLedgerTransaction(tx *sql.Tx, t *domain.LedgerTransaction, lines []ledgerLine) error {
+	var debits, credits, creatorDelta int64
+	for _, l := range lines {
+		debits += l.debit
+		credits += l.credit
+		if l.account == domain.LedgerAccountCreatorPayable {
+			creatorDelta += l.credit - l.debit
+		}
+	}
+	if debits != credits {
+		return fmt.Errorf("opération %s déséquilibrée (débit %d, crédit %d)", t.Kind, debits, credits)
+	}
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, t.CreatorID); err != nil {
+		return fmt.Errorf("verrou du grand livre du créateur %d : %w", t.CreatorID, err)
+	}
+
+	var balance int64
+	err := tx.QueryRow(`
+		SELECT COALESCE(SUM(credit - debit), 0) FROM ledger_entries
+		WHERE account = $1 AND creator_id = $2 AND currency = $3
+	`, domain.LedgerAccountCreatorPayable, t.CreatorID, t.Currency).Scan(&balance)
+	if err != nil {
+		return fmt.Errorf("solde du créateur %d : %w", t.CreatorID, err)
+	}
+	t.BalanceAfter = balance + creatorDelta
+
+	err = tx.QueryRow(`
+		INSERT INTO ledger_transactions (kind, creator_id, payment_id, gross_amount, commission_amount, net_amount,
+			commission_rate, currency, balance_after, payout_batch_id, paid_out_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+		ON CONFLICT (payment_id) WHERE kind = 'payment' DO NOTHING
+		RETURNING id, created_at
+	`, t.Kind, t.CreatorID, t.PaymentID, t.GrossAmount, t.CommissionAmount, t.NetAmount,
+		t.CommissionRate, t.Currency, t.BalanceAfter, t.PayoutBatchID, t.PaidOutAt).Scan(&t.ID, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		log.Printf("[postLedgerTransaction] Paiement %d déjà enregistré au grand livre", *t.PaymentID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("enregistrement de l'opération %s : %w", t.Kind, err)
+	}
+
+	for _, l := range lines {
+		if l.debit == 0 && l.credit == 0 {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO ledger_entries (transaction_id, account, creator_id, currency, debit, credit)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, t.ID, l.account, l.creatorID, t.Currency, l.debit, l.credit)
+		if err != nil {
+			return fmt.Errorf("écriture %s de l'opération %d : %w", l.account, t.ID, err)
+		}
+	}
+
+	log.Printf("[postLedgerTransaction] Opération %d (%s) : brut %d, commission %d, net %d %s pour le créateur %d",
+		t.ID, t.Kind, t.GrossAmount, t.CommissionAmount, t.NetAmount, t.Currency, t.CreatorID)
+	return nil
+}
+
+// GetCreatorEarnings retourne les totaux, le solde et les opérations d'un créateur sur [from, to[.
+// Une borne nil n'est pas appliquée.
+func GetCreatorEarnings(creatorID int64, from, to *time.Time, limit int) (*domain.CreatorEarnings, error) {
+	earnings := &domain.CreatorEarnings{From: from, To: to, Totals: []domain.EarningsTotals{}, Balances: []domain.CreatorBalance{}, Transactions: []domain.LedgerTransaction{}}
+
+	rows, err := database.DB.Query(`
+		SELECT currency, `+earningsAggregates+`
+		FROM ledger_transactions
+		WHERE creator_id = $1 AND ($2::timestamptz IS NULL OR created_at >= $2) AND ($3::timestamptz IS NULL OR created_at < $3)
+		GROUP BY currency
+		ORDER BY currency
+	`, creatorID, from, to)
+	if err != nil {
+		log.Printf("[GetCreatorEarnings] Erreur agrégation des gains du créateur %d : %v", creatorID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var totals domain.EarningsTotals
+		if err := rows.Scan(&totals.Currency, &totals.GrossAmount, &totals.CommissionAmount, &totals.RefundedAmount,
+			&totals.NetAmount, &totals.PaidOutAmount, &totals.PaymentsCount); err != nil {
+			log.Printf("[GetCreatorEarnings] Erreur scan des totaux : %v", err)
+			return nil, err
+		}
+		earnings.Totals = append(earnings.Totals, totals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	balanceRows, err := database.DB.Query(`
+		SELECT currency, COALESCE(SUM(credit - debit), 0)
+		FROM ledger_entries
+		WHERE account = $1 AND creator_id = $2
+		GROUP BY currency
+		ORDER BY currency
+	`, domain.LedgerAccountCreatorPayable, creatorID)
+	if err != nil {
+		log.Printf("[GetCreatorEarnings] Erreur calcul du solde du créateur %d : %v", creatorID, err)
+		return nil, err
+	}
+	defer balanceRows.Close()
+
+	for balanceRows.Next() {
+		var balance domain.CreatorBalance
+		if err := balanceRows.Scan(&balance.Currency, &balance.Amount); err != nil {
+			return nil, err
+		}
+		earnings.Balances = append(earnings.Balances, balance)
+	}
+	if err := balanceRows.Err(); err != nil {
+		return nil, err
+	}
+
+	txRows, err := database.DB.Query(`
+		SELECT id, kind, creator_id, payment_id, gross_amount, commission_amount, net_amount,
+			commission_rate, currency, balance_after, payout_batch_id, paid_out_at, created_at
+		FROM ledger_transactions
+		WHERE creator_id = $1 AND ($2::timestamptz IS NULL OR created_at >= $2) AND ($3::timestamptz IS NULL OR created_at < $3)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`, creatorID, from, to, limit)
+	if err != nil {
+		log.Printf("[GetCreatorEarnings] Erreur récupération des opérations du créateur %d : %v", creatorID, err)
+		return nil, err
+	}
+	defer txRows.Close()
+
+	for txRows.Next() {
+		var t domain.LedgerTransaction
+		if err := txRows.Scan(&t.ID, &t.Kind, &t.CreatorID, &t.PaymentID, &t.GrossAmount, &t.CommissionAmount, &t.NetAmount,
+			&t.CommissionRate, &t.Currency, &t.BalanceAfter, &t.PayoutBatchID, &t.PaidOutAt, &t.CreatedAt); err != nil {
+			log.Printf("[GetCreatorEarnings] Erreur scan d'une opération : %v", err)
+			return nil, err
+		}
+		earnings.Transactions = append(earnings.Transactions, t)
+	}
+
+	return earnings, txRows.Err()
+}
+
+// GetCreatorMonthlyStatements retourne les relevés mensuels d'un créateur sur [from, to[, du plus récent au plus ancien.
+func GetCreatorMonthlyStatements(creatorID int64, from, to time.Time) ([]domain.MonthlyStatement, error) {
+	rows, err := database.DB.Query(`
+		SELECT TO_CHAR(DATE_TRUNC('month', created_at), 'YYYY-MM') AS month, currency, `+earningsAggregates+`
+		FROM ledger_transactions
+		WHERE creator_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY month, currency
+		ORDER BY month DESC, currency
+	`, creatorID, from, to)
+	if err != nil {
+		log.Printf("[GetCreatorMonthlyStatements] Erreur récupération des relevés du créateur %d : %v", creatorID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	statements := []domain.MonthlyStatement{}
+	for rows.Next() {
+		var s domain.MonthlyStatement
+		if err := rows.Scan(&s.Month, &s.Currency, &s.GrossAmount, &s.CommissionAmount, &s.RefundedAmount,
+			&s.NetAmount, &s.PaidOutAmount, &s.PaymentsCount); err != nil {
+			log.Printf("[GetCreatorMonthlyStatements] Erreur scan d'un relevé : %v", err)
+			return nil, err
+		}
+		statements = append(statements, s)
+	}
+
+	return statements, rows.Err()
+}
+
+// CreatePayoutBatch crée un lot de versements pour toutes les opérations non versées antérieures à cutoff.
+// Les opérations incluses sont marquées comme versées et un versement est passé au grand livre
+// pour chaque créateur dont le solde du lot est positif. La création est journalisée au nom de actor.
+func CreatePayoutBatch(actor domain.AuditActor, cutoff time.Time) (*domain.PayoutBatch, error) {
+	adminID := actor.ActorID
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[CreatePayoutBatch] Erreur ouverture transaction : %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	batch := &domain.PayoutBatch{CreatedBy: adminID, Cutoff: cutoff}
+	err = tx.QueryRow(`
+		INSERT INTO payout_batches (created_by, cutoff, created_at)
+		VALUES ($1, $2, NOW())
+		RETURNING id, created_at
+	`, adminID, cutoff).Scan(&batch.ID, &batch.CreatedAt)
+	if err != nil {
+		log.Printf("[CreatePayoutBatch] Erreur création du lot : %v", err)
+		return nil, err
+	}
+
+	// Marquage des opérations des créateurs dont le net non versé est positif
+	rows, err := tx.Query(`
+		UPDATE ledger_transactions
+		SET payout_batch_id = $1, paid_out_at = NOW()
+		WHERE kind IN ('payment', 'refund') AND payout_batch_id IS NULL AND created_at < $2
+			AND (creator_id, currency) IN (
+				SELECT creator_id, currency FROM ledger_transactions
+				WHERE kind IN ('payment', 'refund') AND payout_batch_id IS NULL AND created_at < $2
+				GROUP BY creator_id, currency
+				HAVING SUM(CASE WHEN kind = 'payment' THEN net_amount ELSE -net_amount END) > 0
+			)
+		RETURNING creator_id, currency, kind, net_amount
+	`, batch.ID, cutoff)
+	if err != nil {
+		log.Printf("[CreatePayoutBatch] Erreur marquage des opérations : %v", err)
+		return nil, err
+	}
+
+	type payoutKey struct {
+		creatorID int64
+		currency  string
+	}
+	amounts := map[payoutKey]int64{}
+	var keys []payoutKey
+	for rows.Next() {
+		var key payoutKey
+		var kind string
+		var net int64
+		if err := rows.Scan(&key.creatorID, &key.currency, &kind, &net); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if _, seen := amounts[key]; !seen {
+			keys = append(keys, key)
+		}
+		if kind == domain.LedgerKindRefund {
+			net = -net
+		}
+		amounts[key] += net
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	paidOutAt := time.Now()
+	for _, key := range keys {
+		amount := amounts[key]
+		creatorID := key.creatorID
+		t := &domain.LedgerTransaction{
+			Kind:          domain.LedgerKindPayout,
+			CreatorID:     creatorID,
+			GrossAmount:   amount,
+			NetAmount:     amount,
+			Currency:      key.currency,
+			PayoutBatchID: &batch.ID,
+			PaidOutAt:     &paidOutAt,
+		}
+		err := postLedgerTransaction(tx, t, []ledgerLine{
+			{account: domain.LedgerAccountCreatorPayable, creatorID: &creatorID, debit: amount},
+			{account: domain.LedgerAccountPaymentClearing, credit: amount},
+		})
+		if err != nil {
+			log.Printf("[CreatePayoutBatch] Erreur versement au créateur %d : %v", creatorID, err)
+			return nil, err
+		}
+		batch.TotalAmount += amount
+	}
+	batch.CreatorCount = len(keys)
+
+	if _, err := tx.Exec(`
+		UPDATE payout_batches SET total_amount = $2, creator_count = $3 WHERE id = $1
+	`, batch.ID, batch.TotalAmount, batch.CreatorCount); err != nil {
+		log.Printf("[CreatePayoutBatch] Erreur mise à jour du lot %d : %v", batch.ID, err)
+		return nil, err
+	}
+
+	if err := recordAudit(tx, &actor, domain.AuditPayoutBatchCreate, domain.AuditTargetPayoutBatch, batch.ID, nil, batch); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[CreatePayoutBatch] Erreur commit du lot %d : %v", batch.ID, err)
+		return nil, err
+	}
+
+	log.Printf("[CreatePayoutBatch] Lot %d créé : %d créateur(s), %d centimes", batch.ID, batch.CreatorCount, batch.TotalAmount)
+	return batch, nil
+}
+
+// GetPayoutLines retourne les versements d'un lot avec le pseudo des créateurs.
+func GetPayoutLines(batchID int64) ([]domain.PayoutLine, error) {
+	rows, err := database.DB.Query(`
+		SELECT t.payout_batch_id, t.creator_id, COALESCE(u.username, ''), t.currency, t.net_amount,
+			(SELECT COUNT(*) FROM ledger_transactions i
+			 WHERE i.payout_batch_id = t.payout_batch_id AND i.creator_id = t.creator_id
+				AND i.currency = t.currency AND i.kind <> $2)
+		FROM ledger_transactions t
+		LEFT JOIN users u ON u.id = t.creator_id
+		WHERE t.payout_batch_id = $1 AND t.kind = $2
+		ORDER BY t.creator_id, t.currency
+	`, batchID, domain.LedgerKindPayout)
+	if err != nil {
+		log.Printf("[GetPayoutLines] Erreur récupération du lot %d : %v", batchID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []domain.PayoutLine{}
+	for rows.Next() {
+		var l domain.PayoutLine
+		if err := rows.Scan(&l.BatchID, &l.CreatorID, &l.Username, &l.Currency, &l.Amount, &l.TransactionsCount); err != nil {
+			log.Printf("[GetPayoutLines] Erreur scan d'un versement : %v", err)
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+
+	return lines, rows.Err()
+}
+
+// PayoutBatchExists indique si un lot de versements existe.
+func PayoutBatchExists(batchID int64) (bool, error) {
+	var exists bool
+	err := database.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM payout_batches WHERE id = $1)`, batchID).Scan(&exists)
+	return exists, err
+}
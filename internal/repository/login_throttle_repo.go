@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"database/sql"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"strconv"
+	"time"
+)
+
+// GetLoginThrottle retourne les échecs de connexion enregistrés pour un compte ou une IP, nil s'il n'y en a aucun.
+func GetLoginThrottle(scope, subject string) (*domain.LoginThrottle, error) {
+	throttle := domain.LoginThrottle{Scope: scope, Subject: subject}
+	var lockedUntil sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT failures, last_failure_at, locked_until FROM login_throttles WHERE scope = $1 AND subject = $2
+	`, scope, subject).Scan(&throttle.Failures, &throttle.LastFailureAt, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[GetLoginThrottle] Erreur lecture des échecs (%s %s) : %v", scope, subject, err)
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		throttle.LockedUntil = &lockedUntil.Time
+	}
+	return &throttle, nil
+}
+
+// RecordLoginFailure incrémente atomiquement le compteur d'échecs d'un compte ou d'une IP.
+// Le compteur repart de 1 si le dernier échec est antérieur à resetBefore ou si le verrouillage précédent est levé.
+func RecordLoginFailure(scope, subject string, now, resetBefore time.Time) (*domain.LoginThrottle, error) {
+	throttle := domain.LoginThrottle{Scope: scope, Subject: subject}
+	var lockedUntil sql.NullTime
+	err := database.DB.QueryRow(`
+		INSERT INTO login_throttles (scope, subject, failures, last_failure_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (scope, subject) DO UPDATE
+		SET failures = CASE
+				WHEN login_throttles.last_failure_at < $4 OR login_throttles.locked_until <= $3 THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			locked_until = CASE WHEN login_throttles.locked_until <= $3 THEN NULL ELSE login_throttles.locked_until END,
+			last_failure_at = $3
+		RETURNING failures, last_failure_at, locked_until
+	`, scope, subject, now, resetBefore).Scan(&throttle.Failures, &throttle.LastFailureAt, &lockedUntil)
+	if err != nil {
+		log.Printf("[RecordLoginFailure] Erreur enregistrement d'un échec (%s %s) : %v", scope, subject, err)
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		throttle.LockedUntil = &lockedUntil.Time
+	}
+	return &throttle, nil
+}
+
+// LockLoginSubject verrouille un compte ou une IP jusqu'à `until` et remet son compteur à zéro.
+// Retourne false si un verrouillage était déjà en cours (posé par une autre instance).
+func LockLoginSubject(scope, subject string, until, now time.Time) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE login_throttles SET locked_until = $3, failures = 0
+		WHERE scope = $1 AND subject = $2 AND (locked_until IS NULL OR locked_until <= $4)
+	`, scope, subject, until, now)
+	if err != nil {
+		log.Printf("[LockLoginSubject] Erreur verrouillage (%s %s) : %v", scope, subject, err)
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ClearLoginThrottle efface les échecs et le verrouillage d'un compte ou d'une IP.
+// Retourne false s'il n'y avait rien à effacer.
+func ClearLoginThrottle(scope, subject string) (bool, error) {
+	result, err := database.DB.Exec(`DELETE FROM login_throttles WHERE scope = $1 AND subject = $2`, scope, subject)
+	if err != nil {
+		log.Printf("[ClearLoginThrottle] Erreur remise à zéro (%s %s) : %v", scope, subject, err)
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// UnlockUserAccount efface les échecs et le verrouillage d'un compte à la demande d'un administrateur
+// et journalise l'état effacé dans la même transaction. Retourne false s'il n'y avait rien à effacer.
+func UnlockUserAccount(userID int64, actor domain.AuditActor) (bool, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var failures int
+	var lockedUntil sql.NullTime
+	err = tx.QueryRow(`
+		DELETE FROM login_throttles WHERE scope = $1 AND subject = $2
+		RETURNING failures, locked_until
+	`, domain.LoginThrottleAccount, strconv.FormatInt(userID, 10)).Scan(&failures, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("[UnlockUserAccount] Erreur déverrouillage du compte %d : %v", userID, err)
+		return false, err
+	}
+
+	before := map[string]interface{}{"failures": failures, "locked_until": nil}
+	if lockedUntil.Valid {
+		before["locked_until"] = lockedUntil.Time
+	}
+	if err := recordAudit(tx, &actor, domain.AuditUserUnlock, domain.AuditTargetUser, userID,
+		before, map[string]interface{}{"failures": 0, "locked_until": nil}); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// PurgeLoginThrottles supprime les compteurs sans échec depuis `before` et sans verrouillage en cours.
+func PurgeLoginThrottles(before, now time.Time) (int64, error) {
+	result, err := database.DB.Exec(`
+		DELETE FROM login_throttles
+		WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until <= $2)
+	`, before, now)
+	if err != nil {
+		log.Printf("[PurgeLoginThrottles] Erreur purge des compteurs d'échecs : %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
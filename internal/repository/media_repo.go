@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"onlyflick/internal/database"
+)
+
+// ErrMediaNotFound est retournée pour un fichier dont le propriétaire est inconnu.
+var ErrMediaNotFound = errors.New("média introuvable")
+
+// RecordMediaFile rattache un fichier envoyé à ImageKit à l'utilisateur qui l'a envoyé.
+func RecordMediaFile(fileID string, userID int64, url string) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO media_files (file_id, user_id, url, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (file_id) DO NOTHING
+	`, fileID, userID, url)
+	if err != nil {
+		log.Printf("[RecordMediaFile] Erreur enregistrement du média %s : %v", fileID, err)
+	}
+	return err
+}
+
+// GetMediaOwner retourne l'utilisateur propriétaire d'un fichier. Les fichiers envoyés avant
+// le suivi des médias sont retrouvés par le post qui les utilise.
+func GetMediaOwner(fileID string) (int64, error) {
+	var ownerID int64
+	err := database.DB.QueryRow(`
+		SELECT user_id FROM media_files WHERE file_id = $1
+		UNION ALL
+		SELECT user_id FROM posts WHERE file_id = $1
+		LIMIT 1
+	`, fileID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return 0, ErrMediaNotFound
+	}
+	if err != nil {
+		log.Printf("[GetMediaOwner] Erreur lecture du propriétaire du média %s : %v", fileID, err)
+		return 0, err
+	}
+	return ownerID, nil
+}
+
+// DeleteMediaFile oublie un fichier supprimé d'ImageKit.
+func DeleteMediaFile(fileID string) error {
+	_, err := database.DB.Exec(`DELETE FROM media_files WHERE file_id = $1`, fileID)
+	if err != nil {
+		log.Printf("[DeleteMediaFile] Erreur suppression du média %s : %v", fileID, err)
+	}
+	return err
+}
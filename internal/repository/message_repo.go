@@ -1,18 +1,37 @@
 package repository
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
 	"onlyflick/internal/database"
 	"onlyflick/internal/domain"
+	"onlyflick/internal/utils"
 )
 
+// messageColumns liste les colonnes lues pour un message (alias m), avec le montant du pourboire associé (alias t).
+const messageColumns = `m.id, m.conversation_id, m.sender_id, m.content, m.kind, m.tip_id, COALESCE(t.amount, 0), m.created_at`
+
+// scanMessage lit une ligne sélectionnée avec messageColumns.
+func scanMessage(row interface{ Scan(...interface{}) error }) (*domain.Message, error) {
+	var m domain.Message
+	var tipID sql.NullInt64
+	if err := row.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Content, &m.Kind, &tipID, &m.TipAmount, &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	if tipID.Valid {
+		m.TipID = &tipID.Int64
+	}
+	return &m, nil
+}
+
 // CreateMessage insère un nouveau message dans la base de données et retourne le message créé.
 func CreateMessage(conversationID, senderID int64, content string) (*domain.Message, error) {
 	msg := &domain.Message{
 		ConversationID: conversationID,
 		SenderID:       senderID,
 		Content:        content,
+		Kind:           domain.MessageKindText,
 	}
 
 	err := database.DB.QueryRow(`
@@ -35,10 +54,11 @@ func GetMessages(conversationID int64, limit, offset int) ([]domain.Message, err
 	log.Printf("[GetMessages] Récupération des messages pour la conversation %d avec limite %d et offset %d", conversationID, limit, offset)
 
 	rows, err := database.DB.Query(`
-		SELECT id, conversation_id, sender_id, content, created_at
-		FROM messages
-		WHERE conversation_id = $1
-		ORDER BY created_at ASC
+		SELECT `+messageColumns+`
+		FROM messages m
+		LEFT JOIN tips t ON t.id = m.tip_id
+		WHERE m.conversation_id = $1
+		ORDER BY m.created_at ASC
 		LIMIT $2 OFFSET $3
 	`, conversationID, limit, offset)
 	if err != nil {
@@ -49,12 +69,12 @@ func GetMessages(conversationID int64, limit, offset int) ([]domain.Message, err
 
 	var messages []domain.Message
 	for rows.Next() {
-		var m domain.Message
-		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Content, &m.CreatedAt); err != nil {
+		m, err := scanMessage(rows)
+		if err != nil {
 			log.Printf("[GetMessages][ERREUR] Échec de la lecture d'un message : %v", err)
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
-		messages = append(messages, m)
+		messages = append(messages, *m)
 	}
 
 	log.Printf("[GetMessages] %d messages récupérés pour la conversation %d", len(messages), conversationID)
@@ -217,6 +237,13 @@ func GetConversationsForUser(userID int64) ([]ConversationWithDetails, error) {
 			continue
 		}
 
+		// Noms et prénoms chiffrés en base
+		for _, name := range []*string{conv.OtherUserFirstName, conv.OtherUserLastName, lastMsgSenderFirstName, lastMsgSenderLastName} {
+			if name != nil {
+				*name = utils.DecryptField(*name)
+			}
+		}
+
 		// Construire le dernier message s'il existe
 		if lastMsgID != nil && lastMsgSenderID != nil && lastMsgContent != nil && lastMsgCreatedAt != nil {
 			lastMsg = &MessageWithSender{
@@ -272,10 +299,11 @@ func GetMessagesForConversation(conversationID, userID int64) ([]domain.Message,
 	}
 
 	rows, err := database.DB.Query(`
-		SELECT id, conversation_id, sender_id, content, created_at
-		FROM messages
-		WHERE conversation_id = $1
-		ORDER BY created_at ASC
+		SELECT `+messageColumns+`
+		FROM messages m
+		LEFT JOIN tips t ON t.id = m.tip_id
+		WHERE m.conversation_id = $1
+		ORDER BY m.created_at ASC
 	`, conversationID)
 	if err != nil {
 		log.Printf("[GetMessagesForConversation][ERREUR] Échec de la requête pour récupérer les messages : %v", err)
@@ -285,12 +313,12 @@ func GetMessagesForConversation(conversationID, userID int64) ([]domain.Message,
 
 	var messages []domain.Message
 	for rows.Next() {
-		var m domain.Message
-		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Content, &m.CreatedAt); err != nil {
+		m, err := scanMessage(rows)
+		if err != nil {
 			log.Printf("[GetMessagesForConversation][ERREUR] Échec de la lecture d'un message : %v", err)
 			return nil, fmt.Errorf("[GetMessagesForConversation] Scan échoué : %w", err)
 		}
-		messages = append(messages, m)
+		messages = append(messages, *m)
 	}
 
 	log.Printf("[GetMessagesForConversation] %d messages récupérés pour la conversation %d", len(messages), conversationID)
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// ErrMFAChallengeInvalid est retournée pour un défi de connexion inconnu, expiré, déjà utilisé ou épuisé.
+var ErrMFAChallengeInvalid = errors.New("défi de double authentification invalide")
+
+// GetMFAState retourne l'état de la double authentification de l'utilisateur, nil s'il n'a jamais commencé d'enrôlement.
+func GetMFAState(userID int64) (*domain.MFAState, error) {
+	state := domain.MFAState{UserID: userID}
+	var enabledAt sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT secret, enabled_at, last_used_step FROM user_mfa WHERE user_id = $1
+	`, userID).Scan(&state.Secret, &enabledAt, &state.LastUsedStep)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[GetMFAState] Erreur lecture de la double authentification de l'utilisateur %d : %v", userID, err)
+		return nil, err
+	}
+	if enabledAt.Valid {
+		state.EnabledAt = &enabledAt.Time
+	}
+	return &state, nil
+}
+
+// IsMFAEnabled indique si l'utilisateur a activé la double authentification.
+func IsMFAEnabled(userID int64) (bool, error) {
+	var enabled bool
+	err := database.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM user_mfa WHERE user_id = $1 AND enabled_at IS NOT NULL)
+	`, userID).Scan(&enabled)
+	if err != nil {
+		log.Printf("[IsMFAEnabled] Erreur vérification de la double authentification de l'utilisateur %d : %v", userID, err)
+		return false, err
+	}
+	return enabled, nil
+}
+
+// SaveMFAPendingSecret enregistre (ou remplace) le secret chiffré d'un enrôlement non confirmé.
+// Retourne false si la double authentification est déjà active.
+func SaveMFAPendingSecret(userID int64, encryptedSecret string) (bool, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO user_mfa (user_id, secret, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL
+	`, userID, encryptedSecret)
+	if err != nil {
+		log.Printf("[SaveMFAPendingSecret] Erreur enregistrement du secret de l'utilisateur %d : %v", userID, err)
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// EnableMFA active la double authentification avec le pas TOTP du code de confirmation
+// et remplace les codes de secours, dans une même transaction.
+func EnableMFA(userID, step int64, recoveryCodeHashes []string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[EnableMFA] Erreur ouverture transaction : %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE user_mfa SET enabled_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`, userID, step); err != nil {
+		return fmt.Errorf("activation de la double authentification de l'utilisateur %d : %w", userID, err)
+	}
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("[EnableMFA] Double authentification activée pour l'utilisateur %d", userID)
+	return nil
+}
+
+// ReplaceRecoveryCodes remplace tous les codes de secours de l'utilisateur.
+func ReplaceRecoveryCodes(userID int64, recoveryCodeHashes []string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[ReplaceRecoveryCodes] Erreur ouverture transaction : %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkTOTPStepUsed enregistre le pas TOTP accepté. Retourne false si un code de ce pas
+// (ou d'un pas ultérieur) a déjà été utilisé : un code intercepté ne peut pas être rejoué.
+func MarkTOTPStepUsed(userID, step int64) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		log.Printf("[MarkTOTPStepUsed] Erreur enregistrement du pas TOTP de l'utilisateur %d : %v", userID, err)
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ConsumeRecoveryCode invalide un code de secours. Retourne false s'il est inconnu ou déjà utilisé.
+func ConsumeRecoveryCode(userID int64, codeHash string) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		log.Printf("[ConsumeRecoveryCode] Erreur utilisation d'un code de secours de l'utilisateur %d : %v", userID, err)
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	if rows > 0 {
+		log.Printf("[ConsumeRecoveryCode] Code de secours utilisé par l'utilisateur %d", userID)
+	}
+	return rows > 0, nil
+}
+
+// DisableMFA supprime la double authentification de l'utilisateur et ses codes de secours.
+// Une réinitialisation par un administrateur (actor non nil) est journalisée dans la même transaction.
+func DisableMFA(userID int64, actor *domain.AuditActor) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[DisableMFA] Erreur ouverture transaction : %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("suppression des codes de secours de l'utilisateur %d : %w", userID, err)
+	}
+	result, err := tx.Exec(`DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("suppression de la double authentification de l'utilisateur %d : %w", userID, err)
+	}
+	enrolled, _ := result.RowsAffected()
+
+	if err := recordAudit(tx, actor, domain.AuditUserMFAReset, domain.AuditTargetUser, userID,
+		map[string]interface{}{"mfa_enrolled": enrolled > 0},
+		map[string]interface{}{"mfa_enrolled": false}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("[DisableMFA] Double authentification désactivée pour l'utilisateur %d", userID)
+	return nil
+}
+
+// CreateMFAChallenge enregistre un défi de connexion (haché) en attente du second facteur.
+func CreateMFAChallenge(userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO mfa_challenges (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`, userID, tokenHash, expiresAt)
+	if err != nil {
+		log.Printf("[CreateMFAChallenge] Erreur création du défi de l'utilisateur %d : %v", userID, err)
+	}
+	return err
+}
+
+// StartMFAChallengeAttempt compte une tentative sur un défi encore utilisable et retourne son utilisateur.
+// Au-delà de maxAttempts tentatives, le défi est refusé et la connexion doit être recommencée.
+func StartMFAChallengeAttempt(tokenHash string, now time.Time, maxAttempts int) (int64, error) {
+	var userID int64
+	err := database.DB.QueryRow(`
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2 AND attempts < $3
+		RETURNING user_id
+	`, tokenHash, now, maxAttempts).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		log.Printf("[StartMFAChallengeAttempt] Erreur lecture du défi : %v", err)
+		return 0, err
+	}
+	return userID, nil
+}
+
+// CompleteMFAChallenge marque le défi comme utilisé : il ne peut ouvrir qu'une seule session.
+func CompleteMFAChallenge(tokenHash string, now time.Time) error {
+	result, err := database.DB.Exec(`
+		UPDATE mfa_challenges SET used_at = $2 WHERE token_hash = $1 AND used_at IS NULL
+	`, tokenHash, now)
+	if err != nil {
+		log.Printf("[CompleteMFAChallenge] Erreur clôture du défi : %v", err)
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrMFAChallengeInvalid
+	}
+	return nil
+}
+
+// PurgeExpiredMFAChallenges supprime les défis expirés avant `before`.
+func PurgeExpiredMFAChallenges(before time.Time) (int64, error) {
+	result, err := database.DB.Exec(`DELETE FROM mfa_challenges WHERE expires_at < $1`, before)
+	if err != nil {
+		log.Printf("[PurgeExpiredMFAChallenges] Erreur purge des défis : %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// replaceRecoveryCodes remplace les codes de secours de l'utilisateur dans la transaction en cours.
+func replaceRecoveryCodes(tx *sql.Tx, userID int64, recoveryCodeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("suppression des codes de secours de l'utilisateur %d : %w", userID, err)
+	}
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err := tx.Exec(`
+			INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, NOW())
+		`, userID, codeHash); err != nil {
+			return fmt.Errorf("enregistrement d'un code de secours de l'utilisateur %d : %w", userID, err)
+		}
+	}
+	return nil
+}
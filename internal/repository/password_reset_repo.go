@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// ErrPasswordResetTokenInvalid est retournée pour un jeton de réinitialisation inconnu, expiré ou déjà utilisé.
+var ErrPasswordResetTokenInvalid = errors.New("jeton de réinitialisation invalide")
+
+// Types de tentatives journalisées pour la limitation de la réinitialisation des mots de passe.
+const (
+	PasswordResetAttemptForgot = "forgot" // Demande d'e-mail de réinitialisation
+	PasswordResetAttemptReset  = "reset"  // Soumission d'un nouveau mot de passe
+)
+
+// CreatePasswordResetToken enregistre un jeton de réinitialisation (haché) pour l'utilisateur.
+// Les jetons encore valides émis auparavant sont invalidés : seul le dernier lien reçu fonctionne.
+func CreatePasswordResetToken(userID int64, tokenHash string, expiresAt time.Time) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[CreatePasswordResetToken] Erreur ouverture transaction : %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return fmt.Errorf("invalidation des jetons de l'utilisateur %d : %w", userID, err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`, userID, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("enregistrement du jeton de l'utilisateur %d : %w", userID, err)
+	}
+
+	return tx.Commit()
+}
+
+// GetPasswordResetTokenUser retourne l'utilisateur d'un jeton de réinitialisation encore utilisable.
+func GetPasswordResetTokenUser(tokenHash string, now time.Time) (int64, error) {
+	var userID int64
+	err := database.DB.QueryRow(`
+		SELECT user_id FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+	`, tokenHash, now).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrPasswordResetTokenInvalid
+	}
+	if err != nil {
+		log.Printf("[GetPasswordResetTokenUser] Erreur lecture du jeton : %v", err)
+		return 0, err
+	}
+	return userID, nil
+}
+
+// ResetPasswordWithToken consomme le jeton, remplace le mot de passe (déjà haché) et révoque
+// toutes les sessions de l'utilisateur, le tout dans une même transaction. Retourne l'utilisateur.
+func ResetPasswordWithToken(tokenHash, passwordHash string, now time.Time) (int64, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[ResetPasswordWithToken] Erreur ouverture transaction : %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var tokenID, userID int64
+	err = tx.QueryRow(`
+		SELECT id, user_id FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		FOR UPDATE
+	`, tokenHash, now).Scan(&tokenID, &userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrPasswordResetTokenInvalid
+	}
+	if err != nil {
+		return 0, fmt.Errorf("lecture du jeton de réinitialisation : %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE password_reset_tokens SET used_at = $2 WHERE user_id = $1 AND used_at IS NULL`, userID, now); err != nil {
+		return 0, fmt.Errorf("consommation du jeton %d : %w", tokenID, err)
+	}
+	if _, err := tx.Exec(`UPDATE users SET password = $2, updated_at = $3 WHERE id = $1`, userID, passwordHash, now); err != nil {
+		return 0, fmt.Errorf("mise à jour du mot de passe de l'utilisateur %d : %w", userID, err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE auth_sessions
+		SET revoked_at = $2, revoked_reason = $3
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID, now, domain.SessionRevokedReset); err != nil {
+		return 0, fmt.Errorf("révocation des sessions de l'utilisateur %d : %w", userID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("[ResetPasswordWithToken] Mot de passe de l'utilisateur %d réinitialisé, sessions révoquées", userID)
+	return userID, nil
+}
+
+// RecordPasswordResetAttempt journalise une tentative (demande ou soumission) pour la limitation de débit.
+func RecordPasswordResetAttempt(kind, emailHash, ipAddress string, now time.Time) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO password_reset_attempts (kind, email_hash, ip_address, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, kind, emailHash, ipAddress, now)
+	if err != nil {
+		log.Printf("[RecordPasswordResetAttempt] Erreur journalisation d'une tentative %s : %v", kind, err)
+	}
+	return err
+}
+
+// CountPasswordResetAttempts compte les tentatives du type donné depuis `since`, pour l'empreinte d'e-mail
+// et pour l'adresse IP. Une empreinte ou une IP vide n'est pas comptée.
+func CountPasswordResetAttempts(kind, emailHash, ipAddress string, since time.Time) (int, int, error) {
+	var byEmail, byIP int
+	err := database.DB.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE $2 <> '' AND email_hash = $2),
+			COUNT(*) FILTER (WHERE $3 <> '' AND ip_address = $3)
+		FROM password_reset_attempts
+		WHERE kind = $1 AND created_at > $4
+	`, kind, emailHash, ipAddress, since).Scan(&byEmail, &byIP)
+	if err != nil {
+		log.Printf("[CountPasswordResetAttempts] Erreur comptage des tentatives %s : %v", kind, err)
+		return 0, 0, err
+	}
+	return byEmail, byIP, nil
+}
+
+// PurgePasswordResetData supprime les jetons expirés ou utilisés et les tentatives antérieures à `before`.
+func PurgePasswordResetData(before time.Time) (int64, error) {
+	tokens, err := database.DB.Exec(`DELETE FROM password_reset_tokens WHERE expires_at < $1 OR used_at < $1`, before)
+	if err != nil {
+		log.Printf("[PurgePasswordResetData] Erreur purge des jetons : %v", err)
+		return 0, err
+	}
+	attempts, err := database.DB.Exec(`DELETE FROM password_reset_attempts WHERE created_at < $1`, before)
+	if err != nil {
+		log.Printf("[PurgePasswordResetData] Erreur purge des tentatives : %v", err)
+		return 0, err
+	}
+
+	purgedTokens, _ := tokens.RowsAffected()
+	purgedAttempts, _ := attempts.RowsAffected()
+	return purgedTokens + purgedAttempts, nil
+}
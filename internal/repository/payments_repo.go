@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"onlyflick/internal/database"
@@ -8,10 +10,26 @@ import (
 	"time"
 )
 
+var (
+	// ErrPaymentNotFound est retournée quand le paiement n'existe pas (ou n'est pas un paiement d'origine).
+	ErrPaymentNotFound = errors.New("paiement introuvable")
+	// ErrRefundExceedsAmount est retournée quand le remboursement dépasse le montant restant à rembourser.
+	ErrRefundExceedsAmount = errors.New("montant du remboursement invalide")
+)
+
 // CreatePayment enregistre un paiement effectué pour un abonnement.
+// Un paiement déjà réussi est enregistré au grand livre des revenus dans la même transaction.
 func CreatePayment(subscriptionID int64, stripePaymentID, payerID string, startAt, endAt time.Time, amount int, status string) (*domain.Payment, error) {
+	return CreatePaymentWithRedemption(subscriptionID, stripePaymentID, payerID, startAt, endAt, amount, status, nil)
+}
+
+// CreatePaymentWithRedemption enregistre un paiement d'abonnement et, si redemption est non nul,
+// l'utilisation du code promo qui le réduit dans la même transaction : rien n'est consommé si l'enregistrement
+// échoue, et l'utilisation est libérée si le paiement échoue ensuite (MarkPaymentFailed).
+func CreatePaymentWithRedemption(subscriptionID int64, stripePaymentID, payerID string, startAt, endAt time.Time, amount int, status string, redemption *PromoRedemption) (*domain.Payment, error) {
 	payment := &domain.Payment{
-		SubscriptionID:  subscriptionID,
+		Kind:            domain.PaymentKindSubscription,
+		SubscriptionID:  &subscriptionID,
 		StripePaymentID: stripePaymentID,
 		PayerID:         payerID,
 		StartAt:         startAt,
@@ -20,8 +38,15 @@ func CreatePayment(subscriptionID int64, stripePaymentID, payerID string, startA
 		Status:          status,
 	}
 
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[CreatePayment] Erreur ouverture transaction : %v", err)
+		return nil, fmt.Errorf("[CreatePayment] Erreur d'enregistrement du paiement : %w", err)
+	}
+	defer tx.Rollback()
+
 	// Insertion du paiement dans la base de données
-	err := database.DB.QueryRow(`
+	err = tx.QueryRow(`
 		INSERT INTO payments (subscription_id, stripe_payment_id, payer_id, start_at, end_at, amount, status, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
 		RETURNING id, created_at
@@ -31,6 +56,24 @@ func CreatePayment(subscriptionID int64, stripePaymentID, payerID string, startA
 		return nil, fmt.Errorf("[CreatePayment] Erreur d'enregistrement du paiement : %w", err)
 	}
 
+	if redemption != nil {
+		if err := redeemPromoCode(tx, *redemption, &payment.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if status == domain.PaymentStatusSucceeded {
+		if err := PostPaymentToLedger(tx, payment.ID); err != nil {
+			log.Printf("[CreatePayment] Erreur d'enregistrement au grand livre : %v", err)
+			return nil, fmt.Errorf("[CreatePayment] Erreur d'enregistrement au grand livre : %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[CreatePayment] Erreur commit du paiement : %v", err)
+		return nil, fmt.Errorf("[CreatePayment] Erreur d'enregistrement du paiement : %w", err)
+	}
+
 	log.Printf("[CreatePayment] Paiement enregistré avec succès pour l'abonnement %d, montant %d", subscriptionID, amount)
 	return payment, nil
 }
@@ -61,3 +104,216 @@ func GetPaymentsBySubscriptionID(subscriptionID int64) ([]domain.Payment, error)
 
 	return payments, nil
 }
+
+// GetPaymentByID récupère un paiement par son identifiant.
+func GetPaymentByID(paymentID int64) (*domain.Payment, error) {
+	var p domain.Payment
+	var reason sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT id, kind, subscription_id, creator_id, stripe_payment_id, payer_id, start_at, end_at, amount, status, refund_of, refund_reason, created_at
+		FROM payments
+		WHERE id = $1
+	`, paymentID).Scan(&p.ID, &p.Kind, &p.SubscriptionID, &p.CreatorID, &p.StripePaymentID, &p.PayerID, &p.StartAt, &p.EndAt, &p.Amount, &p.Status, &p.RefundOf, &reason, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		log.Printf("[GetPaymentByID] Erreur récupération du paiement %d : %v", paymentID, err)
+		return nil, err
+	}
+	p.RefundReason = reason.String
+
+	return &p, nil
+}
+
+// GetRefundedAmount retourne le montant total déjà remboursé pour un paiement.
+func GetRefundedAmount(paymentID int64) (int64, error) {
+	var refunded int64
+	err := database.DB.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM payments WHERE refund_of = $1
+	`, paymentID).Scan(&refunded)
+	if err != nil {
+		log.Printf("[GetRefundedAmount] Erreur calcul des remboursements du paiement %d : %v", paymentID, err)
+	}
+	return refunded, err
+}
+
+// ListRefundablePayments retourne les paiements réussis d'un abonnement couvrant une période postérieure à now.
+func ListRefundablePayments(subscriptionID int64, now time.Time) ([]domain.Payment, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, subscription_id, stripe_payment_id, payer_id, start_at, end_at, amount, status, created_at
+		FROM payments
+		WHERE subscription_id = $1 AND refund_of IS NULL AND status IN ($2, $3) AND end_at > $4
+		ORDER BY start_at ASC
+	`, subscriptionID, domain.PaymentStatusSucceeded, domain.PaymentStatusPartiallyRefunded, now)
+	if err != nil {
+		log.Printf("[ListRefundablePayments] Erreur récupération des paiements de l'abonnement %d : %v", subscriptionID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []domain.Payment
+	for rows.Next() {
+		var p domain.Payment
+		if err := rows.Scan(&p.ID, &p.SubscriptionID, &p.StripePaymentID, &p.PayerID, &p.StartAt, &p.EndAt, &p.Amount, &p.Status, &p.CreatedAt); err != nil {
+			log.Printf("[ListRefundablePayments] Erreur scan d'un paiement : %v", err)
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
+
+// RecordRefund enregistre un remboursement émis chez le fournisseur de paiement : ligne de remboursement
+// liée au paiement d'origine, statut du paiement et écriture au grand livre, dans une même transaction.
+// Retourne nil si ce remboursement (providerRefundID) a déjà été enregistré, par exemple par le webhook.
+// Un remboursement décidé par un administrateur (actor non nil) est journalisé dans la transaction.
+func RecordRefund(paymentID int64, providerRefundID string, amount int64, reason string, actor *domain.AuditActor) (*domain.Payment, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[RecordRefund] Erreur ouverture transaction : %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var before interface{}
+	if actor != nil {
+		if before, err = paymentRefundState(tx, paymentID); err != nil {
+			return nil, err
+		}
+	}
+
+	refund, err := applyRefund(tx, paymentID, providerRefundID, amount, reason)
+	if err != nil {
+		log.Printf("[RecordRefund] Erreur remboursement du paiement %d : %v", paymentID, err)
+		return nil, err
+	}
+
+	if actor != nil {
+		after, err := paymentRefundState(tx, paymentID)
+		if err != nil {
+			return nil, err
+		}
+		after["provider_refund_id"] = providerRefundID
+		after["amount"] = amount
+		after["reason"] = reason
+		if err := recordAudit(tx, actor, domain.AuditPaymentRefund, domain.AuditTargetPayment, paymentID, before, after); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[RecordRefund] Erreur commit du remboursement du paiement %d : %v", paymentID, err)
+		return nil, err
+	}
+	return refund, nil
+}
+
+// paymentRefundState retourne le statut et le montant remboursé d'un paiement pour le journal d'audit.
+func paymentRefundState(tx *sql.Tx, paymentID int64) (map[string]interface{}, error) {
+	var status string
+	if err := tx.QueryRow(`SELECT status FROM payments WHERE id = $1`, paymentID).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, fmt.Errorf("lecture du paiement %d : %w", paymentID, err)
+	}
+	refunded, err := refundedAmount(tx, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("montant déjà remboursé du paiement %d : %w", paymentID, err)
+	}
+	return map[string]interface{}{"status": status, "refunded_amount": refunded}, nil
+}
+
+// applyRefund insère la ligne de remboursement, met à jour le statut du paiement d'origine et le grand livre.
+// Un remboursement total retire la période payée si elle est la dernière de l'abonnement
+// (ou l'accès au post pour un achat ponctuel, qui n'est plus considéré comme réglé).
+func applyRefund(tx *sql.Tx, paymentID int64, providerRefundID string, amount int64, reason string) (*domain.Payment, error) {
+	var original domain.Payment
+	err := tx.QueryRow(`
+		SELECT id, subscription_id, payer_id, start_at, end_at, amount, kind, creator_id
+		FROM payments
+		WHERE id = $1 AND refund_of IS NULL
+		FOR UPDATE
+	`, paymentID).Scan(&original.ID, &original.SubscriptionID, &original.PayerID, &original.StartAt, &original.EndAt, &original.Amount,
+		&original.Kind, &original.CreatorID)
+	if err == sql.ErrNoRows {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lecture du paiement %d : %w", paymentID, err)
+	}
+
+	refunded, err := refundedAmount(tx, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("montant déjà remboursé du paiement %d : %w", paymentID, err)
+	}
+	if amount <= 0 || refunded+amount > int64(original.Amount) {
+		return nil, ErrRefundExceedsAmount
+	}
+
+	refund := &domain.Payment{
+		Kind:            original.Kind,
+		SubscriptionID:  original.SubscriptionID,
+		CreatorID:       original.CreatorID,
+		StripePaymentID: providerRefundID,
+		PayerID:         original.PayerID,
+		StartAt:         original.StartAt,
+		EndAt:           original.EndAt,
+		Amount:          int(amount),
+		Status:          domain.PaymentStatusRefund,
+		RefundOf:        &original.ID,
+		RefundReason:    reason,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO payments (subscription_id, stripe_payment_id, payer_id, start_at, end_at, amount, status, refund_of, refund_reason, kind, creator_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+		ON CONFLICT (stripe_payment_id) WHERE refund_of IS NOT NULL AND stripe_payment_id <> '' DO NOTHING
+		RETURNING id, created_at
+	`, refund.SubscriptionID, refund.StripePaymentID, refund.PayerID, refund.StartAt, refund.EndAt, refund.Amount,
+		refund.Status, refund.RefundOf, refund.RefundReason, refund.Kind, refund.CreatorID).Scan(&refund.ID, &refund.CreatedAt)
+	if err == sql.ErrNoRows {
+		log.Printf("[applyRefund] Remboursement %s déjà enregistré", providerRefundID)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("enregistrement du remboursement du paiement %d : %w", paymentID, err)
+	}
+
+	if err := PostRefundToLedger(tx, paymentID, amount); err != nil {
+		return nil, err
+	}
+
+	fullRefund := refunded+amount >= int64(original.Amount)
+	status := domain.PaymentStatusPartiallyRefunded
+	if fullRefund {
+		status = domain.PaymentStatusRefunded
+	}
+	if _, err := tx.Exec(`UPDATE payments SET status = $2 WHERE id = $1`, paymentID, status); err != nil {
+		return nil, fmt.Errorf("statut du paiement %d : %w", paymentID, err)
+	}
+
+	if fullRefund && original.SubscriptionID != nil {
+		_, err = tx.Exec(`
+			UPDATE subscriptions
+			SET status = FALSE, end_at = $2
+			WHERE id = $1 AND end_at <= $3
+		`, *original.SubscriptionID, original.StartAt, original.EndAt)
+		if err != nil {
+			return nil, fmt.Errorf("révocation de l'abonnement %d : %w", *original.SubscriptionID, err)
+		}
+	}
+
+	log.Printf("[applyRefund] Remboursement de %d centimes du paiement %d enregistré (%s)", amount, paymentID, status)
+	return refund, nil
+}
+
+// refundedAmount retourne le montant déjà remboursé d'un paiement au sein d'une transaction.
+func refundedAmount(tx *sql.Tx, paymentID int64) (int64, error) {
+	var refunded int64
+	err := tx.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM payments WHERE refund_of = $1
+	`, paymentID).Scan(&refunded)
+	return refunded, err
+}
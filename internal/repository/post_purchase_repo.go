@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// postLockedSQL retourne la condition SQL vraie quand le post p est payant et que le lecteur
+// (paramètre viewerParam, 0 pour un visiteur) n'en est ni l'auteur ni un acheteur au paiement réglé.
+// Un achat intégralement remboursé ne débloque plus le post.
+func postLockedSQL(viewerParam string) string {
+	return `(p.visibility = 'ppv' AND p.user_id <> ` + viewerParam + ` AND NOT EXISTS (
+		SELECT 1 FROM post_purchases pp
+		JOIN payments pay ON pay.id = pp.payment_id
+		WHERE pp.post_id = p.id AND pp.buyer_id = ` + viewerParam + `
+			AND pay.status IN ('succeeded', 'partially_refunded')
+	))`
+}
+
+// HasUnlockedPost indique si l'utilisateur a acheté le post (paiement réglé).
+func HasUnlockedPost(postID, userID int64) (bool, error) {
+	var unlocked bool
+	err := database.DB.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM post_purchases pp
+			JOIN payments pay ON pay.id = pp.payment_id
+			WHERE pp.post_id = $1 AND pp.buyer_id = $2 AND pay.status IN ($3, $4)
+		)
+	`, postID, userID, domain.PaymentStatusSucceeded, domain.PaymentStatusPartiallyRefunded).Scan(&unlocked)
+	if err != nil {
+		log.Printf("[HasUnlockedPost] Erreur vérification de l'achat du post %d par %d : %v", postID, userID, err)
+		return false, err
+	}
+	return unlocked, nil
+}
+
+// CreatePostPurchase enregistre l'achat d'un post payant : paiement ponctuel en attente au bénéfice
+// de l'auteur et ligne d'achat associée. Le webhook du fournisseur confirmera le paiement.
+func CreatePostPurchase(post *domain.Post, buyerID int64, stripePaymentID string) (*domain.Payment, error) {
+	now := time.Now()
+	payment := &domain.Payment{
+		Kind:            domain.PaymentKindPostPurchase,
+		CreatorID:       &post.UserID,
+		StripePaymentID: stripePaymentID,
+		PayerID:         fmt.Sprintf("%d", buyerID),
+		StartAt:         now,
+		EndAt:           now,
+		Amount:          post.Price,
+		Status:          domain.PaymentStatusPending,
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[CreatePostPurchase] Erreur ouverture transaction : %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO payments (kind, creator_id, stripe_payment_id, payer_id, start_at, end_at, amount, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		RETURNING id, created_at
+	`, payment.Kind, payment.CreatorID, payment.StripePaymentID, payment.PayerID, payment.StartAt, payment.EndAt,
+		payment.Amount, payment.Status).Scan(&payment.ID, &payment.CreatedAt)
+	if err != nil {
+		log.Printf("[CreatePostPurchase] Erreur enregistrement du paiement du post %d : %v", post.ID, err)
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO post_purchases (post_id, buyer_id, payment_id, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`, post.ID, buyerID, payment.ID); err != nil {
+		log.Printf("[CreatePostPurchase] Erreur enregistrement de l'achat du post %d : %v", post.ID, err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[CreatePostPurchase] Erreur commit de l'achat du post %d : %v", post.ID, err)
+		return nil, err
+	}
+
+	log.Printf("[CreatePostPurchase] Achat du post %d par %d en attente (paiement %d, %d centimes)", post.ID, buyerID, payment.ID, payment.Amount)
+	return payment, nil
+}
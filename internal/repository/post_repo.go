@@ -8,6 +8,7 @@ import (
 	"log"
 	"onlyflick/internal/database"
 	"onlyflick/internal/domain"
+	"onlyflick/internal/utils"
 	"strings"
 	"time"
 )
@@ -21,8 +22,8 @@ func CreatePost(post *domain.Post) error {
 	log.Printf("[PostRepo] Création d'un nouveau post pour l'utilisateur ID: %d", post.UserID)
 
 	query := `
-		INSERT INTO posts (user_id, title, description, media_url, file_id, visibility, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		INSERT INTO posts (user_id, title, description, media_url, file_id, visibility, price, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
 		RETURNING id, created_at, updated_at
 	`
 	err := database.DB.QueryRow(
@@ -33,6 +34,7 @@ func CreatePost(post *domain.Post) error {
 		post.MediaURL,
 		post.FileID,
 		post.Visibility,
+		post.Price,
 	).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
 
 	if err != nil {
@@ -172,7 +174,7 @@ func ListPostsByUser(userID int64) ([]domain.Post, error) {
 	log.Printf("[PostRepo] Liste des posts pour l'utilisateur ID: %d", userID)
 
 	query := `
-		SELECT id, user_id, title, description, media_url, visibility, created_at, updated_at
+		SELECT id, user_id, title, description, media_url, visibility, price, created_at, updated_at
 		FROM posts
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -194,6 +196,7 @@ func ListPostsByUser(userID int64) ([]domain.Post, error) {
 			&post.Description,
 			&post.MediaURL,
 			&post.Visibility,
+			&post.Price,
 			&post.CreatedAt,
 			&post.UpdatedAt,
 		)
@@ -240,7 +243,7 @@ func UpdatePost(post *domain.Post) error {
 
 	query := `
 		UPDATE posts
-		SET title = $1, description = $2, media_url = $3, visibility = $4, updated_at = NOW()
+		SET title = $1, description = $2, media_url = $3, visibility = $4, price = $7, updated_at = NOW()
 		WHERE id = $5 AND user_id = $6
 		RETURNING updated_at
 	`
@@ -253,6 +256,7 @@ func UpdatePost(post *domain.Post) error {
 		post.Visibility,
 		post.ID,
 		post.UserID,
+		post.Price,
 	).Scan(&post.UpdatedAt)
 
 	if err != nil {
@@ -265,7 +269,8 @@ func UpdatePost(post *domain.Post) error {
 }
 
 // ListVisiblePosts retourne les posts visibles selon le rôle avec informations utilisateur complètes.
-func ListVisiblePosts(userRole string) ([]domain.Post, error) {
+// Les posts payants non débloqués par le lecteur (viewerID, 0 pour un visiteur) sont verrouillés.
+func ListVisiblePosts(userRole string, viewerID int64) ([]domain.Post, error) {
 	log.Printf("[PostRepo] Listing des posts visibles pour le rôle : %s", userRole)
 
 	var query string
@@ -279,6 +284,8 @@ func ListVisiblePosts(userRole string) ([]domain.Post, error) {
 				p.media_url, 
 				COALESCE(p.file_id, '') as file_id,
 				p.visibility, 
+				p.price,
+				` + postLockedSQL("$1") + ` as locked,
 				p.created_at, 
 				p.updated_at,
 				-- Informations utilisateur depuis la table users
@@ -303,6 +310,7 @@ func ListVisiblePosts(userRole string) ([]domain.Post, error) {
 				FROM comments 
 				GROUP BY post_id
 			) comments_count ON p.id = comments_count.post_id
+			WHERE NOT ` + activeSuspensionSQL("p.user_id") + `
 			ORDER BY p.created_at DESC
 		`
 	} else {
@@ -315,6 +323,8 @@ func ListVisiblePosts(userRole string) ([]domain.Post, error) {
 				p.media_url, 
 				COALESCE(p.file_id, '') as file_id,
 				p.visibility, 
+				p.price,
+				` + postLockedSQL("$1") + ` as locked,
 				p.created_at, 
 				p.updated_at,
 				-- Informations utilisateur depuis la table users
@@ -339,12 +349,13 @@ func ListVisiblePosts(userRole string) ([]domain.Post, error) {
 				FROM comments 
 				GROUP BY post_id
 			) comments_count ON p.id = comments_count.post_id
-			WHERE p.visibility = 'public'
+			WHERE p.visibility IN ('public', 'ppv')
+				AND NOT ` + activeSuspensionSQL("p.user_id") + `
 			ORDER BY p.created_at DESC
 		`
 	}
 
-	rows, err := database.DB.Query(query)
+	rows, err := database.DB.Query(query, viewerID)
 	if err != nil {
 		log.Printf("[PostRepo][ERREUR] Impossible de lister les posts visibles : %v", err)
 		return nil, fmt.Errorf("échec du listing des posts visibles : %w", err)
@@ -365,6 +376,8 @@ func ListVisiblePosts(userRole string) ([]domain.Post, error) {
 			&post.MediaURL,
 			&post.FileID,
 			&post.Visibility,
+			&post.Price,
+			&post.Locked,
 			&post.CreatedAt,
 			&post.UpdatedAt,
 			// Données utilisateur
@@ -384,13 +397,16 @@ func ListVisiblePosts(userRole string) ([]domain.Post, error) {
 
 		// Ajout des données utilisateur au post
 		post.Username = username
-		post.FirstName = firstName
-		post.LastName = lastName
+		post.FirstName = utils.DecryptField(firstName)
+		post.LastName = utils.DecryptField(lastName)
 		post.AvatarUrl = avatarUrl
 		post.Bio = bio
 		post.Role = role
 		post.LikesCount = likesCount
 		post.CommentsCount = commentsCount
+		if post.Locked {
+			post.Lock()
+		}
 
 		posts = append(posts, post)
 	}
@@ -399,7 +415,8 @@ func ListVisiblePosts(userRole string) ([]domain.Post, error) {
 	return posts, nil
 }
 
-// GetPostByID récupère un post par son ID avec ses tags
+// GetPostByID récupère un post par son ID avec ses tags.
+// Le média n'est pas masqué : le verrouillage des posts payants dépend du lecteur (voir HasUnlockedPost).
 func GetPostByID(postID int64) (*domain.Post, error) {
 	log.Printf("[PostRepo] Récupération du post ID: %d", postID)
 
@@ -412,6 +429,7 @@ func GetPostByID(postID int64) (*domain.Post, error) {
 			p.media_url, 
 			COALESCE(p.file_id, '') as file_id,
 			p.visibility, 
+			p.price,
 			p.created_at, 
 			p.updated_at,
 			-- Informations utilisateur
@@ -451,6 +469,7 @@ func GetPostByID(postID int64) (*domain.Post, error) {
 		&post.MediaURL,
 		&post.FileID,
 		&post.Visibility,
+		&post.Price,
 		&post.CreatedAt,
 		&post.UpdatedAt,
 		// Données utilisateur
@@ -471,8 +490,8 @@ func GetPostByID(postID int64) (*domain.Post, error) {
 
 	// Ajout des données utilisateur
 	post.Username = username
-	post.FirstName = firstName
-	post.LastName = lastName
+	post.FirstName = utils.DecryptField(firstName)
+	post.LastName = utils.DecryptField(lastName)
 	post.AvatarUrl = avatarUrl
 	post.Bio = bio
 	post.Role = role
@@ -522,9 +541,11 @@ func getRecommendedPostsWithoutTags(userID int64, limit, offset int) ([]interfac
 			p.description,
 			p.media_url,
 			p.visibility,
+			p.price,
+			` + postLockedSQL("$3") + ` as locked,
 			p.created_at,
 			p.user_id AS author_id,
-			COALESCE(u.username, CONCAT(u.first_name, ' ', u.last_name)) as author_name,
+			COALESCE(u.username, '') as author_name, -- noms chiffrés, inutilisables en SQL
 			COALESCE(u.avatar_url, '') as author_avatar,
 			COALESCE(COUNT(DISTINCT l.user_id), 0) as likes_count,
 			COALESCE(COUNT(DISTINCT c.id), 0) as comments_count,
@@ -534,7 +555,8 @@ func getRecommendedPostsWithoutTags(userID int64, limit, offset int) ([]interfac
 		LEFT JOIN likes l ON p.id = l.post_id
 		LEFT JOIN comments c ON p.id = c.post_id
 		LEFT JOIN post_tags pt ON p.id = pt.post_id
-		WHERE p.visibility = 'public'
+		WHERE p.visibility IN ('public', 'ppv')
+			AND NOT ` + activeSuspensionSQL("p.user_id") + `
 		GROUP BY p.id, u.id, u.username, u.first_name, u.last_name, u.avatar_url
 		ORDER BY 
 			COUNT(DISTINCT l.user_id) * 2 + COUNT(DISTINCT c.id) * 3 DESC,
@@ -542,7 +564,7 @@ func getRecommendedPostsWithoutTags(userID int64, limit, offset int) ([]interfac
 		LIMIT $1 OFFSET $2
 	`
 
-	rows, err := database.DB.Query(query, limit, offset)
+	rows, err := database.DB.Query(query, limit, offset, userID)
 	if err != nil {
 		log.Printf("[PostRepo][ERREUR] Erreur query posts recommandés : %v", err)
 		return nil, 0, err
@@ -582,9 +604,10 @@ func getRecommendedPostsWithTags(userID int64, tags []string, limit, offset int)
 		args = append(args, tag)
 		tagPlaceholders = append(tagPlaceholders, fmt.Sprintf("$%d", i+1))
 	}
-	args = append(args, limit, offset)
+	args = append(args, limit, offset, userID)
 	limitPos := len(tags) + 1
 	offsetPos := len(tags) + 2
+	viewerPos := len(tags) + 3
 
 	query := fmt.Sprintf(`
 		SELECT 
@@ -593,9 +616,11 @@ func getRecommendedPostsWithTags(userID int64, tags []string, limit, offset int)
 			p.description,
 			p.media_url,
 			p.visibility,
+			p.price,
+			%s as locked,
 			p.created_at,
 			p.user_id AS author_id,
-			COALESCE(u.username, CONCAT(u.first_name, ' ', u.last_name)) as author_name,
+			COALESCE(u.username, '') as author_name, -- noms chiffrés, inutilisables en SQL
 			COALESCE(u.avatar_url, '') as author_avatar,
 			COALESCE(COUNT(DISTINCT l.user_id), 0) as likes_count,
 			COALESCE(COUNT(DISTINCT c.id), 0) as comments_count,
@@ -605,14 +630,15 @@ func getRecommendedPostsWithTags(userID int64, tags []string, limit, offset int)
 		INNER JOIN post_tags pt ON p.id = pt.post_id
 		LEFT JOIN likes l ON p.id = l.post_id
 		LEFT JOIN comments c ON p.id = c.post_id
-		WHERE p.visibility = 'public'
+		WHERE p.visibility IN ('public', 'ppv')
 			AND pt.category IN (%s)
+			AND NOT ` + activeSuspensionSQL("p.user_id") + `
 		GROUP BY p.id, u.id, u.username, u.first_name, u.last_name, u.avatar_url
 		ORDER BY 
 			COUNT(DISTINCT l.user_id) * 2 + COUNT(DISTINCT c.id) * 3 DESC,
 			p.created_at DESC
 		LIMIT $%d OFFSET $%d
-	`, strings.Join(tagPlaceholders, ","), limitPos, offsetPos)
+	`, postLockedSQL(fmt.Sprintf("$%d", viewerPos)), strings.Join(tagPlaceholders, ","), limitPos, offsetPos)
 
 	rows, err := database.DB.Query(query, args...)
 	if err != nil {
@@ -646,7 +672,8 @@ func countRecommendedPostsWithoutTags(userID int64) (int, error) {
 	query := `
 		SELECT COUNT(DISTINCT p.id)
 		FROM posts p
-		WHERE p.visibility = 'public' AND p.user_id != $1
+		WHERE p.visibility IN ('public', 'ppv') AND p.user_id != $1
+			AND NOT ` + activeSuspensionSQL("p.user_id") + `
 	`
 
 	var total int
@@ -678,9 +705,10 @@ func countRecommendedPostsWithTags(userID int64, tags []string) (int, error) {
 		SELECT COUNT(DISTINCT p.id)
 		FROM posts p
 		INNER JOIN post_tags pt ON p.id = pt.post_id
-		WHERE p.visibility = 'public' 
+		WHERE p.visibility IN ('public', 'ppv') 
 			AND p.user_id != $1
 			AND pt.category IN (%s)
+			AND NOT ` + activeSuspensionSQL("p.user_id") + `
 	`, strings.Join(tagPlaceholders, ","))
 
 	var total int
@@ -704,6 +732,8 @@ func scanPostsResults(rows *sql.Rows) ([]interface{}, error) {
 			Description   string    `json:"description"`
 			MediaURL      string    `json:"media_url"`
 			Visibility    string    `json:"visibility"`
+			Price         int       `json:"price,omitempty"`
+			Locked        bool      `json:"locked"`
 			CreatedAt     time.Time `json:"created_at"`
 			AuthorID      int64     `json:"author_id"`
 			AuthorName    string    `json:"author_username"`    // 🔥 MAPPING FRONTEND
@@ -721,6 +751,8 @@ func scanPostsResults(rows *sql.Rows) ([]interface{}, error) {
 			&post.Description,
 			&post.MediaURL,
 			&post.Visibility,
+			&post.Price,
+			&post.Locked,
 			&post.CreatedAt,
 			&post.AuthorID,
 			&post.AuthorName,
@@ -733,6 +765,9 @@ func scanPostsResults(rows *sql.Rows) ([]interface{}, error) {
 			log.Printf("[PostRepo][ERREUR] Erreur scan post : %v", err)
 			continue
 		}
+		if post.Locked {
+			post.MediaURL = ""
+		}
 
 		// Parser les tags
 		if tagsArray.Valid && tagsArray.String != "" {
@@ -758,20 +793,22 @@ func scanPostsResults(rows *sql.Rows) ([]interface{}, error) {
 // =====================
 
 // ListPostsFromCreator retourne les posts d'un créateur, avec option pour inclure/exclure les posts privés.
-func ListPostsFromCreator(creatorID int64, includePrivate bool) ([]*domain.Post, error) {
+// Les posts payants sont toujours listés, verrouillés tant que le lecteur (viewerID) ne les a pas débloqués.
+func ListPostsFromCreator(creatorID int64, includePrivate bool, viewerID int64) ([]*domain.Post, error) {
 	log.Printf("[PostRepo] Listing des posts du créateur ID: %d (includePrivate: %v)", creatorID, includePrivate)
 
 	query := `
-		SELECT id, user_id, title, description, media_url, visibility, created_at, updated_at
-		FROM posts
-		WHERE user_id = $1
+		SELECT p.id, p.user_id, p.title, p.description, p.media_url, p.visibility, p.price,
+			` + postLockedSQL("$2") + ` as locked, p.created_at, p.updated_at
+		FROM posts p
+		WHERE p.user_id = $1
 	`
 	if !includePrivate {
-		query += ` AND visibility = 'public'`
+		query += ` AND p.visibility IN ('public', 'ppv')`
 	}
-	query += ` ORDER BY created_at DESC`
+	query += ` ORDER BY p.created_at DESC`
 
-	rows, err := database.DB.Query(query, creatorID)
+	rows, err := database.DB.Query(query, creatorID, viewerID)
 	if err != nil {
 		log.Printf("[PostRepo][ERREUR] Impossible de lister les posts du créateur ID %d : %v", creatorID, err)
 		return nil, err
@@ -781,11 +818,14 @@ func ListPostsFromCreator(creatorID int64, includePrivate bool) ([]*domain.Post,
 	var posts []*domain.Post
 	for rows.Next() {
 		var p domain.Post
-		err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Description, &p.MediaURL, &p.Visibility, &p.CreatedAt, &p.UpdatedAt)
+		err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Description, &p.MediaURL, &p.Visibility, &p.Price, &p.Locked, &p.CreatedAt, &p.UpdatedAt)
 		if err != nil {
 			log.Printf("[PostRepo][ERREUR] Scan du post du créateur ID %d échoué : %v", creatorID, err)
 			return nil, err
 		}
+		if p.Locked {
+			p.Lock()
+		}
 		posts = append(posts, &p)
 	}
 
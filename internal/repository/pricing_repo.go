@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// ErrTierNotFound est retournée quand un palier n'existe pas, est inactif ou appartient à un autre créateur.
+var ErrTierNotFound = errors.New("palier d'abonnement introuvable")
+
+// GetCreatorPricing retourne le tarif de base d'un créateur et ses paliers actifs.
+// Sans configuration, le tarif par défaut est retourné.
+func GetCreatorPricing(creatorID int64) (*domain.CreatorPricing, error) {
+	pricing := &domain.CreatorPricing{
+		CreatorID: creatorID,
+		Price:     domain.DefaultSubscriptionPrice,
+		Currency:  domain.DefaultSubscriptionCurrency,
+		Tiers:     []domain.SubscriptionTier{},
+	}
+
+	var updatedAt time.Time
+	err := database.DB.QueryRow(`
+		SELECT price, currency, updated_at, trial_days
+		FROM creator_pricing
+		WHERE creator_id = $1
+	`, creatorID).Scan(&pricing.Price, &pricing.Currency, &updatedAt, &pricing.TrialDays)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("[GetCreatorPricing] Erreur récupération tarif du créateur %d : %v", creatorID, err)
+		return nil, err
+	}
+	if err == nil {
+		pricing.UpdatedAt = &updatedAt
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, creator_id, name, description, price, active, created_at
+		FROM subscription_tiers
+		WHERE creator_id = $1 AND active = TRUE
+		ORDER BY price ASC, id ASC
+	`, creatorID)
+	if err != nil {
+		log.Printf("[GetCreatorPricing] Erreur récupération paliers du créateur %d : %v", creatorID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tier domain.SubscriptionTier
+		if err := rows.Scan(&tier.ID, &tier.CreatorID, &tier.Name, &tier.Description, &tier.Price, &tier.Active, &tier.CreatedAt); err != nil {
+			log.Printf("[GetCreatorPricing] Erreur scan palier : %v", err)
+			return nil, err
+		}
+		pricing.Tiers = append(pricing.Tiers, tier)
+	}
+
+	return pricing, rows.Err()
+}
+
+// UpsertCreatorPricing crée ou met à jour le tarif mensuel de base d'un créateur et la durée de son essai gratuit.
+func UpsertCreatorPricing(creatorID int64, price int, currency string, trialDays int) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO creator_pricing (creator_id, price, currency, trial_days, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (creator_id)
+		DO UPDATE SET price = EXCLUDED.price, currency = EXCLUDED.currency, trial_days = EXCLUDED.trial_days, updated_at = NOW()
+	`, creatorID, price, currency, trialDays)
+	if err != nil {
+		log.Printf("[UpsertCreatorPricing] Erreur mise à jour tarif du créateur %d : %v", creatorID, err)
+		return err
+	}
+
+	log.Printf("[UpsertCreatorPricing] Tarif du créateur %d : %d %s", creatorID, price, currency)
+	return nil
+}
+
+// CreateSubscriptionTier ajoute un palier d'abonnement pour un créateur.
+func CreateSubscriptionTier(creatorID int64, name, description string, price int) (*domain.SubscriptionTier, error) {
+	tier := &domain.SubscriptionTier{
+		CreatorID:   creatorID,
+		Name:        name,
+		Description: description,
+		Price:       price,
+		Active:      true,
+	}
+
+	err := database.DB.QueryRow(`
+		INSERT INTO subscription_tiers (creator_id, name, description, price, active, created_at)
+		VALUES ($1, $2, $3, $4, TRUE, NOW())
+		RETURNING id, created_at
+	`, creatorID, name, description, price).Scan(&tier.ID, &tier.CreatedAt)
+	if err != nil {
+		log.Printf("[CreateSubscriptionTier] Erreur création palier pour le créateur %d : %v", creatorID, err)
+		return nil, err
+	}
+
+	log.Printf("[CreateSubscriptionTier] Palier %d (%s) créé pour le créateur %d", tier.ID, name, creatorID)
+	return tier, nil
+}
+
+// UpdateSubscriptionTier modifie un palier appartenant au créateur.
+func UpdateSubscriptionTier(tierID, creatorID int64, name, description string, price int) error {
+	result, err := database.DB.Exec(`
+		UPDATE subscription_tiers
+		SET name = $3, description = $4, price = $5
+		WHERE id = $1 AND creator_id = $2 AND active = TRUE
+	`, tierID, creatorID, name, description, price)
+	if err != nil {
+		log.Printf("[UpdateSubscriptionTier] Erreur mise à jour palier %d : %v", tierID, err)
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrTierNotFound
+	}
+
+	log.Printf("[UpdateSubscriptionTier] Palier %d mis à jour", tierID)
+	return nil
+}
+
+// DeactivateSubscriptionTier retire un palier du catalogue. Les abonnements existants conservent leur palier.
+func DeactivateSubscriptionTier(tierID, creatorID int64) error {
+	result, err := database.DB.Exec(`
+		UPDATE subscription_tiers
+		SET active = FALSE
+		WHERE id = $1 AND creator_id = $2 AND active = TRUE
+	`, tierID, creatorID)
+	if err != nil {
+		log.Printf("[DeactivateSubscriptionTier] Erreur désactivation palier %d : %v", tierID, err)
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrTierNotFound
+	}
+
+	log.Printf("[DeactivateSubscriptionTier] Palier %d désactivé", tierID)
+	return nil
+}
+
+// ResolveSubscriptionPrice retourne le montant (en centimes) et la devise à facturer
+// pour un abonnement au créateur, au tarif de base ou au palier demandé.
+func ResolveSubscriptionPrice(creatorID int64, tierID *int64) (int, string, error) {
+	pricing, err := GetCreatorPricing(creatorID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if tierID == nil {
+		return pricing.Price, pricing.Currency, nil
+	}
+
+	for _, tier := range pricing.Tiers {
+		if tier.ID == *tierID {
+			return tier.Price, pricing.Currency, nil
+		}
+	}
+
+	log.Printf("[ResolveSubscriptionPrice] Palier %d introuvable pour le créateur %d", *tierID, creatorID)
+	return 0, "", fmt.Errorf("%w : %d", ErrTierNotFound, *tierID)
+}
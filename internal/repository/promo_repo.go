@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPromoCodeNotFound est retournée pour un code inconnu du créateur.
+	ErrPromoCodeNotFound = errors.New("code promo introuvable")
+	// ErrPromoCodeExists est retournée à la création d'un code déjà utilisé par le créateur.
+	ErrPromoCodeExists = errors.New("code promo déjà existant")
+	// ErrPromoCodeUnavailable est retournée pour un code désactivé, expiré ou épuisé.
+	ErrPromoCodeUnavailable = errors.New("code promo expiré ou épuisé")
+	// ErrPromoCodeAlreadyRedeemed est retournée quand l'abonné a déjà utilisé le code.
+	ErrPromoCodeAlreadyRedeemed = errors.New("code promo déjà utilisé")
+)
+
+const promoCodeColumns = `id, creator_id, code, discount_type, discount_value, max_redemptions,
+	redemptions_count, expires_at, first_period_only, active, created_at`
+
+// NormalizePromoCode met un code promo au format stocké (majuscules, sans espaces autour).
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// scanPromoCode lit une ligne de promo_codes.
+func scanPromoCode(row interface{ Scan(...interface{}) error }) (*domain.PromoCode, error) {
+	var p domain.PromoCode
+	var maxRedemptions sql.NullInt64
+	var expiresAt sql.NullTime
+	err := row.Scan(&p.ID, &p.CreatorID, &p.Code, &p.DiscountType, &p.DiscountValue, &maxRedemptions,
+		&p.RedemptionsCount, &expiresAt, &p.FirstPeriodOnly, &p.Active, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if maxRedemptions.Valid {
+		limit := int(maxRedemptions.Int64)
+		p.MaxRedemptions = &limit
+	}
+	if expiresAt.Valid {
+		p.ExpiresAt = &expiresAt.Time
+	}
+	return &p, nil
+}
+
+// CreatePromoCode enregistre un nouveau code promo pour un créateur.
+func CreatePromoCode(promo *domain.PromoCode) error {
+	promo.Code = NormalizePromoCode(promo.Code)
+	promo.Active = true
+
+	err := database.DB.QueryRow(`
+		INSERT INTO promo_codes (creator_id, code, discount_type, discount_value, max_redemptions, expires_at, first_period_only, active, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, TRUE, NOW())
+		ON CONFLICT (creator_id, code) DO NOTHING
+		RETURNING id, created_at
+	`, promo.CreatorID, promo.Code, promo.DiscountType, promo.DiscountValue, promo.MaxRedemptions,
+		promo.ExpiresAt, promo.FirstPeriodOnly).Scan(&promo.ID, &promo.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrPromoCodeExists
+	}
+	if err != nil {
+		log.Printf("[CreatePromoCode] Erreur création du code %s pour le créateur %d : %v", promo.Code, promo.CreatorID, err)
+		return err
+	}
+
+	log.Printf("[CreatePromoCode] Code %s (%d) créé pour le créateur %d", promo.Code, promo.ID, promo.CreatorID)
+	return nil
+}
+
+// ListPromoCodes retourne les codes promo d'un créateur, du plus récent au plus ancien.
+func ListPromoCodes(creatorID int64) ([]domain.PromoCode, error) {
+	rows, err := database.DB.Query(`
+		SELECT `+promoCodeColumns+`
+		FROM promo_codes
+		WHERE creator_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, creatorID)
+	if err != nil {
+		log.Printf("[ListPromoCodes] Erreur récupération des codes du créateur %d : %v", creatorID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := []domain.PromoCode{}
+	for rows.Next() {
+		promo, err := scanPromoCode(rows)
+		if err != nil {
+			log.Printf("[ListPromoCodes] Erreur scan : %v", err)
+			return nil, err
+		}
+		codes = append(codes, *promo)
+	}
+
+	return codes, rows.Err()
+}
+
+// GetPromoCodeByCode retourne le code promo d'un créateur (insensible à la casse).
+func GetPromoCodeByCode(creatorID int64, code string) (*domain.PromoCode, error) {
+	promo, err := scanPromoCode(database.DB.QueryRow(`
+		SELECT `+promoCodeColumns+`
+		FROM promo_codes
+		WHERE creator_id = $1 AND code = $2
+	`, creatorID, NormalizePromoCode(code)))
+	if err == sql.ErrNoRows {
+		return nil, ErrPromoCodeNotFound
+	}
+	if err != nil {
+		log.Printf("[GetPromoCodeByCode] Erreur récupération du code %s : %v", code, err)
+		return nil, err
+	}
+	return promo, nil
+}
+
+// GetPromoCodeByID retourne un code promo par identifiant.
+func GetPromoCodeByID(promoID int64) (*domain.PromoCode, error) {
+	promo, err := scanPromoCode(database.DB.QueryRow(`
+		SELECT `+promoCodeColumns+`
+		FROM promo_codes
+		WHERE id = $1
+	`, promoID))
+	if err == sql.ErrNoRows {
+		return nil, ErrPromoCodeNotFound
+	}
+	if err != nil {
+		log.Printf("[GetPromoCodeByID] Erreur récupération du code %d : %v", promoID, err)
+		return nil, err
+	}
+	return promo, nil
+}
+
+// DeactivatePromoCode désactive un code du créateur. Les abonnements l'ayant déjà utilisé conservent leur réduction.
+func DeactivatePromoCode(promoID, creatorID int64) error {
+	result, err := database.DB.Exec(`
+		UPDATE promo_codes
+		SET active = FALSE
+		WHERE id = $1 AND creator_id = $2 AND active = TRUE
+	`, promoID, creatorID)
+	if err != nil {
+		log.Printf("[DeactivatePromoCode] Erreur désactivation du code %d : %v", promoID, err)
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrPromoCodeNotFound
+	}
+
+	log.Printf("[DeactivatePromoCode] Code %d désactivé", promoID)
+	return nil
+}
+
+// PromoRedemption décrit l'utilisation d'un code promo par un abonné pour un abonnement, enregistrée
+// dans la même transaction que le paiement ou l'activation (essai, période offerte) qu'elle accompagne.
+type PromoRedemption struct {
+	PromoCodeID    int64
+	UserID         int64
+	SubscriptionID int64
+	At             time.Time
+}
+
+// redeemPromoCode enregistre l'utilisation d'un code dans la transaction tx, rattachée au paiement paymentID
+// s'il est non nul (elle est libérée si ce paiement échoue). Le code est verrouillé pour que le nombre maximal
+// d'utilisations ne soit jamais dépassé. Une nouvelle tentative pour le même abonnement (paiement relancé)
+// ne compte pas de nouvelle utilisation et rattache l'utilisation au nouveau paiement.
+func redeemPromoCode(tx *sql.Tx, r PromoRedemption, paymentID *int64) error {
+	promo, err := scanPromoCode(tx.QueryRow(`
+		SELECT `+promoCodeColumns+`
+		FROM promo_codes
+		WHERE id = $1
+		FOR UPDATE
+	`, r.PromoCodeID))
+	if err == sql.ErrNoRows {
+		return ErrPromoCodeNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("lecture du code promo %d : %w", r.PromoCodeID, err)
+	}
+
+	var redeemedFor int64
+	err = tx.QueryRow(`
+		SELECT subscription_id FROM promo_code_redemptions
+		WHERE promo_code_id = $1 AND user_id = $2
+	`, r.PromoCodeID, r.UserID).Scan(&redeemedFor)
+	if err == nil {
+		if redeemedFor != r.SubscriptionID {
+			return ErrPromoCodeAlreadyRedeemed
+		}
+		if _, err := tx.Exec(`
+			UPDATE promo_code_redemptions SET payment_id = $3 WHERE promo_code_id = $1 AND user_id = $2
+		`, r.PromoCodeID, r.UserID, paymentID); err != nil {
+			return fmt.Errorf("rattachement de l'utilisation du code %d : %w", r.PromoCodeID, err)
+		}
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("vérification de l'utilisation du code %d : %w", r.PromoCodeID, err)
+	}
+
+	if !promo.Redeemable(r.At) {
+		return ErrPromoCodeUnavailable
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO promo_code_redemptions (promo_code_id, user_id, subscription_id, payment_id, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`, r.PromoCodeID, r.UserID, r.SubscriptionID, paymentID); err != nil {
+		return fmt.Errorf("enregistrement de l'utilisation du code %d : %w", r.PromoCodeID, err)
+	}
+	if _, err := tx.Exec(`UPDATE promo_codes SET redemptions_count = redemptions_count + 1 WHERE id = $1`, r.PromoCodeID); err != nil {
+		return fmt.Errorf("compteur du code %d : %w", r.PromoCodeID, err)
+	}
+
+	log.Printf("[redeemPromoCode] Code %d utilisé par l'utilisateur %d (abonnement %d)", r.PromoCodeID, r.UserID, r.SubscriptionID)
+	return nil
+}
+
+// releasePromoRedemption libère, dans la transaction tx, l'utilisation de code promo rattachée à un paiement
+// échoué : le code redevient utilisable par l'abonné et son compteur est décrémenté.
+func releasePromoRedemption(tx *sql.Tx, paymentID int64) error {
+	_, err := tx.Exec(`
+		WITH released AS (
+			DELETE FROM promo_code_redemptions WHERE payment_id = $1
+			RETURNING promo_code_id
+		)
+		UPDATE promo_codes SET redemptions_count = GREATEST(redemptions_count - 1, 0)
+		WHERE id IN (SELECT promo_code_id FROM released)
+	`, paymentID)
+	if err != nil {
+		return fmt.Errorf("libération du code promo du paiement %d : %w", paymentID, err)
+	}
+	return nil
+}
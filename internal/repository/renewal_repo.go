@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// RenewalCandidate représente un abonnement arrivé à échéance à renouveler.
+type RenewalCandidate struct {
+	SubscriptionID   int64
+	SubscriberID     int64
+	CreatorID        int64
+	EndAt            time.Time
+	TierID           *int64
+	RenewalAttempts  int
+	PaymentMethodID  string
+	StripeCustomerID string
+	PromoCodeID      *int64 // Code promo à appliquer au débit (nil = plein tarif)
+}
+
+// ClaimSubscriptionsDueForRenewal réserve jusqu'à leaseUntil et retourne les abonnements actifs échus
+// dont le prochain essai de renouvellement est dû, sans paiement déjà en cours pour la période suivante.
+// La réservation (FOR UPDATE SKIP LOCKED puis renewal_claimed_until) empêche deux instances du planificateur
+// de débiter le même abonnement ; une réservation non libérée (arrêt brutal) expire à leaseUntil.
+func ClaimSubscriptionsDueForRenewal(now, leaseUntil time.Time, maxAttempts, limit int) ([]RenewalCandidate, error) {
+	rows, err := database.DB.Query(`
+		WITH due AS (
+			SELECT s.id
+			FROM subscriptions s
+			WHERE s.status = TRUE
+				AND s.auto_renew = TRUE
+				AND s.end_at <= $1
+				AND (s.next_renewal_at IS NULL OR s.next_renewal_at <= $1)
+				AND (s.renewal_claimed_until IS NULL OR s.renewal_claimed_until <= $1)
+				AND s.renewal_attempts < $2
+				AND NOT EXISTS (
+					SELECT 1 FROM payments p
+					WHERE p.subscription_id = s.id AND p.status = $3 AND p.end_at > s.end_at
+				)
+			ORDER BY s.end_at ASC
+			LIMIT $4
+			FOR UPDATE OF s SKIP LOCKED
+		)
+		UPDATE subscriptions s
+		SET renewal_claimed_until = $5
+		FROM due, users u
+		WHERE s.id = due.id AND u.id = s.subscriber_id
+		RETURNING s.id, s.subscriber_id, s.creator_id, s.end_at, s.tier_id, s.renewal_attempts,
+			COALESCE(s.payment_method_id, ''), COALESCE(u.stripe_customer_id, ''), s.promo_code_id
+	`, now, maxAttempts, domain.PaymentStatusPending, limit, leaseUntil)
+	if err != nil {
+		log.Printf("[ClaimSubscriptionsDueForRenewal] Erreur réservation des renouvellements : %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []RenewalCandidate
+	for rows.Next() {
+		var c RenewalCandidate
+		if err := rows.Scan(&c.SubscriptionID, &c.SubscriberID, &c.CreatorID, &c.EndAt, &c.TierID, &c.RenewalAttempts, &c.PaymentMethodID, &c.StripeCustomerID, &c.PromoCodeID); err != nil {
+			log.Printf("[ClaimSubscriptionsDueForRenewal] Erreur scan : %v", err)
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
+
+// RecordRenewalFailure incrémente le compteur d'échecs, planifie le prochain essai (nil = plus d'essai)
+// et libère la réservation du renouvellement.
+func RecordRenewalFailure(subscriptionID int64, nextAttempt *time.Time) error {
+	_, err := database.DB.Exec(`
+		UPDATE subscriptions
+		SET renewal_attempts = renewal_attempts + 1, next_renewal_at = $2, renewal_claimed_until = NULL
+		WHERE id = $1
+	`, subscriptionID, nextAttempt)
+	if err != nil {
+		log.Printf("[RecordRenewalFailure] Erreur mise à jour de l'abonnement %d : %v", subscriptionID, err)
+		return err
+	}
+	return nil
+}
+
+// ExpireLapsedSubscriptions désactive les abonnements échus depuis avant cutoff (fin du délai de grâce).
+func ExpireLapsedSubscriptions(cutoff time.Time) (int64, error) {
+	result, err := database.DB.Exec(`
+		UPDATE subscriptions
+		SET status = FALSE, next_renewal_at = NULL
+		WHERE status = TRUE AND end_at < $1
+	`, cutoff)
+	if err != nil {
+		log.Printf("[ExpireLapsedSubscriptions] Erreur expiration des abonnements : %v", err)
+		return 0, err
+	}
+
+	expired, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// ConfirmPayment applique de façon synchrone la confirmation d'un paiement
+// (même effet que l'événement payment_intent.succeeded du webhook).
+func ConfirmPayment(stripePaymentID, paymentMethodID string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := MarkPaymentSucceeded(tx, stripePaymentID, paymentMethodID); err != nil {
+		log.Printf("[ConfirmPayment] Erreur confirmation du paiement %s : %v", stripePaymentID, err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetStripeCustomerID retourne l'identifiant client Stripe d'un utilisateur ("" si absent).
+func GetStripeCustomerID(userID int64) (string, error) {
+	var customerID sql.NullString
+	err := database.DB.QueryRow(`SELECT stripe_customer_id FROM users WHERE id = $1`, userID).Scan(&customerID)
+	if err != nil {
+		log.Printf("[GetStripeCustomerID] Erreur récupération du client Stripe de %d : %v", userID, err)
+		return "", err
+	}
+	return customerID.String, nil
+}
+
+// SetStripeCustomerID enregistre l'identifiant client Stripe d'un utilisateur.
+func SetStripeCustomerID(userID int64, customerID string) error {
+	_, err := database.DB.Exec(`UPDATE users SET stripe_customer_id = $1 WHERE id = $2`, customerID, userID)
+	if err != nil {
+		log.Printf("[SetStripeCustomerID] Erreur enregistrement du client Stripe de %d : %v", userID, err)
+		return err
+	}
+	return nil
+}
@@ -122,22 +122,44 @@ func ListReportsByStatus(status string) ([]domain.Report, error) {
 	return reports, nil
 }
 
-// UpdateReportStatus met à jour le statut et la date de traitement d'un signalement.
-func UpdateReportStatus(reportID int64, status string, processedAt time.Time) error {
-	_, err := database.DB.Exec(`
+// UpdateReportStatus met à jour le statut et la date de traitement d'un signalement
+// et journalise le changement au nom de actor dans la même transaction.
+func UpdateReportStatus(reportID int64, status string, processedAt time.Time, actor domain.AuditActor) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[ERREUR] Impossible de démarrer la transaction : %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRow(`SELECT status FROM reports WHERE id = $1 FOR UPDATE`, reportID).Scan(&previous)
+	if err != nil {
+		log.Printf("[ERREUR] Impossible de récupérer le signalement %d : %v", reportID, err)
+		return err
+	}
+
+	_, err = tx.Exec(`
 		UPDATE reports
 		SET status = $1, updated_at = $2
 		WHERE id = $3
 	`, status, processedAt, reportID)
 	if err != nil {
 		log.Printf("[ERREUR] Impossible de mettre à jour le statut du signalement %d : %v", reportID, err)
+		return err
 	}
-	return err
+
+	if err := recordAudit(tx, &actor, domain.AuditReportStatus, domain.AuditTargetReport, reportID,
+		map[string]string{"status": previous}, map[string]string{"status": status}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // AdminActOnReport permet à un administrateur d'agir sur un signalement (approuver ou rejeter).
 // Si approuvé, le contenu signalé et ses signalements associés sont supprimés.
-func AdminActOnReport(reportID int64, action string) error {
+// L'action est journalisée au nom de actor dans la même transaction.
+func AdminActOnReport(reportID int64, action string, actor domain.AuditActor) error {
 	tx, err := database.DB.Begin()
 	if err != nil {
 		log.Printf("[ERREUR] Impossible de démarrer la transaction : %v", err)
@@ -145,11 +167,11 @@ func AdminActOnReport(reportID int64, action string) error {
 	}
 	defer tx.Rollback()
 
-	var contentType string
+	var contentType, previous string
 	var contentID int64
 	err = tx.QueryRow(`
-		SELECT content_type, content_id FROM reports WHERE id = $1
-	`, reportID).Scan(&contentType, &contentID)
+		SELECT content_type, content_id, status FROM reports WHERE id = $1 FOR UPDATE
+	`, reportID).Scan(&contentType, &contentID, &previous)
 	if err != nil {
 		log.Printf("[ERREUR] Impossible de récupérer le contenu du signalement %d : %v", reportID, err)
 		return err
@@ -193,6 +215,12 @@ func AdminActOnReport(reportID int64, action string) error {
 		}
 	}
 
+	if err := recordAudit(tx, &actor, domain.AuditReportAct, domain.AuditTargetReport, reportID,
+		map[string]interface{}{"status": previous, "content_type": contentType, "content_id": contentID},
+		map[string]interface{}{"status": action, "content_type": contentType, "content_id": contentID, "content_deleted": action == "approved"}); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		log.Printf("[ERREUR] Impossible de valider la transaction : %v", err)
 		return err
@@ -11,39 +11,46 @@ import (
 
 	"onlyflick/internal/database"
 	"onlyflick/internal/domain"
+	"onlyflick/internal/utils"
+
+	"github.com/lib/pq"
 )
 
 // ===== RECHERCHE D'UTILISATEURS =====
 
-// SearchUsers recherche des utilisateurs UNIQUEMENT par username
+// SearchUsers recherche des utilisateurs par username ou par début des mots du prénom et du nom
+// (chiffrés en base, retrouvés via leurs index aveugles)
 func SearchUsers(searchTerm string, currentUserID int64, limit, offset int) ([]domain.UserSearchResult, int, error) {
-	log.Printf("[SearchUsers] Recherche par username: '%s', userID: %d, limit: %d, offset: %d", 
+	log.Printf("[SearchUsers] Recherche par username ou nom: '%s', userID: %d, limit: %d, offset: %d", 
 		searchTerm, currentUserID, limit, offset)
 	
-	// Pattern de recherche - seulement pour username
+	// Pattern de recherche pour username, index aveugles pour les noms
 	searchPattern := "%" + strings.ToLower(searchTerm) + "%"
+	nameIndexes := pq.Array(utils.NameSearchIndexes(searchTerm))
 	
 	// Requête ultra-simplifiée - seulement les colonnes de base qui existent
 	query := `
 		SELECT 
 			u.id, 
-			COALESCE(u.username, u.email) as username,
+			COALESCE(u.username, '') as username,
 			COALESCE(u.first_name, '') as first_name, 
 			COALESCE(u.last_name, '') as last_name, 
 			COALESCE(u.email, '') as email,
 			COALESCE(u.role, 'subscriber') as role
 		FROM users u
-		WHERE LOWER(COALESCE(u.username, u.email)) LIKE $1
+		WHERE (LOWER(COALESCE(u.username, '')) LIKE $1
+				OR (cardinality($6::text[]) > 0 AND u.name_bidx @> $6::text[]))
 			AND u.id != $2
+			AND u.deletion_requested_at IS NULL AND u.deleted_at IS NULL
 		ORDER BY 
-			CASE WHEN LOWER(COALESCE(u.username, u.email)) = LOWER($3) THEN 1 ELSE 2 END,
+			CASE WHEN LOWER(COALESCE(u.username, '')) = LOWER($3) THEN 1 ELSE 2 END,
 			u.id ASC
 		LIMIT $4 OFFSET $5
 	`
 	
 	log.Printf("[SearchUsers] Exécution requête avec pattern: %s", searchPattern)
 	
-	rows, err := database.DB.Query(query, searchPattern, currentUserID, searchTerm, limit, offset)
+	rows, err := database.DB.Query(query, searchPattern, currentUserID, searchTerm, limit, offset, nameIndexes)
 	if err != nil {
 		log.Printf("[SearchUsers][ERREUR] Erreur requête recherche users : %v", err)
 		return nil, 0, err
@@ -67,6 +74,10 @@ func SearchUsers(searchTerm string, currentUserID int64, limit, offset int) ([]d
 			continue // Ignorer cette ligne et continuer
 		}
 
+		// Noms chiffrés en base
+		user.FirstName = utils.DecryptField(user.FirstName)
+		user.LastName = utils.DecryptField(user.LastName)
+
 		// Calculer les valeurs dérivées
 		if user.FirstName != "" && user.LastName != "" {
 			user.FullName = user.FirstName + " " + user.LastName
@@ -96,19 +107,21 @@ func SearchUsers(searchTerm string, currentUserID int64, limit, offset int) ([]d
 	countQuery := `
 		SELECT COUNT(*)
 		FROM users u
-		WHERE LOWER(COALESCE(u.username, u.email)) LIKE $1
+		WHERE (LOWER(COALESCE(u.username, '')) LIKE $1
+				OR (cardinality($3::text[]) > 0 AND u.name_bidx @> $3::text[]))
 			AND u.id != $2
+			AND u.deletion_requested_at IS NULL AND u.deleted_at IS NULL
 	`
 	
 	var total int
-	err = database.DB.QueryRow(countQuery, searchPattern, currentUserID).Scan(&total)
+	err = database.DB.QueryRow(countQuery, searchPattern, currentUserID, nameIndexes).Scan(&total)
 	if err != nil {
 		log.Printf("[SearchUsers][ERREUR] Erreur count users : %v", err)
 		// Ne pas retourner d'erreur pour le count, juste loguer
 		total = len(users)
 	}
 
-	log.Printf("[SearchUsers] ✅ Trouvé %d users pour username '%s' (total: %d)", len(users), searchTerm, total)
+	log.Printf("[SearchUsers] ✅ Trouvé %d users pour '%s' (total: %d)", len(users), searchTerm, total)
 	return users, total, nil
 }
 
@@ -130,10 +143,13 @@ func SearchPosts(searchRequest domain.SearchRequest) ([]interface{}, int, error)
 			WHERE s.subscriber_id = $%d 
 			AND s.creator_id = p.user_id 
 			AND s.status = TRUE
+			AND s.end_at > NOW()
 		)
 	))`, argIndex)
 
 	whereConditions = append(whereConditions, baseCondition)
+	// Posts des comptes suspendus masqués
+	whereConditions = append(whereConditions, "NOT "+activeSuspensionSQL("p.user_id"))
 	args = append(args, searchRequest.UserID)
 	argIndex++
 
@@ -189,7 +205,9 @@ func SearchPosts(searchRequest domain.SearchRequest) ([]interface{}, int, error)
 			p.visibility,
 			p.created_at,
 			p.user_id AS author_id,
-			COALESCE(u.first_name || ' ' || u.last_name, u.username) as author_name,
+			COALESCE(u.first_name, '') as author_first_name,
+			COALESCE(u.last_name, '') as author_last_name,
+			COALESCE(u.username, '') as author_username,
 			COALESCE(COUNT(DISTINCT l.user_id), 0) as likes_count,
 			COALESCE(COUNT(DISTINCT c.id), 0) as comments_count,
 			ARRAY_AGG(DISTINCT pt.category) FILTER (WHERE pt.category IS NOT NULL) as tags
@@ -232,6 +250,7 @@ func SearchPosts(searchRequest domain.SearchRequest) ([]interface{}, int, error)
 		}
 
 		var tagsArray sql.NullString
+		var authorFirstName, authorLastName, authorUsername string
 
 		err := rows.Scan(
 			&post.ID,
@@ -241,7 +260,9 @@ func SearchPosts(searchRequest domain.SearchRequest) ([]interface{}, int, error)
 			&post.Visibility,
 			&post.CreatedAt,
 			&post.AuthorID,
-			&post.AuthorName,
+			&authorFirstName,
+			&authorLastName,
+			&authorUsername,
 			&post.LikesCount,
 			&post.CommentsCount,
 			&tagsArray,
@@ -251,6 +272,12 @@ func SearchPosts(searchRequest domain.SearchRequest) ([]interface{}, int, error)
 			continue
 		}
 
+		// Nom complet de l'auteur (chiffré en base), à défaut son username
+		post.AuthorName = strings.TrimSpace(utils.DecryptField(authorFirstName) + " " + utils.DecryptField(authorLastName))
+		if post.AuthorName == "" {
+			post.AuthorName = authorUsername
+		}
+
 		if tagsArray.Valid {
 			post.Tags = strings.Split(strings.Trim(tagsArray.String, "{}"), ",")
 		}
@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+var (
+	// ErrRefreshTokenInvalid est retournée pour un refresh token inconnu, expiré ou d'une session révoquée.
+	ErrRefreshTokenInvalid = errors.New("refresh token invalide")
+	// ErrRefreshTokenReused est retournée quand un refresh token déjà renouvelé est présenté à nouveau :
+	// toute la famille (session) est alors révoquée.
+	ErrRefreshTokenReused = errors.New("refresh token réutilisé")
+	// ErrSessionNotFound est retournée pour une session inconnue de l'utilisateur ou déjà révoquée.
+	ErrSessionNotFound = errors.New("session introuvable")
+)
+
+// CreateSession ouvre une session pour l'utilisateur depuis l'appareil client, avec son premier refresh token (haché).
+func CreateSession(userID int64, client domain.SessionClient, tokenHash string, expiresAt time.Time) (int64, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[CreateSession] Erreur ouverture transaction : %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var sessionID int64
+	if err := tx.QueryRow(`
+		INSERT INTO auth_sessions (user_id, user_agent, ip_address, created_at, last_seen_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING id
+	`, userID, client.UserAgent, client.IPAddress).Scan(&sessionID); err != nil {
+		log.Printf("[CreateSession] Erreur création de la session de l'utilisateur %d : %v", userID, err)
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`, sessionID, tokenHash, expiresAt); err != nil {
+		log.Printf("[CreateSession] Erreur enregistrement du refresh token de la session %d : %v", sessionID, err)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("[CreateSession] Session %d ouverte pour l'utilisateur %d", sessionID, userID)
+	return sessionID, nil
+}
+
+// RotateRefreshToken échange un refresh token contre un nouveau dans la même session.
+// Un token déjà renouvelé révoque la session entière (vol présumé) et retourne ErrRefreshTokenReused.
+// La date de dernière activité et l'adresse IP de la session sont mises à jour.
+// Retourne l'identifiant de session et l'utilisateur.
+func RotateRefreshToken(tokenHash, newTokenHash string, newExpiresAt, now time.Time, client domain.SessionClient) (int64, int64, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[RotateRefreshToken] Erreur ouverture transaction : %v", err)
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var tokenID, sessionID, userID int64
+	var expiresAt time.Time
+	var rotatedAt, revokedAt sql.NullTime
+	err = tx.QueryRow(`
+		SELECT rt.id, rt.session_id, rt.expires_at, rt.rotated_at, s.user_id, s.revoked_at
+		FROM refresh_tokens rt
+		JOIN auth_sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt, s
+	`, tokenHash).Scan(&tokenID, &sessionID, &expiresAt, &rotatedAt, &userID, &revokedAt)
+	if err == sql.ErrNoRows {
+		return 0, 0, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("lecture du refresh token : %w", err)
+	}
+
+	if revokedAt.Valid {
+		return 0, 0, ErrRefreshTokenInvalid
+	}
+
+	if rotatedAt.Valid {
+		if err := revokeSession(tx, sessionID, domain.SessionRevokedReuse); err != nil {
+			return 0, 0, err
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, 0, err
+		}
+		log.Printf("[RotateRefreshToken] ⚠️  Réutilisation d'un refresh token : session %d de l'utilisateur %d révoquée", sessionID, userID)
+		return 0, 0, ErrRefreshTokenReused
+	}
+
+	if !expiresAt.After(now) {
+		return 0, 0, ErrRefreshTokenInvalid
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET rotated_at = $2 WHERE id = $1`, tokenID, now); err != nil {
+		return 0, 0, fmt.Errorf("rotation du refresh token %d : %w", tokenID, err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, sessionID, newTokenHash, newExpiresAt, now); err != nil {
+		return 0, 0, fmt.Errorf("enregistrement du nouveau refresh token de la session %d : %w", sessionID, err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE auth_sessions
+		SET last_seen_at = $2, ip_address = COALESCE(NULLIF($3, ''), ip_address)
+		WHERE id = $1
+	`, sessionID, now, client.IPAddress); err != nil {
+		return 0, 0, fmt.Errorf("activité de la session %d : %w", sessionID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return sessionID, userID, nil
+}
+
+// RevokeSessionByRefreshToken révoque la session à laquelle appartient le refresh token.
+func RevokeSessionByRefreshToken(tokenHash string) error {
+	result, err := database.DB.Exec(`
+		UPDATE auth_sessions
+		SET revoked_at = NOW(), revoked_reason = $2
+		WHERE revoked_at IS NULL AND id = (SELECT session_id FROM refresh_tokens WHERE token_hash = $1)
+	`, tokenHash, domain.SessionRevokedLogout)
+	if err != nil {
+		log.Printf("[RevokeSessionByRefreshToken] Erreur révocation de session : %v", err)
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrRefreshTokenInvalid
+	}
+	return nil
+}
+
+// RevokeSession révoque une session de l'utilisateur.
+func RevokeSession(sessionID, userID int64, reason string) error {
+	result, err := database.DB.Exec(`
+		UPDATE auth_sessions
+		SET revoked_at = NOW(), revoked_reason = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, sessionID, userID, reason)
+	if err != nil {
+		log.Printf("[RevokeSession] Erreur révocation de la session %d : %v", sessionID, err)
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrSessionNotFound
+	}
+
+	log.Printf("[RevokeSession] Session %d de l'utilisateur %d révoquée (%s)", sessionID, userID, reason)
+	return nil
+}
+
+// ListActiveSessions retourne les sessions ouvertes de l'utilisateur, de la plus récemment active à la plus ancienne.
+func ListActiveSessions(userID int64) ([]domain.Session, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at
+		FROM auth_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		log.Printf("[ListActiveSessions] Erreur récupération des sessions de l'utilisateur %d : %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []domain.Session{}
+	for rows.Next() {
+		var session domain.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress, &session.CreatedAt, &session.LastSeenAt); err != nil {
+			log.Printf("[ListActiveSessions] Erreur scan d'une session : %v", err)
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeOtherSessions révoque toutes les sessions ouvertes de l'utilisateur sauf keepSessionID
+// (0 pour les révoquer toutes). Retourne le nombre de sessions fermées.
+func RevokeOtherSessions(userID, keepSessionID int64, reason string) (int64, error) {
+	result, err := database.DB.Exec(`
+		UPDATE auth_sessions
+		SET revoked_at = NOW(), revoked_reason = $3
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`, userID, keepSessionID, reason)
+	if err != nil {
+		log.Printf("[RevokeOtherSessions] Erreur révocation des sessions de l'utilisateur %d : %v", userID, err)
+		return 0, err
+	}
+
+	revoked, _ := result.RowsAffected()
+	log.Printf("[RevokeOtherSessions] %d session(s) de l'utilisateur %d révoquée(s) (%s)", revoked, userID, reason)
+	return revoked, nil
+}
+
+// IsSessionActive indique si la session existe et n'a pas été révoquée.
+func IsSessionActive(sessionID int64) (bool, error) {
+	var active bool
+	err := database.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM auth_sessions WHERE id = $1 AND revoked_at IS NULL)
+	`, sessionID).Scan(&active)
+	if err != nil {
+		log.Printf("[IsSessionActive] Erreur vérification de la session %d : %v", sessionID, err)
+		return false, err
+	}
+	return active, nil
+}
+
+// TouchSession enregistre l'activité d'une session active. La mise à jour n'a lieu que si la précédente
+// date de plus de domain.SessionActivityInterval, pour ne pas écrire à chaque requête.
+func TouchSession(sessionID int64, ipAddress string, now time.Time) error {
+	_, err := database.DB.Exec(`
+		UPDATE auth_sessions
+		SET last_seen_at = $2, ip_address = COALESCE(NULLIF($3, ''), ip_address)
+		WHERE id = $1 AND revoked_at IS NULL AND last_seen_at <= $4
+	`, sessionID, now, ipAddress, now.Add(-domain.SessionActivityInterval))
+	if err != nil {
+		log.Printf("[TouchSession] Erreur mise à jour de l'activité de la session %d : %v", sessionID, err)
+	}
+	return err
+}
+
+// revokeSession révoque une session dans la transaction en cours.
+func revokeSession(tx *sql.Tx, sessionID int64, reason string) error {
+	if _, err := tx.Exec(`
+		UPDATE auth_sessions
+		SET revoked_at = NOW(), revoked_reason = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`, sessionID, reason); err != nil {
+		return fmt.Errorf("révocation de la session %d : %w", sessionID, err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// ProcessStripeEvent enregistre l'événement Stripe et applique ses effets dans une même transaction.
+// Retourne false si l'événement a déjà été traité (idempotence sur l'ID d'événement).
+func ProcessStripeEvent(eventID, eventType string, apply func(tx *sql.Tx) error) (bool, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[ProcessStripeEvent] Erreur ouverture transaction : %v", err)
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO stripe_events (event_id, type, processed_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (event_id) DO NOTHING
+	`, eventID, eventType)
+	if err != nil {
+		log.Printf("[ProcessStripeEvent] Erreur enregistrement événement %s : %v", eventID, err)
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if inserted == 0 {
+		log.Printf("[ProcessStripeEvent] Événement %s déjà traité, ignoré", eventID)
+		return false, nil
+	}
+
+	if err := apply(tx); err != nil {
+		log.Printf("[ProcessStripeEvent] Erreur traitement événement %s (%s) : %v", eventID, eventType, err)
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ProcessStripeEvent] Erreur commit événement %s : %v", eventID, err)
+		return false, err
+	}
+
+	log.Printf("[ProcessStripeEvent] Événement %s (%s) traité avec succès", eventID, eventType)
+	return true, nil
+}
+
+// MarkPaymentSucceeded passe le paiement en "succeeded" et active l'abonnement jusqu'à la fin de la période payée.
+// Le moyen de paiement est conservé pour les renouvellements et le cycle de relance est réinitialisé.
+// Un paiement ponctuel (sans abonnement) débloque le post acheté ou publie le pourboire dans sa conversation.
+// Le paiement est enregistré au grand livre des revenus du créateur. Seul un paiement en attente est
+// confirmé : un événement tardif ou rejoué ne rétablit pas un paiement échoué ou remboursé.
+func MarkPaymentSucceeded(tx *sql.Tx, stripePaymentID, paymentMethodID string) error {
+	var paymentID int64
+	var subscriptionID sql.NullInt64
+	var endAt time.Time
+	err := tx.QueryRow(`
+		UPDATE payments
+		SET status = $2
+		WHERE stripe_payment_id = $1 AND status = $3
+		RETURNING id, subscription_id, end_at
+	`, stripePaymentID, domain.PaymentStatusSucceeded, domain.PaymentStatusPending).Scan(&paymentID, &subscriptionID, &endAt)
+	if err == sql.ErrNoRows {
+		log.Printf("[MarkPaymentSucceeded] Aucun paiement en attente pour %s", stripePaymentID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("mise à jour du paiement %s : %w", stripePaymentID, err)
+	}
+
+	if !subscriptionID.Valid {
+		if err := PostPaymentToLedger(tx, paymentID); err != nil {
+			return err
+		}
+		if err := publishTipMessage(tx, paymentID); err != nil {
+			return err
+		}
+		log.Printf("[MarkPaymentSucceeded] Paiement ponctuel %s confirmé", stripePaymentID)
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE subscriptions
+		SET status = TRUE, end_at = GREATEST(end_at, $2),
+			renewal_attempts = 0, next_renewal_at = NULL,
+			payment_method_id = COALESCE(NULLIF($3, ''), payment_method_id)
+		WHERE id = $1
+	`, subscriptionID.Int64, endAt, paymentMethodID)
+	if err != nil {
+		return fmt.Errorf("activation de l'abonnement %d : %w", subscriptionID.Int64, err)
+	}
+
+	if err := PostPaymentToLedger(tx, paymentID); err != nil {
+		return err
+	}
+
+	log.Printf("[MarkPaymentSucceeded] Paiement %s confirmé, abonnement %d actif jusqu'au %s", stripePaymentID, subscriptionID.Int64, endAt.Format(time.RFC3339))
+	return nil
+}
+
+// SaveSubscriptionPaymentMethod enregistre le moyen de paiement confirmé par un SetupIntent (essai gratuit,
+// période offerte) pour les débits hors session des renouvellements.
+func SaveSubscriptionPaymentMethod(tx *sql.Tx, subscriptionID int64, paymentMethodID string) error {
+	_, err := tx.Exec(`UPDATE subscriptions SET payment_method_id = $2 WHERE id = $1`, subscriptionID, paymentMethodID)
+	if err != nil {
+		return fmt.Errorf("moyen de paiement de l'abonnement %d : %w", subscriptionID, err)
+	}
+
+	log.Printf("[SaveSubscriptionPaymentMethod] Moyen de paiement enregistré pour l'abonnement %d", subscriptionID)
+	return nil
+}
+
+// MarkPaymentFailed passe un paiement en attente en "failed". L'abonnement n'est pas prolongé et
+// le code promo éventuellement utilisé pour ce paiement est libéré.
+func MarkPaymentFailed(tx *sql.Tx, stripePaymentID string) error {
+	var paymentID int64
+	err := tx.QueryRow(`
+		UPDATE payments
+		SET status = $2
+		WHERE stripe_payment_id = $1 AND status = $3
+		RETURNING id
+	`, stripePaymentID, domain.PaymentStatusFailed, domain.PaymentStatusPending).Scan(&paymentID)
+	if err == sql.ErrNoRows {
+		log.Printf("[MarkPaymentFailed] Aucun paiement en attente pour %s", stripePaymentID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("échec du paiement %s : %w", stripePaymentID, err)
+	}
+	if err := releasePromoRedemption(tx, paymentID); err != nil {
+		return err
+	}
+
+	log.Printf("[MarkPaymentFailed] Paiement %s marqué comme échoué", stripePaymentID)
+	return nil
+}
+
+// MarkPaymentRefunded rapproche le montant remboursé cumulé (amountRefunded) d'un paiement :
+// la part non encore enregistrée donne lieu à une ligne de remboursement et à une écriture au grand livre.
+func MarkPaymentRefunded(tx *sql.Tx, stripePaymentID, refundID string, amountRefunded int64) error {
+	var paymentID int64
+	err := tx.QueryRow(`
+		SELECT id FROM payments
+		WHERE stripe_payment_id = $1 AND refund_of IS NULL
+		FOR UPDATE
+	`, stripePaymentID).Scan(&paymentID)
+	if err == sql.ErrNoRows {
+		log.Printf("[MarkPaymentRefunded] Aucun paiement trouvé pour %s", stripePaymentID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("lecture du paiement %s : %w", stripePaymentID, err)
+	}
+
+	refunded, err := refundedAmount(tx, paymentID)
+	if err != nil {
+		return fmt.Errorf("montant déjà remboursé du paiement %d : %w", paymentID, err)
+	}
+	if amountRefunded <= refunded {
+		log.Printf("[MarkPaymentRefunded] Remboursement de %s déjà enregistré", stripePaymentID)
+		return nil
+	}
+
+	_, err = applyRefund(tx, paymentID, refundID, amountRefunded-refunded, "")
+	return err
+}
+
+// EndSubscription désactive immédiatement un abonnement (résiliation côté Stripe).
+func EndSubscription(tx *sql.Tx, subscriptionID int64) error {
+	_, err := tx.Exec(`
+		UPDATE subscriptions
+		SET status = FALSE, end_at = LEAST(end_at, NOW())
+		WHERE id = $1
+	`, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("résiliation de l'abonnement %d : %w", subscriptionID, err)
+	}
+
+	log.Printf("[EndSubscription] Abonnement %d résilié", subscriptionID)
+	return nil
+}
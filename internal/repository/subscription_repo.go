@@ -7,11 +7,7 @@ import (
 	"log"
 	"onlyflick/internal/database"
 	"onlyflick/internal/domain"
-	"os"
 	"time"
-
-	"github.com/stripe/stripe-go"
-	"github.com/stripe/stripe-go/paymentintent"
 )
 
 // ===== STRUCTURES POUR LES ABONNEMENTS =====
@@ -25,6 +21,7 @@ type Subscription struct {
 	CreatedAt    time.Time `json:"created_at"`
 	EndAt        time.Time `json:"end_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	TierID       *int64    `json:"tier_id,omitempty"`
 }
 
 // ===== MÉTHODES POUR LES LISTES D'ABONNEMENTS =====
@@ -125,42 +122,17 @@ func GetUserFollowing(subscriberID int64) ([]Subscription, error) {
 
 // ===== MÉTHODES DE GESTION DES ABONNEMENTS =====
 
-// Subscribe permet à un utilisateur de s'abonner à un créateur.
-// Retourne l'abonnement créé ou une erreur.
-func Subscribe(subscriberID, creatorID int64) (*Subscription, error) {
-	if subscriberID == creatorID {
-		log.Printf("[Subscribe] L'utilisateur %d a tenté de s'abonner à lui-même.", subscriberID)
-		return nil, fmt.Errorf("vous ne pouvez pas vous abonner à vous-même")
-	}
-
-	// Vérifier qu'il n'y a pas déjà un abonnement actif
-	existingSubscription, err := GetActiveSubscription(subscriberID, creatorID)
-	if err != nil {
-		return nil, err
-	}
-	if existingSubscription != nil && existingSubscription.Status {
-		log.Printf("[Subscribe] Abonnement déjà existant et actif: %d", existingSubscription.ID)
-		// Convertir domain.Subscription vers notre structure
-		return &Subscription{
-			ID:           existingSubscription.ID,
-			SubscriberID: existingSubscription.SubscriberID,
-			CreatorID:    existingSubscription.CreatorID,
-			Status:       existingSubscription.Status,
-			CreatedAt:    existingSubscription.CreatedAt,
-			EndAt:        existingSubscription.EndAt,
-			UpdatedAt:    existingSubscription.CreatedAt,
-		}, nil
-	}
-
-	// Créer un nouvel abonnement
+// CreatePendingSubscription crée un abonnement inactif en attente de paiement, au palier donné
+// (nil = tarif de base). Il est activé par le webhook Stripe lorsque le paiement est confirmé.
+func CreatePendingSubscription(subscriberID, creatorID int64, tierID *int64) (*Subscription, error) {
 	var subscription Subscription
 	query := `
-		INSERT INTO subscriptions (subscriber_id, creator_id, created_at, end_at, status)
-		VALUES ($1, $2, NOW(), NOW() + INTERVAL '1 month', TRUE)
-		RETURNING id, subscriber_id, creator_id, status, created_at, end_at, created_at
+		INSERT INTO subscriptions (subscriber_id, creator_id, created_at, end_at, status, tier_id)
+		VALUES ($1, $2, NOW(), NOW(), FALSE, $3)
+		RETURNING id, subscriber_id, creator_id, status, created_at, end_at, created_at, tier_id
 	`
-	
-	err = database.DB.QueryRow(query, subscriberID, creatorID).Scan(
+
+	err := database.DB.QueryRow(query, subscriberID, creatorID, tierID).Scan(
 		&subscription.ID,
 		&subscription.SubscriberID,
 		&subscription.CreatorID,
@@ -168,13 +140,14 @@ func Subscribe(subscriberID, creatorID int64) (*Subscription, error) {
 		&subscription.CreatedAt,
 		&subscription.EndAt,
 		&subscription.UpdatedAt,
+		&subscription.TierID,
 	)
 	if err != nil {
-		log.Printf("[Subscribe] erreur lors de l'abonnement de %d à %d : %v", subscriberID, creatorID, err)
+		log.Printf("[CreatePendingSubscription] erreur lors de la création de l'abonnement %d -> %d : %v", subscriberID, creatorID, err)
 		return nil, err
 	}
 
-	log.Printf("[Subscribe] L'utilisateur %d s'est abonné à %d, abonnement ID: %d", subscriberID, creatorID, subscription.ID)
+	log.Printf("[CreatePendingSubscription] Abonnement %d en attente de paiement (%d -> %d)", subscription.ID, subscriberID, creatorID)
 	return &subscription, nil
 }
 
@@ -209,12 +182,119 @@ func Unsubscribe(subscriberID, creatorID int64) error {
 	return nil
 }
 
-// IsSubscribed vérifie si un utilisateur est abonné à un créateur.
+// SetAutoRenew active ou désactive le renouvellement automatique d'un abonnement
+// (résiliation à la fin de la période, ou reprise).
+func SetAutoRenew(subscriptionID int64, autoRenew bool) error {
+	_, err := database.DB.Exec(`
+		UPDATE subscriptions
+		SET auto_renew = $2, renewal_attempts = 0, next_renewal_at = NULL
+		WHERE id = $1
+	`, subscriptionID, autoRenew)
+	if err != nil {
+		log.Printf("[SetAutoRenew] erreur mise à jour du renouvellement de l'abonnement %d : %v", subscriptionID, err)
+		return err
+	}
+
+	log.Printf("[SetAutoRenew] Renouvellement automatique de l'abonnement %d : %v", subscriptionID, autoRenew)
+	return nil
+}
+
+// EndSubscriptionNow met fin immédiatement à un abonnement, sans renouvellement.
+func EndSubscriptionNow(subscriptionID int64, now time.Time) error {
+	_, err := database.DB.Exec(`
+		UPDATE subscriptions
+		SET status = FALSE, auto_renew = FALSE, end_at = LEAST(end_at, $2)
+		WHERE id = $1
+	`, subscriptionID, now)
+	if err != nil {
+		log.Printf("[EndSubscriptionNow] erreur résiliation de l'abonnement %d : %v", subscriptionID, err)
+		return err
+	}
+
+	log.Printf("[EndSubscriptionNow] Abonnement %d résilié", subscriptionID)
+	return nil
+}
+
+// StartTrialPeriod active un abonnement en essai gratuit jusqu'à trialEnd. Le code promo éventuel
+// s'appliquera au premier débit, à la fin de l'essai ; son utilisation (redemption) est enregistrée
+// dans la même transaction que l'activation.
+func StartTrialPeriod(subscriptionID int64, trialEnd time.Time, promoCodeID *int64, redemption *PromoRedemption) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE subscriptions
+		SET status = TRUE, auto_renew = TRUE, end_at = $2, trial_end_at = $2, promo_code_id = $3,
+			renewal_attempts = 0, next_renewal_at = NULL
+		WHERE id = $1
+	`, subscriptionID, trialEnd, promoCodeID)
+	if err != nil {
+		log.Printf("[StartTrialPeriod] erreur activation de l'essai de l'abonnement %d : %v", subscriptionID, err)
+		return err
+	}
+	if redemption != nil {
+		if err := redeemPromoCode(tx, *redemption, nil); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("[StartTrialPeriod] Abonnement %d en essai jusqu'au %s", subscriptionID, trialEnd.Format(time.RFC3339))
+	return nil
+}
+
+// GrantFreePeriod active ou prolonge un abonnement jusqu'à endAt sans paiement (période offerte par un code promo).
+// L'utilisation du code (redemption, nil pour un renouvellement) est enregistrée dans la même transaction.
+func GrantFreePeriod(subscriptionID int64, endAt time.Time, redemption *PromoRedemption) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE subscriptions
+		SET status = TRUE, end_at = GREATEST(end_at, $2), renewal_attempts = 0, next_renewal_at = NULL
+		WHERE id = $1
+	`, subscriptionID, endAt)
+	if err != nil {
+		log.Printf("[GrantFreePeriod] erreur prolongation de l'abonnement %d : %v", subscriptionID, err)
+		return err
+	}
+	if redemption != nil {
+		if err := redeemPromoCode(tx, *redemption, nil); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("[GrantFreePeriod] Abonnement %d offert jusqu'au %s", subscriptionID, endAt.Format(time.RFC3339))
+	return nil
+}
+
+// SetSubscriptionPromo définit le code promo à appliquer aux prochains débits d'un abonnement (nil = plein tarif).
+func SetSubscriptionPromo(subscriptionID int64, promoCodeID *int64) error {
+	_, err := database.DB.Exec(`UPDATE subscriptions SET promo_code_id = $2 WHERE id = $1`, subscriptionID, promoCodeID)
+	if err != nil {
+		log.Printf("[SetSubscriptionPromo] erreur mise à jour du code promo de l'abonnement %d : %v", subscriptionID, err)
+		return err
+	}
+	return nil
+}
+
+// IsSubscribed vérifie si un utilisateur est abonné à un créateur (abonnement actif et période non échue).
 func IsSubscribed(subscriberID, creatorID int64) (bool, error) {
 	query := `
 		SELECT EXISTS (
 			SELECT 1 FROM subscriptions
-			WHERE subscriber_id = $1 AND creator_id = $2 AND status = TRUE
+			WHERE subscriber_id = $1 AND creator_id = $2 AND status = TRUE AND end_at > NOW()
 		);
 	`
 	var exists bool
@@ -228,12 +308,13 @@ func IsSubscribed(subscriberID, creatorID int64) (bool, error) {
 }
 
 // GetActiveSubscription récupère l'abonnement actif ou inactif d'un utilisateur pour un créateur donné.
+// Un abonnement dont la période est échue est retourné comme inactif.
 func GetActiveSubscription(subscriberID, creatorID int64) (*domain.Subscription, error) {
 	log.Printf("[GetActiveSubscription] Vérification abonnement %d -> %d", subscriberID, creatorID)
 
 	var subscription domain.Subscription
 	query := `
-		SELECT id, subscriber_id, creator_id, created_at, end_at, status
+		SELECT id, subscriber_id, creator_id, created_at, end_at, (status AND end_at > NOW()) AS status, tier_id, auto_renew
 		FROM subscriptions
 		WHERE subscriber_id = $1 AND creator_id = $2
 		ORDER BY created_at DESC
@@ -247,6 +328,8 @@ func GetActiveSubscription(subscriberID, creatorID int64) (*domain.Subscription,
 		&subscription.CreatedAt, 
 		&subscription.EndAt, 
 		&subscription.Status,
+		&subscription.TierID,
+		&subscription.AutoRenew,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -261,8 +344,10 @@ func GetActiveSubscription(subscriberID, creatorID int64) (*domain.Subscription,
 	return &subscription, nil
 }
 
-// ReactivateSubscription réactive un abonnement si la date de fin est supérieure à la date actuelle
-func ReactivateSubscription(subscriptionID int64, today time.Time) error {
+// ReactivateSubscription réactive un abonnement si la date de fin est supérieure à la date actuelle.
+// Retourne false si la période est échue : un nouveau paiement est alors nécessaire
+// et l'abonnement ne sera réactivé qu'à sa confirmation par le webhook Stripe.
+func ReactivateSubscription(subscriptionID int64, today time.Time) (bool, error) {
 	log.Printf("[ReactivateSubscription] Réactivation abonnement %d", subscriptionID)
 
 	// Récupérer l'abonnement
@@ -274,68 +359,43 @@ func ReactivateSubscription(subscriptionID int64, today time.Time) error {
 	`, subscriptionID).Scan(&subscription.ID, &subscription.SubscriberID, &subscription.CreatorID, &subscription.EndAt, &subscription.Status)
 	if err != nil {
 		log.Printf("[ReactivateSubscription] erreur lors de la récupération de l'abonnement %d : %v", subscriptionID, err)
-		return fmt.Errorf("erreur lors de la récupération de l'abonnement")
+		return false, fmt.Errorf("erreur lors de la récupération de l'abonnement")
+	}
+
+	// Si la date de fin est dépassée, on doit procéder à un paiement
+	if !subscription.EndAt.After(today) {
+		log.Printf("[ReactivateSubscription] Abonnement %d échu, paiement requis", subscriptionID)
+		return false, nil
 	}
 
 	// Si l'abonnement est déjà actif et que la date de fin est dans le futur, aucune action n'est nécessaire
-	if subscription.Status && subscription.EndAt.After(today) {
+	if subscription.Status {
 		log.Printf("[ReactivateSubscription] Abonnement %d déjà actif, aucune action requise", subscriptionID)
-		return nil
+		return true, nil
 	}
 
 	// Si l'abonnement est inactif mais que la date de fin est dans le futur, on réactive l'abonnement
-	if !subscription.Status && subscription.EndAt.After(today) {
-		_, err = database.DB.Exec(`
-			UPDATE subscriptions
-			SET status = true
-			WHERE id = $1
-		`, subscriptionID)
-		if err != nil {
-			log.Printf("[ReactivateSubscription] erreur lors de la réactivation de l'abonnement %d : %v", subscriptionID, err)
-			return fmt.Errorf("erreur lors de la réactivation de l'abonnement")
-		}
-
-		log.Printf("[ReactivateSubscription] Abonnement %d réactivé avec succès", subscriptionID)
-		return nil
-	}
-
-	// Si l'abonnement est inactif et que la date de fin est dans le passé, on doit procéder à un paiement
-	stripe.Key = os.Getenv("STRIPE_SECRET_KEY")
-
-	// Créer un PaymentIntent via Stripe
-	intentParams := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(499), // Montant en centimes (499 = 4.99€)
-		Currency: stripe.String(string(stripe.CurrencyEUR)),
-	}
-
-	intent, err := paymentintent.New(intentParams)
+	_, err = database.DB.Exec(`
+		UPDATE subscriptions
+		SET status = true, auto_renew = true
+		WHERE id = $1
+	`, subscriptionID)
 	if err != nil {
-		log.Printf("[ReactivateSubscription] Erreur Stripe lors de la création du PaymentIntent : %v", err)
-		return fmt.Errorf("erreur de paiement Stripe")
+		log.Printf("[ReactivateSubscription] erreur lors de la réactivation de l'abonnement %d : %v", subscriptionID, err)
+		return false, fmt.Errorf("erreur lors de la réactivation de l'abonnement")
 	}
 
-	// Ajouter Metadata après la création du PaymentIntent
-	intent.Metadata = map[string]string{"subscription_id": fmt.Sprintf("%d", subscriptionID)}
+	log.Printf("[ReactivateSubscription] Abonnement %d réactivé avec succès", subscriptionID)
+	return true, nil
+}
 
-	// Enregistrer le paiement dans la base de données
-	_, err = CreatePayment(subscriptionID, intent.ID, fmt.Sprintf("%d", subscription.SubscriberID), today, today.AddDate(0, 1, 0), 499, "succeeded")
+// SetSubscriptionTier change le palier d'un abonnement (nil = tarif de base du créateur).
+func SetSubscriptionTier(subscriptionID int64, tierID *int64) error {
+	_, err := database.DB.Exec(`UPDATE subscriptions SET tier_id = $1 WHERE id = $2`, tierID, subscriptionID)
 	if err != nil {
-		log.Printf("[ReactivateSubscription] erreur d'enregistrement du paiement : %v", err)
-		return fmt.Errorf("erreur d'enregistrement du paiement")
-	}
-
-	// Prolonger l'abonnement de 1 mois
-	_, err = database.DB.Exec(`
-		UPDATE subscriptions
-		SET end_at = $1, status = true
-		WHERE id = $2
-	`, today.AddDate(0, 1, 0), subscriptionID)
-	if err != nil {
-		log.Printf("[ReactivateSubscription] erreur lors de la mise à jour de l'abonnement %d : %v", subscriptionID, err)
-		return fmt.Errorf("erreur lors de la mise à jour de l'abonnement")
+		log.Printf("[SetSubscriptionTier] erreur mise à jour du palier de l'abonnement %d : %v", subscriptionID, err)
+		return err
 	}
-
-	log.Printf("[ReactivateSubscription] Abonnement %d réactivé avec succès et payé", subscriptionID)
 	return nil
 }
 
@@ -365,7 +425,7 @@ func ListMySubscriptions(subscriberID int64) ([]domain.Subscription, error) {
 	log.Printf("[ListMySubscriptions] Récupération abonnements pour utilisateur %d", subscriberID)
 
 	query := `
-		SELECT id, subscriber_id, creator_id, created_at, end_at, status
+		SELECT id, subscriber_id, creator_id, created_at, end_at, status, tier_id, auto_renew
 		FROM subscriptions
 		WHERE subscriber_id = $1
 		ORDER BY created_at DESC;
@@ -380,7 +440,7 @@ func ListMySubscriptions(subscriberID int64) ([]domain.Subscription, error) {
 	var subscriptions []domain.Subscription
 	for rows.Next() {
 		var s domain.Subscription
-		if err := rows.Scan(&s.ID, &s.SubscriberID, &s.CreatorID, &s.CreatedAt, &s.EndAt, &s.Status); err != nil {
+		if err := rows.Scan(&s.ID, &s.SubscriberID, &s.CreatorID, &s.CreatedAt, &s.EndAt, &s.Status, &s.TierID, &s.AutoRenew); err != nil {
 			log.Printf("[ListMySubscriptions] erreur lors du scan d'un abonnement : %v", err)
 			return nil, err
 		}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// ErrSuspensionNotFound est retournée lorsqu'aucune suspension active ne concerne l'utilisateur.
+var ErrSuspensionNotFound = errors.New("aucune suspension active")
+
+// activeSuspensionSQL retourne la condition SQL vraie quand l'utilisateur de la colonne userColumn
+// est suspendu à l'instant NOW() (masquage de ses posts dans les fils et la recherche).
+func activeSuspensionSQL(userColumn string) string {
+	return `EXISTS (
+		SELECT 1 FROM user_suspensions us
+		WHERE us.user_id = ` + userColumn + ` AND us.lifted_at IS NULL
+			AND (us.permanent OR us.ends_at > NOW())
+	)`
+}
+
+const suspensionColumns = `id, user_id, admin_id, reason, permanent, ends_at, created_at, lifted_at, lifted_by`
+
+type suspensionScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSuspension(row suspensionScanner, extra ...interface{}) (*domain.UserSuspension, error) {
+	var s domain.UserSuspension
+	var adminID, liftedBy sql.NullInt64
+	var endsAt, liftedAt sql.NullTime
+	dest := append([]interface{}{&s.ID, &s.UserID, &adminID, &s.Reason, &s.Permanent, &endsAt, &s.CreatedAt, &liftedAt, &liftedBy}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if adminID.Valid {
+		s.AdminID = &adminID.Int64
+	}
+	if endsAt.Valid {
+		s.EndsAt = &endsAt.Time
+	}
+	if liftedAt.Valid {
+		s.LiftedAt = &liftedAt.Time
+	}
+	if liftedBy.Valid {
+		s.LiftedBy = &liftedBy.Int64
+	}
+	return &s, nil
+}
+
+// suspensionAuditState décrit une suspension pour le journal d'audit (nil en l'absence de suspension).
+func suspensionAuditState(s *domain.UserSuspension) interface{} {
+	if s == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"suspension_id": s.ID,
+		"reason":        s.Reason,
+		"permanent":     s.Permanent,
+		"ends_at":       s.EndsAt,
+		"lifted_at":     s.LiftedAt,
+	}
+}
+
+// CreateSuspension enregistre une suspension prononcée par actor ; une suspension non levée du même
+// utilisateur est levée par l'administrateur dans la même transaction (remplacement), qui journalise l'action.
+func CreateSuspension(s *domain.UserSuspension, now time.Time, actor domain.AuditActor) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	s.AdminID = &actor.ActorID
+	previous, err := scanSuspension(tx.QueryRow(`
+		UPDATE user_suspensions SET lifted_at = $2, lifted_by = $3
+		WHERE user_id = $1 AND lifted_at IS NULL
+		RETURNING `+suspensionColumns, s.UserID, now, actor.ActorID))
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("[CreateSuspension] Erreur remplacement de la suspension de l'utilisateur %d : %v", s.UserID, err)
+		return err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO user_suspensions (user_id, admin_id, reason, permanent, ends_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, s.UserID, s.AdminID, s.Reason, s.Permanent, s.EndsAt, now).Scan(&s.ID)
+	if err != nil {
+		log.Printf("[CreateSuspension] Erreur suspension de l'utilisateur %d : %v", s.UserID, err)
+		return err
+	}
+	s.CreatedAt = now
+
+	if err := recordAudit(tx, &actor, domain.AuditUserSuspend, domain.AuditTargetUser, s.UserID,
+		suspensionAuditState(previous), suspensionAuditState(s)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LiftSuspension lève la suspension active d'un utilisateur au nom de actor, journalise la levée
+// dans la même transaction et retourne la suspension levée.
+func LiftSuspension(userID int64, actor domain.AuditActor, now time.Time) (*domain.UserSuspension, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := scanSuspension(tx.QueryRow(`
+		UPDATE user_suspensions SET lifted_at = $2, lifted_by = $3
+		WHERE user_id = $1 AND lifted_at IS NULL AND (permanent OR ends_at > $2)
+		RETURNING `+suspensionColumns, userID, now, actor.ActorID))
+	if err == sql.ErrNoRows {
+		return nil, ErrSuspensionNotFound
+	}
+	if err != nil {
+		log.Printf("[LiftSuspension] Erreur levée de la suspension de l'utilisateur %d : %v", userID, err)
+		return nil, err
+	}
+
+	before := *s
+	before.LiftedAt = nil
+	if err := recordAudit(tx, &actor, domain.AuditUserUnsuspend, domain.AuditTargetUser, userID,
+		suspensionAuditState(&before), suspensionAuditState(s)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetActiveSuspension retourne la suspension en vigueur de l'utilisateur, nil s'il n'est pas suspendu.
+func GetActiveSuspension(userID int64, now time.Time) (*domain.UserSuspension, error) {
+	s, err := scanSuspension(database.DB.QueryRow(`
+		SELECT `+suspensionColumns+` FROM user_suspensions
+		WHERE user_id = $1 AND lifted_at IS NULL AND (permanent OR ends_at > $2)
+	`, userID, now))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[GetActiveSuspension] Erreur lecture de la suspension de l'utilisateur %d : %v", userID, err)
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListSuspensions retourne les suspensions, les plus récentes d'abord, limitées aux suspensions
+// en vigueur si activeOnly est vrai et à un utilisateur si userID est non nul, avec leur total.
+func ListSuspensions(activeOnly bool, userID int64, now time.Time, limit, offset int) ([]domain.UserSuspension, int, error) {
+	rows, err := database.DB.Query(`
+		SELECT s.id, s.user_id, s.admin_id, s.reason, s.permanent, s.ends_at, s.created_at, s.lifted_at, s.lifted_by,
+			COALESCE(u.username, ''), COUNT(*) OVER()
+		FROM user_suspensions s
+		JOIN users u ON u.id = s.user_id
+		WHERE ($1 = 0 OR s.user_id = $1)
+			AND (NOT $2 OR (s.lifted_at IS NULL AND (s.permanent OR s.ends_at > $3)))
+		ORDER BY s.created_at DESC, s.id DESC
+		LIMIT $4 OFFSET $5
+	`, userID, activeOnly, now, limit, offset)
+	if err != nil {
+		log.Printf("[ListSuspensions] Erreur lecture des suspensions : %v", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	suspensions := []domain.UserSuspension{}
+	total := 0
+	for rows.Next() {
+		var username string
+		s, err := scanSuspension(rows, &username, &total)
+		if err != nil {
+			return nil, 0, err
+		}
+		s.Username = username
+		suspensions = append(suspensions, *s)
+	}
+	return suspensions, total, rows.Err()
+}
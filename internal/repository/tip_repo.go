@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"time"
+)
+
+// CreateTip enregistre un pourboire : paiement ponctuel en attente au bénéfice du créateur et ligne de pourboire
+// associée. Le webhook du fournisseur confirmera le paiement et publiera le pourboire.
+func CreateTip(tip *domain.Tip, stripePaymentID string) error {
+	now := time.Now()
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("[CreateTip] Erreur ouverture transaction : %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO payments (kind, creator_id, stripe_payment_id, payer_id, start_at, end_at, amount, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $5, $6, $7, NOW())
+		RETURNING id
+	`, domain.PaymentKindTip, tip.CreatorID, stripePaymentID, fmt.Sprintf("%d", tip.SenderID), now,
+		tip.Amount, domain.PaymentStatusPending).Scan(&tip.PaymentID)
+	if err != nil {
+		log.Printf("[CreateTip] Erreur enregistrement du paiement du pourboire de %d : %v", tip.SenderID, err)
+		return err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO tips (sender_id, creator_id, payment_id, amount, message, post_id, conversation_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING id, created_at
+	`, tip.SenderID, tip.CreatorID, tip.PaymentID, tip.Amount, tip.Message, tip.PostID, tip.ConversationID).Scan(&tip.ID, &tip.CreatedAt)
+	if err != nil {
+		log.Printf("[CreateTip] Erreur enregistrement du pourboire de %d : %v", tip.SenderID, err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[CreateTip] Erreur commit du pourboire de %d : %v", tip.SenderID, err)
+		return err
+	}
+
+	log.Printf("[CreateTip] Pourboire %d de %d pour le créateur %d en attente (%d centimes)", tip.ID, tip.SenderID, tip.CreatorID, tip.Amount)
+	return nil
+}
+
+// publishTipMessage ajoute au fil de la conversation le pourboire réglé par le paiement paymentID.
+// Sans effet pour un paiement qui n'est pas un pourboire envoyé dans une conversation, ou déjà publié.
+func publishTipMessage(tx *sql.Tx, paymentID int64) error {
+	result, err := tx.Exec(`
+		INSERT INTO messages (conversation_id, sender_id, content, kind, tip_id, created_at)
+		SELECT t.conversation_id, t.sender_id, t.message, $2, t.id, NOW()
+		FROM tips t
+		WHERE t.payment_id = $1 AND t.conversation_id IS NOT NULL
+		ON CONFLICT (tip_id) WHERE tip_id IS NOT NULL DO NOTHING
+	`, paymentID, domain.MessageKindTip)
+	if err != nil {
+		return fmt.Errorf("publication du pourboire du paiement %d : %w", paymentID, err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		log.Printf("[publishTipMessage] Pourboire du paiement %d publié dans la conversation", paymentID)
+	}
+	return nil
+}
+
+// ListPostTips retourne les pourboires réglés reçus sur un post, du plus récent au plus ancien.
+func ListPostTips(postID int64) (*domain.PostTips, error) {
+	rows, err := database.DB.Query(`
+		SELECT t.id, t.sender_id, t.creator_id, t.payment_id, t.amount, t.message, t.post_id, t.conversation_id,
+			COALESCE(u.username, ''), t.created_at
+		FROM tips t
+		JOIN payments pay ON pay.id = t.payment_id
+		LEFT JOIN users u ON u.id = t.sender_id
+		WHERE t.post_id = $1 AND pay.status IN ($2, $3)
+		ORDER BY t.created_at DESC, t.id DESC
+	`, postID, domain.PaymentStatusSucceeded, domain.PaymentStatusPartiallyRefunded)
+	if err != nil {
+		log.Printf("[ListPostTips] Erreur récupération des pourboires du post %d : %v", postID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &domain.PostTips{PostID: postID, Tips: []domain.Tip{}}
+	for rows.Next() {
+		var t domain.Tip
+		if err := rows.Scan(&t.ID, &t.SenderID, &t.CreatorID, &t.PaymentID, &t.Amount, &t.Message, &t.PostID, &t.ConversationID,
+			&t.SenderUsername, &t.CreatedAt); err != nil {
+			log.Printf("[ListPostTips] Erreur scan d'un pourboire : %v", err)
+			return nil, err
+		}
+		result.Count++
+		result.TotalAmount += t.Amount
+		result.Tips = append(result.Tips, t)
+	}
+
+	return result, rows.Err()
+}
@@ -8,6 +8,8 @@ import (
 	"onlyflick/internal/domain"
 	"onlyflick/internal/utils"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // ===== STRUCTURES DE DONNÉES PROFIL =====
@@ -28,6 +30,8 @@ type UserPost struct {
 	ImageURL      string `json:"image_url,omitempty"`
 	VideoURL      string `json:"video_url,omitempty"`
 	Visibility    string `json:"visibility"`
+	Price         int    `json:"price,omitempty"` // Prix de déblocage (posts payants)
+	Locked        bool   `json:"locked"`          // Média masqué tant que le lecteur n'a pas acheté le post
 	LikesCount    int    `json:"likes_count"`
 	CommentsCount int    `json:"comments_count"`
 	CreatedAt     string `json:"created_at"`
@@ -50,14 +54,24 @@ type UpdateUserPayload struct {
 // Crée un nouvel utilisateur dans la base de données AVEC USERNAME
 func CreateUser(user *domain.User) error {
 	query := `
-		INSERT INTO users (first_name, last_name, username, email, password, role, avatar_url, bio)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO users (first_name, last_name, username, email, password, role, avatar_url, bio, email_bidx, name_bidx)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at, updated_at
 	`
 	log.Printf("[CreateUser] Création de l'utilisateur: %s %s, username: %s, email: %s",
 		user.FirstName, user.LastName, user.Username, user.Email)
 
-	err := database.DB.QueryRow(
+	// Index aveugles pour la recherche sans déchiffrement
+	emailIndex, err := userEmailIndex(user.Email)
+	if err != nil {
+		return err
+	}
+	nameIndexes, err := userNameIndexes(user.FirstName, user.LastName)
+	if err != nil {
+		return err
+	}
+
+	err = database.DB.QueryRow(
 		query,
 		user.FirstName, // $1 (chiffré)
 		user.LastName,  // $2 (chiffré)
@@ -67,6 +81,8 @@ func CreateUser(user *domain.User) error {
 		user.Role,      // $6
 		user.AvatarURL, // $7
 		user.Bio,       // $8
+		emailIndex,     // $9 (index aveugle)
+		pq.Array(nameIndexes),
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
@@ -81,10 +97,12 @@ func CreateUser(user *domain.User) error {
 func GetUserByEmail(email string) (*domain.User, error) {
 	log.Printf("[GetUserByEmail] Recherche de l'utilisateur avec l'email: %s", email)
 
+	// L'index aveugle cible la ligne sans déchiffrer la table (index complétés au démarrage du serveur)
 	rows, err := database.DB.Query(`
 		SELECT id, first_name, last_name, username, email, password, role, avatar_url, bio, created_at, updated_at 
 		FROM users
-	`)
+		WHERE email_bidx = $1
+	`, utils.EmailBlindIndex(email))
 	if err != nil {
 		log.Printf("[GetUserByEmail][ERREUR] Erreur lors de la requête SQL: %v", err)
 		return nil, fmt.Errorf("erreur lors de la requête des utilisateurs: %v", err)
@@ -126,18 +144,15 @@ func GetUserByEmail(email string) (*domain.User, error) {
 
 		decryptedEmail, err := utils.DecryptAES(user.Email)
 		if err != nil {
-			log.Printf("[GetUserByEmail][ERREUR] Erreur lors du décryptage de l'email: %v", err)
-			return nil, fmt.Errorf("erreur lors du décryptage de l'email: %v", err)
+			// Une ligne illisible (clé retirée du trousseau) ne doit pas bloquer les autres comptes
+			log.Printf("[GetUserByEmail][ERREUR] Erreur lors du décryptage de l'email de l'utilisateur %d: %v", user.ID, err)
+			continue
 		}
 
-		if decryptedEmail == email {
+		if utils.NormalizeEmail(decryptedEmail) == utils.NormalizeEmail(email) {
 			// Décrypter les autres champs pour le retour
-			if decryptedFirstName, err := utils.DecryptAES(user.FirstName); err == nil {
-				user.FirstName = decryptedFirstName
-			}
-			if decryptedLastName, err := utils.DecryptAES(user.LastName); err == nil {
-				user.LastName = decryptedLastName
-			}
+			user.FirstName = utils.DecryptField(user.FirstName)
+			user.LastName = utils.DecryptField(user.LastName)
 			user.Email = decryptedEmail
 
 			log.Printf("[GetUserByEmail] Utilisateur trouvé pour l'email: %s (ID: %d, Username: %s)",
@@ -189,15 +204,9 @@ func GetUserByID(userID int64) (*domain.User, error) {
 	}
 
 	// Décryption des champs chiffrés
-	if decryptedFirstName, err := utils.DecryptAES(user.FirstName); err == nil {
-		user.FirstName = decryptedFirstName
-	}
-	if decryptedLastName, err := utils.DecryptAES(user.LastName); err == nil {
-		user.LastName = decryptedLastName
-	}
-	if decryptedEmail, err := utils.DecryptAES(user.Email); err == nil {
-		user.Email = decryptedEmail
-	}
+	user.FirstName = utils.DecryptField(user.FirstName)
+	user.LastName = utils.DecryptField(user.LastName)
+	user.Email = utils.DecryptField(user.Email)
 
 	// ===== CORRECTION : Assignation des nouveaux champs (non chiffrés) =====
 	if avatarURL.Valid {
@@ -251,27 +260,15 @@ func GetUserByUsername(username string) (*domain.User, error) {
 
 	// Décryptage des champs chiffrés
 	if firstName.Valid {
-		if decryptedFirstName, err := utils.DecryptAES(firstName.String); err == nil {
-			user.FirstName = decryptedFirstName
-		} else {
-			user.FirstName = firstName.String // Fallback
-		}
+		user.FirstName = utils.DecryptField(firstName.String)
 	}
 
 	if lastName.Valid {
-		if decryptedLastName, err := utils.DecryptAES(lastName.String); err == nil {
-			user.LastName = decryptedLastName
-		} else {
-			user.LastName = lastName.String // Fallback
-		}
+		user.LastName = utils.DecryptField(lastName.String)
 	}
 
 	if email.Valid {
-		if decryptedEmail, err := utils.DecryptAES(email.String); err == nil {
-			user.Email = decryptedEmail
-		} else {
-			user.Email = email.String // Fallback
-		}
+		user.Email = utils.DecryptField(email.String)
 	}
 
 	// Champs non chiffrés
@@ -318,8 +315,33 @@ func UpdateUser(userID int64, payload UpdateUserPayload) error {
 		paramIndex++
 	}
 	if payload.Email != nil {
-		query += fmt.Sprintf(" email = $%d,", paramIndex)
-		params = append(params, *payload.Email)
+		emailIndex, err := userEmailIndex(*payload.Email)
+		if err != nil {
+			return err
+		}
+		query += fmt.Sprintf(" email = $%d, email_bidx = $%d,", paramIndex, paramIndex+1)
+		params = append(params, *payload.Email, emailIndex)
+		paramIndex += 2
+	}
+	if payload.FirstName != nil || payload.LastName != nil {
+		// Les index des noms portent sur le prénom et le nom : on complète avec la valeur actuelle
+		var firstName, lastName string
+		if err := database.DB.QueryRow(`SELECT first_name, last_name FROM users WHERE id = $1`, userID).Scan(&firstName, &lastName); err != nil {
+			log.Printf("[UpdateUser][ERREUR] Lecture des noms actuels de l'utilisateur (ID: %d): %v", userID, err)
+			return fmt.Errorf("utilisateur non trouvé")
+		}
+		if payload.FirstName != nil {
+			firstName = *payload.FirstName
+		}
+		if payload.LastName != nil {
+			lastName = *payload.LastName
+		}
+		nameIndexes, err := userNameIndexes(firstName, lastName)
+		if err != nil {
+			return err
+		}
+		query += fmt.Sprintf(" name_bidx = $%d,", paramIndex)
+		params = append(params, pq.Array(nameIndexes))
 		paramIndex++
 	}
 	if payload.Password != nil {
@@ -368,17 +390,6 @@ func UpdateUser(userID int64, payload UpdateUserPayload) error {
 	return nil
 }
 
-// Supprime un utilisateur de la base de données.
-func DeleteUser(userID int64) error {
-	log.Printf("[DeleteUser] Suppression de l'utilisateur (ID: %d)", userID)
-
-	_, err := database.DB.Exec(`DELETE FROM users WHERE id = $1`, userID)
-	if err != nil {
-		log.Printf("[DeleteUser][ERREUR] Erreur lors de la suppression de l'utilisateur (ID: %d): %v", userID, err)
-	}
-	return err
-}
-
 // ===== FONCTIONS PROFIL AVANCÉES =====
 
 // GetProfileStats récupère les statistiques d'un profil utilisateur
@@ -431,9 +442,10 @@ func GetProfileStats(userID int64) (*ProfileStats, error) {
 	// 5. Revenus totaux
 	var totalEarnings sql.NullFloat64
 	err = database.DB.QueryRow(`
-		SELECT COALESCE(SUM(pay.amount), 0) FROM payments pay 
-		JOIN subscriptions sub ON pay.subscription_id = sub.id 
-		WHERE sub.creator_id = $1 AND sub.status = true AND pay.status = 'success'
+		SELECT COALESCE(SUM(CASE WHEN pay.status = 'refund' THEN -pay.amount ELSE pay.amount END), 0) FROM payments pay 
+		LEFT JOIN subscriptions sub ON pay.subscription_id = sub.id 
+		WHERE COALESCE(pay.creator_id, sub.creator_id) = $1 AND (sub.id IS NULL OR sub.status = true)
+			AND pay.status IN ('succeeded', 'partially_refunded', 'refunded', 'refund')
 	`, userID).Scan(&totalEarnings)
 	if err != nil {
 		log.Printf("[GetProfileStats][ERROR] Erreur récupération earnings: %v", err)
@@ -452,8 +464,9 @@ func GetProfileStats(userID int64) (*ProfileStats, error) {
 	return &stats, nil
 }
 
-// GetUserPosts récupère les posts d'un utilisateur avec pagination
-func GetUserPosts(userID int64, page, limit int, postType string) ([]*UserPost, error) {
+// GetUserPosts récupère les posts d'un utilisateur avec pagination.
+// Les posts payants non débloqués par le lecteur (viewerID) sont retournés sans média.
+func GetUserPosts(userID int64, page, limit int, postType string, viewerID int64) ([]*UserPost, error) {
 	log.Printf("[GetUserPosts] Récupération posts pour user %d (page=%d, limit=%d, type=%s)", userID, page, limit, postType)
 
 	offset := (page - 1) * limit
@@ -465,6 +478,8 @@ func GetUserPosts(userID int64, page, limit int, postType string) ([]*UserPost,
 			COALESCE(p.media_url, '') as image_url,
 			'' as video_url,
 			p.visibility,
+			p.price,
+			` + postLockedSQL("$4") + ` as locked,
 			p.created_at
 		FROM posts p
 		WHERE p.user_id = $1
@@ -476,7 +491,7 @@ func GetUserPosts(userID int64, page, limit int, postType string) ([]*UserPost,
 
 	switch postType {
 	case "public":
-		query = baseQuery + " AND p.visibility = 'public'"
+		query = baseQuery + " AND p.visibility IN ('public', 'ppv')"
 		args = []interface{}{userID}
 	case "subscriber":
 		query = baseQuery + " AND p.visibility = 'subscriber'"
@@ -487,7 +502,7 @@ func GetUserPosts(userID int64, page, limit int, postType string) ([]*UserPost,
 	}
 
 	query += " ORDER BY p.created_at DESC LIMIT $2 OFFSET $3"
-	args = append(args, limit, offset)
+	args = append(args, limit, offset, viewerID)
 
 	rows, err := database.DB.Query(query, args...)
 	if err != nil {
@@ -508,6 +523,8 @@ func GetUserPosts(userID int64, page, limit int, postType string) ([]*UserPost,
 			&imageURL,
 			&videoURL,
 			&post.Visibility,
+			&post.Price,
+			&post.Locked,
 			&createdAt,
 		)
 
@@ -523,6 +540,10 @@ func GetUserPosts(userID int64, page, limit int, postType string) ([]*UserPost,
 		if videoURL.Valid {
 			post.VideoURL = videoURL.String
 		}
+		if post.Locked {
+			post.ImageURL = ""
+			post.VideoURL = ""
+		}
 
 		post.CreatedAt = createdAt.Format(time.RFC3339)
 		
@@ -690,7 +711,7 @@ func GetUserPostsCountByType(userID int64, postType string) (int, error) {
 
 	switch postType {
 	case "public":
-		query = `SELECT COUNT(*) FROM posts WHERE user_id = $1 AND visibility = 'public'`
+		query = `SELECT COUNT(*) FROM posts WHERE user_id = $1 AND visibility IN ('public', 'ppv')`
 		args = []interface{}{userID}
 	case "subscriber":
 		query = `SELECT COUNT(*) FROM posts WHERE user_id = $1 AND visibility = 'subscriber'`
@@ -749,15 +770,9 @@ func GetAllUsers() ([]*domain.User, error) {
 		}
 
 		// Décryptage des champs chiffrés
-		if decryptedFirstName, err := utils.DecryptAES(user.FirstName); err == nil {
-			user.FirstName = decryptedFirstName
-		}
-		if decryptedLastName, err := utils.DecryptAES(user.LastName); err == nil {
-			user.LastName = decryptedLastName
-		}
-		if decryptedEmail, err := utils.DecryptAES(user.Email); err == nil {
-			user.Email = decryptedEmail
-		}
+		user.FirstName = utils.DecryptField(user.FirstName)
+		user.LastName = utils.DecryptField(user.LastName)
+		user.Email = utils.DecryptField(user.Email)
 
 		if avatarURL.Valid {
 			user.AvatarURL = avatarURL.String
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAccessTokenNameInvalid est retournée pour un nom de jeton vide ou trop long.
+	ErrAccessTokenNameInvalid = errors.New("nom de jeton invalide")
+	// ErrAccessTokenScopeInvalid est retournée pour une liste de scopes vide ou contenant un scope inconnu.
+	ErrAccessTokenScopeInvalid = errors.New("scopes de jeton invalides")
+	// ErrAccessTokenExpiryInvalid est retournée pour une durée de validité hors bornes.
+	ErrAccessTokenExpiryInvalid = errors.New("durée de validité du jeton invalide")
+	// ErrAccessTokenLimitReached est retournée quand l'utilisateur a déjà trop de jetons actifs.
+	ErrAccessTokenLimitReached = errors.New("nombre maximal de jetons atteint")
+)
+
+const (
+	accessTokenMaxNameLen     = 100
+	accessTokenDefaultDays    = 90
+	accessTokenMaxDays        = 365
+	accessTokenMaxPerUser     = 20
+	accessTokenDisplayedChars = 12 // Préfixe "ofp_" et premiers caractères, pour reconnaître le jeton
+)
+
+// IsPersonalAccessToken indique si la valeur du header Authorization est un jeton d'accès personnel plutôt qu'un JWT.
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, domain.AccessTokenPrefix)
+}
+
+// CreatePersonalAccessToken crée un jeton nommé pour les intégrations de l'utilisateur.
+// expiresInDays vaut 90 jours par défaut (0) et au plus 365 ; le jeton en clair n'est retourné qu'ici.
+func CreatePersonalAccessToken(userID int64, name string, scopes []string, expiresInDays int, now time.Time) (*domain.CreatedAccessToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > accessTokenMaxNameLen {
+		return nil, ErrAccessTokenNameInvalid
+	}
+	scopes, err := normalizeAccessTokenScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	if expiresInDays == 0 {
+		expiresInDays = accessTokenDefaultDays
+	}
+	if expiresInDays < 1 || expiresInDays > accessTokenMaxDays {
+		return nil, ErrAccessTokenExpiryInvalid
+	}
+
+	count, err := repository.CountPersonalAccessTokens(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if count >= accessTokenMaxPerUser {
+		return nil, ErrAccessTokenLimitReached
+	}
+
+	secret, _, err := newRefreshToken()
+	if err != nil {
+		log.Printf("[CreatePersonalAccessToken] Erreur génération du jeton : %v", err)
+		return nil, err
+	}
+	raw := domain.AccessTokenPrefix + secret
+
+	token, err := repository.CreatePersonalAccessToken(userID, name, hashRefreshToken(raw), raw[:accessTokenDisplayedChars], scopes, now.Add(time.Duration(expiresInDays)*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[CreatePersonalAccessToken] Jeton %d (%s) créé pour l'utilisateur %d", token.ID, strings.Join(scopes, " "), userID)
+	return &domain.CreatedAccessToken{PersonalAccessToken: *token, Token: raw}, nil
+}
+
+// AuthenticatePersonalAccessToken retourne l'utilisateur d'un jeton d'accès personnel valide
+// (repository.ErrAccessTokenInvalid sinon) et enregistre sa dernière utilisation.
+func AuthenticatePersonalAccessToken(raw string, now time.Time) (*domain.AccessTokenIdentity, error) {
+	if !IsPersonalAccessToken(raw) {
+		return nil, repository.ErrAccessTokenInvalid
+	}
+	return repository.AuthenticatePersonalAccessToken(hashRefreshToken(raw), now)
+}
+
+// normalizeAccessTokenScopes vérifie les scopes demandés et retire les doublons.
+func normalizeAccessTokenScopes(scopes []string) ([]string, error) {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, scope := range scopes {
+		if !containsString(domain.AccessTokenScopes, scope) {
+			return nil, ErrAccessTokenScopeInvalid
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, ErrAccessTokenScopeInvalid
+	}
+	return result, nil
+}
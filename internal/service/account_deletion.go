@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/utils"
+	"time"
+)
+
+// AccountDeletionGracePeriod est le délai pendant lequel une suppression de compte peut être
+// annulée en se reconnectant ; le compte est ensuite purgé par le scheduler.
+const AccountDeletionGracePeriod = 30 * 24 * time.Hour
+
+// RequestAccountDeletion place le compte en attente de suppression et retourne la date à partir
+// de laquelle il sera purgé. Le profil est masqué immédiatement et toutes les sessions sont fermées.
+func RequestAccountDeletion(userID int64, now time.Time) (time.Time, error) {
+	requestedAt, err := repository.RequestAccountDeletion(userID, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return requestedAt.Add(AccountDeletionGracePeriod), nil
+}
+
+// PurgeAccount supprime les médias de l'utilisateur chez ImageKit puis anonymise son compte.
+// En cas d'échec du stockage, rien n'est anonymisé et la purge sera retentée au prochain passage.
+// actor est l'administrateur à l'origine de la purge, nil pour la purge planifiée.
+func PurgeAccount(userID int64, now time.Time, actor *domain.AuditActor) error {
+	fileIDs, err := repository.ListUserMediaFileIDs(userID)
+	if err != nil {
+		return err
+	}
+	for _, fileID := range fileIDs {
+		if err := DeleteFile(fileID); err != nil {
+			return fmt.Errorf("suppression du média %s : %w", fileID, err)
+		}
+	}
+
+	anon, err := anonymizedUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := repository.AnonymizeUser(userID, anon, now, actor); err != nil {
+		return err
+	}
+	log.Printf("[PurgeAccount] ✅ Compte %d purgé (%d média(s) supprimé(s))", userID, len(fileIDs))
+	return nil
+}
+
+// anonymizedUser prépare les valeurs de remplacement d'un compte purgé, chiffrées comme
+// les autres données personnelles pour ne pas être signalées par la commande de rechiffrement.
+func anonymizedUser(userID int64) (repository.AnonymizedUser, error) {
+	username := fmt.Sprintf("deleted_%d", userID)
+	firstName, err := utils.EncryptAES("Utilisateur")
+	if err != nil {
+		return repository.AnonymizedUser{}, err
+	}
+	lastName, err := utils.EncryptAES("supprimé")
+	if err != nil {
+		return repository.AnonymizedUser{}, err
+	}
+	placeholderEmail := username + "@deleted.invalid"
+	email, err := utils.EncryptAES(placeholderEmail)
+	if err != nil {
+		return repository.AnonymizedUser{}, err
+	}
+	return repository.AnonymizedUser{
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     email,
+		EmailBidx: utils.EmailBlindIndex(placeholderEmail),
+		Username:  username,
+	}, nil
+}
+
+// PurgeDueAccountDeletions purge au plus limit comptes dont le délai de grâce est écoulé.
+// Retourne le nombre de comptes purgés ; un compte en échec est journalisé puis retenté plus tard.
+func PurgeDueAccountDeletions(now time.Time, limit int) (int, error) {
+	userIDs, err := repository.ListAccountsDueForPurge(now.Add(-AccountDeletionGracePeriod), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		if err := PurgeAccount(userID, now, nil); err != nil {
+			log.Printf("[PurgeDueAccountDeletions] ⚠️  Purge du compte %d reportée : %v", userID, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"onlyflick/internal/repository"
+)
+
+// BuildAccountExport construit l'archive ZIP des données personnelles d'un utilisateur
+// (droit d'accès RGPD) : profile.json puis un fichier JSON par section de
+// repository.AccountExportSections.
+func BuildAccountExport(userID int64) ([]byte, error) {
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	if err := writeExportFile(archive, "profile.json", user); err != nil {
+		return nil, err
+	}
+	for _, section := range repository.AccountExportSections {
+		rows, err := repository.ExportRows(section, userID)
+		if err != nil {
+			return nil, fmt.Errorf("export %s : %w", section.Name, err)
+		}
+		if err := writeExportFile(archive, section.Name+".json", rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	log.Printf("[BuildAccountExport] Export des données de l'utilisateur %d généré (%d octets)", userID, buf.Len())
+	return buf.Bytes(), nil
+}
+
+func writeExportFile(archive *zip.Writer, name string, data interface{}) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
@@ -1,9 +1,9 @@
 package service
 
 import (
-	"fmt"
+	"errors"
 	"log"
-	"onlyflick/internal/config"
+	"onlyflick/internal/repository"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -38,44 +38,80 @@ func CheckPasswordHash(password, hash string) bool {
 // Gestion des JWT
 // =====================
 
-// jwtSecret contient la clé secrète utilisée pour signer les tokens JWT.
-var jwtSecret = []byte(config.SecretKey)
+// ErrSessionRevoked est retournée pour un access token dont la session a été révoquée (déconnexion, vol de refresh token).
+var ErrSessionRevoked = errors.New("session révoquée")
 
-// GenerateJWT génère un token JWT pour un utilisateur donné avec une expiration de 24h.
+// AccessTokenTTL retourne la durée de validité des access tokens (ACCESS_TOKEN_TTL, 15 minutes par défaut).
+func AccessTokenTTL() time.Duration {
+	return parseDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+// GenerateJWT génère un access token sans session pour un utilisateur donné (outils internes et tests).
+// Les clients obtiennent leurs jetons via IssueSessionTokens, révocables côté serveur.
 func GenerateJWT(userID int64, role string) (string, error) {
+	return generateAccessToken(userID, role, 0)
+}
+
+// generateAccessToken signe un access token de courte durée. sessionID (claim sid) rattache le token à sa session.
+func generateAccessToken(userID int64, role string, sessionID int64) (string, error) {
 	log.Printf("[AuthService] Génération du JWT pour l'utilisateur ID : %d, rôle : %s\n", userID, role)
 
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"sub":  userID,
 		"role": role,
-		"exp":  time.Now().Add(time.Hour * 24).Unix(), // Expiration dans 24 heures
-		"iat":  time.Now().Unix(),
+		"exp":  now.Add(AccessTokenTTL()).Unix(),
+		"iat":  now.Unix(),
+	}
+	if sessionID > 0 {
+		claims["sid"] = sessionID
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	log.Println("[AuthService] Signature du JWT avec la clé secrète")
-	signedToken, err := token.SignedString(jwtSecret)
+	key := currentJWTKeys().signing
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	log.Printf("[AuthService] Signature du JWT avec la clé %s (%s)\n", key.ID, key.Method.Alg())
+	signedToken, err := token.SignedString(key.Private)
 	if err != nil {
 		log.Printf("[AuthService] Erreur lors de la signature du JWT : %v\n", err)
 	}
 	return signedToken, err
 }
 
-// ValidateJWT valide et parse un token JWT.
+// ValidateJWT valide et parse un token JWT signé par l'une des clés de vérification.
 // Retourne le token si valide, sinon une erreur.
 func ValidateJWT(tokenString string) (*jwt.Token, error) {
 	log.Println("[AuthService] Validation du JWT en cours")
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Vérifie que le token utilise bien l'algorithme HS256
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			log.Println("[AuthService] Méthode de signature invalide détectée")
-			return nil, fmt.Errorf("méthode de signature invalide")
-		}
-		return jwtSecret, nil
-	})
+	// La clé est choisie par le kid du token ; l'algorithme doit être celui de la clé
+	token, err := jwt.Parse(tokenString, jwtVerificationKey)
 
 	if err != nil {
 		log.Printf("[AuthService] Erreur lors de la validation du JWT : %v\n", err)
+		return token, err
+	}
+
+	// Un token rattaché à une session n'est plus accepté une fois celle-ci révoquée
+	if sessionID := SessionIDFromClaims(token.Claims); sessionID > 0 {
+		active, err := repository.IsSessionActive(sessionID)
+		if err != nil {
+			token.Valid = false
+			return token, err
+		}
+		if !active {
+			log.Printf("[AuthService] Session %d révoquée, token refusé\n", sessionID)
+			token.Valid = false
+			return token, ErrSessionRevoked
+		}
+	}
+	return token, nil
+}
+
+// SessionIDFromClaims retourne l'identifiant de session (claim sid) d'un token, 0 pour un token sans session.
+func SessionIDFromClaims(claims jwt.Claims) int64 {
+	mapClaims, ok := claims.(jwt.MapClaims)
+	if !ok {
+		return 0
 	}
-	return token, err
+	sid, _ := mapClaims["sid"].(float64)
+	return int64(sid)
 }
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"onlyflick/internal/config"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidVerificationToken est retournée pour un lien de vérification altéré ou émis pour une autre adresse.
+	ErrInvalidVerificationToken = errors.New("lien de vérification invalide")
+	// ErrVerificationTokenExpired est retournée pour un lien de vérification expiré.
+	ErrVerificationTokenExpired = errors.New("lien de vérification expiré")
+	// ErrVerificationEmailThrottled est retournée quand un e-mail de vérification vient d'être envoyé
+	// ou que l'adresse est déjà confirmée.
+	ErrVerificationEmailThrottled = errors.New("e-mail de vérification déjà envoyé")
+)
+
+// Délai minimal entre deux envois de l'e-mail de vérification
+const verificationResendInterval = time.Minute
+
+// EmailVerificationTTL retourne la durée de validité d'un lien de vérification (EMAIL_VERIFICATION_TTL, 48h par défaut).
+func EmailVerificationTTL() time.Duration {
+	return parseDurationEnv("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+}
+
+// emailVerificationKey dérive de la clé secrète la clé HMAC propre aux liens de vérification.
+func emailVerificationKey() []byte {
+	sum := sha256.Sum256([]byte("email-verification:" + config.SecretKey))
+	return sum[:]
+}
+
+// emailVerificationSignature signe l'utilisateur, l'expiration et l'adresse : un changement d'adresse invalide le lien.
+func emailVerificationSignature(userID, expiresAt int64, email string) []byte {
+	mac := hmac.New(sha256.New, emailVerificationKey())
+	fmt.Fprintf(mac, "%d.%d.%s", userID, expiresAt, strings.ToLower(strings.TrimSpace(email)))
+	return mac.Sum(nil)
+}
+
+// GenerateEmailVerificationToken construit le jeton signé "<user_id>.<expiration>.<signature>" d'un lien de vérification.
+func GenerateEmailVerificationToken(userID int64, email string, expiresAt time.Time) string {
+	exp := expiresAt.Unix()
+	signature := base64.RawURLEncoding.EncodeToString(emailVerificationSignature(userID, exp, email))
+	return fmt.Sprintf("%d.%d.%s", userID, exp, signature)
+}
+
+// VerifyEmailToken valide un lien de vérification et confirme l'adresse de l'utilisateur.
+// Un lien déjà utilisé reste valide jusqu'à son expiration : la confirmation est idempotente.
+func VerifyEmailToken(token string, now time.Time) (int64, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidVerificationToken
+	}
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidVerificationToken
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidVerificationToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return 0, ErrInvalidVerificationToken
+	}
+
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		return 0, ErrInvalidVerificationToken
+	}
+	if !hmac.Equal(signature, emailVerificationSignature(userID, exp, user.Email)) {
+		log.Printf("[VerifyEmailToken] Signature invalide pour l'utilisateur %d", userID)
+		return 0, ErrInvalidVerificationToken
+	}
+	if now.Unix() > exp {
+		return 0, ErrVerificationTokenExpired
+	}
+
+	if _, err := repository.MarkEmailVerified(userID); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// SendVerificationEmail envoie à l'utilisateur (adresse en clair) un lien signé de confirmation de son adresse.
+// Les envois sont espacés d'au moins une minute et cessent une fois l'adresse confirmée.
+func SendVerificationEmail(user *domain.User) error {
+	reserved, err := repository.ReserveVerificationEmail(user.ID, time.Now().Add(-verificationResendInterval))
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return ErrVerificationEmailThrottled
+	}
+
+	expiresAt := time.Now().Add(EmailVerificationTTL())
+	link := envOrDefault("API_BASE_URL", "http://localhost:8080") + "/auth/verify-email?token=" +
+		url.QueryEscape(GenerateEmailVerificationToken(user.ID, user.Email, expiresAt))
+
+	return SendTemplatedEmail(user.Email, "Confirmez votre adresse e-mail OnlyFlick", "verify_email", map[string]string{
+		"Username":  user.Username,
+		"Link":      link,
+		"ExpiresAt": expiresAt.UTC().Format("02/01/2006 à 15:04 (UTC)"),
+	})
+}
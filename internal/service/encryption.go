@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/utils"
+)
+
+// ReencryptionStats résume un passage de rechiffrement des données personnelles.
+type ReencryptionStats struct {
+	Scanned     int // Valeurs lues
+	Reencrypted int // Valeurs ramenées sur la clé courante
+	Conflicts   int // Valeurs modifiées entre la lecture et l'écriture (reprises au prochain passage)
+}
+
+// ReencryptPersonalData parcourt les colonnes chiffrées par lots de batchSize lignes et
+// ramène sur la clé courante les valeurs en clair ou chiffrées par une ancienne clé.
+// En mode dryRun, les valeurs concernées sont comptées sans être écrites.
+func ReencryptPersonalData(batchSize int, dryRun bool) (ReencryptionStats, error) {
+	var stats ReencryptionStats
+	if batchSize <= 0 {
+		return stats, fmt.Errorf("taille de lot invalide : %d", batchSize)
+	}
+	currentID, err := utils.CurrentEncryptionKeyID()
+	if err != nil {
+		return stats, err
+	}
+	log.Printf("[ReencryptPersonalData] Rechiffrement vers la clé %q (lots de %d, simulation : %v)", currentID, batchSize, dryRun)
+
+	for _, col := range repository.EncryptedColumns {
+		var afterID int64
+		for {
+			values, err := repository.ListEncryptedValues(col, afterID, batchSize)
+			if err != nil {
+				return stats, err
+			}
+
+			for _, v := range values {
+				stats.Scanned++
+				needed, err := utils.NeedsReencryption(v.Value)
+				if err != nil {
+					return stats, fmt.Errorf("%s.%s (%d) : %w", col.Table, col.Column, v.ID, err)
+				}
+				if !needed {
+					continue
+				}
+				reencrypted, err := utils.ReencryptAES(v.Value)
+				if err != nil {
+					return stats, fmt.Errorf("%s.%s (%d) : %w", col.Table, col.Column, v.ID, err)
+				}
+				if dryRun {
+					stats.Reencrypted++
+					continue
+				}
+				replaced, err := repository.ReplaceEncryptedValue(col, v.ID, v.Value, reencrypted)
+				if err != nil {
+					return stats, err
+				}
+				if replaced {
+					stats.Reencrypted++
+				} else {
+					stats.Conflicts++
+				}
+			}
+
+			if len(values) < batchSize {
+				break
+			}
+			afterID = values[len(values)-1].ID
+		}
+		log.Printf("[ReencryptPersonalData] %s.%s traitée", col.Table, col.Column)
+	}
+
+	log.Printf("[ReencryptPersonalData] ✅ %d valeurs lues, %d rechiffrées, %d en conflit", stats.Scanned, stats.Reencrypted, stats.Conflicts)
+	return stats, nil
+}
+
+// BlindIndexStats résume un passage de calcul des index aveugles des utilisateurs.
+type BlindIndexStats struct {
+	Scanned int // Utilisateurs lus
+	Indexed int // Utilisateurs dont les index ont été (re)calculés
+	Failed  int // Utilisateurs ignorés (valeur illisible ou e-mail en double)
+}
+
+// BackfillUserBlindIndexes calcule par lots de batchSize les index aveugles des utilisateurs
+// qui n'en ont pas encore, ou de tous les utilisateurs si all est vrai (changement de
+// BLIND_INDEX_KEY). Une ligne en échec est journalisée puis ignorée.
+func BackfillUserBlindIndexes(batchSize int, all bool) (BlindIndexStats, error) {
+	var stats BlindIndexStats
+	if batchSize <= 0 {
+		return stats, fmt.Errorf("taille de lot invalide : %d", batchSize)
+	}
+
+	var afterID int64
+	for {
+		users, err := repository.ListUsersForBlindIndex(afterID, batchSize, !all)
+		if err != nil {
+			return stats, err
+		}
+
+		for _, u := range users {
+			stats.Scanned++
+			if err := repository.RefreshUserBlindIndexes(u); err != nil {
+				log.Printf("[BackfillUserBlindIndexes] ⚠️  Utilisateur %d ignoré : %v", u.ID, err)
+				stats.Failed++
+				continue
+			}
+			stats.Indexed++
+		}
+
+		if len(users) < batchSize {
+			break
+		}
+		afterID = users[len(users)-1].ID
+	}
+
+	log.Printf("[BackfillUserBlindIndexes] ✅ %d utilisateurs lus, %d indexés, %d ignorés", stats.Scanned, stats.Indexed, stats.Failed)
+	return stats, nil
+}
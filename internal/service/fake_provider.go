@@ -0,0 +1,290 @@
+package service
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stripe/stripe-go/webhook"
+)
+
+// Moyen de paiement refusé par le fournisseur fictif lors d'un débit hors session
+const FakeDeclinedPaymentMethod = "pm_fake_declined"
+
+// Secret de signature des webhooks du fournisseur fictif (surchargeable via FAKE_WEBHOOK_SECRET)
+const defaultFakeWebhookSecret = "whsec_fake_onlyflick"
+
+var (
+	// ErrFakeCardDeclined est retournée par le fournisseur fictif pour FakeDeclinedPaymentMethod.
+	ErrFakeCardDeclined = errors.New("carte refusée")
+	// ErrFakeIntentNotFound est retournée quand le PaymentIntent est inconnu du fournisseur fictif.
+	ErrFakeIntentNotFound = errors.New("paymentintent introuvable")
+	// ErrFakeSetupIntentNotFound est retournée quand le SetupIntent est inconnu du fournisseur fictif.
+	ErrFakeSetupIntentNotFound = errors.New("setupintent introuvable")
+	// ErrFakeRefundExceedsAmount est retournée quand le remboursement dépasse le montant restant.
+	ErrFakeRefundExceedsAmount = errors.New("montant du remboursement supérieur au montant restant")
+)
+
+// fakeIntent conserve l'état d'un PaymentIntent du fournisseur fictif.
+type fakeIntent struct {
+	intent   PaymentIntent
+	refunded int64
+	metadata map[string]string
+	err      error // Erreur retournée à la création, rejouée avec la clé d'idempotence
+}
+
+// FakePaymentProvider est un fournisseur de paiement déterministe, sans appel réseau,
+// destiné aux tests et au développement local (PAYMENT_PROVIDER=fake).
+// Les identifiants sont séquentiels et les débits hors session réussissent, sauf avec FakeDeclinedPaymentMethod.
+// Les webhooks sont signés au format Stripe avec FAKE_WEBHOOK_SECRET.
+type FakePaymentProvider struct {
+	mu        sync.Mutex
+	secret    string
+	customers int
+	refunds   int
+	events    int
+	intents   []*fakeIntent
+	setups    []*fakeSetupIntent
+	keyed     map[string]*fakeIntent // Intents par clé d'idempotence
+}
+
+// fakeSetupIntent conserve l'état d'un SetupIntent du fournisseur fictif.
+type fakeSetupIntent struct {
+	intent   SetupIntent
+	customer string
+	metadata map[string]string
+}
+
+// NewFakePaymentProvider crée un fournisseur fictif vide.
+func NewFakePaymentProvider() *FakePaymentProvider {
+	secret := os.Getenv("FAKE_WEBHOOK_SECRET")
+	if secret == "" {
+		secret = defaultFakeWebhookSecret
+	}
+	return &FakePaymentProvider{secret: secret}
+}
+
+// Name retourne "fake".
+func (f *FakePaymentProvider) Name() string {
+	return "fake"
+}
+
+// CreateCustomer retourne un identifiant de client séquentiel.
+func (f *FakePaymentProvider) CreateCustomer(userID int64) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.customers++
+	return fmt.Sprintf("cus_fake_%06d", f.customers), nil
+}
+
+// CreatePaymentIntent crée un intent en attente de paiement, ou le confirme immédiatement pour un débit hors session.
+// Comme chez Stripe, une clé d'idempotence déjà vue retourne le même intent et la même erreur.
+func (f *FakePaymentProvider) CreatePaymentIntent(req PaymentIntentRequest) (*PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if existing, ok := f.keyed[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		result := existing.intent
+		return &result, existing.err
+	}
+
+	id := fmt.Sprintf("pi_fake_%06d", len(f.intents)+1)
+	stored := &fakeIntent{
+		intent: PaymentIntent{
+			ID:           id,
+			ClientSecret: id + "_secret",
+			Status:       IntentStatusRequiresPaymentMethod,
+			Amount:       req.Amount,
+			Currency:     strings.ToLower(req.Currency),
+		},
+		metadata: req.Metadata,
+	}
+	f.intents = append(f.intents, stored)
+	if req.IdempotencyKey != "" {
+		if f.keyed == nil {
+			f.keyed = map[string]*fakeIntent{}
+		}
+		f.keyed[req.IdempotencyKey] = stored
+	}
+
+	if !req.OffSession {
+		result := stored.intent
+		return &result, nil
+	}
+
+	if req.PaymentMethodID == FakeDeclinedPaymentMethod {
+		stored.err = ErrFakeCardDeclined
+		result := stored.intent
+		log.Printf("[FakePaymentProvider] Débit %s refusé", id)
+		return &result, ErrFakeCardDeclined
+	}
+
+	stored.intent.Status = IntentStatusSucceeded
+	result := stored.intent
+	return &result, nil
+}
+
+// CreateSetupIntent crée un SetupIntent en attente du moyen de paiement.
+func (f *FakePaymentProvider) CreateSetupIntent(customerID string, metadata map[string]string) (*SetupIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := fmt.Sprintf("seti_fake_%06d", len(f.setups)+1)
+	stored := &fakeSetupIntent{
+		intent:   SetupIntent{ID: id, ClientSecret: id + "_secret", Status: IntentStatusRequiresPaymentMethod},
+		customer: customerID,
+		metadata: metadata,
+	}
+	f.setups = append(f.setups, stored)
+
+	result := stored.intent
+	return &result, nil
+}
+
+// RefundPayment rembourse un intent réussi, totalement si amount vaut 0.
+func (f *FakePaymentProvider) RefundPayment(paymentIntentID string, amount int64) (*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored := f.findIntent(paymentIntentID)
+	if stored == nil || stored.intent.Status != IntentStatusSucceeded {
+		return nil, ErrFakeIntentNotFound
+	}
+
+	remaining := stored.intent.Amount - stored.refunded
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return nil, ErrFakeRefundExceedsAmount
+	}
+
+	stored.refunded += amount
+	f.refunds++
+	return &Refund{
+		ID:              fmt.Sprintf("re_fake_%06d", f.refunds),
+		PaymentIntentID: paymentIntentID,
+		Amount:          amount,
+		Status:          "succeeded",
+	}, nil
+}
+
+// VerifyWebhook vérifie la signature d'un événement émis par SignEvent.
+func (f *FakePaymentProvider) VerifyWebhook(payload []byte, signatureHeader string) (*WebhookEvent, error) {
+	event, err := webhook.ConstructEvent(payload, signatureHeader, f.secret)
+	if err != nil {
+		log.Printf("[FakePaymentProvider] Signature invalide : %v", err)
+		return nil, err
+	}
+	if event.Data == nil {
+		return nil, fmt.Errorf("données d'événement manquantes")
+	}
+
+	return &WebhookEvent{ID: event.ID, Type: event.Type, Data: event.Data.Raw}, nil
+}
+
+// SignEvent construit un événement au format Stripe et retourne son corps et l'en-tête Stripe-Signature.
+func (f *FakePaymentProvider) SignEvent(eventType string, object map[string]interface{}) ([]byte, string, error) {
+	f.mu.Lock()
+	f.events++
+	eventID := fmt.Sprintf("evt_fake_%06d", f.events)
+	f.mu.Unlock()
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":     eventID,
+		"object": "event",
+		"type":   eventType,
+		"data":   map[string]interface{}{"object": object},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	signature := webhook.ComputeSignature(now, payload, f.secret)
+	return payload, fmt.Sprintf("t=%d,v1=%s", now.Unix(), hex.EncodeToString(signature)), nil
+}
+
+// CompleteIntent simule le paiement par le client d'un intent en attente avec paymentMethodID
+// et retourne le webhook payment_intent.succeeded signé correspondant.
+func (f *FakePaymentProvider) CompleteIntent(paymentIntentID, paymentMethodID string) ([]byte, string, error) {
+	f.mu.Lock()
+	stored := f.findIntent(paymentIntentID)
+	if stored == nil {
+		f.mu.Unlock()
+		return nil, "", ErrFakeIntentNotFound
+	}
+	stored.intent.Status = IntentStatusSucceeded
+	intent := stored.intent
+	metadata := stored.metadata
+	f.mu.Unlock()
+
+	return f.SignEvent(StripeEventPaymentSucceeded, map[string]interface{}{
+		"id":             intent.ID,
+		"object":         "payment_intent",
+		"amount":         intent.Amount,
+		"currency":       intent.Currency,
+		"status":         intent.Status,
+		"payment_method": paymentMethodID,
+		"metadata":       metadata,
+	})
+}
+
+// CompleteSetupIntent simule l'enregistrement de paymentMethodID par le client
+// et retourne le webhook setup_intent.succeeded signé correspondant.
+func (f *FakePaymentProvider) CompleteSetupIntent(setupIntentID, paymentMethodID string) ([]byte, string, error) {
+	f.mu.Lock()
+	var stored *fakeSetupIntent
+	for _, s := range f.setups {
+		if s.intent.ID == setupIntentID {
+			stored = s
+		}
+	}
+	if stored == nil {
+		f.mu.Unlock()
+		return nil, "", ErrFakeSetupIntentNotFound
+	}
+	stored.intent.Status = IntentStatusSucceeded
+	intent := stored.intent
+	customer := stored.customer
+	metadata := stored.metadata
+	f.mu.Unlock()
+
+	return f.SignEvent(StripeEventSetupSucceeded, map[string]interface{}{
+		"id":             intent.ID,
+		"object":         "setup_intent",
+		"customer":       customer,
+		"status":         intent.Status,
+		"payment_method": paymentMethodID,
+		"metadata":       metadata,
+	})
+}
+
+// LastIntent retourne le dernier PaymentIntent créé, ou nil.
+func (f *FakePaymentProvider) LastIntent() *PaymentIntent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.intents) == 0 {
+		return nil
+	}
+	result := f.intents[len(f.intents)-1].intent
+	return &result
+}
+
+// findIntent recherche un intent par identifiant (appelant verrouillé).
+func (f *FakePaymentProvider) findIntent(id string) *fakeIntent {
+	for _, stored := range f.intents {
+		if stored.intent.ID == id {
+			return stored
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// jwtKey est une clé de signature (clé privée présente) ou de vérification seule des access tokens.
+type jwtKey struct {
+	ID      string // kid publié dans l'en-tête des tokens et dans le JWKS
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey // nil pour une clé de vérification seule
+	Public  crypto.PublicKey
+}
+
+// jwtKeySet regroupe la clé de signature courante et toutes les clés acceptées en vérification.
+type jwtKeySet struct {
+	signing      *jwtKey
+	verification map[string]*jwtKey
+}
+
+// JSONWebKey est la représentation publique d'une clé de vérification (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// JSONWebKeySet est le document publié sur /.well-known/jwks.json.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var (
+	jwtKeys     *jwtKeySet
+	jwtKeysOnce sync.Once
+)
+
+// ErrJWTKeysNotConfigured est retournée sans JWT_KEYS_DIR hors développement (JWT_ALLOW_EPHEMERAL_KEYS).
+var ErrJWTKeysNotConfigured = errors.New("JWT_KEYS_DIR non défini (JWT_ALLOW_EPHEMERAL_KEYS=true pour une clé éphémère de développement)")
+
+// InitJWTKeys charge les clés des access tokens depuis JWT_KEYS_DIR : un fichier PEM "<kid>.pem" par clé
+// (Ed25519 ou RSA ≥ 2048 bits, privée ou publique). Toutes les clés sont acceptées en vérification ;
+// JWT_SIGNING_KEY_ID désigne la clé privée qui signe (facultatif s'il n'y en a qu'une).
+// Pour une rotation, ajouter la nouvelle clé, basculer JWT_SIGNING_KEY_ID puis retirer l'ancienne
+// une fois ses derniers tokens expirés. Sans JWT_KEYS_DIR, le chargement échoue, sauf en développement
+// avec JWT_ALLOW_EPHEMERAL_KEYS=true où une clé Ed25519 éphémère est générée.
+func InitJWTKeys() error {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		if os.Getenv("JWT_ALLOW_EPHEMERAL_KEYS") != "true" {
+			return ErrJWTKeysNotConfigured
+		}
+		keys, err := ephemeralJWTKeys()
+		if err != nil {
+			return err
+		}
+		log.Println("[JWT] ⚠️  JWT_KEYS_DIR non défini : clé Ed25519 éphémère, les tokens ne survivront pas au redémarrage")
+		jwtKeys = keys
+		return nil
+	}
+
+	keys, err := loadJWTKeys(dir, os.Getenv("JWT_SIGNING_KEY_ID"))
+	if err != nil {
+		return err
+	}
+	log.Printf("[JWT] Clé de signature %s (%s), %d clé(s) de vérification", keys.signing.ID, keys.signing.Method.Alg(), len(keys.verification))
+	jwtKeys = keys
+	return nil
+}
+
+// currentJWTKeys retourne le jeu de clés, initialisé à la première utilisation s'il n'a pas été chargé au démarrage.
+func currentJWTKeys() *jwtKeySet {
+	jwtKeysOnce.Do(func() {
+		if jwtKeys != nil {
+			return
+		}
+		if err := InitJWTKeys(); err != nil {
+			log.Fatalf("[JWT] ❌ Chargement des clés impossible : %v", err)
+		}
+	})
+	return jwtKeys
+}
+
+// JWKS retourne les clés publiques de vérification des access tokens, triées par kid.
+func JWKS() JSONWebKeySet {
+	keys := currentJWTKeys()
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys.verification))}
+	for _, key := range keys.verification {
+		jwk := JSONWebKey{Kid: key.ID, Alg: key.Method.Alg(), Use: "sig"}
+		switch pub := key.Public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv, jwk.X = "OKP", "Ed25519", base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// jwtVerificationKey retourne la clé publique correspondant au kid et à l'algorithme du token.
+func jwtVerificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := currentJWTKeys().verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("clé de signature inconnue (kid %q)", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("algorithme %s inattendu pour la clé %s", token.Method.Alg(), kid)
+	}
+	return key.Public, nil
+}
+
+// loadJWTKeys lit les fichiers "<kid>.pem" du répertoire et sélectionne la clé de signature.
+func loadJWTKeys(dir, signingKeyID string) (*jwtKeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &jwtKeySet{verification: map[string]*jwtKey{}}
+	var privateKeys []*jwtKey
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseJWTKey(strings.TrimSuffix(filepath.Base(file), ".pem"), data)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", file, err)
+		}
+		keys.verification[key.ID] = key
+		if key.Private != nil {
+			privateKeys = append(privateKeys, key)
+		}
+	}
+
+	switch {
+	case signingKeyID != "":
+		key, ok := keys.verification[signingKeyID]
+		if !ok || key.Private == nil {
+			return nil, fmt.Errorf("clé privée %s introuvable dans %s", signingKeyID, dir)
+		}
+		keys.signing = key
+	case len(privateKeys) == 1:
+		keys.signing = privateKeys[0]
+	default:
+		return nil, fmt.Errorf("%d clé(s) privée(s) dans %s : définir JWT_SIGNING_KEY_ID", len(privateKeys), dir)
+	}
+	return keys, nil
+}
+
+// parseJWTKey décode une clé PEM Ed25519 (EdDSA) ou RSA (RS256), privée ou publique.
+func parseJWTKey(kid string, data []byte) (*jwtKey, error) {
+	if edPrivate, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		private := edPrivate.(ed25519.PrivateKey)
+		return &jwtKey{ID: kid, Method: jwt.SigningMethodEdDSA, Private: private, Public: private.Public()}, nil
+	}
+	if rsaPrivate, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		if rsaPrivate.N.BitLen() < 2048 {
+			return nil, errors.New("clé RSA inférieure à 2048 bits")
+		}
+		return &jwtKey{ID: kid, Method: jwt.SigningMethodRS256, Private: rsaPrivate, Public: &rsaPrivate.PublicKey}, nil
+	}
+	if edPublic, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return &jwtKey{ID: kid, Method: jwt.SigningMethodEdDSA, Public: edPublic.(ed25519.PublicKey)}, nil
+	}
+	if rsaPublic, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return &jwtKey{ID: kid, Method: jwt.SigningMethodRS256, Public: rsaPublic}, nil
+	}
+	return nil, errors.New("clé PEM Ed25519 ou RSA attendue")
+}
+
+// ephemeralJWTKeys génère une clé Ed25519 en mémoire, propre au processus.
+func ephemeralJWTKeys() (*jwtKeySet, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	key := &jwtKey{ID: "ephemeral", Method: jwt.SigningMethodEdDSA, Private: private, Public: public}
+	return &jwtKeySet{signing: key, verification: map[string]*jwtKey{key.ID: key}}, nil
+}
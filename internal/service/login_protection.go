@@ -0,0 +1,153 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"strconv"
+	"time"
+)
+
+// loginThrottlePolicy fixe le délai croissant et le seuil de verrouillage d'une portée (compte ou IP).
+type loginThrottlePolicy struct {
+	freeFailures int // Échecs tolérés sans délai
+	maxFailures  int // Échecs déclenchant le verrouillage temporaire
+}
+
+var loginThrottlePolicies = map[string]loginThrottlePolicy{
+	domain.LoginThrottleAccount: {freeFailures: 3, maxFailures: 10},
+	domain.LoginThrottleIP:      {freeFailures: 10, maxFailures: 50},
+}
+
+const (
+	loginFailureWindow = time.Hour       // Un compteur sans échec depuis ce délai repart de zéro
+	loginBackoffBase   = time.Second     // Premier délai imposé, doublé à chaque nouvel échec
+	loginBackoffMax    = 5 * time.Minute // Délai maximal entre deux tentatives hors verrouillage
+)
+
+// LoginLockoutDuration retourne la durée du verrouillage temporaire (LOGIN_LOCKOUT_DURATION, 15 minutes par défaut).
+func LoginLockoutDuration() time.Duration {
+	return parseDurationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+}
+
+// LoginThrottledError est retournée quand une tentative de connexion arrive pendant le délai imposé
+// ou le verrouillage d'un compte ou d'une IP.
+type LoginThrottledError struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("connexion verrouillée pour %s", e.RetryAfter)
+	}
+	return fmt.Sprintf("nouvelle tentative de connexion possible dans %s", e.RetryAfter)
+}
+
+// loginBackoff retourne le délai à respecter après `failures` échecs consécutifs.
+func loginBackoff(policy loginThrottlePolicy, failures int) time.Duration {
+	extra := failures - policy.freeFailures
+	if extra <= 0 {
+		return 0
+	}
+	delay := loginBackoffBase
+	for i := 1; i < extra && delay < loginBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > loginBackoffMax {
+		delay = loginBackoffMax
+	}
+	return delay
+}
+
+// CheckLoginThrottle refuse la tentative (LoginThrottledError) si le compte ou l'IP est verrouillé
+// ou si le délai imposé depuis le dernier échec n'est pas écoulé.
+func CheckLoginThrottle(scope, subject string, now time.Time) error {
+	throttle, err := repository.GetLoginThrottle(scope, subject)
+	if err != nil || throttle == nil {
+		return err
+	}
+
+	if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+		return &LoginThrottledError{Locked: true, RetryAfter: throttle.LockedUntil.Sub(now)}
+	}
+	if throttle.LastFailureAt.Before(now.Add(-loginFailureWindow)) {
+		return nil
+	}
+	if next := throttle.LastFailureAt.Add(loginBackoff(loginThrottlePolicies[scope], throttle.Failures)); next.After(now) {
+		return &LoginThrottledError{RetryAfter: next.Sub(now)}
+	}
+	return nil
+}
+
+// RecordFailedLogin comptabilise un échec pour l'IP et, si l'adresse correspond à un compte, pour ce compte.
+// Au seuil, le compte est verrouillé temporairement et son titulaire prévenu par e-mail.
+func RecordFailedLogin(user *domain.User, ipAddress string, now time.Time) {
+	if ipAddress != "" {
+		if _, err := recordLoginFailure(domain.LoginThrottleIP, ipAddress, now); err != nil {
+			log.Printf("[RecordFailedLogin] Erreur comptage de l'échec pour l'IP %s : %v", ipAddress, err)
+		}
+	}
+	if user == nil {
+		return
+	}
+
+	lockedUntil, err := recordLoginFailure(domain.LoginThrottleAccount, strconv.FormatInt(user.ID, 10), now)
+	if err != nil {
+		log.Printf("[RecordFailedLogin] Erreur comptage de l'échec pour l'utilisateur %d : %v", user.ID, err)
+		return
+	}
+	if lockedUntil != nil {
+		go sendAccountLockedEmail(*user, *lockedUntil, ipAddress)
+	}
+}
+
+// RecordSuccessfulLogin efface les échecs du compte après une authentification réussie.
+func RecordSuccessfulLogin(userID int64) {
+	if _, err := repository.ClearLoginThrottle(domain.LoginThrottleAccount, strconv.FormatInt(userID, 10)); err != nil {
+		log.Printf("[RecordSuccessfulLogin] Erreur remise à zéro des échecs de l'utilisateur %d : %v", userID, err)
+	}
+}
+
+// UnlockAccount lève le verrouillage et efface les échecs d'un compte (action administrateur).
+// Retourne false si le compte n'avait aucun échec enregistré.
+func UnlockAccount(userID int64, actor domain.AuditActor) (bool, error) {
+	unlocked, err := repository.UnlockUserAccount(userID, actor)
+	if err == nil && unlocked {
+		log.Printf("[UnlockAccount] Compte %d déverrouillé par l'administrateur %d", userID, actor.ActorID)
+	}
+	return unlocked, err
+}
+
+// recordLoginFailure enregistre un échec et verrouille au seuil. Retourne la fin du verrouillage
+// seulement si cet échec vient de le déclencher.
+func recordLoginFailure(scope, subject string, now time.Time) (*time.Time, error) {
+	throttle, err := repository.RecordLoginFailure(scope, subject, now, now.Add(-loginFailureWindow))
+	if err != nil {
+		return nil, err
+	}
+	if throttle.Failures < loginThrottlePolicies[scope].maxFailures {
+		return nil, nil
+	}
+
+	until := now.Add(LoginLockoutDuration())
+	locked, err := repository.LockLoginSubject(scope, subject, until, now)
+	if err != nil || !locked {
+		return nil, err
+	}
+	log.Printf("[recordLoginFailure] ⚠️  Verrouillage (%s %s) jusqu'au %s après %d échecs", scope, subject, until.Format(time.RFC3339), throttle.Failures)
+	return &until, nil
+}
+
+// sendAccountLockedEmail prévient le titulaire du compte du verrouillage et de l'IP à l'origine des échecs.
+func sendAccountLockedEmail(user domain.User, lockedUntil time.Time, ipAddress string) {
+	if err := SendTemplatedEmail(user.Email, "Votre compte OnlyFlick a été temporairement verrouillé", "account_locked", map[string]string{
+		"Username":    user.Username,
+		"LockedUntil": lockedUntil.UTC().Format("02/01/2006 à 15:04 (UTC)"),
+		"IPAddress":   ipAddress,
+		"ResetLink":   envOrDefault("FRONTEND_URL", "http://localhost:3000") + "/forgot-password",
+	}); err != nil {
+		log.Printf("[sendAccountLockedEmail] Erreur envoi de l'e-mail à l'utilisateur %d : %v", user.ID, err)
+	}
+}
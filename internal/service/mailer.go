@@ -0,0 +1,194 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates/*.html templates/*.txt
+var emailTemplatesFS embed.FS
+
+var (
+	htmlEmailTemplates = htmltemplate.Must(htmltemplate.ParseFS(emailTemplatesFS, "templates/*.html"))
+	textEmailTemplates = texttemplate.Must(texttemplate.ParseFS(emailTemplatesFS, "templates/*.txt"))
+)
+
+// EmailMessage est un e-mail prêt à l'envoi, avec ses versions texte et HTML.
+type EmailMessage struct {
+	To       string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// MailSender abstrait l'envoi d'e-mails (SMTP en production et avec Mailpit en local, journal en test).
+type MailSender interface {
+	// Name retourne le nom de l'expéditeur ("smtp", "log").
+	Name() string
+	// Send envoie le message.
+	Send(msg EmailMessage) error
+}
+
+// Expéditeur d'e-mails global
+var mailSender MailSender
+
+// InitMailer sélectionne l'expéditeur via MAIL_PROVIDER ("smtp" par défaut, ou "log" pour journaliser sans envoyer).
+// Le serveur SMTP est configuré par SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD et MAIL_FROM
+// (Mailpit sur localhost:1025 par défaut).
+func InitMailer() {
+	switch strings.ToLower(os.Getenv("MAIL_PROVIDER")) {
+	case "log":
+		mailSender = logMailer{}
+	default:
+		mailSender = &smtpMailer{
+			host:     envOrDefault("SMTP_HOST", "localhost"),
+			port:     envOrDefault("SMTP_PORT", "1025"),
+			username: os.Getenv("SMTP_USERNAME"),
+			password: os.Getenv("SMTP_PASSWORD"),
+			from:     envOrDefault("MAIL_FROM", "OnlyFlick <no-reply@onlyflick.local>"),
+		}
+	}
+
+	log.Printf("✅ [Mailer] Expéditeur d'e-mails initialisé : %s", mailSender.Name())
+}
+
+// SetMailer remplace l'expéditeur d'e-mails (utilisé par les tests).
+func SetMailer(sender MailSender) {
+	mailSender = sender
+}
+
+// Mailer retourne l'expéditeur d'e-mails courant, initialisé depuis la configuration si nécessaire.
+func Mailer() MailSender {
+	if mailSender == nil {
+		InitMailer()
+	}
+	return mailSender
+}
+
+// SendTemplatedEmail rend les gabarits texte et HTML `name` avec data et envoie l'e-mail.
+func SendTemplatedEmail(to, subject, name string, data interface{}) error {
+	var text, html bytes.Buffer
+	if err := textEmailTemplates.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return fmt.Errorf("gabarit texte %s : %w", name, err)
+	}
+	if err := htmlEmailTemplates.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return fmt.Errorf("gabarit HTML %s : %w", name, err)
+	}
+
+	err := Mailer().Send(EmailMessage{To: to, Subject: subject, TextBody: text.String(), HTMLBody: html.String()})
+	if err != nil {
+		log.Printf("[Mailer] Erreur envoi de l'e-mail %s à %s : %v", name, to, err)
+		return err
+	}
+
+	log.Printf("[Mailer] E-mail %s envoyé à %s", name, to)
+	return nil
+}
+
+// envOrDefault lit une variable d'environnement avec une valeur par défaut.
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// =====================
+// Expéditeur SMTP
+// =====================
+
+type smtpMailer struct {
+	host, port         string
+	username, password string
+	from               string
+}
+
+func (m *smtpMailer) Name() string { return "smtp" }
+
+func (m *smtpMailer) Send(msg EmailMessage) error {
+	body, err := buildMIMEMessage(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	// Authentification uniquement si des identifiants sont fournis (Mailpit n'en demande pas)
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(m.host, m.port), auth, extractAddress(m.from), []string{msg.To}, body)
+}
+
+// buildMIMEMessage construit un message multipart/alternative (texte puis HTML).
+func buildMIMEMessage(from string, msg EmailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", msg.TextBody},
+		{"text/html; charset=UTF-8", msg.HTMLBody},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// extractAddress retourne l'adresse d'un expéditeur de la forme "Nom <adresse>".
+func extractAddress(from string) string {
+	if start, end := strings.LastIndex(from, "<"), strings.LastIndex(from, ">"); start >= 0 && end > start {
+		return from[start+1 : end]
+	}
+	return from
+}
+
+// =====================
+// Expéditeur de développement
+// =====================
+
+// logMailer journalise les e-mails sans les envoyer.
+type logMailer struct{}
+
+func (logMailer) Name() string { return "log" }
+
+func (logMailer) Send(msg EmailMessage) error {
+	log.Printf("[Mailer][log] À : %s | Objet : %s\n%s", msg.To, msg.Subject, msg.TextBody)
+	return nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"log"
+	"onlyflick/internal/repository"
+	"time"
+)
+
+// defaultCleanupInterval est la fréquence de la purge des données expirées, toujours active.
+const defaultCleanupInterval = time.Hour
+
+// CleanupConfig regroupe les paramètres de la purge des données expirées et des comptes supprimés.
+// Contrairement au planificateur des abonnements, elle ne peut pas être désactivée (obligation RGPD).
+type CleanupConfig struct {
+	Interval  time.Duration // Fréquence d'exécution
+	BatchSize int           // Nombre maximal de comptes supprimés par passage
+}
+
+// LoadCleanupConfig lit MAINTENANCE_CLEANUP_INTERVAL (durée Go, 1h par défaut). Une valeur nulle,
+// négative ou invalide est ignorée : la purge reste active.
+func LoadCleanupConfig() CleanupConfig {
+	cfg := CleanupConfig{
+		Interval:  parseDurationEnv("MAINTENANCE_CLEANUP_INTERVAL", defaultCleanupInterval),
+		BatchSize: 100,
+	}
+	if cfg.Interval <= 0 {
+		log.Printf("[CLEANUP] ⚠️  MAINTENANCE_CLEANUP_INTERVAL ne peut pas désactiver la purge, utilisation de %s", defaultCleanupInterval)
+		cfg.Interval = defaultCleanupInterval
+	}
+	return cfg
+}
+
+// StartMaintenanceCleanup exécute périodiquement la purge des données expirées jusqu'à l'annulation du contexte.
+func StartMaintenanceCleanup(ctx context.Context, cfg CleanupConfig) {
+	log.Printf("[CLEANUP] Purge des données expirées démarrée (intervalle %s)", cfg.Interval)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	RunMaintenanceCleanup(time.Now(), cfg)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[CLEANUP] Purge des données expirées arrêtée")
+			return
+		case now := <-ticker.C:
+			RunMaintenanceCleanup(now, cfg)
+		}
+	}
+}
+
+// RunMaintenanceCleanup effectue un passage : purge des clés d'idempotence, des réinitialisations de mot
+// de passe, des défis de connexion, des compteurs d'échecs et des connexions OpenID Connect expirés,
+// puis suppression définitive des comptes dont le délai d'annulation est écoulé.
+func RunMaintenanceCleanup(now time.Time, cfg CleanupConfig) {
+	// Les clés d'idempotence expirées ne peuvent plus être rejouées
+	purged, err := repository.PurgeExpiredIdempotencyKeys(now)
+	if err != nil {
+		log.Printf("[CLEANUP] Erreur purge des clés d'idempotence : %v", err)
+	}
+
+	// Jetons de réinitialisation périmés, tentatives sorties de la fenêtre de limitation,
+	// défis de connexion expirés, compteurs d'échecs de connexion inactifs et connexions OpenID Connect abandonnées
+	if _, err := repository.PurgePasswordResetData(now.Add(-24 * time.Hour)); err != nil {
+		log.Printf("[CLEANUP] Erreur purge des réinitialisations de mot de passe : %v", err)
+	}
+	if _, err := repository.PurgeExpiredMFAChallenges(now); err != nil {
+		log.Printf("[CLEANUP] Erreur purge des défis de double authentification : %v", err)
+	}
+	if _, err := repository.PurgeLoginThrottles(now.Add(-24*time.Hour), now); err != nil {
+		log.Printf("[CLEANUP] Erreur purge des compteurs d'échecs de connexion : %v", err)
+	}
+	if _, err := repository.PurgeExpiredOIDCAuthStates(now); err != nil {
+		log.Printf("[CLEANUP] Erreur purge des connexions OpenID Connect expirées : %v", err)
+	}
+
+	// Comptes dont le délai d'annulation de la suppression est écoulé
+	deleted, err := PurgeDueAccountDeletions(now, cfg.BatchSize)
+	if err != nil {
+		log.Printf("[CLEANUP] Erreur purge des comptes supprimés : %v", err)
+	}
+
+	log.Printf("[CLEANUP] Passage terminé : %d clé(s) d'idempotence purgée(s), %d compte(s) supprimé(s)", purged, deleted)
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"log"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/utils"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMFAAlreadyEnabled est retournée pour un enrôlement alors que la double authentification est active.
+	ErrMFAAlreadyEnabled = errors.New("double authentification déjà activée")
+	// ErrMFANotEnrolled est retournée quand aucun enrôlement (en cours ou actif) n'existe.
+	ErrMFANotEnrolled = errors.New("double authentification non configurée")
+	// ErrInvalidMFACode est retournée pour un code TOTP ou de secours erroné, expiré ou déjà utilisé.
+	ErrInvalidMFACode = errors.New("code de double authentification invalide")
+)
+
+// recoveryCodeEncoding produit des codes de secours lisibles (base32 minuscule, sans padding).
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// StartMFAEnrollment génère un nouveau secret TOTP pour l'utilisateur. Il ne devient actif
+// qu'après confirmation d'un premier code (ConfirmMFAEnrollment).
+func StartMFAEnrollment(user *domain.User) (*domain.MFAEnrollment, error) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.EncryptAES(secret)
+	if err != nil {
+		log.Printf("[StartMFAEnrollment] Erreur chiffrement du secret de l'utilisateur %d : %v", user.ID, err)
+		return nil, err
+	}
+
+	saved, err := repository.SaveMFAPendingSecret(user.ID, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	return &domain.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: TOTPProvisioningURI(user.Username, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment active la double authentification si le code correspond au secret en attente,
+// ferme les autres sessions de l'utilisateur et retourne ses codes de secours (affichés une seule fois).
+func ConfirmMFAEnrollment(userID, currentSessionID int64, code string, now time.Time) ([]string, error) {
+	state, err := repository.GetMFAState(userID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if state.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.DecryptAES(state.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := ValidateTOTPCode(secret, code, now)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.EnableMFA(userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	// Les sessions ouvertes avant l'activation n'ont pas présenté de second facteur
+	if _, err := repository.RevokeOtherSessions(userID, currentSessionID, domain.SessionRevokedMFA); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyMFACode vérifie un code TOTP (non rejoué) ou consomme un code de secours de l'utilisateur.
+func VerifyMFACode(userID int64, code string, now time.Time) error {
+	state, err := repository.GetMFAState(userID)
+	if err != nil {
+		return err
+	}
+	if !state.Enabled() {
+		return ErrMFANotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		secret, err := utils.DecryptAES(state.Secret)
+		if err != nil {
+			return err
+		}
+		step, ok := ValidateTOTPCode(secret, code, now)
+		if !ok || step <= state.LastUsedStep {
+			return ErrInvalidMFACode
+		}
+		fresh, err := repository.MarkTOTPStepUsed(userID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	consumed, err := repository.ConsumeRecoveryCode(userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// DisableMFA désactive la double authentification après vérification d'un code valide.
+func DisableMFA(userID int64, code string, now time.Time) error {
+	if err := VerifyMFACode(userID, code, now); err != nil {
+		return err
+	}
+	return repository.DisableMFA(userID, nil)
+}
+
+// RegenerateRecoveryCodes remplace les codes de secours après vérification d'un code valide.
+func RegenerateRecoveryCodes(userID int64, code string, now time.Time) ([]string, error) {
+	if err := VerifyMFACode(userID, code, now); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// StartMFAChallenge ouvre un défi de connexion à présenter avec le second facteur sur POST /auth/mfa/verify.
+func StartMFAChallenge(userID int64, now time.Time) (*domain.MFAChallenge, error) {
+	// Jeton opaque au même format que les refresh tokens, seule son empreinte est stockée
+	token, tokenHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.CreateMFAChallenge(userID, tokenHash, now.Add(domain.MFAChallengeTTL)); err != nil {
+		return nil, err
+	}
+	return &domain.MFAChallenge{
+		MFARequired:    true,
+		ChallengeToken: token,
+		ExpiresIn:      int64(domain.MFAChallengeTTL.Seconds()),
+	}, nil
+}
+
+// CompleteMFAChallenge vérifie le second facteur d'un défi de connexion et ouvre la session de l'utilisateur.
+// Un défi n'accepte qu'un nombre limité de codes erronés et ne sert qu'une fois.
+func CompleteMFAChallenge(challengeToken, code string, client domain.SessionClient, now time.Time) (*domain.User, *domain.AuthTokens, error) {
+	tokenHash := hashRefreshToken(challengeToken)
+	userID, err := repository.StartMFAChallengeAttempt(tokenHash, now, domain.MaxMFAChallengeAttempts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := VerifyMFACode(userID, code, now); err != nil {
+		return nil, nil, err
+	}
+	if err := repository.CompleteMFAChallenge(tokenHash, now); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, err := IssueSessionTokens(user.ID, string(user.Role), client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("[CompleteMFAChallenge] Second facteur validé pour l'utilisateur %d", userID)
+	return user, tokens, nil
+}
+
+// newRecoveryCodes génère les codes de secours au format "xxxxx-xxxxx" et leurs empreintes.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, domain.MFARecoveryCodeCount)
+	hashes := make([]string, 0, domain.MFARecoveryCodeCount)
+	for i := 0; i < domain.MFARecoveryCodeCount; i++ {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))[:10]
+		code := encoded[:5] + "-" + encoded[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode calcule l'empreinte d'un code de secours, indépendamment de la casse et des tirets.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,610 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/utils"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	// ErrOIDCProviderUnknown est retournée pour un fournisseur absent de OIDC_PROVIDERS.
+	ErrOIDCProviderUnknown = errors.New("fournisseur OpenID Connect inconnu")
+	// ErrOIDCIDTokenInvalid est retournée pour un ID token mal signé, expiré ou émis pour un autre client.
+	ErrOIDCIDTokenInvalid = errors.New("ID token invalide")
+	// ErrOIDCLinkRequired est retournée quand un compte local existe pour l'adresse mais ne peut pas être lié
+	// automatiquement : l'utilisateur doit se connecter puis lier le fournisseur depuis son profil.
+	ErrOIDCLinkRequired = errors.New("liaison explicite du compte requise")
+	// ErrOIDCEmailMissing est retournée quand le fournisseur ne communique pas d'adresse pour créer le compte.
+	ErrOIDCEmailMissing = errors.New("adresse e-mail absente de l'ID token")
+)
+
+// OIDCProvider décrit un fournisseur OpenID Connect configuré.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string // Facultatif : client public avec PKCE seul
+	RedirectURL  string
+	Scopes       []string
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]interface{} // Clés publiques du jwks_uri, par kid
+	keysAt    time.Time
+}
+
+// oidcDiscovery est le sous-ensemble utile du document /.well-known/openid-configuration.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCClaims regroupe les informations d'identité extraites d'un ID token vérifié.
+type OIDCClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+// OIDCLoginResult est l'issue d'un retour de fournisseur : connexion (éventuellement après création
+// du compte) ou liaison d'un compte externe à l'utilisateur connecté.
+// LinkOnly signale une liaison démarrée depuis le profil : aucune session ne doit être ouverte.
+type OIDCLoginResult struct {
+	User     *domain.User
+	Created  bool
+	Linked   bool
+	LinkOnly bool
+}
+
+// Algorithmes acceptés pour les ID tokens ; le choix de la clé par kid impose celui de la clé
+var oidcSigningMethods = []string{"RS256", "ES256", "EdDSA"}
+
+// Délai minimal entre deux rechargements du jwks_uri (kid inconnu après une rotation)
+const oidcKeysRefreshInterval = 5 * time.Minute
+
+var (
+	oidcProviders  map[string]*OIDCProvider
+	oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// InitOIDCProviders charge les fournisseurs listés dans OIDC_PROVIDERS (ex. "google,mock"). Pour chacun,
+// OIDC_<NOM>_ISSUER, OIDC_<NOM>_CLIENT_ID et OIDC_<NOM>_REDIRECT_URL sont requis ;
+// OIDC_<NOM>_CLIENT_SECRET et OIDC_<NOM>_SCOPES ("openid email profile" par défaut) sont facultatifs.
+func InitOIDCProviders() {
+	providers := map[string]*OIDCProvider{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := &OIDCProvider{
+			Name:         name,
+			Issuer:       strings.TrimRight(os.Getenv(prefix+"ISSUER"), "/"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(envOrDefault(prefix+"SCOPES", "openid email profile")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			log.Printf("[OIDC] ⚠️  Fournisseur %s ignoré : %sISSUER, %sCLIENT_ID et %sREDIRECT_URL sont requis", name, prefix, prefix, prefix)
+			continue
+		}
+		providers[name] = provider
+		log.Printf("[OIDC] Fournisseur %s configuré (%s)", name, provider.Issuer)
+	}
+	oidcProviders = providers
+}
+
+// SetOIDCProviders remplace les fournisseurs configurés (tests avec un serveur OpenID Connect local).
+func SetOIDCProviders(providers ...*OIDCProvider) {
+	oidcProviders = map[string]*OIDCProvider{}
+	for _, provider := range providers {
+		oidcProviders[provider.Name] = provider
+	}
+}
+
+// OIDCProviderNames retourne les noms des fournisseurs configurés, triés.
+func OIDCProviderNames() []string {
+	if oidcProviders == nil {
+		InitOIDCProviders()
+	}
+	names := make([]string, 0, len(oidcProviders))
+	for name := range oidcProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getOIDCProvider retourne un fournisseur configuré.
+func getOIDCProvider(name string) (*OIDCProvider, error) {
+	if oidcProviders == nil {
+		InitOIDCProviders()
+	}
+	provider, ok := oidcProviders[name]
+	if !ok {
+		return nil, ErrOIDCProviderUnknown
+	}
+	return provider, nil
+}
+
+// StartOIDCLogin prépare l'aller-retour chez le fournisseur (state, nonce, PKCE S256) et retourne l'URL
+// d'autorisation. linkUserID (0 pour une connexion) demande la liaison du compte externe à cet utilisateur.
+func StartOIDCLogin(providerName string, linkUserID int64, now time.Time) (string, error) {
+	provider, err := getOIDCProvider(providerName)
+	if err != nil {
+		return "", err
+	}
+	discovery, err := provider.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	state, stateHash, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, _, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, _, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := repository.CreateOIDCAuthState(stateHash, domain.OIDCAuthState{
+		Provider:     provider.Name,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		LinkUserID:   linkUserID,
+	}, now.Add(domain.OIDCAuthStateTTL)); err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", provider.ClientID)
+	params.Set("redirect_uri", provider.RedirectURL)
+	params.Set("scope", strings.Join(provider.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// CompleteOIDCLogin traite le retour du fournisseur : échange du code (PKCE), vérification de l'ID token,
+// puis connexion par l'identité liée, liaison explicite, liaison par adresse vérifiée ou création du compte.
+func CompleteOIDCLogin(providerName, code, state string, now time.Time) (*OIDCLoginResult, error) {
+	provider, err := getOIDCProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	authState, err := repository.ConsumeOIDCAuthState(hashRefreshToken(state), provider.Name, now)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := provider.exchangeCode(code, authState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := provider.VerifyIDToken(rawIDToken, authState.Nonce, now)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := repository.TouchUserIdentity(provider.Name, claims.Subject, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Liaison explicite depuis le profil d'un utilisateur connecté
+	if authState.LinkUserID > 0 {
+		if userID != 0 && userID != authState.LinkUserID {
+			return nil, repository.ErrIdentityAlreadyLinked
+		}
+		if userID == 0 {
+			if err := repository.CreateUserIdentity(authState.LinkUserID, provider.Name, claims.Subject, now); err != nil {
+				return nil, err
+			}
+		}
+		user, err := repository.GetUserByID(authState.LinkUserID)
+		if err != nil {
+			return nil, err
+		}
+		return &OIDCLoginResult{User: user, Linked: true, LinkOnly: true}, nil
+	}
+
+	// Compte externe déjà lié
+	if userID != 0 {
+		user, err := repository.GetUserByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		return &OIDCLoginResult{User: user}, nil
+	}
+
+	if claims.Email == "" {
+		return nil, ErrOIDCEmailMissing
+	}
+	existing, err := repository.GetUserByEmail(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	// Liaison automatique seulement si l'adresse est vérifiée des deux côtés : un compte local non confirmé
+	// ouvert avec l'adresse d'autrui ne doit pas capter sa connexion externe
+	if existing != nil {
+		if !claims.EmailVerified {
+			return nil, ErrOIDCLinkRequired
+		}
+		verified, err := repository.IsEmailVerified(existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !verified {
+			return nil, ErrOIDCLinkRequired
+		}
+		if err := repository.CreateUserIdentity(existing.ID, provider.Name, claims.Subject, now); err != nil {
+			return nil, err
+		}
+		return &OIDCLoginResult{User: existing, Linked: true}, nil
+	}
+
+	user, err := createOIDCUser(provider, claims, now)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCLoginResult{User: user, Created: true}, nil
+}
+
+// VerifyIDToken vérifie la signature (jwks_uri du fournisseur), l'émetteur, l'audience, l'expiration
+// et le nonce d'un ID token, puis en extrait l'identité.
+func (p *OIDCProvider) VerifyIDToken(rawIDToken, nonce string, now time.Time) (*OIDCClaims, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := jwt.Parser{ValidMethods: oidcSigningMethods}
+	token, err := parser.Parse(rawIDToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.verificationKey(discovery, kid)
+	})
+	if err != nil || !token.Valid {
+		log.Printf("[OIDC] ID token %s refusé : %v", p.Name, err)
+		return nil, ErrOIDCIDTokenInvalid
+	}
+
+	claims, _ := token.Claims.(jwt.MapClaims)
+	if !claims.VerifyIssuer(discovery.Issuer, true) || !claims.VerifyExpiresAt(now.Unix(), true) {
+		return nil, ErrOIDCIDTokenInvalid
+	}
+	audiences := claimStrings(claims["aud"])
+	if !containsString(audiences, p.ClientID) {
+		return nil, ErrOIDCIDTokenInvalid
+	}
+	if azp, ok := claims["azp"].(string); (len(audiences) > 1 || ok) && azp != p.ClientID {
+		return nil, ErrOIDCIDTokenInvalid
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, ErrOIDCIDTokenInvalid
+	}
+
+	result := &OIDCClaims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.GivenName, _ = claims["given_name"].(string)
+	result.FamilyName, _ = claims["family_name"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string: // Certains fournisseurs l'émettent sous forme de chaîne
+		result.EmailVerified = verified == "true"
+	}
+	if result.Subject == "" {
+		return nil, ErrOIDCIDTokenInvalid
+	}
+	return result, nil
+}
+
+// getDiscovery lit (une fois) le document de découverte du fournisseur et vérifie son émetteur.
+func (p *OIDCProvider) getDiscovery() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := oidcGetJSON(p.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("découverte OpenID Connect de %s : %w", p.Name, err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != p.Issuer || discovery.AuthorizationEndpoint == "" ||
+		discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("document de découverte de %s incomplet ou d'un autre émetteur (%s)", p.Name, discovery.Issuer)
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// verificationKey retourne la clé publique du kid, en rechargeant le jwks_uri si elle est inconnue.
+func (p *OIDCProvider) verificationKey(discovery *oidcDiscovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysAt) < oidcKeysRefreshInterval && p.keys != nil {
+		return nil, fmt.Errorf("clé %q inconnue du fournisseur %s", kid, p.Name)
+	}
+
+	keys, err := fetchOIDCKeys(discovery.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.keys, p.keysAt = keys, time.Now()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("clé %q inconnue du fournisseur %s", kid, p.Name)
+}
+
+// exchangeCode échange le code d'autorisation contre les jetons du fournisseur et retourne l'ID token.
+func (p *OIDCProvider) exchangeCode(code, codeVerifier string) (string, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+
+	resp, err := oidcHTTPClient.PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		return "", fmt.Errorf("échange du code auprès de %s : %w", p.Name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("[OIDC] Échange du code refusé par %s (%d) : %s", p.Name, resp.StatusCode, body)
+		return "", ErrOIDCIDTokenInvalid
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil || tokens.IDToken == "" {
+		return "", ErrOIDCIDTokenInvalid
+	}
+	return tokens.IDToken, nil
+}
+
+// fetchOIDCKeys lit un JWKS et retourne ses clés de signature RSA, EC P-256 et Ed25519 par kid.
+func fetchOIDCKeys(jwksURI string) (map[string]interface{}, error) {
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := oidcGetJSON(jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("lecture du JWKS %s : %w", jwksURI, err)
+	}
+
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN == nil && errE == nil {
+				keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX == nil && errY == nil {
+				keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			}
+		case k.Kty == "OKP" && k.Crv == "Ed25519":
+			if x, err := base64.RawURLEncoding.DecodeString(k.X); err == nil && len(x) == ed25519.PublicKeySize {
+				keys[k.Kid] = ed25519.PublicKey(x)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// oidcGetJSON lit un document JSON d'un fournisseur.
+func oidcGetJSON(endpoint string, target interface{}) error {
+	resp, err := oidcHTTPClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("statut HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target)
+}
+
+// createOIDCUser crée un compte abonné à partir de l'identité externe et lie celle-ci.
+// Le mot de passe est aléatoire : l'utilisateur peut en définir un via la réinitialisation par e-mail.
+func createOIDCUser(provider *OIDCProvider, claims *OIDCClaims, now time.Time) (*domain.User, error) {
+	username, err := availableUsername(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	randomPassword, _, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := HashPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedEmail, err := utils.EncryptAES(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	encryptedFirstName, err := utils.EncryptAES(claims.GivenName)
+	if err != nil {
+		return nil, err
+	}
+	encryptedLastName, err := utils.EncryptAES(claims.FamilyName)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &domain.User{
+		FirstName: encryptedFirstName,
+		LastName:  encryptedLastName,
+		Username:  username,
+		Email:     encryptedEmail,
+		Password:  passwordHash,
+		Role:      domain.RoleSubscriber,
+	}
+	if err := repository.CreateUser(user); err != nil {
+		return nil, err
+	}
+	if err := repository.CreateUserIdentity(user.ID, provider.Name, claims.Subject, now); err != nil {
+		return nil, err
+	}
+
+	user.Email, user.FirstName, user.LastName = claims.Email, claims.GivenName, claims.FamilyName
+	if claims.EmailVerified {
+		if _, err := repository.MarkEmailVerified(user.ID); err != nil {
+			return nil, err
+		}
+	} else {
+		go SendVerificationEmail(user)
+	}
+
+	log.Printf("[OIDC] Compte %d créé via %s", user.ID, provider.Name)
+	return user, nil
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Noms d'utilisateur qui ne doivent pas être attribués automatiquement
+var reservedUsernames = map[string]bool{
+	"admin": true, "root": true, "api": true, "www": true, "mail": true,
+	"support": true, "info": true, "contact": true, "help": true,
+}
+
+// availableUsername dérive un pseudo libre (3 à 20 caractères, commençant par une lettre) de l'adresse e-mail.
+func availableUsername(email string) (string, error) {
+	base := usernameInvalidChars.ReplaceAllString(strings.SplitN(email, "@", 2)[0], "")
+	if base == "" || !(base[0] >= 'a' && base[0] <= 'z' || base[0] >= 'A' && base[0] <= 'Z') || reservedUsernames[strings.ToLower(base)] {
+		base = "user" + base
+	}
+	if len(base) > 15 {
+		base = base[:15]
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		available, err := repository.CheckUsernameAvailability(candidate)
+		if err != nil {
+			return "", err
+		}
+		if available {
+			return candidate, nil
+		}
+		suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s_%04d", base, suffix.Int64())
+	}
+	return "", errors.New("aucun pseudo disponible")
+}
+
+// claimStrings normalise un claim chaîne ou tableau de chaînes (ex. aud).
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// containsString indique si la valeur figure dans la liste.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
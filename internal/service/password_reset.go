@@ -0,0 +1,142 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"onlyflick/internal/config"
+	"onlyflick/internal/repository"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPasswordResetThrottled est retournée quand trop de tentatives ont été faites pour l'e-mail ou l'adresse IP.
+	ErrPasswordResetThrottled = errors.New("trop de tentatives de réinitialisation")
+	// ErrInvalidPasswordReset est retournée pour un jeton inconnu, expiré ou déjà utilisé, ou un mot de passe vide.
+	ErrInvalidPasswordReset = errors.New("réinitialisation du mot de passe invalide")
+)
+
+// Limites de tentatives par fenêtre glissante d'une heure.
+const (
+	passwordResetWindow      = time.Hour
+	maxForgotAttemptsByEmail = 3
+	maxForgotAttemptsByIP    = 10
+	maxResetAttemptsByEmail  = 5
+	maxResetAttemptsByIP     = 20
+)
+
+// PasswordResetTTL retourne la durée de validité d'un lien de réinitialisation (PASSWORD_RESET_TTL, 1h par défaut).
+func PasswordResetTTL() time.Duration {
+	return parseDurationEnv("PASSWORD_RESET_TTL", time.Hour)
+}
+
+// passwordResetEmailHash calcule l'empreinte HMAC d'une adresse e-mail : les adresses ne sont jamais
+// journalisées en clair dans les tentatives.
+func passwordResetEmailHash(email string) string {
+	key := sha256.Sum256([]byte("password-reset:" + config.SecretKey))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkPasswordResetThrottle refuse la tentative si l'e-mail ou l'IP a dépassé sa limite, puis la journalise.
+func checkPasswordResetThrottle(kind, emailHash, ipAddress string, maxByEmail, maxByIP int, now time.Time) error {
+	byEmail, byIP, err := repository.CountPasswordResetAttempts(kind, emailHash, ipAddress, now.Add(-passwordResetWindow))
+	if err != nil {
+		return err
+	}
+	if byEmail >= maxByEmail || byIP >= maxByIP {
+		log.Printf("[PasswordReset] ⚠️  Limite atteinte (%s) : %d tentative(s) pour l'e-mail, %d pour l'IP %s", kind, byEmail, byIP, ipAddress)
+		return ErrPasswordResetThrottled
+	}
+	return repository.RecordPasswordResetAttempt(kind, emailHash, ipAddress, now)
+}
+
+// RequestPasswordReset traite une demande de réinitialisation. La réponse est identique que l'adresse
+// corresponde ou non à un compte : la recherche et l'envoi de l'e-mail se font en arrière-plan.
+func RequestPasswordReset(email, ipAddress string, now time.Time) error {
+	email = strings.TrimSpace(email)
+	if err := checkPasswordResetThrottle(repository.PasswordResetAttemptForgot, passwordResetEmailHash(email), ipAddress,
+		maxForgotAttemptsByEmail, maxForgotAttemptsByIP, now); err != nil {
+		return err
+	}
+
+	go sendPasswordResetEmail(email)
+	return nil
+}
+
+// sendPasswordResetEmail émet un jeton de réinitialisation et l'envoie si l'adresse correspond à un compte.
+func sendPasswordResetEmail(email string) {
+	user, err := repository.GetUserByEmail(email)
+	if err != nil || user == nil {
+		log.Println("[sendPasswordResetEmail] Aucun compte pour l'adresse demandée, aucun e-mail envoyé")
+		return
+	}
+
+	// Jeton opaque au même format que les refresh tokens, seule son empreinte est stockée
+	token, tokenHash, err := newRefreshToken()
+	if err != nil {
+		log.Printf("[sendPasswordResetEmail] Erreur génération du jeton : %v", err)
+		return
+	}
+	expiresAt := time.Now().Add(PasswordResetTTL())
+	if err := repository.CreatePasswordResetToken(user.ID, tokenHash, expiresAt); err != nil {
+		log.Printf("[sendPasswordResetEmail] Erreur enregistrement du jeton de l'utilisateur %d : %v", user.ID, err)
+		return
+	}
+
+	link := envOrDefault("FRONTEND_URL", "http://localhost:3000") + "/reset-password?token=" + url.QueryEscape(token)
+	if err := SendTemplatedEmail(user.Email, "Réinitialisation de votre mot de passe OnlyFlick", "password_reset", map[string]string{
+		"Username":  user.Username,
+		"Link":      link,
+		"ExpiresAt": expiresAt.UTC().Format("02/01/2006 à 15:04 (UTC)"),
+	}); err != nil {
+		log.Printf("[sendPasswordResetEmail] Erreur envoi de l'e-mail à l'utilisateur %d : %v", user.ID, err)
+	}
+}
+
+// ResetPassword remplace le mot de passe associé au jeton et révoque toutes les sessions de l'utilisateur.
+// Le jeton est à usage unique.
+func ResetPassword(token, newPassword, ipAddress string, now time.Time) error {
+	if strings.TrimSpace(token) == "" || strings.TrimSpace(newPassword) == "" {
+		return ErrInvalidPasswordReset
+	}
+	tokenHash := hashRefreshToken(token)
+
+	// La limite porte sur l'IP et, si le jeton est reconnu, sur l'adresse du compte
+	emailHash := ""
+	userID, err := repository.GetPasswordResetTokenUser(tokenHash, now)
+	if err != nil && !errors.Is(err, repository.ErrPasswordResetTokenInvalid) {
+		return err
+	}
+	if err == nil {
+		user, err := repository.GetUserByID(userID)
+		if err != nil {
+			return err
+		}
+		emailHash = passwordResetEmailHash(user.Email)
+	}
+	if err := checkPasswordResetThrottle(repository.PasswordResetAttemptReset, emailHash, ipAddress,
+		maxResetAttemptsByEmail, maxResetAttemptsByIP, now); err != nil {
+		return err
+	}
+	if userID == 0 {
+		return ErrInvalidPasswordReset
+	}
+
+	passwordHash, err := HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if _, err := repository.ResetPasswordWithToken(tokenHash, passwordHash, now); err != nil {
+		if errors.Is(err, repository.ErrPasswordResetTokenInvalid) {
+			return ErrInvalidPasswordReset
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+)
+
+// Types d'événements de webhook traités (format Stripe, également émis par le fournisseur fictif)
+const (
+	StripeEventPaymentSucceeded    = "payment_intent.succeeded"
+	StripeEventPaymentFailed       = "payment_intent.payment_failed"
+	StripeEventChargeRefunded      = "charge.refunded"
+	StripeEventSetupSucceeded      = "setup_intent.succeeded"
+	StripeEventSubscriptionDeleted = "customer.subscription.deleted"
+)
+
+// Statuts d'un PaymentIntent renvoyés par les fournisseurs
+const (
+	IntentStatusSucceeded             = "succeeded"
+	IntentStatusProcessing            = "processing"
+	IntentStatusRequiresPaymentMethod = "requires_payment_method"
+	IntentStatusRequiresAction        = "requires_action"
+)
+
+// ErrWebhookSecretMissing est retournée si le secret de signature des webhooks n'est pas configuré.
+var ErrWebhookSecretMissing = errors.New("STRIPE_WEBHOOK_SECRET non définie")
+
+// PaymentIntentRequest décrit un paiement à créer auprès du fournisseur.
+type PaymentIntentRequest struct {
+	Amount          int64             // Montant en centimes
+	Currency        string            // Code ISO 4217 (EUR, USD...)
+	CustomerID      string            // Client du fournisseur (optionnel)
+	PaymentMethodID string            // Moyen de paiement enregistré (paiement hors session)
+	OffSession      bool              // Débit confirmé immédiatement, sans l'utilisateur
+	SaveForFuture   bool              // Conserver le moyen de paiement pour les renouvellements
+	Metadata        map[string]string // Métadonnées (subscription_id...)
+	IdempotencyKey  string            // Clé rendant le débit rejouable sans double prélèvement (optionnelle)
+}
+
+// PaymentIntent est la vue neutre d'un paiement créé chez le fournisseur.
+type PaymentIntent struct {
+	ID           string
+	ClientSecret string
+	Status       string
+	Amount       int64
+	Currency     string
+}
+
+// SetupIntent enregistre un moyen de paiement sans débit immédiat (essai gratuit, période offerte).
+type SetupIntent struct {
+	ID           string
+	ClientSecret string
+	Status       string
+}
+
+// Refund représente un remboursement émis chez le fournisseur.
+type Refund struct {
+	ID              string
+	PaymentIntentID string
+	Amount          int64
+	Status          string
+}
+
+// WebhookEvent est un événement de webhook dont la signature a été vérifiée.
+// Data contient l'objet de l'événement au format JSON Stripe.
+type WebhookEvent struct {
+	ID   string
+	Type string
+	Data json.RawMessage
+}
+
+// PaymentProvider abstrait le prestataire de paiement (Stripe en production, fournisseur fictif en test).
+type PaymentProvider interface {
+	// Name retourne le nom du fournisseur ("stripe", "fake").
+	Name() string
+	// CreateCustomer crée un client pour l'utilisateur et retourne son identifiant.
+	CreateCustomer(userID int64) (string, error)
+	// CreatePaymentIntent crée un paiement. En cas de refus d'un débit hors session,
+	// l'intent (avec son ID) est retourné avec l'erreur.
+	CreatePaymentIntent(req PaymentIntentRequest) (*PaymentIntent, error)
+	// CreateSetupIntent prépare l'enregistrement d'un moyen de paiement du client pour des débits hors session.
+	CreateSetupIntent(customerID string, metadata map[string]string) (*SetupIntent, error)
+	// RefundPayment rembourse un paiement, totalement si amount vaut 0.
+	RefundPayment(paymentIntentID string, amount int64) (*Refund, error)
+	// VerifyWebhook vérifie la signature d'un webhook et retourne l'événement.
+	VerifyWebhook(payload []byte, signatureHeader string) (*WebhookEvent, error)
+}
+
+// Fournisseur de paiement global
+var paymentProvider PaymentProvider
+
+// InitPaymentProvider sélectionne le fournisseur de paiement via PAYMENT_PROVIDER ("stripe" par défaut, ou "fake").
+func InitPaymentProvider() {
+	switch strings.ToLower(os.Getenv("PAYMENT_PROVIDER")) {
+	case "fake":
+		paymentProvider = NewFakePaymentProvider()
+	default:
+		paymentProvider = NewStripePaymentProvider()
+	}
+
+	log.Printf("✅ [Payments] Fournisseur de paiement initialisé : %s", paymentProvider.Name())
+}
+
+// SetPaymentProvider remplace le fournisseur de paiement (utilisé par les tests).
+func SetPaymentProvider(provider PaymentProvider) {
+	paymentProvider = provider
+}
+
+// Payments retourne le fournisseur de paiement courant, initialisé depuis la configuration si nécessaire.
+func Payments() PaymentProvider {
+	if paymentProvider == nil {
+		InitPaymentProvider()
+	}
+	return paymentProvider
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+)
+
+var (
+	// ErrPostNotForSale est retournée pour un post qui n'est pas payant à l'unité.
+	ErrPostNotForSale = errors.New("post non payant")
+	// ErrPostAlreadyUnlocked est retournée quand le lecteur a déjà accès au post (achat réglé ou auteur).
+	ErrPostAlreadyUnlocked = errors.New("post déjà débloqué")
+)
+
+// StartPostPurchase crée le paiement du déblocage d'un post payant auprès du fournisseur et enregistre
+// l'achat en attente. Le post est débloqué quand le webhook confirme le paiement.
+// Les abonnés du créateur paient également : le prix est propre au post.
+func StartPostPurchase(post *domain.Post, buyerID int64) (*domain.PostPurchaseCheckout, error) {
+	if post.Visibility != domain.PayPerView || post.Price <= 0 {
+		return nil, ErrPostNotForSale
+	}
+	if post.UserID == buyerID {
+		return nil, ErrPostAlreadyUnlocked
+	}
+
+	unlocked, err := repository.HasUnlockedPost(post.ID, buyerID)
+	if err != nil {
+		return nil, err
+	}
+	if unlocked {
+		return nil, ErrPostAlreadyUnlocked
+	}
+
+	// Devise du créateur, utilisée également pour son grand livre
+	pricing, err := repository.GetCreatorPricing(post.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	customerID, err := ensureStripeCustomer(buyerID)
+	if err != nil {
+		return nil, err
+	}
+
+	intent, err := Payments().CreatePaymentIntent(PaymentIntentRequest{
+		Amount:     int64(post.Price), // Montant en centimes
+		Currency:   pricing.Currency,
+		CustomerID: customerID,
+		Metadata: map[string]string{
+			"post_id":  fmt.Sprintf("%d", post.ID),
+			"buyer_id": fmt.Sprintf("%d", buyerID),
+		},
+	})
+	if err != nil {
+		log.Printf("[StartPostPurchase] Erreur lors de la création du paiement du post %d : %v", post.ID, err)
+		return nil, err
+	}
+
+	payment, err := repository.CreatePostPurchase(post, buyerID, intent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[StartPostPurchase] Paiement %s initié pour le post %d par %d (%d %s)", intent.ID, post.ID, buyerID, post.Price, pricing.Currency)
+	return &domain.PostPurchaseCheckout{
+		PostID:       post.ID,
+		PaymentID:    payment.ID,
+		Amount:       post.Price,
+		Currency:     pricing.Currency,
+		ClientSecret: intent.ClientSecret,
+	}, nil
+}
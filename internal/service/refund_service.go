@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"time"
+)
+
+var (
+	// ErrPaymentNotRefundable est retournée pour un paiement non encaissé, déjà remboursé ou lui-même un remboursement.
+	ErrPaymentNotRefundable = errors.New("paiement non remboursable")
+	// ErrNoActiveSubscription est retournée lors de la résiliation d'un abonnement inexistant ou échu.
+	ErrNoActiveSubscription = errors.New("aucun abonnement actif")
+	// ErrInvalidCancellationMode est retournée pour un mode de résiliation inconnu.
+	ErrInvalidCancellationMode = errors.New("mode de résiliation invalide")
+)
+
+// RefundPayment rembourse un paiement via le fournisseur de paiement, totalement si amount vaut 0,
+// puis enregistre la ligne de remboursement et l'écriture au grand livre. actor est l'administrateur
+// à l'origine du remboursement, nil pour un remboursement automatique (résiliation au prorata).
+func RefundPayment(paymentID, amount int64, reason string, actor *domain.AuditActor) (*domain.Payment, error) {
+	payment, err := repository.GetPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.RefundOf != nil || payment.StripePaymentID == "" ||
+		(payment.Status != domain.PaymentStatusSucceeded && payment.Status != domain.PaymentStatusPartiallyRefunded) {
+		return nil, ErrPaymentNotRefundable
+	}
+
+	refunded, err := repository.GetRefundedAmount(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	remaining := int64(payment.Amount) - refunded
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return nil, repository.ErrRefundExceedsAmount
+	}
+
+	refund, err := Payments().RefundPayment(payment.StripePaymentID, amount)
+	if err != nil {
+		log.Printf("[RefundPayment] Erreur remboursement du paiement %d chez le fournisseur : %v", paymentID, err)
+		return nil, err
+	}
+
+	record, err := repository.RecordRefund(paymentID, refund.ID, amount, reason, actor)
+	if err != nil {
+		// Le remboursement a été émis : il sera rapproché par le webhook charge.refunded
+		log.Printf("[RefundPayment] Remboursement %s émis mais non enregistré : %v", refund.ID, err)
+		return nil, err
+	}
+	if record == nil {
+		// Déjà enregistré par le webhook
+		record = &domain.Payment{StripePaymentID: refund.ID, Amount: int(amount), Status: domain.PaymentStatusRefund, RefundOf: &paymentID}
+	}
+
+	log.Printf("[RefundPayment] Paiement %d remboursé de %d centimes (%s)", paymentID, amount, refund.ID)
+	return record, nil
+}
+
+// ProratedRefundAmount retourne le montant à rembourser pour la part de la période payée restant après now.
+// Les remboursements déjà effectués sur le paiement sont déduits.
+func ProratedRefundAmount(payment domain.Payment, refunded int64, now time.Time) int64 {
+	amount := int64(payment.Amount)
+	target := amount
+	if now.After(payment.StartAt) {
+		// Calcul à la seconde pour éviter tout dépassement sur les nanosecondes
+		total := int64(payment.EndAt.Sub(payment.StartAt) / time.Second)
+		if total <= 0 || !payment.EndAt.After(now) {
+			return 0
+		}
+		target = amount * int64(payment.EndAt.Sub(now)/time.Second) / total
+	}
+
+	if target -= refunded; target < 0 {
+		return 0
+	}
+	return target
+}
+
+// CancelSubscription résilie l'abonnement d'un utilisateur à un créateur.
+// CancelAtPeriodEnd désactive le renouvellement et conserve l'accès jusqu'à la fin de la période payée ;
+// CancelNow met fin à l'accès et rembourse au prorata les périodes payées restantes.
+func CancelSubscription(subscriberID, creatorID int64, mode string, now time.Time) (*domain.SubscriptionCancellation, error) {
+	if mode != domain.CancelAtPeriodEnd && mode != domain.CancelNow {
+		return nil, ErrInvalidCancellationMode
+	}
+
+	subscription, err := repository.GetActiveSubscription(subscriberID, creatorID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil || !subscription.Status {
+		return nil, ErrNoActiveSubscription
+	}
+
+	result := &domain.SubscriptionCancellation{
+		SubscriptionID: subscription.ID,
+		Mode:           mode,
+		EndsAt:         subscription.EndAt,
+		Refunds:        []domain.Payment{},
+	}
+
+	if mode == domain.CancelAtPeriodEnd {
+		if err := repository.SetAutoRenew(subscription.ID, false); err != nil {
+			return nil, err
+		}
+		log.Printf("[CancelSubscription] Abonnement %d résilié à la fin de la période (%s)", subscription.ID, subscription.EndAt.Format(time.RFC3339))
+		return result, nil
+	}
+
+	payments, err := repository.ListRefundablePayments(subscription.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		refunded, err := repository.GetRefundedAmount(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		amount := ProratedRefundAmount(p, refunded, now)
+		if amount <= 0 || p.StripePaymentID == "" {
+			continue
+		}
+
+		refund, err := RefundPayment(p.ID, amount, "Résiliation immédiate au prorata", nil)
+		if err != nil {
+			return nil, err
+		}
+		result.RefundedAmount += amount
+		result.Refunds = append(result.Refunds, *refund)
+	}
+
+	if err := repository.EndSubscriptionNow(subscription.ID, now); err != nil {
+		return nil, err
+	}
+	result.EndsAt = now
+
+	log.Printf("[CancelSubscription] Abonnement %d résilié immédiatement, %d centimes remboursés", subscription.ID, result.RefundedAmount)
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"time"
+)
+
+// RefreshTokenTTL retourne la durée de validité des refresh tokens (REFRESH_TOKEN_TTL, 30 jours par défaut).
+func RefreshTokenTTL() time.Duration {
+	return parseDurationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// newRefreshToken génère un refresh token aléatoire et son empreinte stockée en base.
+func newRefreshToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashRefreshToken(token), nil
+}
+
+// hashRefreshToken calcule l'empreinte SHA-256 d'un refresh token : le token en clair n'est jamais stocké.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeSessionClient borne le user agent conservé pour une session.
+func normalizeSessionClient(client domain.SessionClient) domain.SessionClient {
+	if len(client.UserAgent) > domain.MaxUserAgentLen {
+		client.UserAgent = client.UserAgent[:domain.MaxUserAgentLen]
+	}
+	return client
+}
+
+// IssueSessionTokens ouvre une nouvelle session pour l'utilisateur depuis l'appareil client
+// et retourne son access token et son refresh token.
+func IssueSessionTokens(userID int64, role string, client domain.SessionClient) (*domain.AuthTokens, error) {
+	refreshToken, refreshHash, err := newRefreshToken()
+	if err != nil {
+		log.Printf("[IssueSessionTokens] Erreur génération du refresh token : %v", err)
+		return nil, err
+	}
+
+	refreshExpiresAt := time.Now().Add(RefreshTokenTTL())
+	sessionID, err := repository.CreateSession(userID, normalizeSessionClient(client), refreshHash, refreshExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessionTokens(userID, role, sessionID, refreshToken, refreshExpiresAt)
+}
+
+// RefreshSessionTokens échange un refresh token contre une nouvelle paire de jetons (rotation).
+// Présenter un refresh token déjà échangé révoque toute la session (repository.ErrRefreshTokenReused).
+func RefreshSessionTokens(refreshToken string, client domain.SessionClient) (*domain.AuthTokens, error) {
+	newToken, newHash, err := newRefreshToken()
+	if err != nil {
+		log.Printf("[RefreshSessionTokens] Erreur génération du refresh token : %v", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	refreshExpiresAt := now.Add(RefreshTokenTTL())
+	sessionID, userID, err := repository.RotateRefreshToken(hashRefreshToken(refreshToken), newHash, refreshExpiresAt, now, normalizeSessionClient(client))
+	if err != nil {
+		return nil, err
+	}
+
+	// Le rôle est relu à chaque renouvellement pour refléter une promotion ou une rétrogradation
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[RefreshSessionTokens] Session %d de l'utilisateur %d renouvelée", sessionID, userID)
+	return sessionTokens(userID, string(user.Role), sessionID, newToken, refreshExpiresAt)
+}
+
+// RevokeRefreshToken ferme la session à laquelle appartient le refresh token (déconnexion).
+func RevokeRefreshToken(refreshToken string) error {
+	return repository.RevokeSessionByRefreshToken(hashRefreshToken(refreshToken))
+}
+
+// sessionTokens signe l'access token de la session et assemble la paire de jetons.
+func sessionTokens(userID int64, role string, sessionID int64, refreshToken string, refreshExpiresAt time.Time) (*domain.AuthTokens, error) {
+	accessToken, err := generateAccessToken(userID, role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AuthTokens{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int64(AccessTokenTTL().Seconds()),
+		RefreshExpiresAt: refreshExpiresAt,
+		SessionID:        sessionID,
+	}, nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/stripe/stripe-go"
+	"github.com/stripe/stripe-go/customer"
+	"github.com/stripe/stripe-go/paymentintent"
+	"github.com/stripe/stripe-go/refund"
+	"github.com/stripe/stripe-go/setupintent"
+	"github.com/stripe/stripe-go/webhook"
+)
+
+// StripePaymentProvider implémente PaymentProvider avec l'API Stripe.
+type StripePaymentProvider struct{}
+
+// NewStripePaymentProvider configure la clé Stripe depuis STRIPE_SECRET_KEY.
+func NewStripePaymentProvider() *StripePaymentProvider {
+	stripe.Key = os.Getenv("STRIPE_SECRET_KEY")
+	return &StripePaymentProvider{}
+}
+
+// Name retourne "stripe".
+func (p *StripePaymentProvider) Name() string {
+	return "stripe"
+}
+
+// CreateCustomer crée un client Stripe rattaché à l'utilisateur via ses métadonnées.
+func (p *StripePaymentProvider) CreateCustomer(userID int64) (string, error) {
+	params := &stripe.CustomerParams{}
+	params.AddMetadata("user_id", fmt.Sprintf("%d", userID))
+
+	c, err := customer.New(params)
+	if err != nil {
+		log.Printf("[StripePaymentProvider] Erreur création du client pour %d : %v", userID, err)
+		return "", err
+	}
+	return c.ID, nil
+}
+
+// CreatePaymentIntent crée un PaymentIntent Stripe.
+func (p *StripePaymentProvider) CreatePaymentIntent(req PaymentIntentRequest) (*PaymentIntent, error) {
+	params := &stripe.PaymentIntentParams{
+		Amount:   stripe.Int64(req.Amount),
+		Currency: stripe.String(strings.ToLower(req.Currency)),
+	}
+	if req.CustomerID != "" {
+		params.Customer = stripe.String(req.CustomerID)
+	}
+	if req.SaveForFuture {
+		params.SetupFutureUsage = stripe.String("off_session")
+	}
+	if req.OffSession {
+		params.PaymentMethod = stripe.String(req.PaymentMethodID)
+		params.Confirm = stripe.Bool(true)
+		params.OffSession = stripe.Bool(true)
+	}
+	for key, value := range req.Metadata {
+		params.AddMetadata(key, value)
+	}
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+
+	intent, err := paymentintent.New(params)
+	if err != nil {
+		// Un refus de carte hors session renvoie tout de même l'intent créé
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.PaymentIntent != nil {
+			return toPaymentIntent(stripeErr.PaymentIntent), err
+		}
+		log.Printf("[StripePaymentProvider] Erreur création du PaymentIntent : %v", err)
+		return nil, err
+	}
+
+	return toPaymentIntent(intent), nil
+}
+
+// CreateSetupIntent crée un SetupIntent Stripe pour un usage hors session.
+func (p *StripePaymentProvider) CreateSetupIntent(customerID string, metadata map[string]string) (*SetupIntent, error) {
+	params := &stripe.SetupIntentParams{
+		Customer: stripe.String(customerID),
+		Usage:    stripe.String("off_session"),
+	}
+	for key, value := range metadata {
+		params.AddMetadata(key, value)
+	}
+
+	intent, err := setupintent.New(params)
+	if err != nil {
+		log.Printf("[StripePaymentProvider] Erreur création du SetupIntent : %v", err)
+		return nil, err
+	}
+
+	return &SetupIntent{ID: intent.ID, ClientSecret: intent.ClientSecret, Status: string(intent.Status)}, nil
+}
+
+// RefundPayment rembourse un PaymentIntent Stripe (totalement si amount vaut 0).
+func (p *StripePaymentProvider) RefundPayment(paymentIntentID string, amount int64) (*Refund, error) {
+	params := &stripe.RefundParams{PaymentIntent: stripe.String(paymentIntentID)}
+	if amount > 0 {
+		params.Amount = stripe.Int64(amount)
+	}
+
+	r, err := refund.New(params)
+	if err != nil {
+		log.Printf("[StripePaymentProvider] Erreur remboursement de %s : %v", paymentIntentID, err)
+		return nil, err
+	}
+
+	return &Refund{ID: r.ID, PaymentIntentID: paymentIntentID, Amount: r.Amount, Status: string(r.Status)}, nil
+}
+
+// VerifyWebhook vérifie l'en-tête Stripe-Signature (HMAC-SHA256 + tolérance sur l'horodatage).
+func (p *StripePaymentProvider) VerifyWebhook(payload []byte, signatureHeader string) (*WebhookEvent, error) {
+	secret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+	if secret == "" {
+		log.Println("[StripePaymentProvider] STRIPE_WEBHOOK_SECRET non définie")
+		return nil, ErrWebhookSecretMissing
+	}
+
+	event, err := webhook.ConstructEvent(payload, signatureHeader, secret)
+	if err != nil {
+		log.Printf("[StripePaymentProvider] Signature invalide : %v", err)
+		return nil, err
+	}
+	if event.Data == nil {
+		return nil, fmt.Errorf("données d'événement manquantes")
+	}
+
+	return &WebhookEvent{ID: event.ID, Type: event.Type, Data: event.Data.Raw}, nil
+}
+
+// toPaymentIntent convertit un PaymentIntent Stripe en vue neutre.
+func toPaymentIntent(intent *stripe.PaymentIntent) *PaymentIntent {
+	return &PaymentIntent{
+		ID:           intent.ID,
+		ClientSecret: intent.ClientSecret,
+		Status:       string(intent.Status),
+		Amount:       intent.Amount,
+		Currency:     intent.Currency,
+	}
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"time"
+)
+
+// ErrNoPaymentMethod est retournée quand un renouvellement ne peut pas être débité faute de moyen de paiement enregistré.
+var ErrNoPaymentMethod = errors.New("aucun moyen de paiement enregistré")
+
+// StartSubscriptionCheckout démarre une souscription : essai gratuit si le créateur en propose un et que
+// l'abonné ne s'est jamais abonné à lui (firstSubscription), paiement de la première période sinon.
+func StartSubscriptionCheckout(subscriptionID, subscriberID, creatorID int64, tierID *int64, promo *domain.PromoCode, firstSubscription bool, now time.Time) (*domain.SubscriptionCheckout, error) {
+	if firstSubscription {
+		pricing, err := repository.GetCreatorPricing(creatorID)
+		if err != nil {
+			return nil, err
+		}
+		if pricing.TrialDays > 0 {
+			return StartSubscriptionTrial(subscriptionID, subscriberID, creatorID, tierID, promo, pricing.TrialDays, now)
+		}
+	}
+	return StartSubscriptionPayment(subscriptionID, subscriberID, creatorID, tierID, promo, now)
+}
+
+// StartSubscriptionPayment crée un paiement auprès du fournisseur pour une période d'abonnement débutant à startAt
+// et enregistre le paiement en attente. Le moyen de paiement est conservé pour les renouvellements.
+// Le code promo éventuel réduit la première période et, s'il n'est pas limité à celle-ci, les suivantes ;
+// son utilisation est enregistrée avec le paiement (ou la période offerte), une fois le fournisseur sollicité.
+// Une période entièrement offerte est activée immédiatement : seul le moyen de paiement est alors demandé.
+// Retourne le client_secret à transmettre au front-end.
+func StartSubscriptionPayment(subscriptionID, subscriberID, creatorID int64, tierID *int64, promo *domain.PromoCode, startAt time.Time) (*domain.SubscriptionCheckout, error) {
+	price, currency, err := repository.ResolveSubscriptionPrice(creatorID, tierID)
+	if err != nil {
+		return nil, err
+	}
+
+	checkout := &domain.SubscriptionCheckout{
+		SubscriptionID: subscriptionID,
+		Amount:         price,
+		OriginalAmount: price,
+		Currency:       currency,
+		IntentType:     domain.IntentTypePayment,
+	}
+
+	// Code promo appliqué aux renouvellements : aucun si la réduction ne porte que sur la première période
+	var nextPromoID *int64
+	if promo != nil {
+		checkout.Amount = promo.Apply(price)
+		checkout.PromoCode = promo.Code
+		if !promo.FirstPeriodOnly {
+			nextPromoID = &promo.ID
+		}
+	}
+	if err := repository.SetSubscriptionPromo(subscriptionID, nextPromoID); err != nil {
+		return nil, err
+	}
+
+	customerID, err := ensureStripeCustomer(subscriberID)
+	if err != nil {
+		return nil, err
+	}
+	metadata := map[string]string{"subscription_id": fmt.Sprintf("%d", subscriptionID)}
+
+	if checkout.Amount == 0 {
+		setup, err := Payments().CreateSetupIntent(customerID, metadata)
+		if err != nil {
+			log.Printf("[StartSubscriptionPayment] Erreur lors de l'enregistrement du moyen de paiement : %v", err)
+			return nil, err
+		}
+		if err := repository.GrantFreePeriod(subscriptionID, startAt.AddDate(0, 1, 0), promoRedemption(promo, subscriberID, subscriptionID, startAt)); err != nil {
+			return nil, err
+		}
+		checkout.ClientSecret = setup.ClientSecret
+		checkout.IntentType = domain.IntentTypeSetup
+
+		log.Printf("[StartSubscriptionPayment] Première période offerte pour l'abonnement %d (code %s)", subscriptionID, checkout.PromoCode)
+		return checkout, nil
+	}
+
+	intent, err := Payments().CreatePaymentIntent(PaymentIntentRequest{
+		Amount:        int64(checkout.Amount), // Montant en centimes
+		Currency:      currency,
+		CustomerID:    customerID,
+		SaveForFuture: true,
+		Metadata:      metadata,
+	})
+	if err != nil {
+		log.Printf("[StartSubscriptionPayment] Erreur lors de la création du paiement : %v", err)
+		return nil, err
+	}
+
+	// Enregistrer le paiement en attente : le webhook confirmera ou rejettera le paiement
+	_, err = repository.CreatePaymentWithRedemption(subscriptionID, intent.ID, fmt.Sprintf("%d", subscriberID), startAt, startAt.AddDate(0, 1, 0), checkout.Amount,
+		domain.PaymentStatusPending, promoRedemption(promo, subscriberID, subscriptionID, startAt))
+	if err != nil {
+		return nil, err
+	}
+	checkout.ClientSecret = intent.ClientSecret
+
+	log.Printf("[StartSubscriptionPayment] Paiement %s initié pour l'abonnement %d (%d %s)", intent.ID, subscriptionID, checkout.Amount, currency)
+	return checkout, nil
+}
+
+// StartSubscriptionTrial active un essai gratuit de trialDays jours et prépare l'enregistrement du moyen de paiement
+// qui sera débité hors session à la fin de l'essai, au plein tarif ou au tarif réduit par le code promo.
+func StartSubscriptionTrial(subscriptionID, subscriberID, creatorID int64, tierID *int64, promo *domain.PromoCode, trialDays int, now time.Time) (*domain.SubscriptionCheckout, error) {
+	price, currency, err := repository.ResolveSubscriptionPrice(creatorID, tierID)
+	if err != nil {
+		return nil, err
+	}
+
+	customerID, err := ensureStripeCustomer(subscriberID)
+	if err != nil {
+		return nil, err
+	}
+
+	setup, err := Payments().CreateSetupIntent(customerID, map[string]string{"subscription_id": fmt.Sprintf("%d", subscriptionID)})
+	if err != nil {
+		log.Printf("[StartSubscriptionTrial] Erreur lors de l'enregistrement du moyen de paiement : %v", err)
+		return nil, err
+	}
+
+	trialEnd := now.AddDate(0, 0, trialDays)
+	checkout := &domain.SubscriptionCheckout{
+		SubscriptionID: subscriptionID,
+		Amount:         0,
+		OriginalAmount: price,
+		Currency:       currency,
+		TrialEndsAt:    &trialEnd,
+		ClientSecret:   setup.ClientSecret,
+		IntentType:     domain.IntentTypeSetup,
+	}
+
+	var promoID *int64
+	if promo != nil {
+		promoID = &promo.ID
+		checkout.PromoCode = promo.Code
+	}
+	if err := repository.StartTrialPeriod(subscriptionID, trialEnd, promoID, promoRedemption(promo, subscriberID, subscriptionID, now)); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[StartSubscriptionTrial] Essai de %d jours pour l'abonnement %d", trialDays, subscriptionID)
+	return checkout, nil
+}
+
+// promoRedemption décrit l'utilisation du code promo d'une souscription (nil sans code).
+func promoRedemption(promo *domain.PromoCode, subscriberID, subscriptionID int64, at time.Time) *repository.PromoRedemption {
+	if promo == nil {
+		return nil
+	}
+	return &repository.PromoRedemption{PromoCodeID: promo.ID, UserID: subscriberID, SubscriptionID: subscriptionID, At: at}
+}
+
+// renewalAmount applique au prix d'un renouvellement le code promo en cours de l'abonnement.
+// Retourne le montant à débiter et le code appliqué (nil = plein tarif).
+func renewalAmount(c repository.RenewalCandidate, price int) (int, *domain.PromoCode, error) {
+	if c.PromoCodeID == nil {
+		return price, nil, nil
+	}
+
+	promo, err := repository.GetPromoCodeByID(*c.PromoCodeID)
+	if errors.Is(err, repository.ErrPromoCodeNotFound) {
+		return price, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return promo.Apply(price), promo, nil
+}
+
+// ChargeSubscriptionRenewal débite hors session, via le fournisseur de paiement, la période suivant la fin de l'abonnement
+// (ou de l'essai gratuit) et enregistre la tentative dans payments, quel qu'en soit le résultat.
+// Retourne le statut du paiement enregistré.
+func ChargeSubscriptionRenewal(c repository.RenewalCandidate) (string, error) {
+	price, currency, err := repository.ResolveSubscriptionPrice(c.CreatorID, c.TierID)
+	if err != nil {
+		return "", err
+	}
+	amount, promo, err := renewalAmount(c, price)
+	if err != nil {
+		return "", err
+	}
+
+	periodStart := c.EndAt
+	periodEnd := c.EndAt.AddDate(0, 1, 0)
+	payerID := fmt.Sprintf("%d", c.SubscriberID)
+
+	// Un code limité à la première période n'est plus appliqué une fois débité : retour au plein tarif
+	consumePromo := func() error {
+		if promo == nil || !promo.FirstPeriodOnly {
+			return nil
+		}
+		return repository.SetSubscriptionPromo(c.SubscriptionID, nil)
+	}
+
+	if amount == 0 {
+		if err := repository.GrantFreePeriod(c.SubscriptionID, periodEnd, nil); err != nil {
+			return "", err
+		}
+		return domain.PaymentStatusSucceeded, consumePromo()
+	}
+
+	if c.StripeCustomerID == "" || c.PaymentMethodID == "" {
+		if _, err := repository.CreatePayment(c.SubscriptionID, "", payerID, periodStart, periodEnd, amount, domain.PaymentStatusFailed); err != nil {
+			return "", err
+		}
+		return domain.PaymentStatusFailed, ErrNoPaymentMethod
+	}
+
+	intent, chargeErr := Payments().CreatePaymentIntent(PaymentIntentRequest{
+		Amount:          int64(amount),
+		Currency:        currency,
+		CustomerID:      c.StripeCustomerID,
+		PaymentMethodID: c.PaymentMethodID,
+		OffSession:      true,
+		Metadata: map[string]string{
+			"subscription_id": fmt.Sprintf("%d", c.SubscriptionID),
+			"renewal":         "true",
+		},
+		IdempotencyKey: renewalIdempotencyKey(c),
+	})
+
+	status := domain.PaymentStatusFailed
+	intentID := ""
+	if intent != nil {
+		// En cas de refus, le fournisseur retourne tout de même l'intent créé
+		intentID = intent.ID
+	}
+	if chargeErr == nil {
+		switch intent.Status {
+		case IntentStatusSucceeded:
+			status = domain.PaymentStatusSucceeded
+		case IntentStatusProcessing:
+			status = domain.PaymentStatusPending
+		}
+	} else {
+		log.Printf("[ChargeSubscriptionRenewal] Échec du débit pour l'abonnement %d : %v", c.SubscriptionID, chargeErr)
+	}
+
+	// Le paiement est d'abord enregistré en attente puis confirmé par la même logique que le webhook
+	recordedStatus := status
+	if status == domain.PaymentStatusSucceeded {
+		recordedStatus = domain.PaymentStatusPending
+	}
+	if _, err := repository.CreatePayment(c.SubscriptionID, intentID, payerID, periodStart, periodEnd, amount, recordedStatus); err != nil {
+		return "", err
+	}
+
+	if status == domain.PaymentStatusSucceeded {
+		if err := repository.ConfirmPayment(intentID, c.PaymentMethodID); err != nil {
+			return "", err
+		}
+	}
+
+	if status != domain.PaymentStatusFailed {
+		if err := consumePromo(); err != nil {
+			return "", err
+		}
+	}
+
+	if status == domain.PaymentStatusFailed {
+		if chargeErr == nil {
+			chargeErr = fmt.Errorf("paiement %s au statut %s", intentID, intent.Status)
+		}
+		return status, chargeErr
+	}
+	return status, nil
+}
+
+// renewalIdempotencyKey identifie un essai de débit d'un renouvellement : rejoué après un arrêt entre le débit
+// et son enregistrement, il retourne le paiement déjà créé ; un nouvel essai après un refus enregistré
+// (renewal_attempts incrémenté) obtient une nouvelle clé.
+func renewalIdempotencyKey(c repository.RenewalCandidate) string {
+	return fmt.Sprintf("renewal:%d:%d:%d", c.SubscriptionID, c.EndAt.AddDate(0, 1, 0).Unix(), c.RenewalAttempts)
+}
+
+// ensureStripeCustomer retourne le client du fournisseur de paiement de l'utilisateur, en le créant si nécessaire.
+func ensureStripeCustomer(userID int64) (string, error) {
+	customerID, err := repository.GetStripeCustomerID(userID)
+	if err != nil {
+		return "", err
+	}
+	if customerID != "" {
+		return customerID, nil
+	}
+
+	customerID, err = Payments().CreateCustomer(userID)
+	if err != nil {
+		log.Printf("[ensureStripeCustomer] Erreur lors de la création du client pour %d : %v", userID, err)
+		return "", err
+	}
+
+	if err := repository.SetStripeCustomerID(userID, customerID); err != nil {
+		return "", err
+	}
+
+	log.Printf("[ensureStripeCustomer] Client %s créé pour l'utilisateur %d", customerID, userID)
+	return customerID, nil
+}
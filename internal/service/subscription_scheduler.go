@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"log"
+	"onlyflick/internal/repository"
+	"os"
+	"strings"
+	"time"
+)
+
+// renewalClaimLease est la durée de réservation d'un renouvellement par un passage du planificateur,
+// au-delà de laquelle un renouvellement non enregistré (arrêt brutal) peut être repris par une autre instance.
+const renewalClaimLease = 15 * time.Minute
+
+// SchedulerConfig regroupe les paramètres du planificateur de renouvellement des abonnements.
+type SchedulerConfig struct {
+	Interval     time.Duration   // Fréquence d'exécution (0 = désactivé)
+	GracePeriod  time.Duration   // Délai après end_at avant de désactiver un abonnement impayé
+	RetryBackoff []time.Duration // Délais entre deux tentatives de débit (relance / dunning)
+	BatchSize    int             // Nombre maximal de renouvellements traités par passage
+}
+
+// LoadSchedulerConfig lit la configuration du planificateur depuis les variables d'environnement :
+// SUBSCRIPTION_SCHEDULER_INTERVAL, SUBSCRIPTION_GRACE_PERIOD et SUBSCRIPTION_RETRY_BACKOFF
+// (durées Go, ex. "1h", "72h", "24h,72h,120h").
+func LoadSchedulerConfig() SchedulerConfig {
+	cfg := SchedulerConfig{
+		Interval:     parseDurationEnv("SUBSCRIPTION_SCHEDULER_INTERVAL", time.Hour),
+		GracePeriod:  parseDurationEnv("SUBSCRIPTION_GRACE_PERIOD", 7*24*time.Hour),
+		RetryBackoff: []time.Duration{24 * time.Hour, 72 * time.Hour, 120 * time.Hour},
+		BatchSize:    100,
+	}
+
+	if raw := os.Getenv("SUBSCRIPTION_RETRY_BACKOFF"); raw != "" {
+		var backoff []time.Duration
+		for _, part := range strings.Split(raw, ",") {
+			d, err := time.ParseDuration(strings.TrimSpace(part))
+			if err != nil || d <= 0 {
+				log.Printf("[SCHEDULER] ⚠️  SUBSCRIPTION_RETRY_BACKOFF invalide (%s), valeurs par défaut conservées", raw)
+				backoff = nil
+				break
+			}
+			backoff = append(backoff, d)
+		}
+		if len(backoff) > 0 {
+			cfg.RetryBackoff = backoff
+		}
+	}
+
+	return cfg
+}
+
+// MaxRenewalAttempts retourne le nombre total de tentatives de débit pour une échéance.
+func (c SchedulerConfig) MaxRenewalAttempts() int {
+	return len(c.RetryBackoff) + 1
+}
+
+// NextRetryDelay retourne le délai avant la prochaine tentative après `attempts` échecs,
+// ou false si toutes les tentatives ont été épuisées.
+func (c SchedulerConfig) NextRetryDelay(attempts int) (time.Duration, bool) {
+	if attempts < 1 || attempts > len(c.RetryBackoff) {
+		return 0, false
+	}
+	return c.RetryBackoff[attempts-1], true
+}
+
+// StartSubscriptionScheduler exécute périodiquement la maintenance des abonnements jusqu'à l'annulation du contexte.
+func StartSubscriptionScheduler(ctx context.Context, cfg SchedulerConfig) {
+	if cfg.Interval <= 0 {
+		log.Println("[SCHEDULER] Planificateur des abonnements désactivé")
+		return
+	}
+
+	log.Printf("[SCHEDULER] Planificateur des abonnements démarré (intervalle %s, délai de grâce %s)", cfg.Interval, cfg.GracePeriod)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	RunSubscriptionMaintenance(time.Now(), cfg)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[SCHEDULER] Planificateur des abonnements arrêté")
+			return
+		case now := <-ticker.C:
+			RunSubscriptionMaintenance(now, cfg)
+		}
+	}
+}
+
+// RunSubscriptionMaintenance effectue un passage : débit des renouvellements échus
+// puis désactivation des abonnements impayés au-delà du délai de grâce. La purge des données
+// expirées relève de RunMaintenanceCleanup, qui reste active même si ce planificateur est désactivé.
+func RunSubscriptionMaintenance(now time.Time, cfg SchedulerConfig) {
+	candidates, err := repository.ClaimSubscriptionsDueForRenewal(now, now.Add(renewalClaimLease), cfg.MaxRenewalAttempts(), cfg.BatchSize)
+	if err != nil {
+		log.Printf("[SCHEDULER] Erreur récupération des renouvellements : %v", err)
+	}
+
+	renewed, failed := 0, 0
+	for _, c := range candidates {
+		status, err := ChargeSubscriptionRenewal(c)
+		if err == nil {
+			log.Printf("[SCHEDULER] Renouvellement de l'abonnement %d : %s", c.SubscriptionID, status)
+			renewed++
+			continue
+		}
+
+		failed++
+		if status == "" {
+			// Erreur technique : la tentative n'a pas été enregistrée, elle sera rejouée à l'expiration de la réservation
+			log.Printf("[SCHEDULER] Erreur renouvellement de l'abonnement %d : %v", c.SubscriptionID, err)
+			continue
+		}
+
+		var nextAttempt *time.Time
+		if delay, ok := cfg.NextRetryDelay(c.RenewalAttempts + 1); ok {
+			next := now.Add(delay)
+			nextAttempt = &next
+		}
+		if err := repository.RecordRenewalFailure(c.SubscriptionID, nextAttempt); err != nil {
+			continue
+		}
+		if nextAttempt != nil {
+			log.Printf("[SCHEDULER] Échec du renouvellement de l'abonnement %d, nouvel essai le %s", c.SubscriptionID, nextAttempt.Format(time.RFC3339))
+		} else {
+			log.Printf("[SCHEDULER] Échec définitif du renouvellement de l'abonnement %d", c.SubscriptionID)
+		}
+	}
+
+	expired, err := repository.ExpireLapsedSubscriptions(now.Add(-cfg.GracePeriod))
+	if err != nil {
+		log.Printf("[SCHEDULER] Erreur expiration des abonnements : %v", err)
+	}
+
+	log.Printf("[SCHEDULER] Passage terminé : %d renouvelé(s), %d échec(s), %d expiré(s)", renewed, failed, expired)
+}
+
+// parseDurationEnv lit une durée depuis une variable d'environnement avec une valeur par défaut.
+func parseDurationEnv(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("[CONFIG] ⚠️  %s invalide (%s), utilisation de %s", key, raw, fallback)
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxSuspensionReasonLen borne la longueur du motif d'une suspension.
+	MaxSuspensionReasonLen = 500
+	// SuspendedErrorCode est le code d'erreur des requêtes et connexions refusées à un compte suspendu.
+	SuspendedErrorCode = "account_suspended"
+)
+
+var (
+	// ErrSuspensionInvalid est retournée pour un motif vide ou trop long, ou une durée absente
+	// pour une suspension temporaire (ou fournie pour une suspension définitive).
+	ErrSuspensionInvalid = errors.New("suspension invalide")
+	// ErrSuspensionForbidden est retournée pour une suspension visant un administrateur ou son auteur.
+	ErrSuspensionForbidden = errors.New("ce compte ne peut pas être suspendu")
+)
+
+// SuspendUser suspend le compte d'un utilisateur pour duration, ou définitivement si permanent est vrai,
+// en remplaçant une éventuelle suspension en cours. L'utilisateur est prévenu par e-mail.
+func SuspendUser(actor domain.AuditActor, userID int64, reason string, duration time.Duration, permanent bool, now time.Time) (*domain.UserSuspension, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len([]rune(reason)) > MaxSuspensionReasonLen || permanent == (duration > 0) || duration < 0 {
+		return nil, ErrSuspensionInvalid
+	}
+	if actor.ActorID == userID {
+		return nil, ErrSuspensionForbidden
+	}
+
+	// ErrAccountNotFound pour un compte inexistant, que GetUserByID ne distingue pas d'une erreur de base
+	if _, err := repository.IsAccountHidden(userID); err != nil {
+		return nil, err
+	}
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsAdmin() {
+		return nil, ErrSuspensionForbidden
+	}
+
+	suspension := &domain.UserSuspension{UserID: userID, Reason: reason, Permanent: permanent}
+	if !permanent {
+		endsAt := now.Add(duration)
+		suspension.EndsAt = &endsAt
+	}
+	if err := repository.CreateSuspension(suspension, now, actor); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[SuspendUser] Utilisateur %d suspendu par l'administrateur %d (définitive : %v)", userID, actor.ActorID, permanent)
+	go sendSuspensionEmail(*user, *suspension)
+	return suspension, nil
+}
+
+// LiftUserSuspension lève la suspension active d'un utilisateur et l'en informe par e-mail.
+func LiftUserSuspension(actor domain.AuditActor, userID int64, now time.Time) (*domain.UserSuspension, error) {
+	suspension, err := repository.LiftSuspension(userID, actor, now)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[LiftUserSuspension] Suspension de l'utilisateur %d levée par l'administrateur %d", userID, actor.ActorID)
+	if user, err := repository.GetUserByID(userID); err == nil {
+		go sendSuspensionLiftedEmail(*user)
+	} else {
+		log.Printf("[LiftUserSuspension] Utilisateur %d introuvable pour la notification : %v", userID, err)
+	}
+	return suspension, nil
+}
+
+// SuspensionMessage décrit une suspension en vigueur pour le message d'erreur retourné à l'utilisateur.
+func SuspensionMessage(suspension *domain.UserSuspension) string {
+	if suspension.Permanent || suspension.EndsAt == nil {
+		return "Compte suspendu définitivement : " + suspension.Reason
+	}
+	return "Compte suspendu jusqu'au " + suspension.EndsAt.UTC().Format(time.RFC3339) + " : " + suspension.Reason
+}
+
+// sendSuspensionEmail informe l'utilisateur de sa suspension, de son motif et de sa durée.
+func sendSuspensionEmail(user domain.User, suspension domain.UserSuspension) {
+	endsAt := ""
+	if suspension.EndsAt != nil {
+		endsAt = suspension.EndsAt.UTC().Format("02/01/2006 à 15:04 (UTC)")
+	}
+	if err := SendTemplatedEmail(user.Email, "Votre compte OnlyFlick a été suspendu", "account_suspended", map[string]interface{}{
+		"Username":  user.Username,
+		"Reason":    suspension.Reason,
+		"Permanent": suspension.Permanent,
+		"EndsAt":    endsAt,
+	}); err != nil {
+		log.Printf("[sendSuspensionEmail] Erreur envoi de l'e-mail à l'utilisateur %d : %v", user.ID, err)
+	}
+}
+
+// sendSuspensionLiftedEmail informe l'utilisateur de la levée de sa suspension.
+func sendSuspensionLiftedEmail(user domain.User) {
+	if err := SendTemplatedEmail(user.Email, "La suspension de votre compte OnlyFlick a été levée", "account_unsuspended", map[string]string{
+		"Username":  user.Username,
+		"LoginLink": envOrDefault("FRONTEND_URL", "http://localhost:3000") + "/login",
+	}); err != nil {
+		log.Printf("[sendSuspensionLiftedEmail] Erreur envoi de l'e-mail à l'utilisateur %d : %v", user.ID, err)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"strings"
+)
+
+var (
+	// ErrInvalidTip est retournée pour un montant ou un message de pourboire hors bornes.
+	ErrInvalidTip = errors.New("pourboire invalide")
+	// ErrTipSelf est retournée quand un créateur tente de s'envoyer un pourboire.
+	ErrTipSelf = errors.New("pourboire à soi-même interdit")
+	// ErrTipRecipient est retournée quand le destinataire n'est pas un créateur.
+	ErrTipRecipient = errors.New("destinataire du pourboire invalide")
+	// ErrTipTarget est retournée quand le post ou la conversation ne concerne pas le créateur et l'expéditeur.
+	ErrTipTarget = errors.New("cible du pourboire invalide")
+)
+
+// StartTip crée le paiement d'un pourboire auprès du fournisseur et enregistre le pourboire en attente.
+// Le pourboire peut viser le profil du créateur, l'un de ses posts ou une conversation entre l'expéditeur et lui ;
+// il est affiché (et publié dans la conversation) quand le webhook confirme le paiement.
+func StartTip(tip *domain.Tip) (*domain.TipCheckout, error) {
+	tip.Message = strings.TrimSpace(tip.Message)
+	if tip.Amount < domain.MinTipAmount || tip.Amount > domain.MaxTipAmount || len([]rune(tip.Message)) > domain.MaxTipMessageLen {
+		return nil, ErrInvalidTip
+	}
+	if tip.SenderID == tip.CreatorID {
+		return nil, ErrTipSelf
+	}
+
+	creator, err := repository.GetUserByID(tip.CreatorID)
+	if err != nil || !creator.IsCreator() {
+		return nil, ErrTipRecipient
+	}
+
+	if tip.PostID != nil {
+		post, err := repository.GetPostByID(*tip.PostID)
+		if err != nil || post.UserID != tip.CreatorID {
+			return nil, ErrTipTarget
+		}
+	}
+
+	if tip.ConversationID != nil {
+		for _, participant := range []int64{tip.SenderID, tip.CreatorID} {
+			ok, err := repository.IsUserInConversation(*tip.ConversationID, participant)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, ErrTipTarget
+			}
+		}
+	}
+
+	// Devise du créateur, utilisée également pour son grand livre
+	pricing, err := repository.GetCreatorPricing(tip.CreatorID)
+	if err != nil {
+		return nil, err
+	}
+	tip.Currency = pricing.Currency
+
+	customerID, err := ensureStripeCustomer(tip.SenderID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{
+		"tip_creator_id": fmt.Sprintf("%d", tip.CreatorID),
+		"sender_id":      fmt.Sprintf("%d", tip.SenderID),
+	}
+	if tip.PostID != nil {
+		metadata["post_id"] = fmt.Sprintf("%d", *tip.PostID)
+	}
+	if tip.ConversationID != nil {
+		metadata["conversation_id"] = fmt.Sprintf("%d", *tip.ConversationID)
+	}
+
+	intent, err := Payments().CreatePaymentIntent(PaymentIntentRequest{
+		Amount:     int64(tip.Amount), // Montant en centimes
+		Currency:   tip.Currency,
+		CustomerID: customerID,
+		Metadata:   metadata,
+	})
+	if err != nil {
+		log.Printf("[StartTip] Erreur lors de la création du paiement du pourboire de %d : %v", tip.SenderID, err)
+		return nil, err
+	}
+
+	if err := repository.CreateTip(tip, intent.ID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[StartTip] Paiement %s initié pour le pourboire %d de %d au créateur %d (%d %s)", intent.ID, tip.ID, tip.SenderID, tip.CreatorID, tip.Amount, tip.Currency)
+	return &domain.TipCheckout{Tip: tip, ClientSecret: intent.ClientSecret}, nil
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Index aveugles : HMAC-SHA256 (clé dédiée) de valeurs normalisées, stockés à côté
+// des colonnes chiffrées pour permettre une recherche exacte (e-mail) ou par
+// préfixe (mots des noms) sans déchiffrer les lignes.
+const (
+	// MinNamePrefixLen est la longueur minimale d'un préfixe de recherche par nom.
+	MinNamePrefixLen = 2
+	// MaxNamePrefixLen borne la longueur des préfixes indexés : au-delà, la
+	// recherche porte sur les MaxNamePrefixLen premiers caractères du mot.
+	MaxNamePrefixLen = 12
+)
+
+var accentReplacer = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "á", "a", "ã", "a", "å", "a",
+	"ç", "c", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "í", "i", "ì", "i", "ñ", "n",
+	"ô", "o", "ö", "o", "ó", "o", "ò", "o", "õ", "o",
+	"û", "u", "ü", "u", "ú", "u", "ù", "u", "ÿ", "y",
+	"æ", "ae", "œ", "oe", "ß", "ss",
+)
+
+var (
+	configuredBlindIndexKey []byte // nil : clé dérivée de SECRET_KEY
+	blindIndexKeyOnce       sync.Once
+)
+
+// LoadBlindIndexKey valide et retient BLIND_INDEX_KEY (32 octets en base64), à appeler au démarrage ;
+// la configuration n'est lue qu'une fois. Une clé mal formée est une erreur : les index calculés
+// avec une autre clé ne correspondraient plus. Sans clé, les index utilisent une clé dérivée de SECRET_KEY.
+func LoadBlindIndexKey() (err error) {
+	blindIndexKeyOnce.Do(func() {
+		encoded := os.Getenv("BLIND_INDEX_KEY")
+		if encoded == "" {
+			return
+		}
+		key, decodeErr := base64.StdEncoding.DecodeString(encoded)
+		if decodeErr != nil || len(key) != 32 {
+			err = errors.New("BLIND_INDEX_KEY doit faire 32 octets encodés en base64")
+			return
+		}
+		configuredBlindIndexKey = key
+	})
+	return err
+}
+
+// blindIndexKey retourne la clé HMAC des index aveugles : BLIND_INDEX_KEY si elle est définie, sinon
+// une clé dérivée de SECRET_KEY. Contrairement aux clés de chiffrement, elle ne tourne pas sans
+// recalcul complet des index.
+func blindIndexKey() []byte {
+	if err := LoadBlindIndexKey(); err != nil {
+		log.Fatalf("[BlindIndex] ❌ %v", err)
+	}
+	if configuredBlindIndexKey != nil {
+		return configuredBlindIndexKey
+	}
+	return DeriveSecretKey("blind-index/v1")
+}
+
+func blindIndex(key []byte, kind, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(kind + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// nameIndex tronque l'index d'un préfixe de nom à 128 bits : un utilisateur en
+// porte plusieurs dizaines, indexées dans un tableau GIN.
+func nameIndex(key []byte, prefix string) string {
+	return blindIndex(key, "name", prefix)[:32]
+}
+
+// NormalizeEmail normalise une adresse e-mail avant indexation ou comparaison.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// EmailBlindIndex retourne l'index aveugle d'une adresse e-mail (recherche exacte).
+func EmailBlindIndex(email string) string {
+	return blindIndex(blindIndexKey(), "email", NormalizeEmail(email))
+}
+
+// NameTokens découpe un ou plusieurs noms en mots normalisés (minuscules, sans accents).
+func NameTokens(names ...string) []string {
+	var tokens []string
+	for _, name := range names {
+		normalized := accentReplacer.Replace(strings.ToLower(name))
+		tokens = append(tokens, strings.FieldsFunc(normalized, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+	return tokens
+}
+
+// NameBlindIndexes retourne les index aveugles de tous les préfixes des mots des
+// noms donnés, à stocker avec l'utilisateur (recherche par préfixe).
+func NameBlindIndexes(names ...string) []string {
+	key := blindIndexKey()
+	seen := make(map[string]bool)
+	indexes := []string{}
+	for _, token := range NameTokens(names...) {
+		runes := []rune(token)
+		for n := MinNamePrefixLen; n <= len(runes) && n <= MaxNamePrefixLen; n++ {
+			idx := nameIndex(key, string(runes[:n]))
+			if !seen[idx] {
+				seen[idx] = true
+				indexes = append(indexes, idx)
+			}
+		}
+	}
+	return indexes
+}
+
+// NameSearchIndexes retourne un index aveugle par mot de la recherche ; un utilisateur
+// correspond s'il possède tous ces index. Les mots trop courts sont ignorés.
+func NameSearchIndexes(query string) []string {
+	key := blindIndexKey()
+	indexes := []string{}
+	for _, token := range NameTokens(query) {
+		runes := []rune(token)
+		if len(runes) < MinNamePrefixLen {
+			continue
+		}
+		if len(runes) > MaxNamePrefixLen {
+			runes = runes[:MaxNamePrefixLen]
+		}
+		indexes = append(indexes, nameIndex(key, string(runes)))
+	}
+	return indexes
+}
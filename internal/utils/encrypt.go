@@ -2,11 +2,54 @@ package utils
 
 import (
 	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
 )
 
+// Format d'un champ chiffré : "enc1:<kid>:<base64url(enveloppe)>".
+// L'enveloppe contient une clé de donnée aléatoire (propre à chaque valeur),
+// chiffrée par la clé maîtresse <kid>, suivie de la donnée chiffrée par cette
+// clé de donnée. Les deux couches utilisent AES-256-GCM.
+const (
+	encryptedPrefix = "enc1:"
+	// SecretDerivedKeyID identifie la clé maîtresse dérivée de SECRET_KEY,
+	// utilisée tant qu'aucune clé n'est configurée dans ENCRYPTION_KEYS.
+	SecretDerivedKeyID = "secret"
+
+	dataKeySize = 32
+	gcmNonceLen = 12
+	gcmTagLen   = 16
+	wrappedLen  = gcmNonceLen + dataKeySize + gcmTagLen
+)
+
+var (
+	ErrEncryptionKeyUnknown = errors.New("clé de chiffrement inconnue")
+	ErrCiphertextInvalid    = errors.New("donnée chiffrée invalide")
+
+	keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+	keyringMu     sync.Mutex
+	keyringCache  *encryptionKeyring
+	keyringSource string
+)
+
+// encryptionKeyring regroupe les clés maîtresses connues : toutes servent au
+// déchiffrement, seule la clé courante sert au chiffrement.
+type encryptionKeyring struct {
+	currentID string
+	keys      map[string][]byte
+}
+
 // GetSecretKey obtient la clé secrète pour le chiffrement
 // Vérifie d'abord la clé de test, puis la variable d'environnement
 func GetSecretKey() string {
@@ -26,47 +69,256 @@ func GetSecretKeyBytes() []byte {
 	return []byte(key)
 }
 
-// EncryptAES chiffre des données avec AES
-func EncryptAES(plainText string) (string, error) {
-	key := GetSecretKeyBytes()
-	if len(key) == 0 {
-		return "", fmt.Errorf("clé secrète vide")
+// DeriveSecretKey dérive de SECRET_KEY une clé de 32 octets propre à un usage
+// (label), afin de ne jamais réutiliser la même clé pour deux fonctions.
+func DeriveSecretKey(label string) []byte {
+	mac := hmac.New(sha256.New, GetSecretKeyBytes())
+	mac.Write([]byte("onlyflick/" + label))
+	return mac.Sum(nil)
+}
+
+// loadKeyring lit ENCRYPTION_KEYS ("kid:clé_base64,kid:clé_base64") et
+// ENCRYPTION_KEY_ID. La clé dérivée de SECRET_KEY reste toujours disponible en
+// déchiffrement pour relire les données écrites avant la configuration des clés.
+func loadKeyring(configured, currentID string) (*encryptionKeyring, error) {
+	ring := &encryptionKeyring{keys: map[string][]byte{
+		SecretDerivedKeyID: DeriveSecretKey("field-encryption/v1"),
+	}}
+
+	var order []string
+	for _, entry := range strings.Split(configured, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, encoded, found := strings.Cut(entry, ":")
+		if !found || !keyIDPattern.MatchString(kid) {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS : identifiant de clé invalide dans %q", kid)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS : la clé %q doit faire 32 octets encodés en base64", kid)
+		}
+		ring.keys[kid] = key
+		order = append(order, kid)
 	}
 
-	log.Printf("[Chiffrement] Utilisation de la clé de taille: %d", len(key))
+	switch {
+	case currentID != "":
+		if _, ok := ring.keys[currentID]; !ok {
+			return nil, fmt.Errorf("ENCRYPTION_KEY_ID %q absent de ENCRYPTION_KEYS", currentID)
+		}
+		ring.currentID = currentID
+	case len(order) == 1:
+		ring.currentID = order[0]
+	case len(order) > 1:
+		return nil, errors.New("ENCRYPTION_KEY_ID est obligatoire lorsque plusieurs clés sont configurées")
+	default:
+		ring.currentID = SecretDerivedKeyID
+	}
+	return ring, nil
+}
 
-	block, err := aes.NewCipher(key)
+// currentKeyring retourne le trousseau correspondant à la configuration
+// actuelle, reconstruit uniquement lorsque celle-ci change (tests, rotation).
+func currentKeyring() (*encryptionKeyring, error) {
+	configured := os.Getenv("ENCRYPTION_KEYS")
+	currentID := strings.TrimSpace(os.Getenv("ENCRYPTION_KEY_ID"))
+	source := GetSecretKey() + "\x00" + configured + "\x00" + currentID
+
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	if keyringCache != nil && keyringSource == source {
+		return keyringCache, nil
+	}
+	ring, err := loadKeyring(configured, currentID)
+	if err != nil {
+		return nil, err
+	}
+	keyringCache, keyringSource = ring, source
+	return ring, nil
+}
+
+// CurrentEncryptionKeyID retourne l'identifiant de la clé utilisée pour chiffrer.
+func CurrentEncryptionKeyID() (string, error) {
+	ring, err := currentKeyring()
 	if err != nil {
-		log.Printf("[Chiffrement] Erreur lors de la création du bloc AES : %v", err)
 		return "", err
 	}
+	return ring.currentID, nil
+}
 
-	// Pour l'instant, retourner le texte en clair pour éviter les erreurs de compilation
-	// TODO: Implémenter le chiffrement complet
-	_ = block
-	return plainText, nil
+// IsEncrypted indique si la valeur est au format chiffré (et non en clair).
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
 }
 
-// DecryptAES déchiffre des données avec AES en utilisant la clé appropriée
+// EncryptAES chiffre une donnée personnelle avec la clé courante.
+func EncryptAES(plainText string) (string, error) {
+	ring, err := currentKeyring()
+	if err != nil {
+		log.Printf("[Chiffrement] Configuration des clés invalide : %v", err)
+		return "", err
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrapped, err := sealGCM(ring.keys[ring.currentID], dataKey, []byte(encryptedPrefix+ring.currentID))
+	if err != nil {
+		return "", err
+	}
+	sealed, err := sealGCM(dataKey, []byte(plainText), []byte(encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	return formatCiphertext(ring.currentID, append(wrapped, sealed...)), nil
+}
+
+// DecryptAES déchiffre une donnée personnelle avec la clé désignée par son préfixe.
+// Une valeur sans préfixe est une donnée historique stockée en clair : elle est
+// retournée telle quelle jusqu'à son passage par la commande de rechiffrement.
 func DecryptAES(cipherText string) (string, error) {
-	key := GetSecretKeyBytes()
-	if len(key) == 0 {
-		return "", fmt.Errorf("clé secrète vide")
+	if !IsEncrypted(cipherText) {
+		return cipherText, nil
+	}
+	ring, err := currentKeyring()
+	if err != nil {
+		log.Printf("[Déchiffrement] Configuration des clés invalide : %v", err)
+		return "", err
+	}
+	kid, envelope, err := parseCiphertext(cipherText)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := unwrapDataKey(ring, kid, envelope)
+	if err != nil {
+		return "", err
+	}
+	plain, err := openGCM(dataKey, envelope[wrappedLen:], []byte(encryptedPrefix))
+	if err != nil {
+		return "", ErrCiphertextInvalid
 	}
+	return string(plain), nil
+}
 
-	log.Printf("[Déchiffrement] Utilisation de la clé de taille: %d", len(key))
+// DecryptField déchiffre une donnée destinée à l'affichage : en cas d'échec,
+// une chaîne vide est retournée plutôt que le texte chiffré.
+func DecryptField(value string) string {
+	plain, err := DecryptAES(value)
+	if err != nil {
+		log.Printf("[Déchiffrement] Champ illisible : %v", err)
+		return ""
+	}
+	return plain
+}
 
-	block, err := aes.NewCipher(key)
+// NeedsReencryption indique si une valeur stockée doit être (re)chiffrée avec
+// la clé courante : donnée en clair ou chiffrée par une ancienne clé.
+func NeedsReencryption(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	if !IsEncrypted(value) {
+		return true, nil
+	}
+	ring, err := currentKeyring()
+	if err != nil {
+		return false, err
+	}
+	kid, _, err := parseCiphertext(value)
+	if err != nil {
+		return false, err
+	}
+	return kid != ring.currentID, nil
+}
+
+// ReencryptAES ramène une valeur stockée sur la clé courante. Pour une valeur
+// déjà chiffrée, seule la clé de donnée est ré-enveloppée : la donnée elle-même
+// n'est pas déchiffrée.
+func ReencryptAES(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return EncryptAES(value)
+	}
+	ring, err := currentKeyring()
+	if err != nil {
+		return "", err
+	}
+	kid, envelope, err := parseCiphertext(value)
+	if err != nil {
+		return "", err
+	}
+	if kid == ring.currentID {
+		return value, nil
+	}
+	dataKey, err := unwrapDataKey(ring, kid, envelope)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := sealGCM(ring.keys[ring.currentID], dataKey, []byte(encryptedPrefix+ring.currentID))
 	if err != nil {
-		log.Printf("[Déchiffrement] Erreur lors de la création du bloc AES : %v", err)
 		return "", err
 	}
+	return formatCiphertext(ring.currentID, append(wrapped, envelope[wrappedLen:]...)), nil
+}
 
-	// Pour l'instant, retourner le texte chiffré pour éviter les erreurs de compilation
-	// TODO: Implémenter le déchiffrement complet
-	_ = block
-	return cipherText, nil
+func formatCiphertext(kid string, envelope []byte) string {
+	return encryptedPrefix + kid + ":" + base64.RawURLEncoding.EncodeToString(envelope)
 }
 
-// Toutes les fonctions de chiffrement devraient utiliser GetSecretKey()
-// au lieu d'accéder directement à os.Getenv("SECRET_KEY")
+func parseCiphertext(value string) (string, []byte, error) {
+	kid, encoded, found := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !found || kid == "" {
+		return "", nil, ErrCiphertextInvalid
+	}
+	envelope, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(envelope) < wrappedLen+gcmNonceLen+gcmTagLen {
+		return "", nil, ErrCiphertextInvalid
+	}
+	return kid, envelope, nil
+}
+
+func unwrapDataKey(ring *encryptionKeyring, kid string, envelope []byte) ([]byte, error) {
+	masterKey, ok := ring.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w : %s", ErrEncryptionKeyUnknown, kid)
+	}
+	dataKey, err := openGCM(masterKey, envelope[:wrappedLen], []byte(encryptedPrefix+kid))
+	if err != nil {
+		return nil, ErrCiphertextInvalid
+	}
+	return dataKey, nil
+}
+
+// sealGCM chiffre avec AES-GCM et retourne nonce || données chiffrées || tag.
+func sealGCM(key, plain, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, aad), nil
+}
+
+func openGCM(key, sealed, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrCiphertextInvalid
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+}
@@ -8,8 +8,9 @@ import (
 
 // ErrorResponse représente la structure d'une réponse d'erreur JSON.
 type ErrorResponse struct {
-	Error   string `json:"error"`   // Type d'erreur HTTP (ex: "Not Found")
-	Message string `json:"message"` // Message détaillé de l'erreur
+	Error   string `json:"error"`          // Type d'erreur HTTP (ex: "Not Found")
+	Message string `json:"message"`        // Message détaillé de l'erreur
+	Code    string `json:"code,omitempty"` // Code applicatif stable, pour les erreurs que le client doit distinguer
 }
 
 // SuccessResponse représente la structure d'une réponse de succès JSON.
@@ -22,9 +23,15 @@ type SuccessResponse struct {
 // code : code de statut HTTP à retourner
 // message : message d'erreur détaillé
 func RespondWithError(w http.ResponseWriter, code int, message string) {
+	RespondWithErrorCode(w, code, "", message)
+}
+
+// RespondWithErrorCode envoie une réponse d'erreur JSON portant un code applicatif (ex: "account_suspended").
+func RespondWithErrorCode(w http.ResponseWriter, code int, errorCode, message string) {
 	resp := ErrorResponse{
 		Error:   http.StatusText(code),
 		Message: message,
+		Code:    errorCode,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
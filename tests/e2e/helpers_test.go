@@ -1,9 +1,18 @@
 package e2e
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"onlyflick/internal/database"
+	"onlyflick/internal/service"
+
+	"github.com/stretchr/testify/require"
 )
 
 // cleanupTestData supprime l’utilisateur pour l’email donné
@@ -25,3 +34,40 @@ func cleanupAllTestData(t *testing.T) {
 		t.Logf("Failed to cleanup all E2E test data: %v", err)
 	}
 }
+
+// createE2EUser insère directement un utilisateur de test et retourne son ID et un JWT valide
+func createE2EUser(t *testing.T, role string) (int64, string) {
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+	email := fmt.Sprintf("e2e_%s_%s@example.com", role, suffix)
+	username := fmt.Sprintf("e2e_%s", suffix[len(suffix)-12:])
+
+	var userID int64
+	err := database.DB.QueryRow(`
+		INSERT INTO users (username, email, password, first_name, last_name, role)
+		VALUES ($1, $2, 'x', 'E2E', 'User', $3)
+		RETURNING id
+	`, username, email, role).Scan(&userID)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { cleanupTestData(t, email) })
+
+	token, err := service.GenerateJWT(userID, role)
+	require.NoError(t, err)
+	return userID, token
+}
+
+// doJSON envoie une requête JSON (authentifiée si token est renseigné) au routeur
+func doJSON(t *testing.T, router http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
@@ -0,0 +1,178 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"onlyflick/api"
+	"onlyflick/internal/config"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubscriptionPaymentLifecycle déroule hors ligne, avec le fournisseur fictif :
+// abonnement → paiement (webhook signé) → renouvellement automatique → résiliation.
+func TestSubscriptionPaymentLifecycle(t *testing.T) {
+	setupE2EEnv(t)
+
+	provider := service.NewFakePaymentProvider()
+	service.SetPaymentProvider(provider)
+	t.Cleanup(func() { service.SetPaymentProvider(nil) })
+
+	router := api.SetupRoutes()
+	subscriberID, subscriberToken := createE2EUser(t, "subscriber")
+	creatorID, creatorToken := createE2EUser(t, "creator")
+
+	// 1) Abonnement : un paiement en attente est créé chez le fournisseur
+	rr := doJSON(t, router, http.MethodPost, fmt.Sprintf("/subscriptions/%d/payment", creatorID), subscriberToken, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	intent := provider.LastIntent()
+	require.NotNil(t, intent)
+	assert.Equal(t, int64(499), intent.Amount)
+
+	subscribed, err := repository.IsSubscribed(subscriberID, creatorID)
+	require.NoError(t, err)
+	assert.False(t, subscribed, "l'abonnement ne doit pas être actif avant le paiement")
+
+	// 2) Paiement : le webhook signé active l'abonnement et enregistre le moyen de paiement
+	payload, signature, err := provider.CompleteIntent(intent.ID, "pm_fake_visa")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", bytes.NewReader(payload))
+	req.Header.Set("Stripe-Signature", signature)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	subscribed, err = repository.IsSubscribed(subscriberID, creatorID)
+	require.NoError(t, err)
+	assert.True(t, subscribed)
+
+	// 3) Renouvellement : la période arrive à échéance et le planificateur débite hors session
+	_, err = database.DB.Exec(`UPDATE subscriptions SET end_at = NOW() - INTERVAL '1 minute' WHERE subscriber_id = $1 AND creator_id = $2`, subscriberID, creatorID)
+	require.NoError(t, err)
+
+	service.RunSubscriptionMaintenance(time.Now(), service.SchedulerConfig{
+		GracePeriod:  72 * time.Hour,
+		RetryBackoff: []time.Duration{24 * time.Hour},
+		BatchSize:    1000,
+	})
+
+	var succeeded int
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*) FROM payments p
+		JOIN subscriptions s ON s.id = p.subscription_id
+		WHERE s.subscriber_id = $1 AND s.creator_id = $2 AND p.status = 'succeeded'
+	`, subscriberID, creatorID).Scan(&succeeded)
+	require.NoError(t, err)
+	assert.Equal(t, 2, succeeded)
+
+	subscribed, err = repository.IsSubscribed(subscriberID, creatorID)
+	require.NoError(t, err)
+	assert.True(t, subscribed, "l'abonnement doit être prolongé après le renouvellement")
+
+	// Les deux paiements alimentent le grand livre du créateur (commission déduite)
+	rr = doJSON(t, router, http.MethodGet, "/creator/earnings", creatorToken, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var earnings domain.CreatorEarnings
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &earnings))
+	require.Len(t, earnings.Balances, 1)
+	assert.Equal(t, 2*(499-repository.ComputeCommission(499, config.PlatformCommissionRate())), earnings.Balances[0].Amount)
+
+	// 4) Résiliation
+	rr = doJSON(t, router, http.MethodDelete, fmt.Sprintf("/subscriptions/%d", creatorID), subscriberToken, map[string]string{"mode": domain.CancelNow})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// La période renouvelée n'est pas entamée : elle est remboursée en quasi-totalité
+	var cancelled struct {
+		Cancellation domain.SubscriptionCancellation `json:"cancellation"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &cancelled))
+	assert.Equal(t, domain.CancelNow, cancelled.Cancellation.Mode)
+	assert.Greater(t, cancelled.Cancellation.RefundedAmount, int64(490))
+	assert.LessOrEqual(t, cancelled.Cancellation.RefundedAmount, int64(499))
+
+	subscribed, err = repository.IsSubscribed(subscriberID, creatorID)
+	require.NoError(t, err)
+	assert.False(t, subscribed)
+}
+
+// TestTrialThenDiscountedFirstPeriod vérifie qu'un essai gratuit suivi d'un code promo limité à la première période
+// débite le tarif réduit à la fin de l'essai, puis le plein tarif au renouvellement suivant.
+func TestTrialThenDiscountedFirstPeriod(t *testing.T) {
+	setupE2EEnv(t)
+
+	provider := service.NewFakePaymentProvider()
+	service.SetPaymentProvider(provider)
+	t.Cleanup(func() { service.SetPaymentProvider(nil) })
+
+	router := api.SetupRoutes()
+	subscriberID, subscriberToken := createE2EUser(t, "subscriber")
+	creatorID, creatorToken := createE2EUser(t, "creator")
+
+	rr := doJSON(t, router, http.MethodPut, "/creator/pricing", creatorToken, map[string]interface{}{"price": 1000, "currency": "EUR", "trial_days": 7})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(t, router, http.MethodPost, "/creator/promo-codes", creatorToken, map[string]interface{}{
+		"code": "welcome50", "discount_type": "percent", "discount_value": 50, "max_redemptions": 1, "first_period_only": true,
+	})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	// 1) Souscription : essai actif sans paiement, la carte est enregistrée via un SetupIntent
+	rr = doJSON(t, router, http.MethodPost, fmt.Sprintf("/subscriptions/%d/payment", creatorID), subscriberToken, map[string]string{"promo_code": "WELCOME50"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var started struct {
+		Checkout domain.SubscriptionCheckout `json:"checkout"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &started))
+	require.NotNil(t, started.Checkout.TrialEndsAt)
+	assert.Equal(t, domain.IntentTypeSetup, started.Checkout.IntentType)
+
+	subscribed, err := repository.IsSubscribed(subscriberID, creatorID)
+	require.NoError(t, err)
+	assert.True(t, subscribed, "l'essai gratuit donne accès immédiatement")
+
+	setupID := strings.TrimSuffix(started.Checkout.ClientSecret, "_secret")
+	payload, signature, err := provider.CompleteSetupIntent(setupID, "pm_fake_visa")
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", bytes.NewReader(payload))
+	req.Header.Set("Stripe-Signature", signature)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Le code n'autorisait qu'une utilisation
+	otherID, otherToken := createE2EUser(t, "subscriber")
+	rr = doJSON(t, router, http.MethodPost, fmt.Sprintf("/subscriptions/%d/payment", creatorID), otherToken, map[string]string{"promo_code": "WELCOME50"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "abonné %d", otherID)
+
+	// 2) Fin de l'essai, puis renouvellement suivant : tarif réduit puis plein tarif
+	cfg := service.SchedulerConfig{GracePeriod: 72 * time.Hour, RetryBackoff: []time.Duration{24 * time.Hour}, BatchSize: 1000}
+	var amounts []int
+	for i := 0; i < 2; i++ {
+		_, err = database.DB.Exec(`UPDATE subscriptions SET end_at = NOW() - INTERVAL '1 minute' WHERE subscriber_id = $1 AND creator_id = $2`, subscriberID, creatorID)
+		require.NoError(t, err)
+		service.RunSubscriptionMaintenance(time.Now(), cfg)
+
+		var amount int
+		err = database.DB.QueryRow(`
+			SELECT p.amount FROM payments p
+			JOIN subscriptions s ON s.id = p.subscription_id
+			WHERE s.subscriber_id = $1 AND s.creator_id = $2 AND p.status = 'succeeded'
+			ORDER BY p.id DESC LIMIT 1
+		`, subscriberID, creatorID).Scan(&amount)
+		require.NoError(t, err)
+		amounts = append(amounts, amount)
+	}
+	assert.Equal(t, []int{500, 1000}, amounts)
+}
@@ -26,8 +26,15 @@ CREATE TABLE IF NOT EXISTS users (
     first_name TEXT NOT NULL,
     last_name TEXT NOT NULL,
     role VARCHAR(20) NOT NULL DEFAULT 'subscriber',
+    admin_role VARCHAR(20) NOT NULL DEFAULT '',
+    email_bidx TEXT UNIQUE,
+    name_bidx TEXT[] NOT NULL DEFAULT '{}',
+    deletion_requested_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
     bio TEXT,
     avatar_url TEXT,
+    email_verified_at TIMESTAMPTZ DEFAULT NOW(),
+    email_verification_sent_at TIMESTAMPTZ,
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
@@ -38,6 +45,7 @@ CREATE TABLE IF NOT EXISTS posts (
     content TEXT NOT NULL,
     media_url TEXT,
     visibility VARCHAR(20) NOT NULL DEFAULT 'public',
+    price INT NOT NULL DEFAULT 0,
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
@@ -59,6 +67,282 @@ CREATE TABLE IF NOT EXISTS likes (
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     UNIQUE(user_id, post_id)
 );
+
+CREATE TABLE IF NOT EXISTS creator_pricing (
+    creator_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    price INT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS subscription_tiers (
+    id SERIAL PRIMARY KEY,
+    creator_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price INT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS stripe_customer_id TEXT;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS tier_id INTEGER REFERENCES subscription_tiers(id) ON DELETE SET NULL;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS auto_renew BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS renewal_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS next_renewal_at TIMESTAMPTZ;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS renewal_claimed_until TIMESTAMPTZ;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS payment_method_id TEXT;
+
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id BIGSERIAL PRIMARY KEY,
+    creator_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code VARCHAR(32) NOT NULL,
+    discount_type VARCHAR(10) NOT NULL,
+    discount_value INT NOT NULL,
+    max_redemptions INT,
+    redemptions_count INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    first_period_only BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (creator_id, code)
+);
+
+CREATE TABLE IF NOT EXISTS promo_code_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    promo_code_id BIGINT NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (promo_code_id, user_id)
+);
+
+ALTER TABLE creator_pricing ADD COLUMN IF NOT EXISTS trial_days INT NOT NULL DEFAULT 0;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS promo_code_id BIGINT REFERENCES promo_codes(id) ON DELETE SET NULL;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS trial_end_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS payments (
+    id SERIAL PRIMARY KEY,
+    subscription_id BIGINT REFERENCES subscriptions(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL DEFAULT 'subscription',
+    creator_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    stripe_payment_id TEXT NOT NULL,
+    payer_id TEXT NOT NULL,
+    start_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    end_at TIMESTAMPTZ NOT NULL,
+    amount INT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    refund_of BIGINT REFERENCES payments(id) ON DELETE CASCADE,
+    refund_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+ALTER TABLE promo_code_redemptions ADD COLUMN IF NOT EXISTS payment_id BIGINT REFERENCES payments(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_payments_refund_provider_id ON payments(stripe_payment_id) WHERE refund_of IS NOT NULL AND stripe_payment_id <> '';
+
+CREATE TABLE IF NOT EXISTS post_purchases (
+    id SERIAL PRIMARY KEY,
+    post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    buyer_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ,
+    revoked_reason VARCHAR(20) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES auth_sessions(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    rotated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS password_reset_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(10) NOT NULL,
+    email_hash TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMPTZ,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS login_throttles (
+    scope VARCHAR(10) NOT NULL,
+    subject TEXT NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, subject)
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMPTZ,
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+CREATE TABLE IF NOT EXISTS oidc_auth_states (
+    state_hash TEXT PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    code_verifier TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    link_user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    scopes TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS media_files (
+    file_id TEXT PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_suspensions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    admin_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    permanent BOOLEAN NOT NULL DEFAULT FALSE,
+    ends_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    lifted_at TIMESTAMPTZ,
+    lifted_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    CHECK (permanent OR ends_at IS NOT NULL)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_suspensions_open ON user_suspensions(user_id) WHERE lifted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id BIGINT NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32) NOT NULL,
+    target_id BIGINT NOT NULL,
+    before JSONB,
+    after JSONB,
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    idem_key TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL DEFAULT '',
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (user_id, idem_key)
+);
+
+CREATE TABLE IF NOT EXISTS stripe_events (
+    event_id TEXT PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS payout_batches (
+    id SERIAL PRIMARY KEY,
+    created_by BIGINT,
+    cutoff TIMESTAMPTZ NOT NULL,
+    total_amount BIGINT NOT NULL DEFAULT 0,
+    creator_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS ledger_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    creator_id BIGINT NOT NULL,
+    payment_id BIGINT REFERENCES payments(id) ON DELETE SET NULL,
+    gross_amount BIGINT NOT NULL,
+    commission_amount BIGINT NOT NULL DEFAULT 0,
+    net_amount BIGINT NOT NULL,
+    commission_rate INT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL,
+    balance_after BIGINT NOT NULL,
+    payout_batch_id BIGINT REFERENCES payout_batches(id),
+    paid_out_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_ledger_transactions_payment ON ledger_transactions(payment_id) WHERE kind = 'payment';
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL REFERENCES ledger_transactions(id),
+    account VARCHAR(30) NOT NULL,
+    creator_id BIGINT,
+    currency VARCHAR(3) NOT NULL,
+    debit BIGINT NOT NULL DEFAULT 0,
+    credit BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
 `
 
 func TestMain(m *testing.M) {
@@ -92,6 +376,11 @@ func TestMain(m *testing.M) {
 	utils.SetSecretKeyForTesting(secretKey)
 	log.Println("SECRET_KEY configurée")
 
+	// Clé JWT éphémère sans JWT_KEYS_DIR
+	if os.Getenv("JWT_KEYS_DIR") == "" {
+		os.Setenv("JWT_ALLOW_EPHEMERAL_KEYS", "true")
+	}
+
 	// 3) DATABASE_URL & connexion
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
@@ -0,0 +1,12 @@
+package performance
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Clé JWT éphémère : les tests ne fournissent pas de JWT_KEYS_DIR
+	os.Setenv("JWT_ALLOW_EPHEMERAL_KEYS", "true")
+	os.Exit(m.Run())
+}
@@ -0,0 +1,85 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"onlyflick/internal/middleware"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func expectAccessTokenLookup(mock sqlmock.Sqlmock, role, scopes string) {
+	mock.ExpectQuery("UPDATE personal_access_tokens t").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "role", "scopes"}).AddRow(int64(3), int64(42), role, scopes))
+}
+
+func TestAccessTokenScopesAreEnforced(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	var gotUserID int64
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, _ = r.Context().Value(middleware.ContextUserIDKey).(int64)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cases := []struct {
+		name    string
+		method  string
+		path    string
+		role    string
+		scopes  string
+		handler http.Handler
+		want    int
+	}{
+		{"scope accordé", http.MethodGet, "/profile/stats", "creator", "stats:read", middleware.JWTMiddleware(ok), http.StatusOK},
+		{"scope manquant", http.MethodPost, "/posts", "creator", "stats:read", middleware.JWTMiddlewareWithRole("creator", "admin")(ok), http.StatusForbidden},
+		{"route fermée aux jetons", http.MethodPost, "/profile/tokens", "creator", "posts:write stats:read messages:read", middleware.JWTMiddleware(ok), http.StatusForbidden},
+		{"rôle courant vérifié", http.MethodPost, "/posts", "subscriber", "posts:write", middleware.JWTMiddlewareWithRole("creator", "admin")(ok), http.StatusForbidden},
+		{"publication autorisée", http.MethodPost, "/posts", "creator", "posts:write", middleware.JWTMiddlewareWithRole("creator", "admin")(ok), http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotUserID = 0
+			expectAccessTokenLookup(mock, tc.role, tc.scopes)
+			if tc.want == http.StatusOK {
+				mock.ExpectQuery("FROM user_suspensions").
+					WithArgs(int64(42), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(nil))
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer ofp_secret")
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.want, rr.Code)
+			if tc.want == http.StatusOK {
+				assert.Equal(t, int64(42), gotUserID)
+			}
+		})
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAccessTokenUnknownIsRejected(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("UPDATE personal_access_tokens t").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "role", "scopes"}))
+
+	handler := middleware.JWTMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/profile/stats", nil)
+	req.Header.Set("Authorization", "Bearer ofp_inconnu")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
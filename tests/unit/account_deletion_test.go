@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"onlyflick/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestAccountDeletionSchedulesPurge(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	requestedAt := now.Add(-48 * time.Hour) // Demande déjà en cours : date conservée
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE users SET deletion_requested_at = COALESCE").
+		WithArgs(int64(7), now).
+		WillReturnRows(sqlmock.NewRows([]string{"deletion_requested_at"}).AddRow(requestedAt))
+	mock.ExpectExec("UPDATE auth_sessions SET revoked_at").
+		WithArgs(int64(7), now, domain.SessionRevokedDeletion).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM personal_access_tokens").
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	purgeAt, err := service.RequestAccountDeletion(7, now)
+	assert.NoError(t, err)
+	assert.Equal(t, requestedAt.Add(30*24*time.Hour), purgeAt)
+
+	mock.ExpectExec("UPDATE users SET deletion_requested_at = NULL").
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	cancelled, err := repository.CancelAccountDeletion(7)
+	assert.NoError(t, err)
+	assert.True(t, cancelled)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeAccountKeepsTombstone(t *testing.T) {
+	utils.SetSecretKeyForTesting("12345678901234567890123456789012")
+	defer utils.SetSecretKeyForTesting("")
+
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT file_id FROM media_files").
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"file_id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET").
+		WithArgs(int64(7), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "deleted_7", now, utils.EmailBlindIndex("deleted_7@deleted.invalid")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for i := 0; i < 13; i++ {
+		mock.ExpectExec(".").WithArgs(int64(7)).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs(int64(9), domain.AuditUserDelete, domain.AuditTargetUser, int64(7), `{"deleted":false}`, sqlmock.AnyArg(), "203.0.113.5").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, service.PurgeAccount(7, now, &domain.AuditActor{ActorID: 9, IPAddress: "203.0.113.5"}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportRowsConvertsBytes(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("FROM likes WHERE user_id").
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "created_at"}).AddRow(int64(3), []byte("2026-03-01")))
+
+	var likes repository.ExportSection
+	for _, section := range repository.AccountExportSections {
+		if section.Name == "likes" {
+			likes = section
+		}
+	}
+
+	rows, err := repository.ExportRows(likes, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"post_id": int64(3), "created_at": "2026-03-01"}}, rows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
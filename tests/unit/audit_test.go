@@ -0,0 +1,99 @@
+package unit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/handler"
+	"onlyflick/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var auditRowColumns = []string{"id", "actor_id", "action", "target_type", "target_id", "before", "after", "ip_address", "created_at"}
+
+func TestSetAdminRoleIsAuditedInTransaction(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	actor := domain.AuditActor{ActorID: 1, IPAddress: "203.0.113.5"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT admin_role FROM users").
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"admin_role"}).AddRow("support"))
+	mock.ExpectExec("UPDATE users SET admin_role").
+		WithArgs(int64(42), "finance").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs(int64(1), domain.AuditUserAdminRole, domain.AuditTargetUser, int64(42),
+			`{"admin_role":"support"}`, `{"admin_role":"finance"}`, "203.0.113.5").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.SetAdminRole(42, domain.AdminRoleFinance, actor))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditFailureRollsBackAction(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT user_id, status FROM creator_requests").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "status"}).AddRow(int64(42), "pending"))
+	mock.ExpectExec("UPDATE creator_requests").
+		WithArgs(int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WillReturnError(errors.New("connexion perdue"))
+	mock.ExpectRollback()
+
+	assert.Error(t, repository.RejectCreatorRequest(5, domain.AuditActor{ActorID: 1}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListAuditLogCursorPagination(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	// limit=2 : une entrée de plus est lue pour savoir s'il reste une page
+	mock.ExpectQuery("FROM admin_audit_log").
+		WithArgs(int64(100), domain.AuditTargetUser, int64(42), since, int64(3)).
+		WillReturnRows(sqlmock.NewRows(auditRowColumns).
+			AddRow(int64(99), int64(1), domain.AuditUserSuspend, domain.AuditTargetUser, int64(42), nil, []byte(`{"permanent":true}`), "203.0.113.5", now).
+			AddRow(int64(97), int64(1), domain.AuditUserUnlock, domain.AuditTargetUser, int64(42), []byte(`{"failures":5}`), []byte(`{"failures":0}`), "203.0.113.5", now).
+			AddRow(int64(90), int64(2), domain.AuditUserDelete, domain.AuditTargetUser, int64(42), nil, nil, "", now))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/audit?cursor=100&target_type=user&target_id=42&since=2026-03-01&limit=2", nil)
+	rr := httptest.NewRecorder()
+	handler.ListAuditLog(rr, req)
+
+	var body struct {
+		Entries    []domain.AuditEntry `json:"entries"`
+		NextCursor *int64              `json:"next_cursor"`
+	}
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Len(t, body.Entries, 2)
+	assert.JSONEq(t, `{"failures":5}`, string(body.Entries[1].Before))
+	if assert.NotNil(t, body.NextCursor) {
+		assert.Equal(t, int64(97), *body.NextCursor)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListAuditLogRejectsInvalidFilters(t *testing.T) {
+	for _, query := range []string{"actor_id=abc", "cursor=-1", "since=hier"} {
+		rr := httptest.NewRecorder()
+		handler.ListAuditLog(rr, httptest.NewRequest(http.MethodGet, "/admin/audit?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
@@ -0,0 +1,61 @@
+package unit
+
+import (
+	"onlyflick/internal/repository"
+	"onlyflick/internal/utils"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameBlindIndexPrefixMatch(t *testing.T) {
+	utils.SetSecretKeyForTesting("12345678901234567890123456789012")
+	defer utils.SetSecretKeyForTesting("")
+
+	stored := utils.NameBlindIndexes("Élodie", "Martin-Dupont")
+	contains := func(query string) bool {
+		indexes := utils.NameSearchIndexes(query)
+		for _, idx := range indexes {
+			if !slices.Contains(stored, idx) {
+				return false
+			}
+		}
+		return len(indexes) > 0
+	}
+
+	assert.True(t, contains("elo"))
+	assert.True(t, contains("ÉLODIE dup"))
+	assert.True(t, contains("martin"))
+	assert.False(t, contains("lodie"))
+	assert.False(t, contains("e"))
+
+	// Index liés à la clé : une autre clé produit d'autres valeurs
+	utils.SetSecretKeyForTesting("abcdefghijklmnopqrstuvwxyz123456")
+	assert.NotEqual(t, stored, utils.NameBlindIndexes("Élodie", "Martin-Dupont"))
+	assert.Equal(t, utils.EmailBlindIndex("Alice@Example.com "), utils.EmailBlindIndex("alice@example.com"))
+}
+
+func TestGetUserByEmailUsesBlindIndex(t *testing.T) {
+	utils.SetSecretKeyForTesting("12345678901234567890123456789012")
+	defer utils.SetSecretKeyForTesting("")
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	encryptedEmail, _ := utils.EncryptAES("alice@example.com")
+	columns := []string{"id", "first_name", "last_name", "username", "email", "password", "role", "avatar_url", "bio", "created_at", "updated_at"}
+	mock.ExpectQuery("FROM users\\s+WHERE email_bidx = \\$1\\s*$").
+		WithArgs(utils.EmailBlindIndex("alice@example.com")).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(int64(3), "Alice", "Martin", "alice", encryptedEmail, "hash", "subscriber", nil, nil, time.Now(), nil))
+
+	user, err := repository.GetUserByEmail("Alice@example.com")
+	assert.NoError(t, err)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, int64(3), user.ID)
+		assert.Equal(t, "alice@example.com", user.Email)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
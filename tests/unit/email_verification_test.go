@@ -0,0 +1,48 @@
+package unit
+
+import (
+	"onlyflick/internal/service"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var verificationUserColumns = []string{"id", "first_name", "last_name", "username", "email", "password", "role", "avatar_url", "bio", "created_at", "updated_at"}
+
+func expectVerificationUser(mock sqlmock.Sqlmock, email string) {
+	mock.ExpectQuery("SELECT id, first_name, last_name, username, email, password, role").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows(verificationUserColumns).
+			AddRow(int64(5), "", "", "alice", email, "hash", "subscriber", nil, nil, time.Now(), nil))
+}
+
+func TestVerifyEmailTokenRejectsInvalidLinks(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	token := service.GenerateEmailVerificationToken(5, "alice@example.com", now.Add(time.Hour))
+
+	// L'adresse a changé depuis l'envoi du lien : la signature ne correspond plus
+	expectVerificationUser(mock, "bob@example.com")
+	_, err := service.VerifyEmailToken(token, now)
+	assert.ErrorIs(t, err, service.ErrInvalidVerificationToken)
+
+	// Lien expiré
+	expectVerificationUser(mock, "alice@example.com")
+	_, err = service.VerifyEmailToken(token, now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, service.ErrVerificationTokenExpired)
+
+	// Lien valide : l'adresse est confirmée
+	expectVerificationUser(mock, "alice@example.com")
+	mock.ExpectExec("UPDATE users SET email_verified_at = NOW\\(\\)").
+		WithArgs(int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	userID, err := service.VerifyEmailToken(token, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), userID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,10 +1,16 @@
 package unit
 
 import (
+	"bytes"
+	"encoding/base64"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
 	"onlyflick/internal/utils"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -46,3 +52,93 @@ func TestGetSecretKey(t *testing.T) {
 	key = utils.GetSecretKey()
 	assert.Equal(t, envKey, key)
 }
+
+func TestEncryptionKeyRotation(t *testing.T) {
+	utils.SetSecretKeyForTesting("12345678901234567890123456789012")
+	defer utils.SetSecretKeyForTesting("")
+	oldKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	newKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+
+	t.Setenv("ENCRYPTION_KEYS", "2025-01:"+oldKey)
+	t.Setenv("ENCRYPTION_KEY_ID", "")
+	encrypted, err := utils.EncryptAES("Alice")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, "enc1:2025-01:"))
+
+	// Rotation : la nouvelle clé chiffre, l'ancienne reste disponible en lecture
+	t.Setenv("ENCRYPTION_KEYS", "2026-10:"+newKey+",2025-01:"+oldKey)
+	t.Setenv("ENCRYPTION_KEY_ID", "2026-10")
+	decrypted, err := utils.DecryptAES(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", decrypted)
+
+	needed, err := utils.NeedsReencryption(encrypted)
+	assert.NoError(t, err)
+	assert.True(t, needed)
+	reencrypted, err := utils.ReencryptAES(encrypted)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(reencrypted, "enc1:2026-10:"))
+
+	// Une fois l'ancienne clé retirée, seule la valeur rechiffrée reste lisible
+	t.Setenv("ENCRYPTION_KEYS", "2026-10:"+newKey)
+	decrypted, err = utils.DecryptAES(reencrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", decrypted)
+	_, err = utils.DecryptAES(encrypted)
+	assert.ErrorIs(t, err, utils.ErrEncryptionKeyUnknown)
+}
+
+func TestDecryptAESLegacyAndTampered(t *testing.T) {
+	utils.SetSecretKeyForTesting("12345678901234567890123456789012")
+	defer utils.SetSecretKeyForTesting("")
+
+	// Les lignes historiques stockées en clair restent lisibles
+	plain, err := utils.DecryptAES("bob@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob@example.com", plain)
+	needed, _ := utils.NeedsReencryption("bob@example.com")
+	assert.True(t, needed)
+
+	// Deux chiffrements d'une même valeur diffèrent, et toute altération est détectée
+	first, _ := utils.EncryptAES("bob@example.com")
+	second, _ := utils.EncryptAES("bob@example.com")
+	assert.NotEqual(t, first, second)
+	tampered := first[:len(first)-2] + "AA"
+	if tampered == first {
+		tampered = first[:len(first)-2] + "BB"
+	}
+	_, err = utils.DecryptAES(tampered)
+	assert.ErrorIs(t, err, utils.ErrCiphertextInvalid)
+}
+
+func TestReencryptPersonalDataBatches(t *testing.T) {
+	utils.SetSecretKeyForTesting("12345678901234567890123456789012")
+	defer utils.SetSecretKeyForTesting("")
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	current, _ := utils.EncryptAES("Martin")
+	for _, col := range repository.EncryptedColumns {
+		rows := sqlmock.NewRows([]string{col.KeyColumn, col.Column})
+		if col.Column == "first_name" {
+			rows.AddRow(int64(1), "Alice").AddRow(int64(2), current)
+		}
+		mock.ExpectQuery("SELECT "+col.KeyColumn+", "+col.Column+" FROM "+col.Table).
+			WithArgs(int64(0), 2).
+			WillReturnRows(rows)
+		if col.Column == "first_name" {
+			mock.ExpectExec("UPDATE users SET first_name").
+				WithArgs(sqlmock.AnyArg(), int64(1), "Alice").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery("SELECT id, first_name FROM users").
+				WithArgs(int64(2), 2).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "first_name"}))
+		}
+	}
+
+	stats, err := service.ReencryptPersonalData(2, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Scanned)
+	assert.Equal(t, 1, stats.Reencrypted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
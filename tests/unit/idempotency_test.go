@@ -0,0 +1,109 @@
+package unit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"onlyflick/internal/middleware"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var idempotencyColumns = []string{"id", "fingerprint", "status_code", "content_type", "response_body", "expires_at"}
+
+// idempotentRequest exécute une requête POST /tips avec une clé d'idempotence pour l'utilisateur 1
+func idempotentRequest(body string, next http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/tips", strings.NewReader(body))
+	req.Header.Set(middleware.IdempotencyKeyHeader, "cle-1")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextUserIDKey, int64(1)))
+	rr := httptest.NewRecorder()
+	middleware.IdempotencyMiddleware(next).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIdempotencyMiddlewareStoresAndReplays(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	body := `{"creator_id":2,"amount":500}`
+	sum := sha256.Sum256([]byte("POST /tips\n" + body))
+	fingerprint := hex.EncodeToString(sum[:])
+	calls := 0
+	next := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"client_secret":"pi_1_secret"}`))
+	}
+
+	// Première requête : clé réservée, réponse mémorisée
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WithArgs(int64(1), "cle-1", fingerprint, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(10)))
+	mock.ExpectExec("UPDATE idempotency_keys").
+		WithArgs(int64(10), http.StatusCreated, "application/json", []byte(`{"client_secret":"pi_1_secret"}`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := idempotentRequest(body, next)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	// Nouvelle tentative : la réponse est rejouée sans rappeler le handler
+	mock.ExpectQuery("INSERT INTO idempotency_keys").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT id, fingerprint, status_code.*FROM idempotency_keys").
+		WithArgs(int64(1), "cle-1").
+		WillReturnRows(sqlmock.NewRows(idempotencyColumns).
+			AddRow(int64(10), fingerprint, http.StatusCreated, "application/json", []byte(`{"client_secret":"pi_1_secret"}`), time.Now().Add(time.Hour)))
+
+	rr = idempotentRequest(body, next)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.JSONEq(t, `{"client_secret":"pi_1_secret"}`, rr.Body.String())
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyMiddlewareRejectsDifferentBody(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("INSERT INTO idempotency_keys").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT id, fingerprint, status_code.*FROM idempotency_keys").
+		WillReturnRows(sqlmock.NewRows(idempotencyColumns).
+			AddRow(int64(10), "empreinte-d-une-autre-requete", http.StatusCreated, "application/json", []byte(`{}`), time.Now().Add(time.Hour)))
+
+	rr := idempotentRequest(`{"creator_id":2,"amount":5000}`, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("le handler ne doit pas être appelé")
+	})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyMiddlewareReclaimsAbandonedInFlightKey(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// Une clé restée sans réponse au-delà du bail est réservée à nouveau au lieu de répondre 409
+	mock.ExpectQuery(`INSERT INTO idempotency_keys.*status_code = 0 AND idempotency_keys.created_at <= \$6`).
+		WithArgs(int64(1), "cle-1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(11)))
+	mock.ExpectExec("UPDATE idempotency_keys").
+		WithArgs(int64(11), http.StatusCreated, "", []byte(nil)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	calls := 0
+	rr := idempotentRequest(`{"creator_id":2,"amount":500}`, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,84 @@
+package unit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"onlyflick/internal/service"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePrivateKeyPEM(t *testing.T, path string, key interface{}) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+}
+
+func TestJWTKeyRotationKeepsOldTokensValid(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writePrivateKeyPEM(t, filepath.Join(dir, "old.pem"), rsaKey)
+	writePrivateKeyPEM(t, filepath.Join(dir, "new.pem"), edKey)
+
+	t.Setenv("JWT_KEYS_DIR", dir)
+	defer func() {
+		os.Unsetenv("JWT_KEYS_DIR")
+		require.NoError(t, service.InitJWTKeys())
+	}()
+
+	// Token signé par l'ancienne clé RS256
+	t.Setenv("JWT_SIGNING_KEY_ID", "old")
+	require.NoError(t, service.InitJWTKeys())
+	oldToken, err := service.GenerateJWT(1, "subscriber")
+	require.NoError(t, err)
+
+	// Bascule sur la nouvelle clé Ed25519 : l'ancien token reste vérifiable
+	t.Setenv("JWT_SIGNING_KEY_ID", "new")
+	require.NoError(t, service.InitJWTKeys())
+	newToken, err := service.GenerateJWT(1, "subscriber")
+	require.NoError(t, err)
+
+	for _, tokenString := range []string{oldToken, newToken} {
+		token, err := service.ValidateJWT(tokenString)
+		assert.NoError(t, err)
+		assert.True(t, token.Valid)
+	}
+	parsed, _ := service.ValidateJWT(newToken)
+	assert.Equal(t, "new", parsed.Header["kid"])
+	assert.Equal(t, "EdDSA", parsed.Header["alg"])
+
+	jwks := service.JWKS()
+	if assert.Len(t, jwks.Keys, 2) {
+		assert.Equal(t, "new", jwks.Keys[0].Kid)
+		assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+		assert.Equal(t, "old", jwks.Keys[1].Kid)
+		assert.Equal(t, "RS256", jwks.Keys[1].Alg)
+	}
+
+	// Une fois l'ancienne clé retirée, ses tokens sont refusés
+	require.NoError(t, os.Remove(filepath.Join(dir, "old.pem")))
+	require.NoError(t, service.InitJWTKeys())
+	_, err = service.ValidateJWT(oldToken)
+	assert.Error(t, err)
+}
+
+func TestInitJWTKeysFailsClosedWithoutKeysDir(t *testing.T) {
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("JWT_ALLOW_EPHEMERAL_KEYS", "")
+	defer func() {
+		os.Setenv("JWT_ALLOW_EPHEMERAL_KEYS", "true")
+		require.NoError(t, service.InitJWTKeys())
+	}()
+
+	assert.ErrorIs(t, service.InitJWTKeys(), service.ErrJWTKeysNotConfigured)
+}
@@ -0,0 +1,113 @@
+package unit
+
+import (
+	"onlyflick/internal/config"
+	"onlyflick/internal/database"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectPaymentLedgerPosting attend l'enregistrement au grand livre d'un paiement réussi (commission par défaut)
+func expectPaymentLedgerPosting(mock sqlmock.Sqlmock, paymentID, creatorID, gross, balanceBefore int64) {
+	commission := repository.ComputeCommission(gross, config.DefaultCommissionRate)
+	net := gross - commission
+
+	mock.ExpectQuery("SELECT p.amount, COALESCE\\(p.creator_id, s.creator_id\\).*FROM payments p").
+		WithArgs(paymentID, "EUR").
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "creator_id", "currency"}).AddRow(gross, creatorID, "EUR"))
+	mock.ExpectExec("pg_advisory_xact_lock").WithArgs(creatorID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SUM\\(credit - debit\\).*FROM ledger_entries").
+		WithArgs("creator_payable", creatorID, "EUR").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(balanceBefore))
+	mock.ExpectQuery("INSERT INTO ledger_transactions").
+		WithArgs("payment", creatorID, paymentID, gross, commission, net, config.DefaultCommissionRate, "EUR", balanceBefore+net, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(int64(1), time.Now()))
+	mock.ExpectExec("INSERT INTO ledger_entries").WithArgs(int64(1), "payment_clearing", nil, "EUR", gross, int64(0)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO ledger_entries").WithArgs(int64(1), "platform_revenue", nil, "EUR", int64(0), commission).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO ledger_entries").WithArgs(int64(1), "creator_payable", sqlmock.AnyArg(), "EUR", int64(0), net).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+}
+
+func TestPlatformCommissionRate(t *testing.T) {
+	assert.Equal(t, config.DefaultCommissionRate, config.PlatformCommissionRate())
+
+	os.Setenv("PLATFORM_COMMISSION_PERCENT", "12.5")
+	defer os.Unsetenv("PLATFORM_COMMISSION_PERCENT")
+	assert.Equal(t, 1250, config.PlatformCommissionRate())
+
+	// 12,5 % de 4,99 € arrondi au centime
+	assert.Equal(t, int64(62), repository.ComputeCommission(499, 1250))
+}
+
+func TestPartialRefundIsProratedInLedger(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	start := time.Now().AddDate(0, -1, 0)
+	end := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM payments.*FOR UPDATE").WithArgs("pi_refund").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(10)))
+
+	// 200 déjà remboursés : seuls les 400 supplémentaires sont enregistrés
+	mock.ExpectQuery("SUM\\(amount\\), 0\\) FROM payments WHERE refund_of").WithArgs(int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(int64(200)))
+	mock.ExpectQuery("SELECT id, subscription_id, payer_id, start_at, end_at, amount.*FOR UPDATE").WithArgs(int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "payer_id", "start_at", "end_at", "amount", "kind", "creator_id"}).
+			AddRow(int64(10), int64(4), "1", start, end, 1000, "subscription", nil))
+	mock.ExpectQuery("SUM\\(amount\\), 0\\) FROM payments WHERE refund_of").WithArgs(int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(int64(200)))
+	mock.ExpectQuery("INSERT INTO payments.*refund_of").
+		WithArgs(int64(4), "re_2", "1", start, end, 400, "refund", int64(10), "", "subscription", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(int64(11), time.Now()))
+	mock.ExpectQuery("SELECT creator_id, gross_amount, commission_amount").WithArgs(int64(10), "payment").
+		WillReturnRows(sqlmock.NewRows([]string{"creator_id", "gross_amount", "commission_amount", "commission_rate", "currency"}).
+			AddRow(int64(2), int64(1000), int64(200), 2000, "EUR"))
+	mock.ExpectExec("pg_advisory_xact_lock").WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SUM\\(credit - debit\\).*FROM ledger_entries").
+		WithArgs("creator_payable", int64(2), "EUR").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(int64(640)))
+	mock.ExpectQuery("INSERT INTO ledger_transactions").
+		WithArgs("refund", int64(2), int64(10), int64(400), int64(80), int64(320), 2000, "EUR", int64(320), nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(int64(5), time.Now()))
+	mock.ExpectExec("INSERT INTO ledger_entries").WithArgs(int64(5), "payment_clearing", nil, "EUR", int64(0), int64(400)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO ledger_entries").WithArgs(int64(5), "platform_revenue", nil, "EUR", int64(80), int64(0)).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO ledger_entries").WithArgs(int64(5), "creator_payable", sqlmock.AnyArg(), "EUR", int64(320), int64(0)).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec("UPDATE payments SET status").WithArgs(int64(10), "partially_refunded").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, err := database.DB.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, repository.MarkPaymentRefunded(tx, "pi_refund", "re_2", 600))
+	assert.NoError(t, tx.Commit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProratedRefundAmount(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	payment := domain.Payment{Amount: 1000, StartAt: start, EndAt: start.AddDate(0, 0, 10)}
+
+	// Période non entamée : remboursement intégral
+	assert.Equal(t, int64(1000), service.ProratedRefundAmount(payment, 0, start.Add(-time.Hour)))
+	// 4 jours consommés sur 10
+	assert.Equal(t, int64(600), service.ProratedRefundAmount(payment, 0, start.AddDate(0, 0, 4)))
+	// Les remboursements déjà effectués sont déduits
+	assert.Equal(t, int64(100), service.ProratedRefundAmount(payment, 500, start.AddDate(0, 0, 4)))
+	assert.Equal(t, int64(0), service.ProratedRefundAmount(payment, 800, start.AddDate(0, 0, 4)))
+	// Période échue
+	assert.Equal(t, int64(0), service.ProratedRefundAmount(payment, 0, start.AddDate(0, 0, 11)))
+}
@@ -0,0 +1,47 @@
+package unit
+
+import (
+	"errors"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/service"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var loginThrottleColumns = []string{"failures", "last_failure_at", "locked_until"}
+
+func TestCheckLoginThrottleAppliesBackoffAndLockout(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	// 5 échecs (3 tolérés) : 2 secondes d'attente depuis le dernier échec
+	mock.ExpectQuery("FROM login_throttles").
+		WithArgs(domain.LoginThrottleAccount, "7").
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).AddRow(5, now.Add(-time.Second), nil))
+	err := service.CheckLoginThrottle(domain.LoginThrottleAccount, "7", now)
+	var throttled *service.LoginThrottledError
+	assert.True(t, errors.As(err, &throttled))
+	assert.False(t, throttled.Locked)
+	assert.Equal(t, time.Second, throttled.RetryAfter)
+
+	// Compte verrouillé : refusé même si le délai est écoulé
+	mock.ExpectQuery("FROM login_throttles").
+		WithArgs(domain.LoginThrottleAccount, "7").
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).AddRow(0, now.Add(-time.Hour), now.Add(10*time.Minute)))
+	err = service.CheckLoginThrottle(domain.LoginThrottleAccount, "7", now)
+	assert.True(t, errors.As(err, &throttled))
+	assert.True(t, throttled.Locked)
+
+	// Verrouillage levé : la tentative est autorisée
+	mock.ExpectQuery("FROM login_throttles").
+		WithArgs(domain.LoginThrottleAccount, "7").
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).AddRow(0, now.Add(-time.Hour), now.Add(-time.Minute)))
+	assert.NoError(t, service.CheckLoginThrottle(domain.LoginThrottleAccount, "7", now))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,62 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/service"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Secret ASCII "12345678901234567890" des vecteurs de test de la RFC 6238, encodé en base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeMatchesRFC6238Vectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range vectors {
+		code, err := service.TOTPCode(rfc6238Secret, time.Unix(unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "t=%d", unix)
+	}
+
+	// Un code du pas précédent est toléré (décalage d'horloge), pas au-delà
+	now := time.Unix(1111111109, 0)
+	step, ok := service.ValidateTOTPCode(rfc6238Secret, "081804", now.Add(30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, int64(1111111109/30), step)
+	_, ok = service.ValidateTOTPCode(rfc6238Secret, "081804", now.Add(90*time.Second))
+	assert.False(t, ok)
+}
+
+func TestRequireAdminMFABlocksUnenrolledAdmin(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM user_mfa").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	handler := middleware.RequireAdminMFA(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/dashboard", nil)
+	ctx := context.WithValue(req.Context(), middleware.ContextUserIDKey, int64(1))
+	ctx = context.WithValue(ctx, middleware.ContextUserRoleKey, "admin")
+	req = req.WithContext(ctx)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
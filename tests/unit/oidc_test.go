@@ -0,0 +1,94 @@
+package unit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"onlyflick/internal/service"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockOIDCIssuer démarre un fournisseur OpenID Connect minimal (découverte + JWKS) dont la clé Ed25519 signe les ID tokens.
+func newMockOIDCIssuer(t *testing.T) (*httptest.Server, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "mock-1", "kty": "OKP", "crv": "Ed25519", "use": "sig",
+				"x": base64.RawURLEncoding.EncodeToString(pub),
+			}},
+		})
+	})
+	return srv, priv
+}
+
+func signMockIDToken(t *testing.T, key ed25519.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = "mock-1"
+	raw, err := token.SignedString(key)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestOIDCVerifyIDToken(t *testing.T) {
+	srv, key := newMockOIDCIssuer(t)
+	provider := &service.OIDCProvider{Name: "mock", Issuer: srv.URL, ClientID: "onlyflick", RedirectURL: "http://localhost:3000/callback"}
+	now := time.Now()
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            srv.URL,
+			"aud":            "onlyflick",
+			"sub":            "mock-user-42",
+			"email":          "alice@example.com",
+			"email_verified": true,
+			"nonce":          "n-123",
+			"iat":            now.Unix(),
+			"exp":            now.Add(5 * time.Minute).Unix(),
+		}
+	}
+
+	claims, err := provider.VerifyIDToken(signMockIDToken(t, key, validClaims()), "n-123", now)
+	require.NoError(t, err)
+	assert.Equal(t, "mock-user-42", claims.Subject)
+	assert.Equal(t, "alice@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+
+	// Nonce d'une autre tentative de connexion
+	_, err = provider.VerifyIDToken(signMockIDToken(t, key, validClaims()), "autre", now)
+	assert.ErrorIs(t, err, service.ErrOIDCIDTokenInvalid)
+
+	// Jeton émis pour un autre client
+	wrongAud := validClaims()
+	wrongAud["aud"] = "autre-client"
+	_, err = provider.VerifyIDToken(signMockIDToken(t, key, wrongAud), "n-123", now)
+	assert.ErrorIs(t, err, service.ErrOIDCIDTokenInvalid)
+
+	// Jeton signé par une clé inconnue du fournisseur
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = provider.VerifyIDToken(signMockIDToken(t, otherKey, validClaims()), "n-123", now)
+	assert.ErrorIs(t, err, service.ErrOIDCIDTokenInvalid)
+}
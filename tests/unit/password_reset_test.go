@@ -0,0 +1,49 @@
+package unit
+
+import (
+	"onlyflick/internal/service"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetThrottledPerIP(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	// L'IP a déjà épuisé ses demandes : aucune tentative n'est journalisée ni aucun e-mail envoyé
+	mock.ExpectQuery("FROM password_reset_attempts").
+		WithArgs("forgot", sqlmock.AnyArg(), "203.0.113.7", now.Add(-time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"by_email", "by_ip"}).AddRow(0, 10))
+
+	err := service.RequestPasswordReset("alice@example.com", "203.0.113.7", now)
+
+	assert.ErrorIs(t, err, service.ErrPasswordResetThrottled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetPasswordRejectsUnknownToken(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	// Jeton inconnu : la tentative est comptée pour l'IP seulement et le mot de passe n'est pas modifié
+	mock.ExpectQuery("SELECT user_id FROM password_reset_tokens").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery("FROM password_reset_attempts").
+		WithArgs("reset", "", "203.0.113.7", now.Add(-time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"by_email", "by_ip"}).AddRow(0, 1))
+	mock.ExpectExec("INSERT INTO password_reset_attempts").
+		WithArgs("reset", "", "203.0.113.7", now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := service.ResetPassword("jeton-inconnu", "NouveauMotDePasse1", "203.0.113.7", now)
+
+	assert.ErrorIs(t, err, service.ErrInvalidPasswordReset)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
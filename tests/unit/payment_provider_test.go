@@ -0,0 +1,73 @@
+package unit
+
+import (
+	"onlyflick/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakePaymentProviderIsDeterministic(t *testing.T) {
+	provider := service.NewFakePaymentProvider()
+
+	customerID, err := provider.CreateCustomer(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "cus_fake_000001", customerID)
+
+	intent, err := provider.CreatePaymentIntent(service.PaymentIntentRequest{Amount: 499, Currency: "EUR"})
+	assert.NoError(t, err)
+	assert.Equal(t, "pi_fake_000001", intent.ID)
+	assert.Equal(t, service.IntentStatusRequiresPaymentMethod, intent.Status)
+
+	// Débit hors session : accepté avec une carte valide, refusé avec la carte de test dédiée
+	renewal, err := provider.CreatePaymentIntent(service.PaymentIntentRequest{Amount: 499, Currency: "EUR", PaymentMethodID: "pm_fake_visa", OffSession: true})
+	assert.NoError(t, err)
+	assert.Equal(t, service.IntentStatusSucceeded, renewal.Status)
+
+	declined, err := provider.CreatePaymentIntent(service.PaymentIntentRequest{Amount: 499, Currency: "EUR", PaymentMethodID: service.FakeDeclinedPaymentMethod, OffSession: true})
+	assert.ErrorIs(t, err, service.ErrFakeCardDeclined)
+	assert.Equal(t, "pi_fake_000003", declined.ID)
+
+	// Remboursement partiel puis dépassement du montant restant
+	refund, err := provider.RefundPayment(renewal.ID, 200)
+	assert.NoError(t, err)
+	assert.Equal(t, "re_fake_000001", refund.ID)
+
+	_, err = provider.RefundPayment(renewal.ID, 300)
+	assert.ErrorIs(t, err, service.ErrFakeRefundExceedsAmount)
+}
+
+func TestFakePaymentProviderReplaysIdempotencyKey(t *testing.T) {
+	provider := service.NewFakePaymentProvider()
+
+	req := service.PaymentIntentRequest{Amount: 499, Currency: "EUR", PaymentMethodID: "pm_fake_visa", OffSession: true, IdempotencyKey: "renewal:5:1780000000:0"}
+	first, err := provider.CreatePaymentIntent(req)
+	assert.NoError(t, err)
+
+	// Même clé (reprise après un arrêt) : aucun second débit
+	replay, err := provider.CreatePaymentIntent(req)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, replay.ID)
+
+	// Nouvel essai après un refus enregistré : nouvelle clé, nouveau débit
+	req.IdempotencyKey = "renewal:5:1780000000:1"
+	retry, err := provider.CreatePaymentIntent(req)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.ID, retry.ID)
+}
+
+func TestFakePaymentProviderWebhookSignature(t *testing.T) {
+	provider := service.NewFakePaymentProvider()
+	intent, _ := provider.CreatePaymentIntent(service.PaymentIntentRequest{Amount: 499, Currency: "EUR"})
+
+	payload, signature, err := provider.CompleteIntent(intent.ID, "pm_fake_visa")
+	assert.NoError(t, err)
+
+	event, err := provider.VerifyWebhook(payload, signature)
+	assert.NoError(t, err)
+	assert.Equal(t, service.StripeEventPaymentSucceeded, event.Type)
+	assert.Contains(t, string(event.Data), intent.ID)
+
+	_, err = provider.VerifyWebhook(payload, "t=1,v1=deadbeef")
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/handler"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/policy"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyCan(t *testing.T) {
+	var (
+		guest      = policy.Actor{}
+		subscriber = policy.Actor{UserID: 1, Role: domain.RoleSubscriber}
+		creator    = policy.Actor{UserID: 2, Role: domain.RoleCreator}
+		other      = policy.Actor{UserID: 3, Role: domain.RoleCreator}
+		admin      = policy.Actor{UserID: 10, Role: domain.RoleAdmin}
+		moderator  = policy.Actor{UserID: 11, Role: domain.RoleAdmin, AdminRole: domain.AdminRoleModerator}
+		finance    = policy.Actor{UserID: 12, Role: domain.RoleAdmin, AdminRole: domain.AdminRoleFinance}
+		support    = policy.Actor{UserID: 13, Role: domain.RoleAdmin, AdminRole: domain.AdminRoleSupport}
+	)
+	ownedByCreator := policy.Resource{OwnerID: creator.UserID}
+	conversation := policy.Resource{OwnerID: creator.UserID, ParticipantIDs: []int64{creator.UserID, subscriber.UserID}}
+	subscribedConversation := conversation
+	subscribedConversation.Subscribed = true
+
+	cases := []struct {
+		name     string
+		actor    policy.Actor
+		action   policy.Action
+		resource policy.Resource
+		want     bool
+	}{
+		// Posts
+		{"visiteur ne publie pas", guest, policy.PostCreate, policy.None, false},
+		{"abonné ne publie pas", subscriber, policy.PostCreate, policy.None, false},
+		{"créateur publie", creator, policy.PostCreate, policy.None, true},
+		{"admin complet publie", admin, policy.PostCreate, policy.None, true},
+		{"modérateur ne publie pas", moderator, policy.PostCreate, policy.None, false},
+		{"auteur modifie son post", creator, policy.PostUpdate, ownedByCreator, true},
+		{"autre créateur ne modifie pas", other, policy.PostUpdate, ownedByCreator, false},
+		{"modérateur ne modifie pas", moderator, policy.PostUpdate, ownedByCreator, false},
+		{"admin complet modifie", admin, policy.PostUpdate, ownedByCreator, true},
+		{"auteur supprime son post", creator, policy.PostDelete, ownedByCreator, true},
+		{"autre créateur ne supprime pas", other, policy.PostDelete, ownedByCreator, false},
+		{"modérateur supprime un post", moderator, policy.PostDelete, ownedByCreator, true},
+		{"finance ne supprime pas de post", finance, policy.PostDelete, ownedByCreator, false},
+		{"contenu abonné : non abonné", subscriber, policy.PostViewSubscriberContent, ownedByCreator, false},
+		{"contenu abonné : abonné", subscriber, policy.PostViewSubscriberContent, policy.Resource{OwnerID: creator.UserID, Subscribed: true}, true},
+		{"contenu abonné : créateur", creator, policy.PostViewSubscriberContent, ownedByCreator, true},
+		{"contenu abonné : modérateur", moderator, policy.PostViewSubscriberContent, ownedByCreator, true},
+		{"contenu abonné : support", support, policy.PostViewSubscriberContent, ownedByCreator, false},
+
+		// Commentaires
+		{"visiteur ne commente pas", guest, policy.CommentCreate, policy.None, false},
+		{"abonné commente", subscriber, policy.CommentCreate, policy.None, true},
+		{"auteur supprime son commentaire", subscriber, policy.CommentDelete, policy.Resource{OwnerID: subscriber.UserID}, true},
+		{"autre ne supprime pas le commentaire", other, policy.CommentDelete, policy.Resource{OwnerID: subscriber.UserID}, false},
+		{"modérateur supprime un commentaire", moderator, policy.CommentDelete, policy.Resource{OwnerID: subscriber.UserID}, true},
+		{"support ne supprime pas de commentaire", support, policy.CommentDelete, policy.Resource{OwnerID: subscriber.UserID}, false},
+
+		// Médias
+		{"créateur envoie un média", creator, policy.MediaUpload, policy.None, true},
+		{"abonné n'envoie pas de média", subscriber, policy.MediaUpload, policy.None, false},
+		{"propriétaire supprime son média", creator, policy.MediaDelete, ownedByCreator, true},
+		{"autre créateur ne supprime pas le média", other, policy.MediaDelete, ownedByCreator, false},
+		{"modérateur supprime un média", moderator, policy.MediaDelete, ownedByCreator, true},
+		{"finance ne supprime pas de média", finance, policy.MediaDelete, ownedByCreator, false},
+
+		// Conversations
+		{"abonné démarre avec un créateur", subscriber, policy.ConversationStart, policy.Resource{OwnerID: creator.UserID, OwnerRole: domain.RoleCreator, Subscribed: true}, true},
+		{"non abonné ne démarre pas", subscriber, policy.ConversationStart, policy.Resource{OwnerID: creator.UserID, OwnerRole: domain.RoleCreator}, false},
+		{"pas de conversation avec un abonné", creator, policy.ConversationStart, policy.Resource{OwnerID: subscriber.UserID, OwnerRole: domain.RoleSubscriber, Subscribed: true}, false},
+		{"pas de conversation avec soi-même", creator, policy.ConversationStart, policy.Resource{OwnerID: creator.UserID, OwnerRole: domain.RoleCreator, Subscribed: true}, false},
+		{"participant lit", subscriber, policy.ConversationRead, conversation, true},
+		{"non participant ne lit pas", other, policy.ConversationRead, conversation, false},
+		{"admin ne lit pas les messages privés", admin, policy.ConversationRead, conversation, false},
+		{"créateur répond", creator, policy.ConversationSend, conversation, true},
+		{"abonné expiré n'écrit plus", subscriber, policy.ConversationSend, conversation, false},
+		{"abonné écrit", subscriber, policy.ConversationSend, subscribedConversation, true},
+		{"non participant n'écrit pas", other, policy.ConversationSend, subscribedConversation, false},
+
+		// Signalements
+		{"abonné signale", subscriber, policy.ReportCreate, policy.None, true},
+		{"visiteur ne signale pas", guest, policy.ReportCreate, policy.None, false},
+		{"créateur ne lit pas les signalements", creator, policy.ReportRead, policy.None, false},
+		{"modérateur lit les signalements", moderator, policy.ReportRead, policy.None, true},
+		{"modérateur traite les signalements", moderator, policy.ReportModerate, policy.None, true},
+		{"finance ne traite pas les signalements", finance, policy.ReportModerate, policy.None, false},
+		{"admin complet traite les signalements", admin, policy.ReportModerate, policy.None, true},
+
+		// Administration
+		{"finance rembourse", finance, policy.PaymentRefund, policy.None, true},
+		{"support ne rembourse pas", support, policy.PaymentRefund, policy.None, false},
+		{"finance gère les versements", finance, policy.PayoutManage, policy.None, true},
+		{"modérateur ne gère pas les versements", moderator, policy.PayoutManage, policy.None, false},
+		{"support déverrouille un compte", support, policy.UserSecurityManage, policy.None, true},
+		{"modérateur suspend un compte", moderator, policy.UserSuspend, policy.None, true},
+		{"finance ne suspend pas de compte", finance, policy.UserSuspend, policy.None, false},
+		{"support traite les demandes créateur", support, policy.CreatorRequestReview, policy.None, true},
+		{"support ne supprime pas de compte", support, policy.UserDelete, policy.None, false},
+		{"admin complet supprime un compte", admin, policy.UserDelete, policy.None, true},
+		{"seul l'admin complet gère les périmètres", moderator, policy.AdminRoleManage, policy.None, false},
+		{"admin complet gère les périmètres", admin, policy.AdminRoleManage, policy.None, true},
+		{"seul l'admin complet lit le journal d'audit", finance, policy.AuditRead, policy.None, false},
+		{"admin complet lit le journal d'audit", admin, policy.AuditRead, policy.None, true},
+		{"tout périmètre voit le tableau de bord", finance, policy.AdminDashboard, policy.None, true},
+		{"créateur n'a pas de tableau de bord", creator, policy.AdminDashboard, policy.None, false},
+
+		// Compte
+		{"abonné désactive sa MFA", subscriber, policy.MFADisable, policy.None, true},
+		{"admin ne désactive pas sa MFA", support, policy.MFADisable, policy.None, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, policy.Can(tc.actor, tc.action, tc.resource))
+		})
+	}
+}
+
+func TestDeleteMediaRequiresOwnership(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// Le fichier appartient à l'utilisateur 7
+	mock.ExpectQuery("SELECT user_id FROM media_files").
+		WithArgs("file_abc").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(7)))
+
+	req := httptest.NewRequest(http.MethodDelete, "/media/file_abc", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("file_id", "file_abc")
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, middleware.ContextUserIDKey, int64(2))
+	ctx = context.WithValue(ctx, middleware.ContextUserRoleKey, "creator")
+	rr := httptest.NewRecorder()
+	handler.DeleteMedia(rr, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMediaRefusesCreatorOnFileWithoutOwner(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// Fichier du post d'un autre créateur sans propriétaire enregistré : seul un modérateur peut le supprimer
+	mock.ExpectQuery("SELECT user_id FROM media_files").
+		WithArgs("file_other").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/media/file_other", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("file_id", "file_other")
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, middleware.ContextUserIDKey, int64(2))
+	ctx = context.WithValue(ctx, middleware.ContextUserRoleKey, "creator")
+	rr := httptest.NewRecorder()
+	handler.DeleteMedia(rr, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,40 @@
+package unit
+
+import (
+	"onlyflick/internal/domain"
+	"onlyflick/internal/service"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostLockHidesMedia(t *testing.T) {
+	post := &domain.Post{ID: 1, MediaURL: "https://cdn/photo.jpg", FileID: "f1", Visibility: domain.PayPerView, Price: 499}
+	post.Lock()
+
+	assert.True(t, post.Locked)
+	assert.Empty(t, post.MediaURL)
+	assert.Empty(t, post.FileID)
+	assert.Equal(t, 499, post.Price)
+}
+
+func TestStartPostPurchaseRejected(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	_, err := service.StartPostPurchase(&domain.Post{ID: 1, UserID: 2, Visibility: domain.SubscriberOnly}, 3)
+	assert.ErrorIs(t, err, service.ErrPostNotForSale)
+
+	// L'auteur a déjà accès à son post
+	ppv := &domain.Post{ID: 1, UserID: 2, Visibility: domain.PayPerView, Price: 499}
+	_, err = service.StartPostPurchase(ppv, 2)
+	assert.ErrorIs(t, err, service.ErrPostAlreadyUnlocked)
+
+	// Achat déjà réglé : aucun nouveau paiement n'est créé
+	mock.ExpectQuery("SELECT EXISTS.*FROM post_purchases").WithArgs(int64(1), int64(3), "succeeded", "partially_refunded").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	_, err = service.StartPostPurchase(ppv, 3)
+	assert.ErrorIs(t, err, service.ErrPostAlreadyUnlocked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"database/sql"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var tierColumns = []string{"id", "creator_id", "name", "description", "price", "active", "created_at"}
+
+func TestResolveSubscriptionPriceDefault(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	creatorID := int64(7)
+
+	// Aucun tarif configuré : le tarif par défaut s'applique
+	mock.ExpectQuery("SELECT price, currency, updated_at, trial_days FROM creator_pricing").
+		WithArgs(creatorID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("FROM subscription_tiers").
+		WithArgs(creatorID).
+		WillReturnRows(sqlmock.NewRows(tierColumns))
+
+	amount, currency, err := repository.ResolveSubscriptionPrice(creatorID, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DefaultSubscriptionPrice, amount)
+	assert.Equal(t, "EUR", currency)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveSubscriptionPriceWithTier(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	creatorID := int64(7)
+	vipTier := int64(3)
+	unknownTier := int64(99)
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SELECT price, currency, updated_at, trial_days FROM creator_pricing").
+			WithArgs(creatorID).
+			WillReturnRows(sqlmock.NewRows([]string{"price", "currency", "updated_at", "trial_days"}).AddRow(900, "USD", time.Now(), 0))
+		mock.ExpectQuery("FROM subscription_tiers").
+			WithArgs(creatorID).
+			WillReturnRows(sqlmock.NewRows(tierColumns).
+				AddRow(int64(2), creatorID, "Basic", "", 500, true, time.Now()).
+				AddRow(vipTier, creatorID, "VIP", "Accès complet", 1999, true, time.Now()))
+	}
+
+	amount, currency, err := repository.ResolveSubscriptionPrice(creatorID, &vipTier)
+	assert.NoError(t, err)
+	assert.Equal(t, 1999, amount)
+	assert.Equal(t, "USD", currency)
+
+	_, _, err = repository.ResolveSubscriptionPrice(creatorID, &unknownTier)
+	assert.ErrorIs(t, err, repository.ErrTierNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFormatPrice(t *testing.T) {
+	assert.Equal(t, "4.99", domain.FormatPrice(499))
+	assert.Equal(t, "10.00", domain.FormatPrice(1000))
+	assert.Equal(t, "0.05", domain.FormatPrice(5))
+}
+
+func TestPromoCodeApply(t *testing.T) {
+	percent := domain.PromoCode{DiscountType: domain.DiscountPercent, DiscountValue: 50}
+	fixed := domain.PromoCode{DiscountType: domain.DiscountFixed, DiscountValue: 300}
+	full := domain.PromoCode{DiscountType: domain.DiscountPercent, DiscountValue: 100}
+
+	assert.Equal(t, 249, percent.Apply(499))
+	assert.Equal(t, 199, fixed.Apply(499))
+	assert.Equal(t, 0, full.Apply(499))
+	// Un montant réduit non nul ne descend pas sous le minimum débitable
+	assert.Equal(t, domain.MinChargeAmount, fixed.Apply(320))
+	assert.Equal(t, 0, fixed.Apply(250))
+}
+
+func TestPromoCodeRedeemable(t *testing.T) {
+	now := time.Now()
+	limit := 2
+	expired := now.Add(-time.Minute)
+
+	promo := domain.PromoCode{Active: true, MaxRedemptions: &limit, RedemptionsCount: 1}
+	assert.True(t, promo.Redeemable(now))
+
+	promo.RedemptionsCount = 2
+	assert.False(t, promo.Redeemable(now))
+
+	promo = domain.PromoCode{Active: true, ExpiresAt: &expired}
+	assert.False(t, promo.Redeemable(now))
+
+	promo = domain.PromoCode{Active: false}
+	assert.False(t, promo.Redeemable(now))
+}
@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var refreshTokenColumns = []string{"id", "session_id", "expires_at", "rotated_at", "user_id", "revoked_at"}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	// Le token présenté a déjà été échangé : la session entière est révoquée
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rt.id, rt.session_id.*FROM refresh_tokens rt").
+		WithArgs("ancien-hash").
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).AddRow(int64(1), int64(7), now.Add(time.Hour), now.Add(-time.Minute), int64(3), nil))
+	mock.ExpectExec("UPDATE auth_sessions.*SET revoked_at = NOW\\(\\)").
+		WithArgs(int64(7), "reuse").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, _, err := repository.RotateRefreshToken("ancien-hash", "nouveau-hash", now.Add(24*time.Hour), now, domain.SessionClient{})
+
+	assert.ErrorIs(t, err, repository.ErrRefreshTokenReused)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidateJWTRejectsRevokedSession(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO auth_sessions").WithArgs(int64(3), "Mozilla/5.0", "203.0.113.7").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	mock.ExpectExec("INSERT INTO refresh_tokens").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tokens, err := service.IssueSessionTokens(3, "subscriber", domain.SessionClient{UserAgent: "Mozilla/5.0", IPAddress: "203.0.113.7"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	// Session fermée entre-temps (déconnexion) : l'access token n'est plus accepté
+	mock.ExpectQuery("SELECT EXISTS.*FROM auth_sessions").WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	token, err := service.ValidateJWT(tokens.AccessToken)
+	assert.ErrorIs(t, err, service.ErrSessionRevoked)
+	assert.False(t, token.Valid)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJWTMiddlewareRecordsSessionActivity(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO auth_sessions").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	mock.ExpectExec("INSERT INTO refresh_tokens").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tokens, err := service.IssueSessionTokens(3, "subscriber", domain.SessionClient{})
+	assert.NoError(t, err)
+
+	// Requête authentifiée : l'activité de la session est enregistrée, au plus une fois par intervalle
+	mock.ExpectQuery("SELECT EXISTS.*FROM auth_sessions").WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("FROM user_suspensions").WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectExec("UPDATE auth_sessions.*SET last_seen_at.*last_seen_at <= \\$4").
+		WithArgs(int64(7), sqlmock.AnyArg(), "203.0.113.7", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := httptest.NewRequest(http.MethodGet, "/profile/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	req.RemoteAddr = "203.0.113.7:4321"
+	rr := httptest.NewRecorder()
+	middleware.JWTMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, int64(7), r.Context().Value(middleware.ContextSessionIDKey))
+	})).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
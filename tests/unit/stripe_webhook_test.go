@@ -0,0 +1,192 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"onlyflick/internal/handler"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stripe/stripe-go/webhook"
+)
+
+const testWebhookSecret = "whsec_test_onlyflick"
+
+// signStripePayload construit un en-tête Stripe-Signature valide pour une fixture locale
+func signStripePayload(payload []byte, secret string, at time.Time) string {
+	signature := webhook.ComputeSignature(at, payload, secret)
+	return fmt.Sprintf("t=%d,v1=%s", at.Unix(), hex.EncodeToString(signature))
+}
+
+// stripeEventFixture retourne le JSON d'un événement Stripe minimal
+func stripeEventFixture(eventID, eventType string, object map[string]interface{}) []byte {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"id":     eventID,
+		"object": "event",
+		"type":   eventType,
+		"data":   map[string]interface{}{"object": object},
+	})
+	return payload
+}
+
+func postStripeWebhook(payload []byte, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", bytes.NewReader(payload))
+	req.Header.Set("Stripe-Signature", signature)
+	rr := httptest.NewRecorder()
+	handler.StripeWebhookHandler(rr, req)
+	return rr
+}
+
+func TestStripeWebhookPaymentSucceeded(t *testing.T) {
+	os.Setenv("STRIPE_WEBHOOK_SECRET", testWebhookSecret)
+	defer os.Unsetenv("STRIPE_WEBHOOK_SECRET")
+
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	payload := stripeEventFixture("evt_succeeded_1", "payment_intent.succeeded", map[string]interface{}{
+		"id":             "pi_123",
+		"object":         "payment_intent",
+		"status":         "succeeded",
+		"payment_method": "pm_card_visa",
+	})
+	endAt := time.Now().AddDate(0, 1, 0)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO stripe_events").
+		WithArgs("evt_succeeded_1", "payment_intent.succeeded").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE payments.*RETURNING id, subscription_id, end_at").
+		WithArgs("pi_123", "succeeded", "pending").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "end_at"}).AddRow(int64(7), int64(42), endAt))
+	mock.ExpectExec("UPDATE subscriptions.*SET status = TRUE").
+		WithArgs(int64(42), endAt, "pm_card_visa").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPaymentLedgerPosting(mock, 7, 2, 499, 0)
+	mock.ExpectCommit()
+
+	rr := postStripeWebhook(payload, signStripePayload(payload, testWebhookSecret, time.Now()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "processed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStripeWebhookLateSucceededDoesNotRestoreRefundedPayment(t *testing.T) {
+	os.Setenv("STRIPE_WEBHOOK_SECRET", testWebhookSecret)
+	defer os.Unsetenv("STRIPE_WEBHOOK_SECRET")
+
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	payload := stripeEventFixture("evt_succeeded_late", "payment_intent.succeeded", map[string]interface{}{
+		"id":     "pi_refunded",
+		"object": "payment_intent",
+		"status": "succeeded",
+	})
+
+	// Le paiement n'est plus en attente (remboursé) : ni abonnement ni grand livre ne sont touchés
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO stripe_events").
+		WithArgs("evt_succeeded_late", "payment_intent.succeeded").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE payments.*RETURNING id, subscription_id, end_at").
+		WithArgs("pi_refunded", "succeeded", "pending").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "end_at"}))
+	mock.ExpectCommit()
+
+	rr := postStripeWebhook(payload, signStripePayload(payload, testWebhookSecret, time.Now()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStripeWebhookPaymentFailedReleasesPromoRedemption(t *testing.T) {
+	os.Setenv("STRIPE_WEBHOOK_SECRET", testWebhookSecret)
+	defer os.Unsetenv("STRIPE_WEBHOOK_SECRET")
+
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	payload := stripeEventFixture("evt_failed_2", "payment_intent.payment_failed", map[string]interface{}{
+		"id":     "pi_declined",
+		"object": "payment_intent",
+	})
+
+	// Le code promo enregistré avec le paiement en attente redevient disponible
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO stripe_events").
+		WithArgs("evt_failed_2", "payment_intent.payment_failed").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE payments.*RETURNING id").
+		WithArgs("pi_declined", "failed", "pending").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(9)))
+	mock.ExpectExec("DELETE FROM promo_code_redemptions WHERE payment_id.*UPDATE promo_codes SET redemptions_count").
+		WithArgs(int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	rr := postStripeWebhook(payload, signStripePayload(payload, testWebhookSecret, time.Now()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStripeWebhookDuplicateEventIsIgnored(t *testing.T) {
+	os.Setenv("STRIPE_WEBHOOK_SECRET", testWebhookSecret)
+	defer os.Unsetenv("STRIPE_WEBHOOK_SECRET")
+
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	payload := stripeEventFixture("evt_failed_1", "payment_intent.payment_failed", map[string]interface{}{
+		"id":     "pi_456",
+		"object": "payment_intent",
+	})
+
+	// L'événement est déjà présent : aucune mise à jour des paiements ne doit être faite
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO stripe_events").
+		WithArgs("evt_failed_1", "payment_intent.payment_failed").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	rr := postStripeWebhook(payload, signStripePayload(payload, testWebhookSecret, time.Now()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "duplicate")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStripeWebhookRejectsInvalidSignature(t *testing.T) {
+	os.Setenv("STRIPE_WEBHOOK_SECRET", testWebhookSecret)
+	defer os.Unsetenv("STRIPE_WEBHOOK_SECRET")
+
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	payload := stripeEventFixture("evt_refund_1", "charge.refunded", map[string]interface{}{
+		"id":              "ch_1",
+		"object":          "charge",
+		"amount":          499,
+		"amount_refunded": 499,
+		"payment_intent":  "pi_789",
+	})
+
+	// Signature calculée avec un autre secret
+	rr := postStripeWebhook(payload, signStripePayload(payload, "whsec_wrong", time.Now()))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Signature trop ancienne (hors tolérance)
+	rr = postStripeWebhook(payload, signStripePayload(payload, testWebhookSecret, time.Now().Add(-time.Hour)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
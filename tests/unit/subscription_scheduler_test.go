@@ -0,0 +1,138 @@
+package unit
+
+import (
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerConfigFromEnv(t *testing.T) {
+	os.Setenv("SUBSCRIPTION_SCHEDULER_INTERVAL", "15m")
+	os.Setenv("SUBSCRIPTION_GRACE_PERIOD", "48h")
+	os.Setenv("SUBSCRIPTION_RETRY_BACKOFF", "1h, 6h")
+	defer func() {
+		os.Unsetenv("SUBSCRIPTION_SCHEDULER_INTERVAL")
+		os.Unsetenv("SUBSCRIPTION_GRACE_PERIOD")
+		os.Unsetenv("SUBSCRIPTION_RETRY_BACKOFF")
+	}()
+
+	cfg := service.LoadSchedulerConfig()
+
+	assert.Equal(t, 15*time.Minute, cfg.Interval)
+	assert.Equal(t, 48*time.Hour, cfg.GracePeriod)
+	assert.Equal(t, 3, cfg.MaxRenewalAttempts())
+}
+
+func TestSchedulerDunningBackoff(t *testing.T) {
+	cfg := service.SchedulerConfig{RetryBackoff: []time.Duration{24 * time.Hour, 72 * time.Hour}}
+
+	delay, ok := cfg.NextRetryDelay(1)
+	assert.True(t, ok)
+	assert.Equal(t, 24*time.Hour, delay)
+
+	delay, ok = cfg.NextRetryDelay(2)
+	assert.True(t, ok)
+	assert.Equal(t, 72*time.Hour, delay)
+
+	// Toutes les relances sont épuisées : plus de nouvel essai
+	_, ok = cfg.NextRetryDelay(3)
+	assert.False(t, ok)
+}
+
+func TestRunSubscriptionMaintenanceWithoutPaymentMethod(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	endAt := now.Add(-time.Hour)
+	cfg := service.SchedulerConfig{GracePeriod: 72 * time.Hour, RetryBackoff: []time.Duration{24 * time.Hour}, BatchSize: 10}
+
+	mock.ExpectQuery("FOR UPDATE OF s SKIP LOCKED.*SET renewal_claimed_until").
+		WithArgs(now, cfg.MaxRenewalAttempts(), "pending", 10, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subscriber_id", "creator_id", "end_at", "tier_id", "renewal_attempts", "payment_method_id", "stripe_customer_id", "promo_code_id"}).
+			AddRow(int64(5), int64(1), int64(2), endAt, nil, 0, "", "", nil))
+
+	// Tarif par défaut du créateur
+	mock.ExpectQuery("FROM creator_pricing").WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"price", "currency", "updated_at", "trial_days"}))
+	mock.ExpectQuery("FROM subscription_tiers").WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows(tierColumns))
+
+	// La tentative est enregistrée comme échouée puis replanifiée
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO payments").
+		WithArgs(int64(5), "", "1", endAt, endAt.AddDate(0, 1, 0), 499, "failed").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(int64(9), now))
+	mock.ExpectCommit()
+	mock.ExpectExec("UPDATE subscriptions.*renewal_attempts = renewal_attempts \\+ 1").
+		WithArgs(int64(5), now.Add(24*time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec("UPDATE subscriptions.*SET status = FALSE").
+		WithArgs(now.Add(-72 * time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	service.RunSubscriptionMaintenance(now, cfg)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunMaintenanceCleanupPurgesExpiredData(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	cfg := service.CleanupConfig{Interval: time.Hour, BatchSize: 10}
+
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE expires_at").
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM password_reset_tokens").
+		WithArgs(now.Add(-24 * time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM password_reset_attempts").
+		WithArgs(now.Add(-24 * time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM mfa_challenges").
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM login_throttles").
+		WithArgs(now.Add(-24*time.Hour), now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM oidc_auth_states").
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id FROM users").
+		WithArgs(now.Add(-service.AccountDeletionGracePeriod), cfg.BatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	service.RunMaintenanceCleanup(now, cfg)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCleanupConfigCannotBeDisabled(t *testing.T) {
+	t.Setenv("MAINTENANCE_CLEANUP_INTERVAL", "0")
+
+	assert.Equal(t, time.Hour, service.LoadCleanupConfig().Interval)
+}
+
+func TestIsSubscribedHonoursEndAt(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT EXISTS.*status = TRUE AND end_at > NOW\\(\\)").
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	subscribed, err := repository.IsSubscribed(1, 2)
+
+	assert.NoError(t, err)
+	assert.False(t, subscribed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,89 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"onlyflick/internal/domain"
+	"onlyflick/internal/middleware"
+	"onlyflick/internal/repository"
+	"onlyflick/internal/service"
+	"onlyflick/pkg/response"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var suspensionRowColumns = []string{"id", "user_id", "admin_id", "reason", "permanent", "ends_at", "created_at", "lifted_at", "lifted_by"}
+
+func TestSuspendedUserIsRejectedByMiddleware(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	endsAt := now.Add(72 * time.Hour)
+	expectAccessTokenLookup(mock, "creator", "stats:read")
+	mock.ExpectQuery("FROM user_suspensions").
+		WithArgs(int64(42), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(suspensionRowColumns).AddRow(int64(1), int64(42), int64(9), "Spam", false, endsAt, now, nil, nil))
+
+	called := false
+	handler := middleware.JWTMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/profile/stats", nil)
+	req.Header.Set("Authorization", "Bearer ofp_secret")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var body response.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, service.SuspendedErrorCode, body.Code)
+	assert.Contains(t, body.Message, "Spam")
+	assert.False(t, called)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSuspendUserValidation(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name      string
+		reason    string
+		duration  time.Duration
+		permanent bool
+	}{
+		{"motif vide", "  ", time.Hour, false},
+		{"durée manquante", "Spam", 0, false},
+		{"durée et définitive", "Spam", time.Hour, true},
+		{"durée négative", "Spam", -time.Hour, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.SuspendUser(domain.AuditActor{ActorID: 1}, 2, tc.reason, tc.duration, tc.permanent, now)
+			assert.ErrorIs(t, err, service.ErrSuspensionInvalid)
+		})
+	}
+
+	_, err := service.SuspendUser(domain.AuditActor{ActorID: 1}, 1, "Spam", time.Hour, false, now)
+	assert.ErrorIs(t, err, service.ErrSuspensionForbidden)
+}
+
+func TestLiftSuspensionWithoutActiveSuspension(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE user_suspensions SET lifted_at").
+		WithArgs(int64(42), now, int64(9)).
+		WillReturnRows(sqlmock.NewRows(suspensionRowColumns))
+	mock.ExpectRollback()
+
+	_, err := service.LiftUserSuspension(domain.AuditActor{ActorID: 9}, 42, now)
+	assert.ErrorIs(t, err, repository.ErrSuspensionNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
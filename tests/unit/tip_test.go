@@ -0,0 +1,34 @@
+package unit
+
+import (
+	"onlyflick/internal/domain"
+	"onlyflick/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartTipRejectsInvalidTips(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	tests := []struct {
+		name string
+		tip  domain.Tip
+		err  error
+	}{
+		{"montant trop faible", domain.Tip{SenderID: 1, CreatorID: 2, Amount: domain.MinTipAmount - 1}, service.ErrInvalidTip},
+		{"montant trop élevé", domain.Tip{SenderID: 1, CreatorID: 2, Amount: domain.MaxTipAmount + 1}, service.ErrInvalidTip},
+		{"pourboire à soi-même", domain.Tip{SenderID: 2, CreatorID: 2, Amount: 500}, service.ErrTipSelf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.StartTip(&tt.tip)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	// Aucune requête ni paiement pour un pourboire rejeté
+	assert.NoError(t, mock.ExpectationsWereMet())
+}